@@ -3,6 +3,7 @@
 package main
 
 import (
+	"fmt"
 	"syscall/js"
 
 	"fixture-generator/pkg/generator"
@@ -20,7 +21,12 @@ func generateFixtures(this js.Value, args []js.Value) interface{} {
 		}
 	}
 
-	source := args[0].String()
+	sources, err := jsSources(args[0])
+	if err != nil {
+		return map[string]interface{}{
+			"error": err.Error(),
+		}
+	}
 	pkgName := args[1].String()
 
 	opts := generator.GenerateOptions{
@@ -35,17 +41,60 @@ func generateFixtures(this js.Value, args []js.Value) interface{} {
 	if len(args) >= 5 {
 		opts.ModStyle = args[4].Bool()
 	}
+	// Import path of the Type Prefix package, which can't be told from the pasted source
+	if len(args) >= 6 && args[5].String() != "" {
+		opts.TypeImport = args[5].String()
+	}
 
-	model, err := generator.ParseSource(source)
+	model, err := generator.ParseSources(sources)
 	if err != nil {
 		return map[string]interface{}{
 			"error": err.Error(),
 		}
 	}
 
-	result, _ := generator.GenerateFormattedWithOptions(model, pkgName, opts)
+	result, err := generator.GenerateFormattedWithOptions(model, pkgName, opts)
+	if err != nil {
+		// Code gofmt rejects is still returned, for the error to be found in
+		return map[string]interface{}{
+			"output": result,
+			"error":  err.Error(),
+		}
+	}
 
 	return map[string]interface{}{
 		"output": result,
 	}
 }
+
+// jsSources returns the source files passed as the first argument: the source code of a single file,
+// or an array of several files of one package, each the source code or a {name, source} object
+func jsSources(v js.Value) (map[string]string, error) {
+	if v.Type() == js.TypeString {
+		return map[string]string{"input.go": v.String()}, nil
+	}
+	if !v.InstanceOf(js.Global().Get("Array")) {
+		return nil, fmt.Errorf("expected the source code or an array of source files")
+	}
+	sources := make(map[string]string, v.Length())
+	for i := 0; i < v.Length(); i++ {
+		file := v.Index(i)
+		name, source := fmt.Sprintf("input%d.go", i+1), ""
+		switch file.Type() {
+		case js.TypeString:
+			source = file.String()
+		case js.TypeObject:
+			if n := file.Get("name"); n.Type() == js.TypeString && n.String() != "" {
+				name = n.String()
+			}
+			source = file.Get("source").String()
+		default:
+			return nil, fmt.Errorf("source file %d: expected the source code or a {name, source} object", i+1)
+		}
+		if _, ok := sources[name]; ok {
+			return nil, fmt.Errorf("source file %s given twice", name)
+		}
+		sources[name] = source
+	}
+	return sources, nil
+}
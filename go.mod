@@ -5,6 +5,7 @@ go 1.24.5
 require (
 	golang.org/x/tools v0.40.0
 	google.golang.org/protobuf v1.36.11
+	sigs.k8s.io/yaml v1.4.0
 )
 
 require (
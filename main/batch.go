@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/go/packages"
+	"sigs.k8s.io/yaml"
+)
+
+// batchConfig is the JSON or YAML file read by `fixture-generator batch -config <file>`
+type batchConfig struct {
+	// Jobs limits how many targets are generated at the same time (default: number of CPUs)
+	Jobs int `json:"jobs"`
+	// FailOn and WarnOn are the exit policy of targets that don't set their own
+	FailOn  string   `json:"failOn"`
+	WarnOn  string   `json:"warnOn"`
+	Targets []target `json:"targets"`
+}
+
+// batchResult is the outcome of generating a single batch target
+type batchResult struct {
+	Target  target
+	Skipped bool
+	Err     error
+}
+
+// batchCommand implements `fixture-generator batch`, which generates every target of a config file
+func batchCommand(fs *flag.FlagSet) func() int {
+	configPath := fs.String("config", "", "path to the JSON or YAML batch config file")
+	jobs := fs.Int("j", 0, "number of targets to generate in parallel (overrides the config file)")
+	failOn := fs.String("fail-on", "", "comma-separated conditions that fail a target: load, skipped, format, drift or all (overrides the config file)")
+	warnOn := fs.String("warn-on", "", "comma-separated conditions that only print a warning (overrides the config file)")
+	return func() int {
+		return runBatch(*configPath, *jobs, *failOn, *warnOn)
+	}
+}
+
+func runBatch(configPath string, jobs int, failOn, warnOn string) int {
+	if configPath == "" {
+		fmt.Fprintln(os.Stderr, "error: -config flag is required")
+		return 1
+	}
+
+	cfg, err := readBatchConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	if jobs > 0 {
+		cfg.Jobs = jobs
+	}
+	for i := range cfg.Targets {
+		if failOn != "" {
+			cfg.Targets[i].FailOn = failOn
+		}
+		if warnOn != "" {
+			cfg.Targets[i].WarnOn = warnOn
+		}
+		if err := cfg.Targets[i].validatePolicy(); err != nil {
+			fmt.Fprintf(os.Stderr, "error: target %d: %v\n", i, err)
+			return 1
+		}
+	}
+	if cfg.Jobs <= 0 {
+		cfg.Jobs = runtime.NumCPU()
+	}
+
+	results := runTargets(cfg.Targets, cfg.Jobs, newLoadCache(load).load)
+	return printBatchReport(results)
+}
+
+func readBatchConfig(path string) (*batchConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if ext := filepath.Ext(path); ext == ".yaml" || ext == ".yml" {
+		if data, err = yaml.YAMLToJSON(data); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	var cfg batchConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	// Targets start from the options set through the environment, which their config overrides
+	var raw struct {
+		Targets []json.RawMessage `json:"targets"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for i, r := range raw.Targets {
+		t, err := envTarget()
+		if err != nil {
+			return nil, err
+		}
+		if cfg.FailOn != "" {
+			t.FailOn = cfg.FailOn
+		}
+		if cfg.WarnOn != "" {
+			t.WarnOn = cfg.WarnOn
+		}
+		if err := json.Unmarshal(r, t); err != nil {
+			return nil, fmt.Errorf("%s: target %d: %w", path, i, err)
+		}
+		cfg.Targets[i] = *t
+	}
+
+	// Paths in the config are relative to the config file, not the working directory
+	dir := filepath.Dir(path)
+	for i := range cfg.Targets {
+		t := &cfg.Targets[i]
+		if t.Pkg == "" && t.Src == "" && t.OpenAPI == "" && t.SQL == "" && t.Descriptors == "" && t.JSON == "" {
+			return nil, fmt.Errorf("%s: target %d has no pkg, src, openapi, sql, descriptors or json", path, i)
+		}
+		for _, p := range []*string{&t.Pkg, &t.Src, &t.OpenAPI, &t.SQL, &t.Descriptors, &t.JSON, &t.Out, &t.OutDir, &t.Seeds, &t.SeedDir, &t.WireMock, &t.Examples, &t.HTTPFile, &t.Curl, &t.Summary, &t.PluginOut, &t.RoundTrip, &t.ValidateTests, &t.Testdata} {
+			if *p != "" && *p != "-" && !filepath.IsAbs(*p) {
+				*p = filepath.Join(dir, *p)
+			}
+		}
+		// Plugins given by name are looked up in PATH
+		if strings.ContainsRune(t.Plugin, '/') && !filepath.IsAbs(t.Plugin) {
+			t.Plugin = filepath.Join(dir, t.Plugin)
+		}
+	}
+	return &cfg, nil
+}
+
+// runTargets generates every target with at most jobs running concurrently. Targets without output
+// file write to stdout one after the other.
+func runTargets(targets []target, jobs int, loader loaderFunc) []batchResult {
+	results := make([]batchResult, len(targets))
+	parallel(len(targets), jobs, func(i int) {
+		// Each target is a single package, so extraction itself runs on one worker
+		skipped, err := generateTarget(targets[i], 1, loader)
+		results[i] = batchResult{Target: targets[i], Skipped: skipped, Err: err}
+	})
+	return results
+}
+
+func printBatchReport(results []batchResult) int {
+	var generated, skipped, failed int
+	code := 0
+	for _, r := range results {
+		switch {
+		case r.Err != nil:
+			failed++
+			// The first failure tells the exit code
+			if code == 0 {
+				code = exitCode(r.Err)
+			}
+			fmt.Fprintf(os.Stderr, "FAIL %s: %v\n", r.Target.source(), r.Err)
+		case r.Skipped:
+			skipped++
+		default:
+			generated++
+		}
+	}
+	fmt.Fprintf(os.Stderr, "batch: %d generated, %d skipped, %d failed\n", generated, skipped, failed)
+	return code
+}
+
+// loadCache shares loaded packages between batch targets that point at the same package
+type loadCache struct {
+	loader  loaderFunc
+	mu      sync.Mutex
+	entries map[string]*loadEntry
+}
+
+type loadEntry struct {
+	once sync.Once
+	pkgs []*packages.Package
+	err  error
+}
+
+func newLoadCache(loader loaderFunc) *loadCache {
+	return &loadCache{loader: loader, entries: make(map[string]*loadEntry)}
+}
+
+func (c *loadCache) load(pattern string, opts loadOptions) ([]*packages.Package, error) {
+	key := pattern
+	if abs, err := filepath.Abs(pattern); err == nil {
+		key = abs
+	}
+	key = fmt.Sprintf("%s|%+v", key, opts)
+
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	if !ok {
+		e = &loadEntry{}
+		c.entries[key] = e
+	}
+	c.mu.Unlock()
+
+	e.once.Do(func() {
+		e.pkgs, e.err = c.loader(pattern, opts)
+	})
+	return e.pkgs, e.err
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"strconv"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// cgoBroken reports whether a package failed to load and has files importing "C". Without a working C
+// toolchain the cgo files break the type info of the whole package.
+func cgoBroken(pkgs []*packages.Package) bool {
+	for _, pkg := range pkgs {
+		if len(pkg.Errors) > 0 && len(cgoFiles(pkg.GoFiles)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutCgo makes cfg load packages with cgo disabled, which leaves the files importing "C" out of
+// the package instead of failing it
+func withoutCgo(cfg *packages.Config) {
+	env := cfg.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	cfg.Env = append(env, "CGO_ENABLED=0")
+}
+
+// cgoFiles returns the files importing "C"
+func cgoFiles(files []string) []string {
+	var result []string
+	for _, file := range files {
+		f, err := parser.ParseFile(token.NewFileSet(), file, nil, parser.ImportsOnly)
+		if err != nil {
+			continue
+		}
+		for _, imp := range f.Imports {
+			if path, _ := strconv.Unquote(imp.Path.Value); path == "C" {
+				result = append(result, file)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// cgoUnavailable returns the types declared only in the cgo files left out of pkg, by file
+func cgoUnavailable(pkg *packages.Package) map[string][]string {
+	return unavailableTypes(pkg, cgoFiles(pkg.IgnoredFiles))
+}
+
+// unavailableTypes returns the types declared in files but missing from pkg, by file
+func unavailableTypes(pkg *packages.Package, files []string) map[string][]string {
+	result := make(map[string][]string)
+	for _, file := range files {
+		f, err := parser.ParseFile(token.NewFileSet(), file, nil, parser.SkipObjectResolution)
+		if err != nil {
+			continue
+		}
+		for _, decl := range f.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok || gd.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gd.Specs {
+				name := spec.(*ast.TypeSpec).Name.Name
+				// A fallback for the other builds may declare the type as well
+				if pkg.Types != nil && pkg.Types.Scope().Lookup(name) != nil {
+					continue
+				}
+				result[file] = append(result[file], name)
+			}
+		}
+	}
+	return result
+}
+
+// reportCgo warns about the types of pkgs that got no fixtures because they are declared in cgo files
+func reportCgo(pkgs []*packages.Package) {
+	for _, pkg := range pkgs {
+		unavailable := cgoUnavailable(pkg)
+		for _, file := range sortedNames(unavailable) {
+			for _, name := range unavailable[file] {
+				fmt.Fprintf(os.Stderr, "warning: %s: type %s is unavailable, it is declared in a cgo file and cgo is disabled or broken\n", file, name)
+			}
+		}
+	}
+}
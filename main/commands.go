@@ -0,0 +1,404 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"fixture-generator/pkg/generator"
+)
+
+// command is a subcommand of the CLI. setup registers its flags and returns the function running it
+// once they are parsed; completions register the flags on a throwaway set without running anything.
+type command struct {
+	name    string
+	summary string
+	setup   func(fs *flag.FlagSet) func() int
+}
+
+// commands are the subcommands in the order they are listed by help. It is filled in init since
+// completion refers back to it.
+var commands []command
+
+func init() {
+	commands = []command{
+		{"generate", "generate fixtures (the default when no command is given)", generateCommand},
+		{"check", "exit non-zero if the generated file is out of date, without writing it", checkCommand},
+		{"lint", "report types and fields the generator cannot handle", lintCommand},
+		{"graph", "print the type reference graph as DOT or JSON", graphCommand},
+		{"batch", "generate every target of a JSON or YAML config file", batchCommand},
+		{"diff", "compare two generated fixture files by their fixtures and field values", diffCommand},
+		{"list", "list the types fixtures are generated for", listCommand},
+		{"completion", "print a shell completion script: bash, zsh or fish", completionCommand},
+	}
+}
+
+// invocation holds the arguments fixture-generator was run with, named in the header of generated files
+var invocation []string
+
+// run dispatches args to their subcommand. Without one, args are the flags of generate.
+func run(args []string) int {
+	invocation = args
+	name := "generate"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	switch name {
+	case "help":
+		usage(os.Stdout)
+		return 0
+	case "version":
+		fmt.Println("fixture-generator " + version())
+		return 0
+	}
+	if len(args) > 0 && (args[0] == "--version" || args[0] == "-version") {
+		fmt.Println("fixture-generator " + version())
+		return 0
+	}
+	c, ok := findCommand(name)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "error: unknown command %q\n\n", name)
+		usage(os.Stderr)
+		return 2
+	}
+
+	fs := flag.NewFlagSet(c.name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: fixture-generator %s [flags]\n\n%s\n\nFlags:\n", c.name, c.summary)
+		fs.PrintDefaults()
+	}
+	runCommand := c.setup(fs)
+	fs.Parse(args)
+	if err := applyEnv(fs); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	return runCommand()
+}
+
+func findCommand(name string) (command, bool) {
+	for _, c := range commands {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return command{}, false
+}
+
+func usage(w *os.File) {
+	fmt.Fprintln(w, "Usage: fixture-generator [command] [flags]")
+	fmt.Fprintln(w, "\nCommands:")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, c := range commands {
+		fmt.Fprintf(tw, "  %s\t%s\n", c.name, c.summary)
+	}
+	tw.Flush()
+	fmt.Fprintln(w, "\nRun 'fixture-generator <command> -h' for the flags of a command and")
+	fmt.Fprintln(w, "'fixture-generator --version' for the version of the generator.")
+}
+
+// inputFlags registers the input flags shared by the commands that only read a model
+func inputFlags(fs *flag.FlagSet, verb string) (*target, *int) {
+	t := &target{}
+	fs.StringVar(&t.Pkg, "pkg", "", "path to the Go package to "+verb)
+	fs.StringVar(&t.Src, "src", "", "path to a Go source file to "+verb+" instead of a package, or - to read it from stdin")
+	fs.StringVar(&t.OpenAPI, "openapi", "", "path to an OpenAPI 3 document (JSON) to "+verb+" instead of a Go package")
+	fs.StringVar(&t.SQL, "sql", "", "path to a SQL script with CREATE TABLE statements to "+verb+" instead of a Go package")
+	fs.StringVar(&t.Descriptors, "descriptors", "", "path to a protobuf FileDescriptorSet (protoc --descriptor_set_out) to "+verb+" instead of a Go package")
+	fs.StringVar(&t.JSON, "json", "", "path to a sample JSON payload to "+verb+" instead of a Go package")
+	fs.StringVar(&t.JSONType, "jsontype", "", "root struct name for -json (default 'Sample')")
+	jobs := fs.Int("j", runtime.NumCPU(), "number of packages to process in parallel")
+	fs.BoolVar(&t.Deep, "deep", false, "load the full dependency graph from source when struct fields reference other packages")
+	fs.BoolVar(&t.Monorepo, "monorepo", false, "load only the target package plus the packages its struct fields reference")
+	fs.BoolVar(&t.IncludeTests, "include-tests", false, "also "+verb+" the types declared in the _test.go files of the package")
+	fs.StringVar(&t.GOOS, "goos", "", "load the package as built for this GOOS (default: the host's)")
+	fs.StringVar(&t.GOARCH, "goarch", "", "load the package as built for this GOARCH (default: the host's)")
+	fs.Var((*listFlag)(&t.SkipFields), "skipfields", "comma-separated internal fields to leave out besides the protobuf ones, as 'Field' or 'Type.Field'")
+	fs.Var((*repeatFlag)(&t.ExcludeConsts), "excludeconst", "regular expression matching generator-internal constants that aren't enum values (repeatable)")
+	return t, jobs
+}
+
+// checkCommand implements `fixture-generator check`, which generates the file in memory and compares it
+// to the one on disk, so CI can verify fixtures were regenerated after the source types changed
+func checkCommand(fs *flag.FlagSet) func() int {
+	t, jobs := generateFlags(fs)
+	diff := fs.Bool("diff", true, "print a unified diff of the drift, with the generated functions that changed")
+	color := fs.String("color", "auto", "color the diff: auto (if stdout is a terminal and NO_COLOR is unset), always or never")
+	return func() int {
+		if err := applyProfile(fs); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+		if err := t.validate(); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+		return runCheck(t, *jobs, *diff, *color)
+	}
+}
+
+// runCheck regenerates the output file of t in memory and reports drift from the file on disk,
+// for `check` and `generate -check`
+func runCheck(t *target, jobs int, diff bool, color string) int {
+	if t.Out == "" && t.OutDir == "" && !t.Self {
+		fmt.Fprintln(os.Stderr, "error: -out or -outdir flag is required")
+		return 1
+	}
+	old, new, err := checkTarget(t, jobs, load)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return exitCode(err)
+	}
+	if bytes.Equal(old, new) {
+		return 0
+	}
+	out := t.Out
+	if t.OutDir != "" {
+		out = t.OutDir
+	}
+	if diff {
+		writeDiff(os.Stdout, out, old, new, colorEnabled(color, os.Stdout))
+	}
+	if err := t.condition(condDrift, out+" is out of date, run fixture-generator generate to update it"); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// checkTarget returns the output file of t as it is on disk (nil if missing) and as it would be
+// generated, both without the header comments so files written by another build or command line
+// compare equal. With -self it resolves the output file of t first, with -outdir the files are
+// concatenated, each introduced by its name.
+func checkTarget(t *target, jobs int, loader loaderFunc) (old, new []byte, err error) {
+	model, pkgs, err := t.model(jobs, loader)
+	if err != nil {
+		return nil, nil, err
+	}
+	if t.Self {
+		if err := t.selfPackage(pkgs); err != nil {
+			return nil, nil, err
+		}
+	}
+	if n := loadErrors(pkgs); n > 0 {
+		if err := t.condition(condLoad, fmt.Sprintf("%s has %d load errors", t.Pkg, n)); err != nil {
+			return nil, nil, err
+		}
+	}
+	opts, err := t.options(model, pkgs)
+	if err != nil {
+		return nil, nil, err
+	}
+	if t.SelfTest {
+		if err := t.selfTest(model, pkgs, t.outPkg(), opts); err != nil {
+			return nil, nil, err
+		}
+	}
+	if t.OutDir != "" {
+		files, err := generator.GenerateFiles(model, t.outPkg(), opts, generator.FileLayout(model, t.TypesPerFile))
+		if err != nil {
+			return nil, nil, err
+		}
+		return checkOutDir(t.OutDir, files)
+	}
+	var want bytes.Buffer
+	if err := generator.GenerateTo(&want, model, t.outPkg(), opts); err != nil {
+		return nil, nil, err
+	}
+	got, err := os.ReadFile(t.Out)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, nil, err
+	}
+	return withoutHeader(got), withoutHeader(want.Bytes()), nil
+}
+
+// checkOutDir returns the files of -outdir as they are in dir and as they would be generated, for
+// checkTarget. Generated files of earlier runs that would be deleted count as drift too.
+func checkOutDir(dir string, files map[string][]byte) (old, new []byte, err error) {
+	names := make(map[string]bool)
+	for name := range files {
+		names[name] = true
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, nil, err
+	}
+	for _, e := range entries {
+		if outputFile.MatchString(e.Name()) && !e.IsDir() {
+			names[e.Name()] = true
+		}
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	for _, name := range sorted {
+		got, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, nil, err
+		}
+		if got != nil {
+			old = append(append(old, "==> "+name+" <==\n"...), withoutHeader(got)...)
+		}
+		if want, ok := files[name]; ok {
+			new = append(append(new, "==> "+name+" <==\n"...), withoutHeader(want)...)
+		}
+	}
+	return old, new, nil
+}
+
+// withoutHeader drops the comment lines before the package clause from generated output: the
+// generated code marker with the command line, the generator version and the model hash
+func withoutHeader(content []byte) []byte {
+	rest := content
+	for bytes.HasPrefix(rest, []byte("//")) {
+		_, rest, _ = bytes.Cut(rest, []byte("\n"))
+	}
+	return rest
+}
+
+// listCommand implements `fixture-generator list`, which prints the types of the input with their kind
+func listCommand(fs *flag.FlagSet) func() int {
+	t, jobs := inputFlags(fs, "list")
+	return func() int {
+		if t.Pkg == "" && t.Src == "" && t.OpenAPI == "" && t.SQL == "" && t.Descriptors == "" && t.JSON == "" {
+			fmt.Fprintln(os.Stderr, "error: -pkg, -src, -openapi, -sql, -descriptors or -json flag is required")
+			return 1
+		}
+		model, _, err := t.model(*jobs, load)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, n := range generator.BuildGraph(model).Nodes {
+			if n.Kind == "external" {
+				continue
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", n.Name, n.Kind, listDetail(model, n))
+		}
+		tw.Flush()
+		return 0
+	}
+}
+
+func listDetail(m *generator.Model, n generator.GraphNode) string {
+	switch n.Kind {
+	case "struct":
+		if s, ok := m.Structs[n.Name]; ok {
+			return fmt.Sprintf("%d fields", len(s.Fields))
+		}
+	case "enum":
+		if e, ok := m.Enums[n.Name]; ok {
+			return fmt.Sprintf("%d values", len(e.Values))
+		}
+	case "oneof":
+		if variants := m.Variants[n.Name]; len(variants) > 0 {
+			return "implemented by " + strings.Join(variants, ", ")
+		}
+		if impl := m.OneOfs[n.Name]; impl != "" {
+			return "implemented by " + impl
+		}
+		return "no implementation"
+	case "interface":
+		if i, ok := m.Interfaces[n.Name]; ok {
+			return fmt.Sprintf("%d methods", len(i.Methods))
+		}
+	}
+	return ""
+}
+
+// completionCommand implements `fixture-generator completion <shell>`
+func completionCommand(fs *flag.FlagSet) func() int {
+	return func() int {
+		script, err := completion(fs.Arg(0))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+		fmt.Print(script)
+		return 0
+	}
+}
+
+// commandFlags returns the flags of every command, read from throwaway flag sets
+func commandFlags() map[string][]*flag.Flag {
+	flags := make(map[string][]*flag.Flag)
+	for _, c := range commands {
+		fs := flag.NewFlagSet(c.name, flag.ContinueOnError)
+		c.setup(fs)
+		fs.VisitAll(func(f *flag.Flag) {
+			flags[c.name] = append(flags[c.name], f)
+		})
+	}
+	return flags
+}
+
+// completion renders the completion script for shell
+func completion(shell string) (string, error) {
+	flags := commandFlags()
+	var names []string
+	for _, c := range commands {
+		names = append(names, c.name)
+	}
+	names = append(names, "help", "version")
+
+	var b strings.Builder
+	switch shell {
+	case "bash", "zsh":
+		if shell == "zsh" {
+			b.WriteString("autoload -U +X bashcompinit && bashcompinit\n")
+		}
+		b.WriteString("_fixture_generator() {\n")
+		b.WriteString("\tlocal cur=\"${COMP_WORDS[COMP_CWORD]}\" flags\n")
+		fmt.Fprintf(&b, "\tif [ \"$COMP_CWORD\" -eq 1 ] && [[ \"$cur\" != -* ]]; then\n\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n\t\treturn\n\tfi\n", strings.Join(names, " "))
+		b.WriteString("\tcase \"${COMP_WORDS[1]}\" in\n")
+		for _, c := range commands {
+			pattern := c.name
+			if c.name == "generate" {
+				pattern = "generate | -*"
+			}
+			fmt.Fprintf(&b, "\t%s) flags=%q ;;\n", pattern, flagNames(flags[c.name]))
+		}
+		b.WriteString("\tesac\n")
+		b.WriteString("\tif [[ \"$cur\" == -* ]]; then\n\t\tCOMPREPLY=($(compgen -W \"$flags\" -- \"$cur\"))\n\telse\n\t\tCOMPREPLY=($(compgen -f -- \"$cur\"))\n\tfi\n")
+		b.WriteString("}\n")
+		b.WriteString("complete -F _fixture_generator fixture-generator\n")
+	case "fish":
+		for _, c := range commands {
+			fmt.Fprintf(&b, "complete -c fixture-generator -n __fish_use_subcommand -a %s -d %s\n", c.name, fishQuote(c.summary))
+		}
+		for _, c := range commands {
+			for _, f := range flags[c.name] {
+				cond := "__fish_seen_subcommand_from " + c.name
+				if c.name == "generate" {
+					cond = "not __fish_seen_subcommand_from " + strings.Join(names[1:], " ")
+				}
+				fmt.Fprintf(&b, "complete -c fixture-generator -n %s -o %s -d %s\n", fishQuote(cond), f.Name, fishQuote(f.Usage))
+			}
+		}
+	default:
+		return "", fmt.Errorf("unknown shell %q (want bash, zsh or fish)", shell)
+	}
+	return b.String(), nil
+}
+
+func flagNames(flags []*flag.Flag) string {
+	var names []string
+	for _, f := range flags {
+		names = append(names, "-"+f.Name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, " ")
+}
+
+func fishQuote(s string) string {
+	return "'" + strings.ReplaceAll(strings.ReplaceAll(s, `\`, `\\`), "'", `\'`) + "'"
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// customFixtures returns the fixture functions written by hand in the output package of t, like the
+// ones of a fixtures_custom.go next to the output, so they aren't generated again. Test files,
+// generated files, files of other packages and the output itself are left out, as are files that
+// don't parse, like ones being edited; without an output file there are none.
+func (t target) customFixtures() ([]string, error) {
+	dir := t.OutDir
+	if dir == "" && t.Out != "" {
+		dir = filepath.Dir(t.Out)
+	}
+	if dir == "" {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, e := range entries {
+		name := e.Name()
+		path := filepath.Join(dir, name)
+		if e.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		if t.OutDir != "" && outputFile.MatchString(name) || t.Out != "" && sameFile(path, t.Out) {
+			continue
+		}
+		// Files of other packages, like example programs behind build tags, don't need to parse
+		if f, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.PackageClauseOnly); err != nil || f.Name.Name != t.outPkg() {
+			continue
+		}
+		f, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.ParseComments|parser.SkipObjectResolution)
+		if err != nil || ast.IsGenerated(f) {
+			continue
+		}
+		for _, decl := range f.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil && strings.HasPrefix(fn.Name.Name, "Fixture") {
+				names = append(names, fn.Name.Name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// sameFile reports whether the paths a and b name the same file
+func sameFile(a, b string) bool {
+	a, errA := filepath.Abs(a)
+	b, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && a == b
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// maxDiffEdits bounds the edit distance the diff searches for; beyond it the whole file is shown
+// as replaced, since such a diff wouldn't be readable anyway
+const maxDiffEdits = 2000
+
+// diffLine is a line of a unified diff: ' ' for context, '-' for a removed and '+' for an added line
+type diffLine struct {
+	Op   byte
+	Text string
+	// A and B are the 0-based line numbers in the old and new file
+	A, B int
+}
+
+// diffLines returns the shortest line edit script turning a into b (Myers' algorithm)
+func diffLines(a, b []string) []diffLine {
+	n, m := len(a), len(b)
+	v := map[int]int{1: 0}
+	var trace []map[int]int
+	for d := 0; d <= n+m; d++ {
+		if d > maxDiffEdits {
+			return replaceAll(a, b)
+		}
+		snapshot := make(map[int]int, len(v))
+		for k, x := range v {
+			snapshot[k] = x
+		}
+		trace = append(trace, snapshot)
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || k != d && v[k-1] < v[k+1] {
+				x = v[k+1]
+			} else {
+				x = v[k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[k] = x
+			if x >= n && y >= m {
+				return backtrack(a, b, trace)
+			}
+		}
+	}
+	return nil
+}
+
+// backtrack walks the snapshots of diffLines back from the end of both files to recover the edits
+func backtrack(a, b []string, trace []map[int]int) []diffLine {
+	var lines []diffLine
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+		prevK := k - 1
+		if k == -d || k != d && v[k-1] < v[k+1] {
+			prevK = k + 1
+		}
+		prevX := v[prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x, y = x-1, y-1
+			lines = append(lines, diffLine{Op: ' ', Text: a[x], A: x, B: y})
+		}
+		if x == prevX {
+			y--
+			lines = append(lines, diffLine{Op: '+', Text: b[y], A: x, B: y})
+		} else {
+			x--
+			lines = append(lines, diffLine{Op: '-', Text: a[x], A: x, B: y})
+		}
+	}
+	for x > 0 && y > 0 {
+		x, y = x-1, y-1
+		lines = append(lines, diffLine{Op: ' ', Text: a[x], A: x, B: y})
+	}
+	for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+		lines[i], lines[j] = lines[j], lines[i]
+	}
+	return lines
+}
+
+func replaceAll(a, b []string) []diffLine {
+	var lines []diffLine
+	for i, s := range a {
+		lines = append(lines, diffLine{Op: '-', Text: s, A: i})
+	}
+	for i, s := range b {
+		lines = append(lines, diffLine{Op: '+', Text: s, A: len(a), B: i})
+	}
+	return lines
+}
+
+// diffHunk is a run of changes with the context lines around it
+type diffHunk struct {
+	Lines []diffLine
+	// Func is the generated function or declared type the first change of the hunk is in, if any,
+	// and Keyword "func" or "type"
+	Func, Keyword string
+}
+
+var funcDecl = regexp.MustCompile(`^(func|type) (\w+)`)
+
+// hunks groups the changes of lines into hunks with context unchanged lines around them
+func hunks(lines []diffLine, context int) []diffHunk {
+	var result []diffHunk
+	for i := 0; i < len(lines); {
+		if lines[i].Op == ' ' {
+			i++
+			continue
+		}
+		start := max(0, i-context)
+		end := i
+		// Extend the hunk while the next change is close enough to share context
+		for j := i; j < len(lines); j++ {
+			if lines[j].Op != ' ' {
+				end = j
+			} else if j-end > 2*context {
+				break
+			}
+		}
+		stop := min(len(lines), end+context+1)
+		h := diffHunk{Lines: lines[start:stop]}
+		for j := i; j >= 0; j-- {
+			if m := funcDecl.FindStringSubmatch(lines[j].Text); m != nil {
+				h.Keyword, h.Func = m[1], m[2]
+				break
+			}
+		}
+		result = append(result, h)
+		i = stop
+	}
+	return result
+}
+
+// diffColors are the ANSI colors of a diff; all empty when color is off
+type diffColors struct {
+	header, hunk, del, add, reset string
+}
+
+func newDiffColors(enabled bool) diffColors {
+	if !enabled {
+		return diffColors{}
+	}
+	return diffColors{header: "\x1b[1m", hunk: "\x1b[36m", del: "\x1b[31m", add: "\x1b[32m", reset: "\x1b[0m"}
+}
+
+// colorEnabled resolves a -color mode of "auto", "always" or "never" for output to f
+func colorEnabled(mode string, f *os.File) bool {
+	switch mode {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// writeDiff writes a unified diff from the file at path to the generated content, followed by the
+// generated functions that changed, and reports whether they differ
+func writeDiff(w io.Writer, path string, old, new []byte, color bool) bool {
+	lines := diffLines(splitLines(old), splitLines(new))
+	hs := hunks(lines, 3)
+	if len(hs) == 0 {
+		return false
+	}
+	c := newDiffColors(color)
+	fmt.Fprintf(w, "%s--- %s (on disk)%s\n", c.header, path, c.reset)
+	fmt.Fprintf(w, "%s+++ %s (generated)%s\n", c.header, path, c.reset)
+
+	var changed []string
+	seen := make(map[string]bool)
+	for _, h := range hs {
+		first := h.Lines[0]
+		var oldLen, newLen int
+		for _, l := range h.Lines {
+			if l.Op != '+' {
+				oldLen++
+			}
+			if l.Op != '-' {
+				newLen++
+			}
+		}
+		fmt.Fprintf(w, "%s@@ -%d,%d +%d,%d @@%s", c.hunk, first.A+1, oldLen, first.B+1, newLen, c.reset)
+		if h.Func != "" {
+			fmt.Fprintf(w, " %s %s", h.Keyword, h.Func)
+		}
+		fmt.Fprintln(w)
+		for _, l := range h.Lines {
+			color := ""
+			switch l.Op {
+			case '-':
+				color = c.del
+			case '+':
+				color = c.add
+			}
+			if color != "" {
+				fmt.Fprintf(w, "%s%c%s%s\n", color, l.Op, l.Text, c.reset)
+			} else {
+				fmt.Fprintf(w, "%c%s\n", l.Op, l.Text)
+			}
+		}
+		for _, name := range changedFuncs(h) {
+			if !seen[name] {
+				seen[name] = true
+				changed = append(changed, name)
+			}
+		}
+	}
+	if len(changed) > 0 {
+		fmt.Fprintf(w, "changed: %s\n", strings.Join(changed, ", "))
+	}
+	return true
+}
+
+// changedFuncs returns the functions and types of a hunk with changed lines, so drift is reported
+// per type
+func changedFuncs(h diffHunk) []string {
+	var names []string
+	current := h.Func
+	for _, l := range h.Lines {
+		if m := funcDecl.FindStringSubmatch(l.Text); m != nil {
+			current = m[2]
+		}
+		if l.Op != ' ' && current != "" && (len(names) == 0 || names[len(names)-1] != current) {
+			names = append(names, current)
+		}
+	}
+	return names
+}
+
+func splitLines(content []byte) []string {
+	s := strings.TrimSuffix(string(content), "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
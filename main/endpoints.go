@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"fixture-generator/pkg/generator"
+)
+
+// endpoints parses the endpoints of t, ordered by route
+func (t target) endpoints() ([]generator.Endpoint, error) {
+	routes := make([]string, 0, len(t.Endpoints))
+	for route := range t.Endpoints {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+
+	var eps []generator.Endpoint
+	for _, route := range routes {
+		e, err := generator.ParseEndpoint(route, t.Endpoints[route])
+		if err != nil {
+			return nil, err
+		}
+		eps = append(eps, e)
+	}
+	return eps, nil
+}
+
+// writeEndpointFiles writes the stub mappings and request samples of the endpoints of t, with the
+// fixtures generated with opts as bodies
+func (t target) writeEndpointFiles(m *generator.Model, opts generator.GenerateOptions) error {
+	if t.WireMock == "" && t.HTTPFile == "" && t.Curl == "" {
+		return nil
+	}
+	eps, err := t.endpoints()
+	if err != nil {
+		return err
+	}
+	baseURL := t.BaseURL
+	if baseURL == "" {
+		baseURL = "http://localhost:8080"
+	}
+
+	if t.WireMock != "" {
+		if err := writeWireMock(t.WireMock, m, eps, opts); err != nil {
+			return err
+		}
+	}
+	if t.HTTPFile != "" {
+		content, err := generator.HTTPRequests(m, eps, baseURL, opts)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(t.HTTPFile, []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+	if t.Curl != "" {
+		content, err := generator.CurlScript(m, eps, baseURL, opts)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(t.Curl, []byte(content), 0755); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeWireMock writes a WireMock stub mapping per endpoint into dir
+func writeWireMock(dir string, m *generator.Model, eps []generator.Endpoint, opts generator.GenerateOptions) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, e := range eps {
+		data, err := generator.WireMockMapping(m, e, opts)
+		if err != nil {
+			return fmt.Errorf("%s %s: %w", e.Method, e.Path, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, e.Name()+".json"), data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addExamples sets the fixtures as examples of the matching schemas of the OpenAPI document at path
+func addExamples(path string, m *generator.Model, opts generator.GenerateOptions) error {
+	doc, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	updated, schemas, err := generator.AddOpenAPIExamples(doc, m, opts)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if len(schemas) == 0 {
+		fmt.Fprintf(os.Stderr, "warning: %s has no schemas matching the fixtures\n", path)
+		return nil
+	}
+	return os.WriteFile(path, updated, 0644)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// envPrefix starts the environment variables configuring the CLI: FIXTUREGEN_<FLAG>, with the flag
+// name upper-cased and dashes turned into underscores (FIXTUREGEN_TYPEPREFIX, FIXTUREGEN_FAIL_ON).
+//
+// Options are resolved in this order, the first one set winning: command-line flags, the batch config
+// file, environment variables, the -profile preset, defaults.
+const envPrefix = "FIXTUREGEN_"
+
+// envName returns the environment variable of a flag
+func envName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// flagAliases maps the other names flags can be given by to their own. Aliases share the value of
+// the flag but have no environment variable of their own.
+var flagAliases = map[string]string{"out-dir": "outdir"}
+
+// explicitFlags returns the names of the flags set on the command line, those given by an alias
+// under their own name
+func explicitFlags(fs *flag.FlagSet) map[string]bool {
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+		if name, ok := flagAliases[f.Name]; ok {
+			explicit[name] = true
+		}
+	})
+	return explicit
+}
+
+// applyEnv sets the flags of fs not given on the command line from their environment variables.
+// Repeatable flags take several values separated by ';'.
+func applyEnv(fs *flag.FlagSet) error {
+	explicit := explicitFlags(fs)
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		value, ok := os.LookupEnv(envName(f.Name))
+		if _, alias := flagAliases[f.Name]; !ok || alias || explicit[f.Name] || err != nil {
+			return
+		}
+		values := []string{value}
+		switch f.Value.(type) {
+		case mapFlag, *repeatFlag:
+			values = strings.Split(value, ";")
+		}
+		for _, v := range values {
+			if setErr := fs.Set(f.Name, strings.TrimSpace(v)); setErr != nil {
+				err = fmt.Errorf("%s: %w", envName(f.Name), setErr)
+				return
+			}
+		}
+	})
+	return err
+}
+
+// envTarget returns a target with the options set through environment variables (and the profile they
+// select), which batch targets start from before their config is applied
+func envTarget() (*target, error) {
+	fs := flag.NewFlagSet("batch", flag.ContinueOnError)
+	t, _ := generateFlags(fs)
+	if err := applyEnv(fs); err != nil {
+		return nil, err
+	}
+	if err := applyProfile(fs); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"io"
+	"os"
+	"strings"
+)
+
+// diffCommand implements `fixture-generator diff old.go new.go`, which compares two generated fixture
+// files by their fixtures and field values instead of their text, so regenerations can be reviewed
+func diffCommand(fs *flag.FlagSet) func() int {
+	color := fs.String("color", "auto", "color the diff: auto (if stdout is a terminal and NO_COLOR is unset), always or never")
+	exitCode := fs.Bool("exit-code", false, "exit with 1 if the files differ")
+	return func() int {
+		if fs.NArg() != 2 {
+			fmt.Fprintln(os.Stderr, "error: diff takes two fixture files: fixture-generator diff old.go new.go")
+			return 1
+		}
+		old, err := readFixtureFile(fs.Arg(0))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+		new, err := readFixtureFile(fs.Arg(1))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+		if writeFixtureDiff(os.Stdout, old, new, colorEnabled(*color, os.Stdout)) && *exitCode {
+			return 1
+		}
+		return 0
+	}
+}
+
+// fixtureDecl is a top-level function or constant of a generated file, reduced to what a review
+// of its value cares about
+type fixtureDecl struct {
+	Name string
+	// Fields are the values set in the struct literal the function builds, and through promoted
+	// field assignments, by field name
+	Fields map[string]string
+	// Order lists the names of Fields in the order they are set
+	Order []string
+	// Body is the whole declaration with whitespace collapsed, compared when Fields are equal
+	Body string
+}
+
+// fixtureFile holds the declarations of a generated file in the order they appear
+type fixtureFile struct {
+	Decls  []*fixtureDecl
+	byName map[string]*fixtureDecl
+}
+
+func readFixtureFile(path string) (*fixtureFile, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseFixtureFile(path, src)
+}
+
+// parseFixtureFile extracts the functions, constants and variables of a generated file
+func parseFixtureFile(path string, src []byte) (*fixtureFile, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, path, src, 0)
+	if err != nil {
+		return nil, err
+	}
+	ff := &fixtureFile{byName: make(map[string]*fixtureDecl)}
+	add := func(d *fixtureDecl) {
+		if _, ok := ff.byName[d.Name]; !ok {
+			ff.Decls = append(ff.Decls, d)
+		}
+		ff.byName[d.Name] = d
+	}
+	for _, decl := range f.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			d := &fixtureDecl{Name: decl.Name.Name, Fields: make(map[string]string), Body: exprText(fset, decl)}
+			if decl.Recv != nil && len(decl.Recv.List) > 0 {
+				d.Name = exprText(fset, decl.Recv.List[0].Type) + "." + d.Name
+			}
+			if decl.Body != nil {
+				literalFields(fset, decl.Body, d)
+			}
+			add(d)
+		case *ast.GenDecl:
+			if decl.Tok != token.CONST && decl.Tok != token.VAR {
+				continue
+			}
+			for _, spec := range decl.Specs {
+				vs := spec.(*ast.ValueSpec)
+				for i, name := range vs.Names {
+					d := &fixtureDecl{Name: name.Name, Fields: make(map[string]string)}
+					if i < len(vs.Values) {
+						d.Body = exprText(fset, vs.Values[i])
+					}
+					add(d)
+				}
+			}
+		}
+	}
+	return ff, nil
+}
+
+// literalFields records the keyed fields of the first struct literal in body, the one a fixture
+// builds, and the promoted fields assigned to it afterwards (value.Base.CreatedAt = ...)
+func literalFields(fset *token.FileSet, body *ast.BlockStmt, d *fixtureDecl) {
+	set := func(name, value string) {
+		if _, ok := d.Fields[name]; !ok {
+			d.Order = append(d.Order, name)
+		}
+		d.Fields[name] = value
+	}
+	found := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.CompositeLit:
+			if found || len(n.Elts) == 0 {
+				return true
+			}
+			for _, elt := range n.Elts {
+				if _, ok := elt.(*ast.KeyValueExpr); !ok {
+					return true
+				}
+			}
+			found = true
+			for _, elt := range n.Elts {
+				kv := elt.(*ast.KeyValueExpr)
+				set(exprText(fset, kv.Key), exprText(fset, kv.Value))
+			}
+			return false
+		case *ast.AssignStmt:
+			if !found || len(n.Lhs) != 1 || len(n.Rhs) != 1 {
+				return true
+			}
+			if name, ok := strings.CutPrefix(exprText(fset, n.Lhs[0]), "value."); ok {
+				set(name, exprText(fset, n.Rhs[0]))
+			}
+		}
+		return true
+	})
+}
+
+// exprText renders node on a single line, so reformatting doesn't count as a change
+func exprText(fset *token.FileSet, node ast.Node) string {
+	var b bytes.Buffer
+	printer.Fprint(&b, fset, node)
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// writeFixtureDiff writes the fixtures added to, removed from and changed between old and new, with
+// the field values that changed, and reports whether there were any
+func writeFixtureDiff(w io.Writer, old, new *fixtureFile, color bool) bool {
+	c := newDiffColors(color)
+	changed := false
+	for _, o := range old.Decls {
+		n, ok := new.byName[o.Name]
+		if !ok {
+			fmt.Fprintf(w, "%s- %s%s\n", c.del, o.Name, c.reset)
+			changed = true
+			continue
+		}
+		lines := fieldChanges(o, n, c)
+		if len(lines) == 0 && o.Body != n.Body {
+			lines = append(lines, "    body changed")
+		}
+		if len(lines) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "%s~ %s%s\n", c.hunk, o.Name, c.reset)
+		for _, l := range lines {
+			fmt.Fprintln(w, l)
+		}
+		changed = true
+	}
+	for _, n := range new.Decls {
+		if _, ok := old.byName[n.Name]; !ok {
+			fmt.Fprintf(w, "%s+ %s%s\n", c.add, n.Name, c.reset)
+			changed = true
+		}
+	}
+	return changed
+}
+
+// fieldChanges renders the fields removed from, changed in and added to the literal of a fixture
+func fieldChanges(old, new *fixtureDecl, c diffColors) []string {
+	var lines []string
+	for _, name := range old.Order {
+		v, ok := new.Fields[name]
+		switch {
+		case !ok:
+			lines = append(lines, fmt.Sprintf("    %s- %s: %s%s", c.del, name, old.Fields[name], c.reset))
+		case v != old.Fields[name]:
+			lines = append(lines, fmt.Sprintf("    ~ %s: %s%s%s -> %s%s%s", name, c.del, old.Fields[name], c.reset, c.add, v, c.reset))
+		}
+	}
+	for _, name := range new.Order {
+		if _, ok := old.Fields[name]; !ok {
+			lines = append(lines, fmt.Sprintf("    %s+ %s: %s%s", c.add, name, new.Fields[name], c.reset))
+		}
+	}
+	return lines
+}
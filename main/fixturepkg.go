@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// fixtureSubpackage is the conventional place of a package's generated fixtures
+const fixtureSubpackage = "fixtures"
+
+// fixturePackages returns the fixtures packages to reuse for the other packages referenced by
+// struct fields of pkgs: the explicit mapping, plus every referenced package that has a
+// "fixtures" subpackage with generated fixtures in it.
+func fixturePackages(pattern string, pkgs []*packages.Package, explicit map[string]string) (map[string]string, error) {
+	result := make(map[string]string)
+	for typePkg, fixturePkg := range explicit {
+		result[typePkg] = fixturePkg
+	}
+
+	var missing []string
+	for _, path := range fieldDeps(pkgs) {
+		if _, ok := result[path]; !ok {
+			missing = append(missing, path)
+		}
+	}
+	if len(missing) > 0 {
+		absPath, err := filepath.Abs(pattern)
+		if err != nil {
+			return nil, err
+		}
+		// Only file names are needed to find the package directories, not type information
+		cfg := &packages.Config{Mode: packages.NeedName | packages.NeedFiles, Dir: absPath}
+		deps, err := packages.Load(cfg, missing...)
+		if err != nil {
+			return nil, err
+		}
+		for _, dep := range deps {
+			if len(dep.GoFiles) == 0 {
+				continue
+			}
+			if hasFixtures(filepath.Join(filepath.Dir(dep.GoFiles[0]), fixtureSubpackage)) {
+				result[dep.PkgPath] = dep.PkgPath + "/" + fixtureSubpackage
+			}
+		}
+	}
+
+	if len(result) == 0 {
+		return nil, nil
+	}
+	return result, nil
+}
+
+// hasFixtures reports whether dir holds Go files declaring fixture functions
+func hasFixtures(dir string) bool {
+	files, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if err == nil && bytes.Contains(data, []byte("\nfunc Fixture")) {
+			return true
+		}
+	}
+	return false
+}
+
+// mapFlag collects repeated "key=value" flags
+type mapFlag map[string]string
+
+func (f mapFlag) String() string {
+	pairs := make([]string, 0, len(f))
+	for k, v := range f {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (f mapFlag) Set(value string) error {
+	k, v, ok := strings.Cut(value, "=")
+	if !ok || k == "" || v == "" {
+		return fmt.Errorf("expected key=value, got %q", value)
+	}
+	f[k] = v
+	return nil
+}
+
+// repeatFlag collects the values of repeated flags as given, for values like regular expressions
+// that may contain commas
+type repeatFlag []string
+
+func (f *repeatFlag) String() string {
+	return strings.Join(*f, ";")
+}
+
+func (f *repeatFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// listFlag collects comma-separated values from repeated flags
+type listFlag []string
+
+func (f *listFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *listFlag) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*f = append(*f, v)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"fixture-generator/pkg/generator"
+)
+
+// graphCommand implements `fixture-generator graph`, which prints the type reference graph of the
+// input instead of generating fixtures
+func graphCommand(fs *flag.FlagSet) func() int {
+	t, jobs := inputFlags(fs, "graph")
+	format := fs.String("format", "dot", "output format: dot or json")
+	outFile := fs.String("out", "", "output file path (prints to stdout if not specified)")
+	return func() int {
+		return writeGraph(*t, *format, *outFile, *jobs)
+	}
+}
+
+func writeGraph(t target, format, outFile string, jobs int) int {
+	if t.Pkg == "" && t.Src == "" && t.OpenAPI == "" && t.SQL == "" && t.Descriptors == "" && t.JSON == "" {
+		fmt.Fprintln(os.Stderr, "error: -pkg, -src, -openapi, -sql, -descriptors or -json flag is required")
+		return 1
+	}
+	if format != "dot" && format != "json" {
+		fmt.Fprintf(os.Stderr, "error: unknown -format %q (want dot or json)\n", format)
+		return 1
+	}
+
+	model, _, err := t.model(jobs, load)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+
+	g := generator.BuildGraph(model)
+	var out []byte
+	if format == "json" {
+		if out, err = json.MarshalIndent(g, "", "  "); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+		out = append(out, '\n')
+	} else {
+		out = []byte(g.DOT())
+	}
+
+	if outFile == "" {
+		os.Stdout.Write(out)
+	} else if err := os.WriteFile(outFile, out, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	for _, cycle := range g.Cycles {
+		fmt.Fprintf(os.Stderr, "cycle: %v\n", cycle)
+	}
+	return 0
+}
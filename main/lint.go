@@ -0,0 +1,172 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+
+	"fixture-generator/pkg/generator"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// lintCommand implements `fixture-generator lint`, which reports the types and fields of a package the
+// generator cannot build a fixture value for, without generating code. It exits non-zero if any
+// are found, so it can gate changes to the source types; warnings are only printed.
+func lintCommand(fs *flag.FlagSet) func() int {
+	pkgPath := fs.String("pkg", "", "path to the Go package to lint")
+	jobs := fs.Int("j", runtime.NumCPU(), "number of packages to process in parallel")
+	deep := fs.Bool("deep", false, "load the full dependency graph from source when struct fields reference other packages")
+	monorepo := fs.Bool("monorepo", false, "load only the target package plus the packages its struct fields reference")
+	return func() int {
+		return runLint(target{Pkg: *pkgPath, Deep: *deep, Monorepo: *monorepo}, *jobs)
+	}
+}
+
+func runLint(t target, jobs int) int {
+	if t.Pkg == "" {
+		fmt.Fprintln(os.Stderr, "error: -pkg flag is required")
+		return 1
+	}
+
+	model, pkgs, err := t.model(jobs, load)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	issues := lint(model, pkgs)
+	failed := 0
+	for _, issue := range issues {
+		fmt.Println(issue)
+		if !issue.Warning {
+			failed++
+		}
+	}
+	if failed > 0 {
+		fmt.Fprintf(os.Stderr, "lint: %d issues\n", failed)
+		return 1
+	}
+	return 0
+}
+
+// lintIssue is a construct the generator cannot handle
+type lintIssue struct {
+	Pos token.Position
+	// Name is the type, or "Type.Field" for a field
+	Name    string
+	Message string
+	// Warning marks constructs the fixtures handle, but maybe not as expected; they don't fail lint
+	Warning bool
+}
+
+func (i lintIssue) String() string {
+	message := i.Message
+	if i.Warning {
+		message = "warning: " + message
+	}
+	if i.Pos.IsValid() {
+		return fmt.Sprintf("%s: %s: %s", i.Pos, i.Name, message)
+	}
+	return fmt.Sprintf("%s: %s", i.Name, message)
+}
+
+// lint lists the fields left nil by the generated fixtures, reference cycles, structs whose fields
+// are all unexported and oneofs without implementation, ordered by position
+func lint(m *generator.Model, pkgs []*packages.Package) []lintIssue {
+	positions := declPositions(pkgs)
+	var issues []lintIssue
+	add := func(name, message string) {
+		issues = append(issues, lintIssue{Pos: positions[name], Name: name, Message: message})
+	}
+
+	summary := generator.Summarize(m, generator.GenerateOptions{ModStyle: true})
+	for _, sk := range append(summary.Skipped, summary.Missing...) {
+		name := sk.Type
+		if sk.Field != "" {
+			name += "." + sk.Field
+		}
+		add(name, sk.Reason)
+	}
+	for _, name := range sortedNames(m.OneOfs) {
+		if m.OneOfs[name] == "" {
+			add(name, "oneof has no implementation")
+		}
+	}
+	for _, name := range sortedNames(m.Structs) {
+		s := m.Structs[name]
+		exported := false
+		for _, f := range s.Fields {
+			exported = exported || ast.IsExported(f.Name)
+		}
+		if len(s.Fields) > 0 && !exported {
+			add(name, "struct has only unexported fields, its fixture is always empty")
+		}
+	}
+	// Fixtures cut reference cycles off with nil, which tests may not expect
+	for _, cycle := range generator.BuildGraph(m).Cycles {
+		issues = append(issues, lintIssue{
+			Pos:     positions[cycle[0]],
+			Name:    cycle[0],
+			Message: "reference cycle " + strings.Join(append(cycle, cycle[0]), " -> ") + ", its fixtures end it with nil after -maxdepth levels",
+			Warning: true,
+		})
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		a, b := issues[i].Pos, issues[j].Pos
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return issues[i].Name < issues[j].Name
+	})
+	return issues
+}
+
+// declPositions maps the type declarations of pkgs to their position, and struct fields as "Type.Field"
+func declPositions(pkgs []*packages.Package) map[string]token.Position {
+	positions := make(map[string]token.Position)
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Syntax {
+			for _, decl := range file.Decls {
+				gd, ok := decl.(*ast.GenDecl)
+				if !ok || gd.Tok != token.TYPE {
+					continue
+				}
+				for _, spec := range gd.Specs {
+					ts := spec.(*ast.TypeSpec)
+					if _, ok := positions[ts.Name.Name]; ok {
+						continue
+					}
+					positions[ts.Name.Name] = pkg.Fset.Position(ts.Name.Pos())
+					st, ok := ts.Type.(*ast.StructType)
+					if !ok {
+						continue
+					}
+					for _, field := range st.Fields.List {
+						for _, name := range field.Names {
+							positions[ts.Name.Name+"."+name.Name] = pkg.Fset.Position(name.Pos())
+						}
+					}
+				}
+			}
+		}
+	}
+	return positions
+}
+
+func sortedNames[V any](items map[string]V) []string {
+	names := make([]string, 0, len(items))
+	for name := range items {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
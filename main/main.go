@@ -1,248 +1,1425 @@
 package main
 
 import (
+	"bufio"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"go/ast"
-	"go/format"
 	"go/token"
 	"go/types"
+	"io"
 	"os"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 
 	"fixture-generator/pkg/generator"
 
+	"golang.org/x/tools/go/gcexportdata"
 	"golang.org/x/tools/go/packages"
 )
 
 func main() {
-	pkgPath := flag.String("pkg", "", "path to the Go package to generate fixtures for")
-	pkgName := flag.String("outpkg", "fixtures", "package name for the generated file")
-	outFile := flag.String("out", "", "output file path (prints to stdout if not specified)")
-	typePrefix := flag.String("typeprefix", "", "prefix for type names (e.g., 'productionorderbase' -> 'productionorderbase.Operation')")
-	funcPrefix := flag.String("funcprefix", "", "prefix for fixture function names (e.g., 'PB' -> 'FixturePBOperation')")
-	modStyle := flag.Bool("modstyle", true, "generate fixtures with functional options pattern (default: true)")
-	flag.Parse()
+	os.Exit(run(os.Args[1:]))
+}
 
-	if *pkgPath == "" {
-		fmt.Fprintln(os.Stderr, "error: -pkg flag is required")
-		os.Exit(1)
+// generateFlags registers the flags of the generate and check commands, which fill the returned target
+func generateFlags(fs *flag.FlagSet) (*target, *int) {
+	t := &target{
+		Routes:          mapFlag{},
+		Endpoints:       mapFlag{},
+		AnyPayloads:     mapFlag{},
+		FieldOverrides:  mapFlag{},
+		EnumValues:      mapFlag{},
+		FixturePackages: mapFlag{},
+		Pairwise:        mapFlag{},
+		Aggregates:      mapFlag{},
+		Weights:         mapFlag{},
+		Relations:       mapFlag{},
+		ExternalTypes:   mapFlag{},
+		SliceLens:       mapFlag{},
 	}
+	fs.StringVar(&t.Pkg, "pkg", "", "path to the Go package to generate fixtures for, or several as a comma-separated list or ./... pattern, each written to its fixtures subpackage")
+	fs.StringVar(&t.Src, "src", "", "path to a Go source file to generate fixtures for instead of a package, or - to read it from stdin")
+	fs.StringVar(&t.JSON, "json", "", "path to a sample JSON payload whose values become the fixture defaults")
+	fs.StringVar(&t.JSONType, "jsontype", "", "struct the -json sample describes (matched against -pkg if set, default 'Sample' otherwise)")
+	fs.BoolVar(&t.Anonymize, "anonymize", false, "replace names, emails, phone numbers and IDs of the -json sample with consistent pseudonyms and shift its dates")
+	fs.StringVar(&t.AnonymizeSalt, "anonymizesalt", "", "secret mixed into the -anonymize pseudonyms, so they can't be reversed by hashing guessed values")
+	fs.StringVar(&t.SQL, "sql", "", "path to a SQL script with CREATE TABLE statements to generate fixtures for instead of a Go package")
+	fs.StringVar(&t.Seeds, "seeds", "", "also write INSERT statements matching the fixtures to this file (with -sql)")
+	fs.StringVar(&t.OpenAPI, "openapi", "", "path to an OpenAPI 3 document (JSON) to generate fixtures for instead of a Go package")
+	fs.StringVar(&t.Descriptors, "descriptors", "", "path to a protobuf FileDescriptorSet (protoc --descriptor_set_out) to generate fixtures for instead of the generated Go code")
+	fs.StringVar(&t.OutPkg, "outpkg", "fixtures", "package name for the generated file")
+	fs.StringVar(&t.Header, "header", "", "comment opening the generated files (default: 'Code generated by fixture-generator <args>. DO NOT EDIT.')")
+	fs.StringVar(&t.Out, "out", "", "output file path (prints to stdout if not specified)")
+	fs.StringVar(&t.OutDir, "outdir", "", "split the output into files in this directory, mirroring the source files of the types, instead of writing one -out file")
+	fs.Var(fs.Lookup("outdir").Value, "out-dir", "same as -outdir")
+	fs.IntVar(&t.TypesPerFile, "typesperfile", 0, "with -outdir, split the output into files of this many types each instead of mirroring the source files")
+	fs.BoolVar(&t.Self, "self", false, "generate into the package of the source types, as <package>_fixtures.go next to them unless -out is given")
+	fs.StringVar(&t.TypePrefix, "typeprefix", "", "prefix for type names (e.g., 'productionorderbase' -> 'productionorderbase.Operation')")
+	fs.StringVar(&t.TypeImport, "typeimport", "", "import path of the -typeprefix package (default: that of -pkg)")
+	fs.StringVar(&t.FuncPrefix, "funcprefix", "", "prefix for fixture function names (e.g., 'PB' -> 'FixturePBOperation')")
+	t.ModStyle = fs.Bool("modstyle", true, "generate fixtures with functional options pattern (default: true)")
+	fs.StringVar(&t.ModSignature, "modsig", "pointer", "signature of the mods of -modstyle fixtures: 'pointer' (func(*T)) or 'value' (func(T) T)")
+	fs.StringVar(&t.ModReturn, "modreturn", "pointer", "what -modstyle fixtures return: 'pointer' (*T) or 'value' (T, with mods applied to a local copy)")
+	fs.BoolVar(&t.NilOptionals, "niloptionals", false, "leave pointers to scalars nil, like proto3 optional fields, instead of pointing them to a value")
+	fs.BoolVar(&t.Sparse, "sparse", false, "leave pointer fields of -modstyle fixtures nil and generate WithX mods populating them")
+	fs.BoolVar(&t.Options, "options", false, "generate a WithX(v) mod per field of -modstyle fixtures setting it to v")
+	fs.BoolVar(&t.Incremental, "incremental", false, "record the content hash in each output file and skip the files it still matches, also without the stamps in the user cache")
+	jobs := fs.Int("j", runtime.NumCPU(), "number of packages to process in parallel")
+	fs.BoolVar(&t.IncludeTests, "include-tests", false, "also extract the types declared in the _test.go files of the package")
+	fs.StringVar(&t.GOOS, "goos", "", "load the package as built for this GOOS, for types declared in platform-specific files (default: the host's)")
+	fs.StringVar(&t.GOARCH, "goarch", "", "load the package as built for this GOARCH (default: the host's)")
+	fs.BoolVar(&t.Deep, "deep", false, "load the full dependency graph from source when struct fields reference other packages, whose values are then filled in instead of left zero")
+	fs.BoolVar(&t.Force, "force", false, "always format and write the output, even if it would be unchanged")
+	fs.BoolVar(&t.SelfTest, "selftest", false, "type-check the generated fixtures against the loaded package and fail with the type errors instead of writing them")
+	fs.StringVar(&t.SeedDir, "seeddir", "", "also write a numbered seed migration with the fixture INSERTs of each table into this directory")
+	fs.StringVar(&t.SeedFormat, "seedformat", "migrate", "format of -seeddir files: 'migrate' (golang-migrate) or 'goose'")
+	fs.BoolVar(&t.SeedFuncs, "seedfuncs", false, "also generate SeedX(ctx, db, mods...) helpers inserting fixtures of structs with db or gorm tags")
+	fs.StringVar(&t.Placeholder, "placeholder", "$", "bind parameter style of -seedfuncs queries: '$' ($1, $2) or '?'")
+	fs.Var(mapFlag(t.Pairwise), "pairwise", "also generate PairwiseX() returning fixtures covering every pair of values of some fields, as 'Type=Field,Field' (repeatable)")
+	fs.Var(mapFlag(t.Aggregates), "aggregate", "also generate ArrangeXAggregate() returning the fixtures of a struct and related ones wired by IDs and references, as 'Root=Type,Type' (repeatable)")
+	fs.Var(mapFlag(t.Relations), "relation", "give a field the value of the field it refers to in fixtures and seeds, so related fixtures share keys, as 'Order.CustomerID=Customer.ID' (repeatable)")
+	fs.BoolVar(&t.Rapid, "rapid", false, "also generate RapidX(pin...) property test generators for pgregory.net/rapid, drawing each field on its own so failures shrink")
+	fs.BoolVar(&t.Clock, "clock", false, "set time fields from a generated FixtureNow func that test suites freezing time can point at their clock")
+	fs.Var(mapFlag(t.Weights), "weights", "weights the -rapid generators draw enum values or oneof implementations with, as 'Type=Value:80,Value:20' (repeatable)")
+	fs.BoolVar(&t.Hooks, "hooks", false, "also generate XDefaults variables with a func per field that fixtures take the value from, for test suites to re-point")
+	fs.StringVar(&t.Providers, "providers", "", "also generate ProvideFixtureX constructors and a FixtureProviders set of them for dependency injection: 'wire' or 'fx'")
+	fs.StringVar(&t.Values, "values", "static", "how fixtures get their values: 'static' literals, 'random' values drawn at runtime, reseeded with the generated SeedFixtures(seed), or 'faker' realistic literals for fields like Email, Phone or City")
+	fs.Int64Var(&t.FakerSeed, "fakerseed", 0, "seed picking the -values faker literals, which stay the same for the same seed")
+	fs.IntVar(&t.Bytes, "bytes", 0, "length of the deterministic data []byte fields get instead of []byte(\"Field\")")
+	fs.StringVar(&t.Interfaces, "interfaces", "nil", "what fields of interface types are set to: 'nil', 'defaults' for standard implementations of io.Reader, context.Context and other well-known interfaces, or 'stub' for those and generated StubX implementations of the package's interfaces")
+	fs.IntVar(&t.MaxDepth, "maxdepth", 0, "levels of recursive references (Category.Parent) fixtures populate with nested literals; deeper ones are nil")
+	fs.BoolVar(&t.Dump, "dump", false, "also generate DumpX(v) helpers rendering a fixture one field per line, for failure messages")
+	fs.StringVar(&t.EnumDefault, "enumdefault", "first", "value enum fixtures return: the 'first' declared or the first 'specified' one, skipping values like STATUS_UNSPECIFIED or StatusUnknown")
+	fs.Var(mapFlag(t.EnumValues), "enumvalue", "value the fixture of an enum returns, as 'Enum=Value' by constant or label (repeatable)")
+	fs.BoolVar(&t.EnumConstructors, "enumconstructors", false, "also generate a FixtureX per enum value X returning it, e.g. FixtureStatusActive()")
+	fs.BoolVar(&t.Registry, "registry", false, "also generate a Fixtures() func returning a constructor of every fixture by type name")
+	fs.StringVar(&t.Asserts, "asserts", "", "also generate AssertXEqual(t, expected, actual) helpers reporting a go-cmp diff without unexported fields: 'cmp', or 'protocmp' for protobuf messages")
+	fs.BoolVar(&t.Calls, "calls", false, "also generate a FixtureXCall() per pair of XRequest and XResponse structs, returning the request and response of the gRPC method X")
+	fs.StringVar(&t.RoundTrip, "roundtrip", "", "also write a test file marshaling every fixture and unmarshaling it back, failing if it changed")
+	fs.StringVar(&t.RoundTripFormat, "roundtripformat", "json", "encoding of -roundtrip tests: 'json' or 'protojson' (protobuf messages with protojson)")
+	fs.StringVar(&t.ValidateTests, "validatetests", "", "also write a test file asserting every fixture passes its Validate/ValidateAll method (protoc-gen-validate)")
+	fs.BoolVar(&t.Protovalidate, "protovalidate", false, "in -validatetests, check protobuf messages without such a method with protovalidate")
+	fs.StringVar(&t.Testdata, "testdata", "", "also write the fixture of every struct as <type>.yaml into this directory and generate LoadXFixture(t, name) loaders for such files")
+	fs.StringVar(&t.Snapshots, "snapshots", "", "also generate WriteSnapshots(dir) serializing every fixture with a manifest: 'json' or 'protojson'")
+	fs.Var(mapFlag(t.Routes), "route", "serve a fixture from the generated FixtureHandler, as 'PATTERN=Type' (e.g. 'GET /users/{id}=User', repeatable)")
+	fs.StringVar(&t.Examples, "examples", "", "add the fixtures as examples to the matching schemas of this OpenAPI document (JSON, edited in place)")
+	fs.Var(mapFlag(t.Endpoints), "endpoint", "an endpoint of the service, as 'METHOD /path=Request:Response' or 'METHOD /path=Response' (repeatable)")
+	fs.StringVar(&t.WireMock, "wiremock", "", "also write a WireMock stub mapping per -endpoint into this directory")
+	fs.StringVar(&t.HTTPFile, "httpfile", "", "also write an .http file with a request per -endpoint, using the request fixture as body")
+	fs.StringVar(&t.Curl, "curl", "", "also write a shell script with a curl command per -endpoint, using the request fixture as body")
+	fs.StringVar(&t.BaseURL, "baseurl", "http://localhost:8080", "base URL of the requests in -httpfile and -curl")
+	fs.Var(mapFlag(t.FieldOverrides), "override", "set a field to a Go expression instead of its generated value, as 'Struct.Field=expr' (repeatable)")
+	fs.IntVar(&t.SliceLen, "slicelen", 1, "number of elements of slices, whose strings and numbers differ between the elements (\"Tags1\", \"Tags2\")")
+	fs.Var(mapFlag(t.SliceLens), "len", "number of elements of a slice field, as 'Struct.Field=N', 0 for an empty slice (repeatable)")
+	fs.Var(mapFlag(t.AnyPayloads), "any", "pack a message fixture into an anypb.Any field, as 'Struct.Field=Message' or 'Field=Message' (repeatable)")
+	fs.Var(mapFlag(t.ExternalTypes), "externaltype", "default value of a type without fixtures, as 'importpath.Type=expression', e.g. 'github.com/google/uuid.UUID=uuid.MustParse(\"...\")' (repeatable)")
+	fs.Var(mapFlag(t.FixturePackages), "fixturepkg", "reuse an existing fixtures package for the types of another package, as 'typepkg=fixturepkg' import paths (repeatable)")
+	fs.BoolVar(&t.Opaque, "opaque", false, "build protobuf messages of the hybrid API through their Message_builder too (opaque API messages always are)")
+	fs.Var((*repeatFlag)(&t.ExcludeConsts), "excludeconst", "regular expression matching generator-internal constants that aren't enum values, e.g. '^_minVersion$' (repeatable)")
+	fs.Var((*repeatFlag)(&t.Include), "include", "generate fixtures only for the types matching this glob ('*Request') or regular expression, and the types they need (repeatable)")
+	fs.Var((*repeatFlag)(&t.Exclude), "exclude", "skip the fixtures of the types matching this glob or regular expression, unless an included type needs them (repeatable)")
+	fs.Var((*listFlag)(&t.SkipFields), "skipfields", "comma-separated generated-code internal fields to leave out besides the protobuf ones, as 'Field' or 'Type.Field' (repeatable)")
+	fs.StringVar(&t.Summary, "summary", "", "also write the generation summary printed to stderr as JSON to this file")
+	fs.BoolVar(&t.Monorepo, "monorepo", false, "load only the target package plus the packages its struct fields reference, filling in the values of their types (for very large repositories)")
+	fs.StringVar(&t.FailOn, "fail-on", "", "comma-separated conditions that fail the run: load, skipped, format, drift or all")
+	fs.StringVar(&t.GoVersion, "go", "", "Go version the generated code has to build with; before 1.18 typed ptr helpers replace the generic one")
+	fs.StringVar(&t.Plugin, "plugin", "", "executable receiving the model as JSON on stdin and returning files to write (see generator.PluginRequest)")
+	fs.StringVar(&t.PluginOpt, "plugin-opt", "", "parameter passed to -plugin")
+	fs.StringVar(&t.PluginOut, "plugin-out", "", "directory the -plugin files are written to (default: the directory of -out)")
+	profileFlag(fs)
+	fs.StringVar(&t.WarnOn, "warn-on", "", "comma-separated conditions that only print a warning, overriding -fail-on (drift fails by default)")
+	return t, jobs
+}
 
-	pkgs := load(*pkgPath)
-	model := extract(pkgs)
-	opts := generator.GenerateOptions{
-		TypePrefix: *typePrefix,
-		FuncPrefix: *funcPrefix,
-		ModStyle:   *modStyle,
+// validate checks the flags of the generate and check commands
+func (t *target) validate() error {
+	if t.Pkg == "" && t.Src == "" && t.OpenAPI == "" && t.JSON == "" && t.SQL == "" && t.Descriptors == "" {
+		return fmt.Errorf("-pkg, -src, -openapi, -sql, -descriptors or -json flag is required")
+	}
+	if t.Src != "" && (t.Pkg != "" || t.OpenAPI != "" || t.SQL != "" || t.Descriptors != "") {
+		return fmt.Errorf("-src can't be combined with -pkg, -openapi, -sql or -descriptors")
+	}
+	if t.Self && (t.Pkg == "" || t.TypePrefix != "") {
+		return fmt.Errorf("-self needs -pkg and no -typeprefix")
+	}
+	if multiPackage(t.Pkg) && (t.Out != "" || t.OutDir != "" || t.TypePrefix != "" || t.JSON != "") {
+		return fmt.Errorf("-out, -outdir, -typeprefix and -json can't be used when -pkg names several packages, whose outputs are set per package")
+	}
+	if t.OutDir != "" && (t.Out != "" || t.Self) {
+		return fmt.Errorf("-outdir can't be combined with -out or -self")
+	}
+	if t.TypesPerFile < 0 || t.TypesPerFile > 0 && t.OutDir == "" {
+		return fmt.Errorf("-typesperfile needs -outdir and a positive number of types")
+	}
+	if t.Asserts != "" && t.Asserts != "cmp" && t.Asserts != "protocmp" {
+		return fmt.Errorf("-asserts must be 'cmp' or 'protocmp'")
+	}
+	if t.Snapshots != "" && t.Snapshots != "json" && t.Snapshots != "protojson" {
+		return fmt.Errorf("-snapshots must be 'json' or 'protojson'")
+	}
+	if t.EnumDefault != "" && t.EnumDefault != "first" && t.EnumDefault != "specified" {
+		return fmt.Errorf("-enumdefault must be 'first' or 'specified'")
+	}
+	if t.Values != "" && t.Values != "static" && t.Values != "random" && t.Values != "faker" {
+		return fmt.Errorf("-values must be 'static', 'random' or 'faker'")
+	}
+	if t.Interfaces != "" && t.Interfaces != "nil" && t.Interfaces != "defaults" && t.Interfaces != "stub" {
+		return fmt.Errorf("-interfaces must be 'nil', 'defaults' or 'stub'")
+	}
+	if t.Values == "random" && len(t.Relations) > 0 {
+		return fmt.Errorf("-relation needs -values static, random values would differ between the related fixtures")
+	}
+	if t.MaxDepth < 0 {
+		return fmt.Errorf("-maxdepth must not be negative")
+	}
+	if t.SliceLen < 0 {
+		return fmt.Errorf("-slicelen must not be negative")
+	}
+	if t.Bytes < 0 {
+		return fmt.Errorf("-bytes must not be negative")
+	}
+	if t.Providers != "" && t.Providers != "wire" && t.Providers != "fx" {
+		return fmt.Errorf("-providers must be 'wire' or 'fx'")
+	}
+	if t.ModSignature != "" && t.ModSignature != "pointer" && t.ModSignature != "value" {
+		return fmt.Errorf("-modsig must be 'pointer' or 'value'")
+	}
+	if t.ModReturn != "" && t.ModReturn != "pointer" && t.ModReturn != "value" {
+		return fmt.Errorf("-modreturn must be 'pointer' or 'value'")
+	}
+	if t.Sparse && t.ModStyle != nil && !*t.ModStyle {
+		return fmt.Errorf("-sparse needs -modstyle, its WithX helpers are mods")
+	}
+	if t.Options && t.ModStyle != nil && !*t.ModStyle {
+		return fmt.Errorf("-options needs -modstyle, its WithX helpers are mods")
+	}
+	if t.RoundTripFormat != "" && t.RoundTripFormat != "json" && t.RoundTripFormat != "protojson" {
+		return fmt.Errorf("-roundtripformat must be 'json' or 'protojson'")
+	}
+	if _, err := t.excludedConstants(); err != nil {
+		return err
+	}
+	if _, err := generator.CompileTypePatterns(t.Include); err != nil {
+		return fmt.Errorf("-include: %w", err)
+	}
+	if _, err := generator.CompileTypePatterns(t.Exclude); err != nil {
+		return fmt.Errorf("-exclude: %w", err)
+	}
+	if t.Placeholder != "$" && t.Placeholder != "?" {
+		return fmt.Errorf("-placeholder must be '$' or '?'")
+	}
+	if t.Anonymize && t.JSON == "" {
+		return fmt.Errorf("-anonymize needs a -json sample")
+	}
+	if (t.Pkg != "" || t.Src != "") && t.JSON != "" && t.JSONType == "" {
+		return fmt.Errorf("-jsontype is required when -json is used with -pkg or -src")
+	}
+	if t.sampleOnly() && t.TypePrefix != "" {
+		return fmt.Errorf("-typeprefix can't be used with -json alone, whose structs are declared in the output")
+	}
+	if minor := generator.GoMinor(t.GoVersion); t.GoVersion != "" && minor == 0 {
+		return fmt.Errorf("-go %q is not a Go version like 1.17", t.GoVersion)
+	} else if minor > 0 && minor < 22 && len(t.Routes) > 0 {
+		return fmt.Errorf("-route needs the method patterns of Go 1.22's http.ServeMux, not -go %s", t.GoVersion)
+	} else if minor > 0 && minor < 18 && t.Snapshots != "" {
+		return fmt.Errorf("-snapshots needs Go 1.18, not -go %s", t.GoVersion)
+	} else if len(t.Weights) > 0 && !t.Rapid {
+		return fmt.Errorf("-weights needs -rapid, whose generators draw the weighted values")
+	} else if minor > 0 && minor < 18 && t.Rapid {
+		return fmt.Errorf("-rapid needs the generics of Go 1.18, not -go %s", t.GoVersion)
+	}
+	return t.validatePolicy()
+}
+
+// generateCommand implements `fixture-generator generate`, the default command
+func generateCommand(fs *flag.FlagSet) func() int {
+	t, jobs := generateFlags(fs)
+	interactive := fs.Bool("interactive", false, "pick the types to generate fixtures for in an interactive terminal UI with a live preview")
+	check := fs.Bool("check", false, "regenerate in memory and exit non-zero with a diff if -out is out of date, without writing it (like the check command)")
+	return func() int {
+		if err := applyProfile(fs); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+		if err := t.validate(); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+		if *check {
+			if *interactive || multiPackage(t.Pkg) {
+				fmt.Fprintln(os.Stderr, "error: -check needs -pkg to name a single package and no -interactive")
+				return 1
+			}
+			return runCheck(t, *jobs, true, "auto")
+		}
+		if multiPackage(t.Pkg) {
+			if *interactive {
+				fmt.Fprintln(os.Stderr, "error: -interactive needs -pkg to name a single package")
+				return 1
+			}
+			targets, err := packageTargets(*t)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				return exitCode(err)
+			}
+			return printBatchReport(runTargets(targets, *jobs, newLoadCache(load).load))
+		}
+		loader := loaderFunc(load)
+		if *interactive {
+			// The UI and the final generation share one load of the package
+			loader = newLoadCache(load).load
+			types, err := pickTypes(*t, *jobs, loader)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				return exitCode(err)
+			}
+			if len(types) == 0 {
+				fmt.Fprintln(os.Stderr, "no types selected")
+				return 0
+			}
+			t.Types = types
+		}
+		if _, err := generateTarget(*t, *jobs, loader); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return exitCode(err)
+		}
+		return 0
+	}
+}
+
+// target describes one package to generate fixtures for
+type target struct {
+	Pkg         string `json:"pkg"`
+	Src         string `json:"src"` // Go source file, "-" for stdin
+	OpenAPI     string `json:"openapi"`
+	SQL         string `json:"sql"`
+	Descriptors string `json:"descriptors"`
+	Seeds       string `json:"seeds"`
+	SeedDir     string `json:"seeddir"`
+	SeedFormat  string `json:"seedformat"` // "migrate" (default) or "goose"
+	JSON        string `json:"json"`
+	JSONType    string `json:"jsontype"`
+	OutPkg      string `json:"outpkg"`
+	Out         string `json:"out"`
+	// OutDir is the directory the output is split into files in, see generator.FileLayout
+	OutDir string `json:"outdir"`
+	// TypesPerFile is how many types each file in OutDir holds, 0 to mirror the source files
+	TypesPerFile int    `json:"typesperfile"`
+	Header       string `json:"header"` // "" means the generated code marker with the command line
+	TypePrefix   string `json:"typeprefix"`
+	TypeImport   string `json:"typeimport"` // "" means the import path of the loaded package
+	FuncPrefix   string `json:"funcprefix"`
+	ModStyle     *bool  `json:"modstyle"` // nil means the default (true)
+	Incremental  bool   `json:"incremental"`
+	Deep         bool   `json:"deep"`
+	Monorepo     bool   `json:"monorepo"`
+	Force        bool   `json:"force"`
+	SelfTest     bool   `json:"selftest"`
+	SeedFuncs    bool   `json:"seedfuncs"`
+	Placeholder  string `json:"placeholder"`
+	Snapshots    string `json:"snapshots"` // "json" or "protojson"
+	Testdata     string `json:"testdata"`
+	WireMock     string `json:"wiremock"`
+	Examples     string `json:"examples"`
+	HTTPFile     string `json:"httpfile"`
+	Curl         string `json:"curl"`
+	BaseURL      string `json:"baseurl"`
+	Summary      string `json:"summary"`
+	// FailOn and WarnOn are comma-separated conditions (load, skipped, format, drift or all) that fail
+	// the target or are only warned about; by default only drift fails
+	FailOn string `json:"failOn"`
+	WarnOn string `json:"warnOn"`
+	// GoVersion is the Go version the generated code has to build with, e.g. "1.17"
+	GoVersion string `json:"go"`
+	// ModSignature is "pointer" for func(*T) mods (the default) or "value" for func(T) T
+	ModSignature string `json:"modsig"`
+	// ModReturn is "pointer" for fixtures returning *T (the default) or "value" for T
+	ModReturn string `json:"modreturn"`
+	// NilOptionals leaves pointers to scalars nil
+	NilOptionals bool `json:"niloptionals"`
+	// Sparse leaves pointer fields nil, to be populated by the generated WithX mods
+	Sparse bool `json:"sparse"`
+	// Options generates a WithX(v) mod per field setting it to v
+	Options bool `json:"options"`
+	// Clock sets time fields from the generated FixtureNow func instead of a literal
+	Clock bool `json:"clock"`
+	// Weights maps enum and oneof interface names to comma-separated "Value:weight" pairs the RapidX
+	// generators draw them with
+	Weights map[string]string `json:"weights"`
+	// Hooks generates XDefaults variables the fixtures take their field values from
+	Hooks bool `json:"hooks"`
+	// Providers generates a FixtureProviders set for dependency injection: "wire" or "fx"
+	Providers string `json:"providers"`
+	// Values is "static", "random" or "faker", the ValueStrategy of the fixtures
+	Values string `json:"values"`
+	// FakerSeed picks the values of the "faker" strategy
+	FakerSeed int64 `json:"fakerseed"`
+	// Bytes is the ByteLength of []byte fields, 0 for []byte("Field")
+	Bytes int `json:"bytes"`
+	// Interfaces is "nil", "defaults" or "stub", the InterfaceStrategy of the fixtures
+	Interfaces string `json:"interfaces"`
+	// MaxDepth is how many levels of recursive references fixtures populate
+	MaxDepth int `json:"maxdepth"`
+	// Dump generates DumpX helpers rendering fixtures for failure messages
+	Dump bool `json:"dump"`
+	// EnumDefault is the policy picking the value of enum fixtures, see generator.Model.PickEnumDefaults
+	EnumDefault string `json:"enumdefault"`
+	// EnumValues maps enum names to the value their fixture returns, by constant or label
+	EnumValues map[string]string `json:"enumvalues"`
+	// EnumConstructors generates a fixture per enum value
+	EnumConstructors bool `json:"enumconstructors"`
+	// Registry generates a Fixtures() func listing every fixture by type name
+	Registry bool `json:"registry"`
+	// Calls generates a FixtureXCall() per request and response pair
+	Calls bool `json:"calls"`
+	// Asserts generates AssertXEqual helpers: "cmp" or "protocmp"
+	Asserts string `json:"asserts"`
+	// Pairwise maps struct names to the comma-separated fields combined pairwise by PairwiseX
+	Pairwise map[string]string `json:"pairwise"`
+	// Aggregates maps root struct names to the comma-separated related structs arranged with them by
+	// ArrangeXAggregate
+	Aggregates map[string]string `json:"aggregates"`
+	// Relations maps "Struct.Field" to the "Struct.Field" it refers to, whose value it takes
+	Relations map[string]string `json:"relations"`
+	// Rapid generates RapidX generators for property tests with pgregory.net/rapid
+	Rapid bool `json:"rapid"`
+	// RoundTrip is the test file checking that fixtures survive a RoundTripFormat ("json" or
+	// "protojson") round trip
+	RoundTrip       string `json:"roundtrip"`
+	RoundTripFormat string `json:"roundtripformat"`
+	// ValidateTests is the test file asserting that the fixtures pass validation, with protovalidate
+	// for messages without a Validate method if Protovalidate is set
+	ValidateTests string `json:"validatetests"`
+	Protovalidate bool   `json:"protovalidate"`
+	// Anonymize replaces the personal data of the JSON sample with pseudonyms derived from
+	// AnonymizeSalt, see generator.AnonymizeJSON
+	Anonymize     bool   `json:"anonymize"`
+	AnonymizeSalt string `json:"anonymizeSalt"`
+	// Self writes the fixtures into the package of the source types, see selfPackage
+	Self bool `json:"self"`
+	// IncludeTests extracts the types of _test.go files as well, which are skipped by default
+	IncludeTests bool `json:"includeTests"`
+	// GOOS and GOARCH select the platform whose files make up the package, the host's by default
+	GOOS   string `json:"goos"`
+	GOARCH string `json:"goarch"`
+	// SkipFields are left out of the fixtures in addition to generator.ProtoInternalFields, as "Field"
+	// or "Type.Field", for the internal fields of other code generators
+	SkipFields []string `json:"skipFields"`
+	// Opaque builds every protobuf message with a builder through it, also those of the hybrid API
+	// whose fields could be set in a literal. Messages of the opaque API always are.
+	Opaque bool `json:"opaque"`
+	// ExcludeConsts are regular expressions matching constants that aren't enum values, in addition
+	// to generator.InternalConstants
+	ExcludeConsts []string `json:"excludeConsts"`
+	// Plugin is an executable rendering the model into further files, see generator.PluginRequest
+	Plugin    string `json:"plugin"`
+	PluginOpt string `json:"pluginOpt"`
+	PluginOut string `json:"pluginOut"`
+	// Types limits generation to these types and the types their fixtures need
+	Types []string `json:"types"`
+	// Include and Exclude filter the types fixtures are generated for by glob or regular expression,
+	// keeping the types the remaining fixtures need
+	Include []string `json:"include"`
+	Exclude []string `json:"exclude"`
+	// FieldOverrides maps "Struct.Field" to the Go expression the field is set to
+	FieldOverrides map[string]string `json:"overrides"`
+	// SliceLen is the number of elements of slices
+	SliceLen int `json:"slicelen"`
+	// SliceLens maps "Struct.Field" to the number of elements of that slice field
+	SliceLens map[string]string `json:"lens"`
+	// AnyPayloads maps "Struct.Field" or "Field" to the message packed into that anypb.Any field
+	AnyPayloads map[string]string `json:"any"`
+	// Endpoints maps "METHOD /path" to "Request:Response" body types, for stub mappings and request samples
+	Endpoints map[string]string `json:"endpoints"`
+	// Routes maps HTTP route patterns to the type served on them by the generated FixtureHandler
+	Routes map[string]string `json:"routes"`
+	// FixturePackages maps type package import paths to existing fixtures packages for them.
+	// Packages with a "fixtures" subpackage are picked up without being listed.
+	FixturePackages map[string]string `json:"fixturePackages"`
+	// ExternalTypes maps "importpath.Type" to the Go expression fields of the type default to
+	ExternalTypes map[string]string `json:"externalTypes"`
+
+	// onePackageOf is set on the targets of the packages -pkg ./... matched, whose -include patterns
+	// only have to match types of some of them
+	onePackageOf bool
+}
+
+// deps returns how much of the dependency graph has to be loaded for t
+func (t target) deps() depMode {
+	switch {
+	case t.Deep:
+		return depsAll
+	case t.Monorepo:
+		return depsDirect
+	}
+	return depsNone
+}
+
+// loadOptions returns how the package of t is loaded
+func (t target) loadOptions() loadOptions {
+	return loadOptions{deps: t.deps(), tests: t.IncludeTests, goos: t.GOOS, goarch: t.GOARCH}
+}
+
+// excludedConstants compiles the -excludeconst patterns of t
+func (t target) excludedConstants() ([]*regexp.Regexp, error) {
+	var patterns []*regexp.Regexp
+	for _, pattern := range t.ExcludeConsts {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("-excludeconst: %w", err)
+		}
+		patterns = append(patterns, re)
 	}
-	out, _ := generator.GenerateFormattedWithOptions(model, *pkgName, opts)
+	return patterns, nil
+}
 
-	// Format the output
-	formatted, err := format.Source([]byte(out))
+// source returns the input t is generated from, for messages
+func (t target) source() string {
+	switch {
+	case t.OpenAPI != "":
+		return t.OpenAPI
+	case t.SQL != "":
+		return t.SQL
+	case t.Descriptors != "":
+		return t.Descriptors
+	case t.Src == "-":
+		return "stdin"
+	case t.Src != "":
+		return t.Src
+	case t.Pkg == "":
+		return t.JSON
+	}
+	return t.Pkg
+}
+
+// model builds the model for t from its Go package or source file, OpenAPI document, SQL script or protobuf descriptors. A JSON
+// sample either provides the defaults for a struct of the package or source file or, on its own, is the model.
+// The loaded packages are returned as well when t is a Go package. Fields with Relations take the value
+// of the field they refer to. If t lists types or Include/Exclude patterns, the model is limited to
+// them and the types their fixtures need.
+func (t target) model(jobs int, loader loaderFunc) (*generator.Model, []*packages.Package, error) {
+	m, pkgs, err := t.fullModel(jobs, loader)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := m.Relate(t.Relations); err != nil {
+		return nil, nil, err
+	}
+	include, err := generator.CompileTypePatterns(t.Include)
 	if err != nil {
-		formatted = []byte(out)
+		return nil, nil, fmt.Errorf("-include: %w", err)
+	}
+	// A pattern matching nothing is most likely a typo, which would silently drop its fixtures
+	names := m.TypeNames()
+	for i, re := range include {
+		if !t.onePackageOf && !slices.ContainsFunc(names, re.MatchString) {
+			return nil, nil, fmt.Errorf("-include %q matches no type", t.Include[i])
+		}
+	}
+	exclude, err := generator.CompileTypePatterns(t.Exclude)
+	if err != nil {
+		return nil, nil, fmt.Errorf("-exclude: %w", err)
+	}
+	if err := m.PickEnumDefaults(t.EnumDefault, t.EnumValues); err != nil {
+		return nil, nil, err
+	}
+	m = m.Filter(include, exclude)
+	if len(t.Types) == 0 {
+		return m, pkgs, nil
+	}
+	return m.Subset(t.Types), pkgs, nil
+}
+
+func (t target) fullModel(jobs int, loader loaderFunc) (*generator.Model, []*packages.Package, error) {
+	if t.OpenAPI != "" {
+		data, err := os.ReadFile(t.OpenAPI)
+		if err != nil {
+			return nil, nil, err
+		}
+		m, err := generator.ParseOpenAPI(data)
+		return m, nil, err
+	}
+	if t.SQL != "" {
+		data, err := os.ReadFile(t.SQL)
+		if err != nil {
+			return nil, nil, err
+		}
+		m, err := generator.ParseDDL(string(data))
+		return m, nil, err
+	}
+	if t.Descriptors != "" {
+		data, err := os.ReadFile(t.Descriptors)
+		if err != nil {
+			return nil, nil, err
+		}
+		m, err := generator.ParseDescriptorSet(data)
+		return m, nil, err
+	}
+
+	var sample []byte
+	if t.JSON != "" {
+		var err error
+		if sample, err = os.ReadFile(t.JSON); err != nil {
+			return nil, nil, err
+		}
+		if t.Anonymize {
+			if sample, err = generator.AnonymizeJSON(sample, t.AnonymizeSalt); err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", t.JSON, err)
+			}
+		}
 	}
 
-	if *outFile != "" {
-		err := os.WriteFile(*outFile, formatted, 0644)
+	if t.Src != "" {
+		m, err := t.sourceModel()
 		if err != nil {
-			panic(err)
+			return nil, nil, err
+		}
+		if sample != nil {
+			if err := generator.ApplySample(m, t.JSONType, sample); err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", t.JSON, err)
+			}
+		}
+		return m, nil, nil
+	}
+
+	if t.Pkg == "" {
+		name := t.JSONType
+		if name == "" {
+			name = "Sample"
+		}
+		m, err := generator.InferJSON(name, sample)
+		return m, nil, err
+	}
+
+	pkgs, err := loader(t.Pkg, t.loadOptions())
+	if err != nil {
+		return nil, nil, err
+	}
+	excluded, err := t.excludedConstants()
+	if err != nil {
+		return nil, nil, err
+	}
+	m := extract(pkgs, jobs)
+	// The packages -deep and -monorepo load give the fields of their types values
+	if t.deps() != depsNone {
+		m.Deps = generator.ExtractDeps(pkgs, fieldDeps(pkgs))
+	}
+	if t.Opaque {
+		generator.UseBuilders(pkgs, m)
+	}
+	m.SkipFields(t.SkipFields)
+	m.ExcludeConstants(excluded)
+	if sample != nil {
+		if err := generator.ApplySample(m, t.JSONType, sample); err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", t.JSON, err)
 		}
+	}
+	return m, pkgs, nil
+}
+
+// stdin is the Go source of -src -, read once however often the model is built
+var stdin = sync.OnceValues(func() ([]byte, error) {
+	return io.ReadAll(os.Stdin)
+})
+
+// sourceModel parses the Go source file of -src into a model, as the WASM build does with the source
+// pasted into it. Without type information only the types declared in the file are known.
+func (t target) sourceModel() (*generator.Model, error) {
+	var src []byte
+	var err error
+	if t.Src == "-" {
+		src, err = stdin()
 	} else {
-		fmt.Print(string(formatted))
+		src, err = os.ReadFile(t.Src)
 	}
+	if err != nil {
+		return nil, err
+	}
+	excluded, err := t.excludedConstants()
+	if err != nil {
+		return nil, err
+	}
+	m, err := generator.ParseSource(string(src))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", t.source(), err)
+	}
+	m.SkipFields(t.SkipFields)
+	m.ExcludeConstants(excluded)
+	return m, nil
 }
 
-func load(pattern string) []*packages.Package {
-	absPath, err := filepath.Abs(pattern)
+// generateTarget loads, extracts and writes fixtures for t.
+// It reports skipped=true when incremental generation found the output up to date.
+func generateTarget(t target, jobs int, loader loaderFunc) (skipped bool, err error) {
+	model, pkgs, err := t.model(jobs, loader)
 	if err != nil {
-		panic(err)
+		return false, err
+	}
+	if t.Self {
+		if err := t.selfPackage(pkgs); err != nil {
+			return false, err
+		}
+	}
+	if n := loadErrors(pkgs); n > 0 {
+		if err := t.condition(condLoad, fmt.Sprintf("%s has %d load errors", t.Pkg, n)); err != nil {
+			return false, err
+		}
+	}
+	if t.Seeds != "" {
+		if err := os.WriteFile(t.Seeds, []byte(generator.GenerateSQLSeeds(model)), 0644); err != nil {
+			return false, err
+		}
+	}
+	opts, err := t.options(model, pkgs)
+	if err != nil {
+		return false, err
+	}
+	if t.Examples != "" {
+		if err := addExamples(t.Examples, model, opts); err != nil {
+			return false, err
+		}
+	}
+	if err := t.writeEndpointFiles(model, opts); err != nil {
+		return false, err
+	}
+	if t.SeedDir != "" {
+		format := t.SeedFormat
+		if format == "" {
+			format = "migrate"
+		}
+		if err := writeSeedFiles(t.SeedDir, format, generator.SQLSeeds(model)); err != nil {
+			return false, err
+		}
+	}
+	// Fail before writing anything if skipped fields are fatal
+	summary := generator.Summarize(model, opts)
+	if n := len(summary.Skipped) + len(summary.Missing); n > 0 && t.fatal(condSkipped) {
+		return false, fmt.Errorf("%d types or fields would be skipped (%s):\n%s", n, condSkipped, summary)
+	}
+	var formatErrors []error
+	opts.FormatError = func(err error) {
+		// Point the diagnostics at the file written
+		if fe, ok := err.(*generator.FormatError); ok {
+			switch {
+			case t.OutDir != "":
+				fe.File = filepath.Join(t.OutDir, fe.File)
+			case t.Out != "":
+				fe.File = t.Out
+			default:
+				fe.File = "stdout"
+			}
+		}
+		formatErrors = append(formatErrors, err)
+	}
+	outPkg := t.outPkg()
+	if t.RoundTrip != "" {
+		tests, err := generator.GenerateRoundTripTests(model, outPkg, opts, t.RoundTripFormat)
+		if err != nil {
+			return false, err
+		}
+		if err := os.WriteFile(t.RoundTrip, tests, 0644); err != nil {
+			return false, err
+		}
+	}
+	if t.Testdata != "" {
+		if err := writeYAMLFiles(t.Testdata, model, opts); err != nil {
+			return false, err
+		}
+	}
+	if t.ValidateTests != "" {
+		tests, err := generator.GenerateValidationTests(model, outPkg, opts, t.Protovalidate)
+		if err != nil {
+			return false, err
+		}
+		if err := os.WriteFile(t.ValidateTests, tests, 0644); err != nil {
+			return false, err
+		}
+	}
+	if t.Plugin != "" {
+		if err := runPlugin(t.Plugin, t.PluginOpt, t.pluginDir(), model, outPkg, opts); err != nil {
+			return false, err
+		}
+		// With a plugin the built-in fixtures are only written to a file, not to stdout
+		if t.Out == "" && t.OutDir == "" {
+			return false, nil
+		}
 	}
 
-	cfg := &packages.Config{
-		Mode: packages.NeedName | packages.NeedSyntax | packages.NeedTypes | packages.NeedTypesInfo | packages.NeedDeps | packages.NeedImports,
-		Dir:  absPath,
+	if t.SelfTest {
+		if err := t.selfTest(model, pkgs, outPkg, opts); err != nil {
+			return false, err
+		}
 	}
 
-	pkgs, err := packages.Load(cfg, ".")
-	if err != nil {
-		panic(err)
+	var size int
+	if t.OutDir != "" {
+		hashes := make(map[string]string)
+		opts.Unchanged = func(file, hash string) bool {
+			hashes[file] = hash
+			return !t.Force && t.upToDate(filepath.Join(t.OutDir, file), hash)
+		}
+		files, err := generator.GenerateFiles(model, outPkg, opts, generator.FileLayout(model, t.TypesPerFile))
+		if err != nil {
+			return false, err
+		}
+		if size, err = writeOutputFiles(t.OutDir, files); err != nil {
+			return false, err
+		}
+		written := 0
+		for name, data := range files {
+			if data != nil {
+				writeStamp(filepath.Join(t.OutDir, name), hashes[name])
+				written++
+			}
+		}
+		if written == 0 {
+			fmt.Fprintf(os.Stderr, "%s is up to date\n", t.OutDir)
+			return true, nil
+		}
+	} else if t.Out == "" {
+		if size, err = writeStdout(model, outPkg, opts); err != nil {
+			return false, err
+		}
+	} else {
+		content := generator.ContentHash(model, outPkg, opts)
+		if !t.Force && t.upToDate(t.Out, content) {
+			fmt.Fprintf(os.Stderr, "%s is up to date\n", t.Out)
+			return true, nil
+		}
+		// The fixtures subpackages of -pkg ./... are created once there is something to write
+		if err := os.MkdirAll(filepath.Dir(t.Out), 0755); err != nil {
+			return false, err
+		}
+		if size, err = writeOutput(t.Out, model, outPkg, opts); err != nil {
+			return false, err
+		}
+		writeStamp(t.Out, content)
 	}
-	if len(pkgs) == 0 {
-		panic("no packages found")
+	if err := t.report(summary, size); err != nil {
+		return false, err
+	}
+	if n := len(summary.Skipped) + len(summary.Missing); n > 0 {
+		if err := t.condition(condSkipped, fmt.Sprintf("%d types or fields were skipped", n)); err != nil {
+			return false, err
+		}
 	}
+	if len(formatErrors) > 0 {
+		for _, err := range formatErrors {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		msg := fmt.Sprintf("%d declarations could not be formatted and were written as is", len(formatErrors))
+		if err := t.condition(condFormat, msg); err != nil {
+			return false, err
+		}
+	}
+	return false, nil
+}
 
-	for _, pkg := range pkgs {
-		if len(pkg.Errors) > 0 {
-			for _, e := range pkg.Errors {
-				fmt.Fprintf(os.Stderr, "warning: %v\n", e)
+// sampleOnly reports whether the model of t is inferred from its -json sample alone
+func (t target) sampleOnly() bool {
+	return t.JSON != "" && t.Pkg == "" && t.Src == "" && t.OpenAPI == "" && t.SQL == "" && t.Descriptors == ""
+}
+
+// declaresTypes reports whether the types of t are only described by its -json sample, OpenAPI
+// document or SQL schema, and are declared in the output. With -typeprefix they are declared in
+// the package it names instead.
+func (t target) declaresTypes() bool {
+	return t.Pkg == "" && t.Src == "" && t.Descriptors == "" && t.TypePrefix == ""
+}
+
+// options returns the generator options of t for its model and loaded packages
+func (t target) options(model *generator.Model, pkgs []*packages.Package) (generator.GenerateOptions, error) {
+	opts := generator.GenerateOptions{
+		TypePrefix:  t.TypePrefix,
+		TypeImport:  t.typeImport(pkgs),
+		FuncPrefix:  t.FuncPrefix,
+		ModStyle:    t.ModStyle == nil || *t.ModStyle,
+		Incremental: t.Incremental,
+		// The types of samples, OpenAPI documents and SQL schemas exist nowhere else
+		DeclareTypes: t.declaresTypes(),
+
+		Seed:              t.SeedFuncs,
+		SeedPlaceholder:   t.Placeholder,
+		Routes:            t.Routes,
+		Snapshots:         t.Snapshots,
+		YAMLLoaders:       t.Testdata != "",
+		AnyPayloads:       t.AnyPayloads,
+		FieldOverrides:    t.FieldOverrides,
+		Header:            t.header(),
+		Version:           version(),
+		GoVersion:         t.GoVersion,
+		PtrFunc:           t.ptrFunc(),
+		ModSignature:      t.ModSignature,
+		ModReturn:         t.ModReturn,
+		Sparse:            t.Sparse,
+		NilOptionals:      t.NilOptionals,
+		Options:           t.Options,
+		Dump:              t.Dump,
+		Registry:          t.Registry,
+		Calls:             t.Calls,
+		Asserts:           t.Asserts,
+		EnumConstructors:  t.EnumConstructors,
+		Rapid:             t.Rapid,
+		Clock:             t.Clock,
+		Hooks:             t.Hooks,
+		Providers:         t.Providers,
+		MaxDepth:          t.MaxDepth,
+		ValueStrategy:     t.Values,
+		FakerSeed:         t.FakerSeed,
+		ByteLength:        t.Bytes,
+		SliceLen:          t.SliceLen,
+		InterfaceStrategy: t.Interfaces,
+	}
+	for pattern, name := range t.Routes {
+		if !generator.HasFixture(model, name) {
+			return opts, fmt.Errorf("route %q: no fixture for type %s", pattern, name)
+		}
+	}
+	for key := range t.FieldOverrides {
+		name, field, _ := strings.Cut(key, ".")
+		if s := model.Structs[name]; s == nil || !slices.ContainsFunc(s.Fields, func(f generator.Field) bool { return f.Name == field }) {
+			return opts, fmt.Errorf("override %s: no field %s of struct %s", key, field, name)
+		}
+	}
+	for key, value := range t.SliceLens {
+		name, field, _ := strings.Cut(key, ".")
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return opts, fmt.Errorf("len %s: %q is no number of elements", key, value)
+		}
+		if s := model.Structs[name]; s == nil || !slices.ContainsFunc(s.Fields, func(f generator.Field) bool { return f.Name == field && f.Type.Kind == "slice" }) {
+			return opts, fmt.Errorf("len %s: no slice field %s of struct %s", key, field, name)
+		}
+		if opts.SliceLens == nil {
+			opts.SliceLens = make(map[string]int)
+		}
+		opts.SliceLens[key] = n
+	}
+	for name, fields := range t.Pairwise {
+		if model.Structs[name] == nil {
+			return opts, fmt.Errorf("pairwise: no struct %s", name)
+		}
+		if opts.Pairwise == nil {
+			opts.Pairwise = make(map[string][]string)
+		}
+		opts.Pairwise[name] = strings.Split(fields, ",")
+	}
+	for name, weights := range t.Weights {
+		w, err := parseWeights(model, name, weights)
+		if err != nil {
+			return opts, fmt.Errorf("weights %s: %w", name, err)
+		}
+		if opts.Weights == nil {
+			opts.Weights = make(map[string]map[string]int)
+		}
+		opts.Weights[name] = w
+	}
+	for key, value := range t.ExternalTypes {
+		i := strings.LastIndex(key, ".")
+		if i <= 0 || i == len(key)-1 {
+			return opts, fmt.Errorf("externaltype %s: expected an import path and type name like github.com/google/uuid.UUID", key)
+		}
+		if opts.ExternalTypes == nil {
+			opts.ExternalTypes = make(map[string]generator.ExternalType)
+		}
+		opts.ExternalTypes[key] = generator.NewExternalType(key[:i], value)
+	}
+	for root, related := range t.Aggregates {
+		members := strings.Split(related, ",")
+		for _, name := range append([]string{root}, members...) {
+			if model.Structs[name] == nil {
+				return opts, fmt.Errorf("aggregate %s: no struct %s", root, name)
 			}
 		}
+		if opts.Aggregates == nil {
+			opts.Aggregates = make(map[string][]string)
+		}
+		opts.Aggregates[root] = members
+	}
+	if pkgs != nil {
+		var err error
+		if opts.FixturePackages, err = fixturePackages(t.Pkg, pkgs, t.FixturePackages); err != nil {
+			return opts, err
+		}
+	}
+	var err error
+	if opts.CustomFixtures, err = t.customFixtures(); err != nil {
+		return opts, fmt.Errorf("custom fixtures: %w", err)
 	}
-	return pkgs
+	return opts, nil
 }
 
-func extract(pkgs []*packages.Package) *generator.Model {
-	m := generator.NewModel()
+// typeImport returns the import path of the package of the types, which -typeprefix refers to:
+// -typeimport, or the path of the loaded package. Types of other packages are qualified in fixtures.
+func (t target) typeImport(pkgs []*packages.Package) string {
+	if t.TypeImport != "" {
+		return t.TypeImport
+	}
+	return rootPkgPath(pkgs)
+}
 
+// rootPkgPath returns the import path of the loaded package, "" if none was loaded. The external test
+// package of a package can't be imported.
+func rootPkgPath(pkgs []*packages.Package) string {
 	for _, pkg := range pkgs {
-		extractEnums(pkg, m)
-		extractOneOfs(pkg, m)
-		extractTypeDefs(pkg, m)
-		extractStructs(pkg, m)
+		if !strings.HasSuffix(pkg.PkgPath, "_test") {
+			return pkg.PkgPath
+		}
 	}
+	return ""
+}
 
-	return m
+// parseWeights parses the "Value:weight" pairs of -weights for the enum or oneof interface name,
+// checking the values belong to it
+func parseWeights(m *generator.Model, name, pairs string) (map[string]int, error) {
+	e := m.Enums[name]
+	_, oneof := m.OneOfs[name]
+	if e == nil && !oneof {
+		return nil, fmt.Errorf("no enum or oneof %s", name)
+	}
+	// Implementations of isOrder_Payload are named Order_<Field>
+	message := strings.TrimPrefix(name, "is")
+	prefix := message[:strings.LastIndex(message, "_")+1]
+	weights := make(map[string]int)
+	total := 0
+	for _, pair := range strings.Split(pairs, ",") {
+		value, weight, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		n, err := strconv.Atoi(weight)
+		if !ok || err != nil || n < 0 {
+			return nil, fmt.Errorf("expected Value:weight with a non-negative weight, got %q", pair)
+		}
+		if e != nil && !slices.Contains(e.Values, value) || e == nil && (prefix == "" || !strings.HasPrefix(value, prefix)) {
+			return nil, fmt.Errorf("%s is no value of %s", value, name)
+		}
+		weights[value] = n
+		total += n
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("all weights are 0")
+	}
+	return weights, nil
 }
 
-func extractEnums(pkg *packages.Package, m *generator.Model) {
-	for ident, obj := range pkg.TypesInfo.Defs {
-		c, ok := obj.(*types.Const)
-		if !ok {
-			continue
+// header returns the comment opening generated files: -header, or the marker of generated code with
+// the command line regenerating them
+func (t target) header() string {
+	if t.Header != "" {
+		return t.Header
+	}
+	cmd := "fixture-generator"
+	for _, arg := range invocation {
+		if arg == "" || strings.ContainsAny(arg, " \t\"'") {
+			arg = strconv.Quote(arg)
 		}
-		if ident.Name == "_" || ident.Name == "EnforceVersion" {
+		cmd += " " + arg
+	}
+	return "Code generated by " + cmd + ". DO NOT EDIT."
+}
+
+func (t target) outPkg() string {
+	if t.OutPkg == "" {
+		return "fixtures"
+	}
+	return t.OutPkg
+}
+
+// selfPackage sets up t to generate into the package of its source types: the output is named after
+// the package and, unless -out is given, written as <package>_fixtures.go next to the types. Types
+// aren't qualified then, so unexported fields can be set too.
+func (t *target) selfPackage(pkgs []*packages.Package) error {
+	if len(pkgs) != 1 || len(pkgs[0].GoFiles) == 0 {
+		return fmt.Errorf("-self needs -pkg to match a single package with Go files, not %s", t.Pkg)
+	}
+	pkg := pkgs[0]
+	t.OutPkg = pkg.Name
+	if t.Out == "" {
+		t.Out = filepath.Join(filepath.Dir(pkg.GoFiles[0]), pkg.Name+"_fixtures.go")
+	}
+	return nil
+}
+
+// ptrFunc returns the name of the ptr helper: with -self one the package of the types is unlikely to
+// declare as well, the default otherwise
+func (t target) ptrFunc() string {
+	if t.Self {
+		return "fixturePtr"
+	}
+	return ""
+}
+
+// report prints the generation summary to stderr and writes it as JSON if requested
+func (t target) report(summary *generator.Summary, size int) error {
+	summary.Bytes = size
+	out := t.Out
+	if t.OutDir != "" {
+		out = t.OutDir
+	}
+	if out == "" {
+		out = "stdout"
+	}
+	// One write, so summaries of concurrent batch targets don't interleave
+	fmt.Fprint(os.Stderr, out+": "+summary.String())
+	if t.Summary == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(t.Summary, append(data, '\n'), 0644)
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += n
+	return n, err
+}
+
+// writeYAMLFiles writes the fixture of every struct of model as YAML into dir, replacing the files of
+// earlier runs. Files of other names, such as the variants tests load, are left alone.
+func writeYAMLFiles(dir string, model *generator.Model, opts generator.GenerateOptions) error {
+	files, err := generator.YAMLFiles(model, opts)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for name, data := range files {
+		if data == nil {
 			continue
 		}
-		named, ok := c.Type().(*types.Named)
-		if !ok {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeOutputFiles writes the files of the output split by -outdir into dir and returns their size.
+// Files without content are up to date and left alone. Files of earlier runs named like the
+// generated ones, left over from types that moved or were removed, are deleted so they don't
+// redeclare fixtures.
+func writeOutputFiles(dir string, files map[string][]byte) (int, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return 0, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if _, ok := files[name]; outputFile.MatchString(name) && !ok && !e.IsDir() {
+			if err := os.Remove(filepath.Join(dir, name)); err != nil {
+				return 0, err
+			}
+		}
+	}
+	size := 0
+	for name, data := range files {
+		if data == nil {
 			continue
 		}
-		name := named.Obj().Name()
-		e, ok := m.Enums[name]
-		if !ok {
-			e = &generator.Enum{Name: name}
-			m.Enums[name] = e
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			return 0, err
 		}
-		e.Values = append(e.Values, ident.Name)
+		size += len(data)
+	}
+	return size, nil
+}
+
+// outputFile matches the names generator.FileLayout gives the files of -outdir
+var outputFile = regexp.MustCompile(`^(\w+_fixtures|fixtures_[0-9]+)\.go$`)
+
+// stdoutMu serializes the targets of a batch writing to stdout, whose files would interleave otherwise
+var stdoutMu sync.Mutex
+
+// writeStdout writes the generated file to stdout and returns its size
+func writeStdout(model *generator.Model, pkgName string, opts generator.GenerateOptions) (int, error) {
+	stdoutMu.Lock()
+	defer stdoutMu.Unlock()
+	w := bufio.NewWriter(os.Stdout)
+	cw := &countingWriter{w: w}
+	if err := generator.GenerateTo(cw, model, pkgName, opts); err != nil {
+		return 0, err
 	}
+	return cw.n, w.Flush()
 }
 
-func extractOneOfs(pkg *packages.Package, m *generator.Model) {
-	for _, file := range pkg.Syntax {
-		for _, decl := range file.Decls {
-			gd, ok := decl.(*ast.GenDecl)
-			if !ok || gd.Tok != token.TYPE {
-				continue
+// writeOutput writes the generated file to path and returns its size
+func writeOutput(path string, model *generator.Model, pkgName string, opts generator.GenerateOptions) (int, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	cw := &countingWriter{w: w}
+	if err := generator.GenerateTo(cw, model, pkgName, opts); err != nil {
+		return 0, err
+	}
+	if err := w.Flush(); err != nil {
+		return 0, err
+	}
+	return cw.n, f.Close()
+}
+
+// loadMode is what extraction needs: syntax and type info for the target package only.
+// Dependencies are type-checked from export data instead of being parsed from source, which takes
+// their import graph but not their syntax.
+const loadMode = packages.NeedName | packages.NeedFiles | packages.NeedImports | packages.NeedSyntax | packages.NeedTypes | packages.NeedTypesInfo
+
+// exportData reports whether go/packages can read the export data the go command compiles, which it
+// can't for Go releases newer than it knows. It exits the process on export data it fails to read,
+// so that is tried on a small standard package first; without it dependencies are loaded from source.
+var exportData = sync.OnceValue(func() bool {
+	pkgs, err := packages.Load(&packages.Config{Mode: packages.NeedName | packages.NeedExportFile}, "errors")
+	if err != nil || len(pkgs) != 1 || pkgs[0].ExportFile == "" {
+		return false
+	}
+	f, err := os.Open(pkgs[0].ExportFile)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	r, err := gcexportdata.NewReader(bufio.NewReader(f))
+	if err != nil {
+		return false
+	}
+	_, err = gcexportdata.Read(r, token.NewFileSet(), make(map[string]*types.Package), "errors")
+	return err == nil
+})
+
+// depMode controls which dependency packages are loaded from source
+type depMode int
+
+const (
+	// depsNone loads only the target package
+	depsNone depMode = iota
+	// depsDirect also loads the packages referenced by struct fields, but not their dependencies
+	depsDirect
+	// depsAll loads the full dependency graph when struct fields reference other packages
+	depsAll
+)
+
+// loadOptions selects what is loaded besides the syntax and types of the target package
+type loadOptions struct {
+	deps depMode
+	// tests also loads the _test.go files of the package
+	tests bool
+	// goos and goarch select the platform whose files make up the package, the host's when empty
+	goos, goarch string
+}
+
+// loaderFunc loads the package at pattern as selected by opts
+type loaderFunc func(pattern string, opts loadOptions) ([]*packages.Package, error)
+
+func load(pattern string, opts loadOptions) ([]*packages.Package, error) {
+	absPath, err := filepath.Abs(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &packages.Config{
+		Mode:  loadMode,
+		Dir:   absPath,
+		Tests: opts.tests,
+		Env:   platformEnv(opts.goos, opts.goarch),
+	}
+	if !exportData() {
+		cfg.Mode |= packages.NeedDeps
+	}
+
+	pkgs, err := loadRoots(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) == 0 {
+		return nil, fmt.Errorf("%w in %s", generator.ErrNoPackages, pattern)
+	}
+	// Retry without the cgo files rather than extracting from broken type info
+	if cgoBroken(pkgs) {
+		withoutCgo(cfg)
+		if pkgs, err = loadRoots(cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	// Only pay for dependencies when a field actually references another package
+	if paths := fieldDeps(pkgs); len(paths) > 0 {
+		switch opts.deps {
+		case depsAll:
+			cfg.Mode |= packages.NeedDeps | packages.NeedImports
+			if pkgs, err = loadRoots(cfg); err != nil {
+				return nil, err
 			}
-			for _, spec := range gd.Specs {
-				ts := spec.(*ast.TypeSpec)
-				name := ts.Name.Name
-
-				if _, ok := ts.Type.(*ast.StructType); ok {
-					for ifaceName := range m.OneOfs {
-						if m.OneOfs[ifaceName] == "" {
-							parentName := ifaceName[2:] // remove "is" prefix
-							for i := len(parentName) - 1; i >= 0; i-- {
-								if parentName[i] == '_' {
-									prefix := parentName[:i]
-									if len(name) > len(prefix) && name[:len(prefix)] == prefix && name[len(prefix)] == '_' {
-										m.OneOfs[ifaceName] = name
-										break
-									}
-								}
-							}
-						}
-					}
-				}
+		case depsDirect:
+			if err := loadDirectDeps(cfg, pkgs, paths); err != nil {
+				return nil, err
+			}
+		}
+	}
 
-				if _, ok := ts.Type.(*ast.InterfaceType); ok {
-					if len(name) > 2 && name[:2] == "is" {
-						m.OneOfs[name] = ""
-					}
-				}
+	for _, pkg := range pkgs {
+		if len(pkg.Errors) > 0 {
+			for _, e := range pkg.Errors {
+				fmt.Fprintf(os.Stderr, "warning: %v\n", e)
 			}
 		}
 	}
+	reportCgo(pkgs)
+	reportPlatform(pkgs)
+	return pkgs, nil
 }
 
-func extractStructs(pkg *packages.Package, m *generator.Model) {
-	for _, file := range pkg.Syntax {
-		for _, decl := range file.Decls {
-			gd, ok := decl.(*ast.GenDecl)
-			if !ok || gd.Tok != token.TYPE {
-				continue
-			}
-			for _, spec := range gd.Specs {
-				ts := spec.(*ast.TypeSpec)
-				st, ok := ts.Type.(*ast.StructType)
-				if !ok {
-					continue
-				}
-				s := &generator.Struct{Name: ts.Name.Name}
-				for _, field := range st.Fields.List {
-					tr := resolveType(pkg.TypesInfo.TypeOf(field.Type))
-					for _, name := range field.Names {
-						if generator.ProtoInternalFields[name.Name] {
-							continue
-						}
-						s.Fields = append(s.Fields, generator.Field{
-							Name: name.Name,
-							Type: tr,
-						})
-					}
-				}
-				m.Structs[s.Name] = s
-			}
+// loadRoots loads the package in cfg.Dir, with cfg.Tests as the variant including its _test.go files
+func loadRoots(cfg *packages.Config) ([]*packages.Package, error) {
+	pkgs, err := packages.Load(cfg, ".")
+	if err != nil || !cfg.Tests {
+		return pkgs, err
+	}
+	return testVariants(pkgs), nil
+}
+
+// testVariants replaces the packages loaded with tests by their variant including the _test.go files
+// and drops external _test packages and test mains, whose types can't be referenced by fixtures
+func testVariants(pkgs []*packages.Package) []*packages.Package {
+	variants := make(map[string]bool)
+	for _, pkg := range pkgs {
+		if pkg.ID == pkg.PkgPath+" ["+pkg.PkgPath+".test]" {
+			variants[pkg.PkgPath] = true
 		}
 	}
+	var roots []*packages.Package
+	for _, pkg := range pkgs {
+		switch {
+		case strings.HasSuffix(pkg.PkgPath, "_test") || strings.HasSuffix(pkg.PkgPath, ".test"):
+		case pkg.ID == pkg.PkgPath && variants[pkg.PkgPath]:
+		default:
+			roots = append(roots, pkg)
+		}
+	}
+	return roots
 }
 
-func extractTypeDefs(pkg *packages.Package, m *generator.Model) {
-	for _, file := range pkg.Syntax {
-		for _, decl := range file.Decls {
-			gd, ok := decl.(*ast.GenDecl)
-			if !ok || gd.Tok != token.TYPE {
-				continue
-			}
-			for _, spec := range gd.Specs {
-				ts := spec.(*ast.TypeSpec)
-				name := ts.Name.Name
+// loadDirectDeps loads the given import paths from source and links them into the Imports of pkgs,
+// the same place a full NeedDeps load would put them, without pulling in their own dependencies
+func loadDirectDeps(cfg *packages.Config, pkgs []*packages.Package, paths []string) error {
+	// Only the target package is loaded with its tests
+	depCfg := *cfg
+	depCfg.Tests = false
+	deps, err := packages.Load(&depCfg, paths...)
+	if err != nil {
+		return err
+	}
+	for _, pkg := range pkgs {
+		if pkg.Imports == nil {
+			pkg.Imports = make(map[string]*packages.Package)
+		}
+		for _, dep := range deps {
+			pkg.Imports[dep.PkgPath] = dep
+		}
+	}
+	return nil
+}
 
-				// Skip unexported types
-				if name[0] >= 'a' && name[0] <= 'z' {
-					continue
+// fieldDeps returns the import paths of other packages whose types are used by struct fields,
+// ignoring well-known external types that are rendered without loading their package
+func fieldDeps(pkgs []*packages.Package) []string {
+	seen := make(map[string]bool)
+	var deps []string
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Syntax {
+			ast.Inspect(file, func(n ast.Node) bool {
+				st, ok := n.(*ast.StructType)
+				if !ok {
+					return true
 				}
-
-				// Check if it's a type alias like `type TenantID string`
-				if ident, ok := ts.Type.(*ast.Ident); ok {
-					underlying := resolveType(pkg.TypesInfo.TypeOf(ident))
-					if underlying.Kind == "primitive" {
-						m.TypeDefs[name] = &generator.TypeDef{
-							Name:       name,
-							Underlying: underlying,
-						}
+				for _, field := range st.Fields.List {
+					path := namedPkgPath(pkg.TypesInfo.TypeOf(field.Type))
+					if path == "" || path == pkg.PkgPath || seen[path] {
+						continue
 					}
+					seen[path] = true
+					deps = append(deps, path)
 				}
-			}
+				return true
+			})
 		}
 	}
+	sort.Strings(deps)
+	return deps
 }
 
-func resolveType(t types.Type) generator.TypeRef {
+// namedPkgPath returns the package path of the named type behind pointers, slices, arrays and maps
+func namedPkgPath(t types.Type) string {
 	switch tt := t.(type) {
-	case *types.Basic:
-		return generator.TypeRef{Kind: "primitive", Name: tt.Name()}
+	case *types.Pointer:
+		return namedPkgPath(tt.Elem())
+	case *types.Slice:
+		return namedPkgPath(tt.Elem())
+	case *types.Array:
+		return namedPkgPath(tt.Elem())
+	case *types.Map:
+		return namedPkgPath(tt.Elem())
 	case *types.Named:
-		name := tt.Obj().Name()
-		// Use simple type name for external types lookup
-		if _, ok := generator.ExternalTypes[name]; ok {
-			return generator.TypeRef{Kind: "external", Name: name}
-		}
-		if _, ok := tt.Underlying().(*types.Struct); ok {
-			return generator.TypeRef{Kind: "struct", Name: name}
+		obj := tt.Obj()
+		if obj.Pkg() == nil {
+			return ""
 		}
-		if _, ok := tt.Underlying().(*types.Interface); ok {
-			return generator.TypeRef{Kind: "oneof", Name: name}
+		if generator.IsExternal(obj) {
+			return ""
 		}
-		return generator.TypeRef{Kind: "enum", Name: name}
-	case *types.Pointer:
-		elem := resolveType(tt.Elem())
-		return generator.TypeRef{Kind: "pointer", Elem: &elem}
-	case *types.Slice:
-		elem := resolveType(tt.Elem())
-		return generator.TypeRef{Kind: "slice", Elem: &elem}
+		return obj.Pkg().Path()
+	}
+	return ""
+}
+
+func extract(pkgs []*packages.Package, jobs int) *generator.Model {
+	// Each package is extracted into its own model so workers never share state
+	models := make([]*generator.Model, len(pkgs))
+	parallel(len(pkgs), jobs, func(i int) {
+		models[i] = generator.ExtractPackage(pkgs[i])
+	})
+
+	m := generator.NewModel()
+	for _, pm := range models {
+		m.Merge(pm)
+	}
+	return m
+}
+
+// parallel calls fn for every index in [0, n) using at most jobs goroutines
+func parallel(n, jobs int, fn func(i int)) {
+	if jobs < 1 {
+		jobs = 1
+	}
+	sem := make(chan struct{}, jobs)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(i)
+		}(i)
 	}
-	return generator.TypeRef{Kind: "unknown"}
+	wg.Wait()
 }
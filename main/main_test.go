@@ -1,10 +1,33 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"fixture-generator/pkg/generator"
+
+	"golang.org/x/tools/go/packages"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
 )
 
 func TestGenValue(t *testing.T) {
@@ -154,7 +177,7 @@ func TestGenValue(t *testing.T) {
 }
 
 func TestProtoInternalFieldsSkipped(t *testing.T) {
-	internalFields := []string{"state", "unknownFields", "sizeCache"}
+	internalFields := []string{"state", "unknownFields", "sizeCache", "XXX_unrecognized"}
 
 	for _, field := range internalFields {
 		t.Run(field, func(t *testing.T) {
@@ -378,3 +401,4558 @@ func TestGenerate(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerateTo(t *testing.T) {
+	m := generator.NewModel()
+	m.Structs["User"] = &generator.Struct{
+		Name: "User",
+		Fields: []generator.Field{
+			{Name: "ID", Type: generator.TypeRef{Kind: "primitive", Name: "string"}},
+			{Name: "CreatedAt", Type: generator.TypeRef{Kind: "pointer", Elem: &generator.TypeRef{Kind: "external", Name: "Timestamp"}}},
+		},
+	}
+	m.Enums["Status"] = &generator.Enum{Name: "Status", Values: []string{"STATUS_UNSPECIFIED"}}
+	opts := generator.GenerateOptions{ModStyle: true}
+
+	var got bytes.Buffer
+	if err := generator.GenerateTo(&got, m, "fixtures", opts); err != nil {
+		t.Fatalf("GenerateTo() error = %v", err)
+	}
+
+	want, err := format.Source([]byte(generator.GenerateWithOptions(m, "fixtures", opts)))
+	if err != nil {
+		t.Fatalf("format.Source() error = %v", err)
+	}
+	if got.String() != string(want) {
+		t.Errorf("GenerateTo() output differs from formatted GenerateWithOptions()\nGot:\n%s\nWant:\n%s", got.String(), want)
+	}
+}
+
+func TestReadBatchConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "batch.json")
+	config := `{"jobs": 2, "targets": [{"pkg": "./account", "out": "./account/fixtures.go", "modstyle": false}, {"pkg": "./orders", "outdir": "./orders/fixtures"}]}`
+	if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := readBatchConfig(path)
+	if err != nil {
+		t.Fatalf("readBatchConfig() error = %v", err)
+	}
+	if cfg.Jobs != 2 || len(cfg.Targets) != 2 {
+		t.Fatalf("readBatchConfig() = %+v, want 2 jobs and 2 targets", cfg)
+	}
+	got := cfg.Targets[0]
+	if got.Pkg != filepath.Join(dir, "account") {
+		t.Errorf("Pkg = %q, want it resolved relative to the config file", got.Pkg)
+	}
+	if got.Out != filepath.Join(dir, "account", "fixtures.go") {
+		t.Errorf("Out = %q, want it resolved relative to the config file", got.Out)
+	}
+	if got.ModStyle == nil || *got.ModStyle {
+		t.Errorf("ModStyle = %v, want false", got.ModStyle)
+	}
+	if got := cfg.Targets[1].OutDir; got != filepath.Join(dir, "orders", "fixtures") {
+		t.Errorf("OutDir = %q, want it resolved relative to the config file", got)
+	}
+
+	yaml := filepath.Join(dir, "batch.yaml")
+	os.WriteFile(yaml, []byte("jobs: 3\ntargets:\n  - pkg: ./account\n    modstyle: false\n"), 0644)
+	cfg, err = readBatchConfig(yaml)
+	if err != nil {
+		t.Fatalf("readBatchConfig(batch.yaml) error = %v", err)
+	}
+	if cfg.Jobs != 3 || len(cfg.Targets) != 1 || cfg.Targets[0].Pkg != filepath.Join(dir, "account") || cfg.Targets[0].ModStyle == nil || *cfg.Targets[0].ModStyle {
+		t.Errorf("readBatchConfig(batch.yaml) = %+v, want the config of the YAML", cfg)
+	}
+	os.WriteFile(yaml, []byte("targets: [\n"), 0644)
+	if _, err := readBatchConfig(yaml); err == nil {
+		t.Error("readBatchConfig() accepted invalid YAML")
+	}
+}
+
+func TestLoadCache(t *testing.T) {
+	calls := 0
+	cache := newLoadCache(func(pattern string, opts loadOptions) ([]*packages.Package, error) {
+		calls++
+		return []*packages.Package{{PkgPath: pattern}}, nil
+	})
+
+	cache.load("./account", loadOptions{})
+	cache.load("./account", loadOptions{})
+	cache.load("./account", loadOptions{deps: depsAll})
+	cache.load("./account", loadOptions{goos: "windows"})
+
+	if calls != 3 {
+		t.Errorf("loader called %d times, want 3", calls)
+	}
+}
+
+func TestLoadPackage(t *testing.T) {
+	// ./example imports timestamppb, whose types come from export data or its source
+	pkgs, err := load("../example", loadOptions{})
+	if err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	m := extract(pkgs, 1)
+	if m.Structs["User"] == nil {
+		t.Fatalf("structs = %v, want User", m.Structs)
+	}
+	for _, f := range m.Structs["User"].Fields {
+		if f.Name == "CreatedAt" && (f.Type.Elem == nil || f.Type.Elem.Kind != "external" || f.Type.Elem.Name != "Timestamp") {
+			t.Errorf("CreatedAt type = %+v, want *timestamppb.Timestamp", f.Type)
+		}
+	}
+}
+
+func TestDependencyValues(t *testing.T) {
+	dir := t.TempDir()
+	for name, src := range map[string]string{
+		"go.mod": "module example.com/mr\n\ngo 1.24\n",
+		"b/b.go": "package b\n\ntype Status int\n\nconst (\n\tStatusOpen Status = iota\n\tStatusDone\n)\n\n" +
+			"type Item struct {\n\tName   string\n\tStatus Status\n\tNext   *Item\n\tsecret string\n}\n",
+		"a/a.go": "package a\n\nimport \"example.com/mr/b\"\n\ntype Order struct {\n\tItem   b.Item\n\tRef    *b.Item\n\tStatus b.Status\n}\n",
+	} {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []string{
+		`Item:   b.Item{Name: "Name", Status: b.StatusOpen}`,
+		`Ref:    &b.Item{Name: "Name", Status: b.StatusOpen}`,
+		"Status: b.StatusOpen,",
+	}
+	for _, tg := range []target{{Deep: true}, {Monorepo: true}, {}} {
+		tg.Pkg = filepath.Join(dir, "a")
+		tg.TypePrefix = "a"
+		m, pkgs, err := tg.model(1, load)
+		if err != nil {
+			t.Fatal(err)
+		}
+		opts, err := tg.options(m, pkgs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := generator.GenerateFormattedWithOptions(m, "fixtures", opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		loaded := tg.Deep || tg.Monorepo
+		for _, w := range want {
+			if strings.Contains(out, w) != loaded {
+				t.Errorf("deep %v, monorepo %v: output contains %q = %v\n%s", tg.Deep, tg.Monorepo, w, !loaded, out)
+			}
+		}
+		if strings.Contains(out, "TODO: no fixture for example.com/mr/b.Item") == loaded {
+			t.Errorf("deep %v, monorepo %v: TODO for b.Item = %v\n%s", tg.Deep, tg.Monorepo, !loaded, out)
+		}
+	}
+}
+
+func TestExtractFromPackages(t *testing.T) {
+	// The load mode of the Library Usage section of the README
+	pkgs, err := packages.Load(&packages.Config{
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedImports | packages.NeedDeps | packages.NeedSyntax | packages.NeedTypes | packages.NeedTypesInfo,
+		Dir:  "../example",
+	}, ".")
+	if err != nil {
+		t.Fatalf("packages.Load() error = %v", err)
+	}
+	m := generator.ExtractFromPackages(pkgs)
+	user := m.Structs["User"]
+	if user == nil {
+		t.Fatalf("structs = %v, want User", m.Structs)
+	}
+	for _, f := range user.Fields {
+		if f.Name == "CreatedAt" && (f.Type.Elem == nil || f.Type.Elem.Kind != "external" || f.Type.Elem.Name != "Timestamp") {
+			t.Errorf("CreatedAt type = %+v, want *timestamppb.Timestamp", f.Type)
+		}
+	}
+	if _, ok := m.OneOfs["isUserReference_Id"]; !ok {
+		t.Errorf("oneofs = %v, want isUserReference_Id", m.OneOfs)
+	}
+	out, err := generator.GenerateFormattedWithOptions(m, "fixtures", generator.GenerateOptions{ModStyle: true, TypePrefix: "example"})
+	if err != nil {
+		t.Fatalf("GenerateFormattedWithOptions() error = %v", err)
+	}
+	if !strings.Contains(string(out), "func FixtureUser(mods ...func(*example.User)) *example.User") {
+		t.Errorf("output lacks FixtureUser:\n%s", out)
+	}
+}
+
+func TestContentHashDeterministic(t *testing.T) {
+	m := generator.NewModel()
+	for _, name := range []string{"User", "Address", "Order", "Invoice", "Account"} {
+		m.Structs[name] = &generator.Struct{
+			Name:   name,
+			Fields: []generator.Field{{Name: "ID", Type: generator.TypeRef{Kind: "primitive", Name: "string"}}},
+		}
+	}
+	opts := generator.GenerateOptions{ModStyle: true}
+
+	want := generator.ContentHash(m, "fixtures", opts)
+	for i := 0; i < 10; i++ {
+		if got := generator.ContentHash(m, "fixtures", opts); got != want {
+			t.Fatalf("ContentHash() = %q on run %d, want %q", got, i, want)
+		}
+	}
+
+	opts.Incremental = true
+	out := generator.GenerateWithOptions(m, "fixtures", opts)
+	if got, want := generator.ReadHash([]byte(out)), generator.ContentHash(m, "fixtures", opts); got != want {
+		t.Errorf("ReadHash() = %q, want the content hash %q", got, want)
+	}
+}
+
+func TestParseOpenAPI(t *testing.T) {
+	spec := `{"openapi": "3.0.0", "components": {"schemas": {
+		"User": {"type": "object", "required": ["id", "status"], "properties": {
+			"id": {"type": "string"},
+			"first_name": {"type": "string"},
+			"status": {"$ref": "#/components/schemas/Status"},
+			"tags": {"type": "array", "items": {"type": "string"}},
+			"pet": {"$ref": "#/components/schemas/Pet"}
+		}},
+		"Status": {"type": "string", "enum": ["active", "archived"]},
+		"Pet": {"oneOf": [{"$ref": "#/components/schemas/Cat"}, {"$ref": "#/components/schemas/Dog"}]},
+		"Cat": {"type": "object", "properties": {"lives": {"type": "integer"}}},
+		"Dog": {"type": "object", "properties": {"good": {"type": "boolean"}}}
+	}}}`
+
+	m, err := generator.ParseOpenAPI([]byte(spec))
+	if err != nil {
+		t.Fatalf("ParseOpenAPI() error = %v", err)
+	}
+
+	user, ok := m.Structs["User"]
+	if !ok {
+		t.Fatal("User struct not found")
+	}
+	want := []generator.Field{
+		{Name: "ID", Type: generator.TypeRef{Kind: "primitive", Name: "string"}},
+		{Name: "FirstName", Type: generator.TypeRef{Kind: "pointer", Elem: &generator.TypeRef{Kind: "primitive", Name: "string"}}},
+		{Name: "Status", Type: generator.TypeRef{Kind: "enum", Name: "Status"}},
+		{Name: "Tags", Type: generator.TypeRef{Kind: "slice", Name: "string", Elem: &generator.TypeRef{Kind: "primitive", Name: "string"}}},
+		{Name: "Pet", Type: generator.TypeRef{Kind: "oneof", Name: "Pet"}},
+	}
+	if len(user.Fields) != len(want) {
+		t.Fatalf("User has %d fields, want %d", len(user.Fields), len(want))
+	}
+	for i, f := range user.Fields {
+		if generator.TypeName(f.Type) != generator.TypeName(want[i].Type) || f.Name != want[i].Name || f.Type.Kind != want[i].Type.Kind {
+			t.Errorf("field %d = %s %s (%s), want %s %s (%s)", i, f.Name, generator.TypeName(f.Type), f.Type.Kind, want[i].Name, generator.TypeName(want[i].Type), want[i].Type.Kind)
+		}
+	}
+
+	if got := m.Enums["Status"]; got == nil || len(got.Values) != 2 || got.Values[0] != "StatusActive" {
+		t.Errorf("Status enum = %+v, want values [StatusActive StatusArchived]", got)
+	}
+	if got := m.OneOfs["Pet"]; got != "Cat" {
+		t.Errorf("Pet oneof implementation = %q, want %q", got, "Cat")
+	}
+}
+
+func TestInferJSON(t *testing.T) {
+	sample := `{"id": "u-1", "first_name": "Ada", "age": 36, "tags": ["admin", "beta"], "address": {"city": "London"}}`
+
+	m, err := generator.InferJSON("User", []byte(sample))
+	if err != nil {
+		t.Fatalf("InferJSON() error = %v", err)
+	}
+	if _, ok := m.Structs["UserAddress"]; !ok {
+		t.Error("nested object should become struct UserAddress")
+	}
+
+	got := generator.GenerateWithOptions(m, "fixtures", generator.GenerateOptions{})
+	for _, want := range []string{
+		`ID: "u-1"`,
+		`FirstName: "Ada"`,
+		"Age: 36",
+		`Tags: []string{"admin", "beta"}`,
+		"Address: FixtureUserAddress()",
+		`City: "London"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q\nGot:\n%s", want, got)
+		}
+	}
+
+	// -json alone declares the inferred structs, which exist nowhere else
+	tg := target{JSON: filepath.Join(t.TempDir(), "user.json"), OutPkg: "fixtures", Placeholder: "$"}
+	os.WriteFile(tg.JSON, []byte(sample), 0644)
+	m, _, err = tg.model(1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts, err := tg.options(m, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	declared, err := generator.GenerateFormattedWithOptions(m, "fixtures", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"type Sample struct {\n\tID        string        `json:\"id\"`",
+		"\tAddress   SampleAddress `json:\"address\"`",
+		"type SampleAddress struct {\n\tCity string `json:\"city\"`\n}",
+	} {
+		if !strings.Contains(declared, want) {
+			t.Errorf("output missing %q\nGot:\n%s", want, declared)
+		}
+	}
+	tg.TypePrefix = "api"
+	if err := tg.validate(); err == nil || !strings.Contains(err.Error(), "-typeprefix") {
+		t.Errorf("validate() = %v, want -typeprefix rejected with -json alone", err)
+	}
+}
+
+func TestApplySample(t *testing.T) {
+	m := generator.NewModel()
+	m.Structs["User"] = &generator.Struct{
+		Name: "User",
+		Fields: []generator.Field{
+			{Name: "FirstName", Type: generator.TypeRef{Kind: "primitive", Name: "string"}},
+			{Name: "Age", Type: generator.TypeRef{Kind: "primitive", Name: "int32"}},
+			{Name: "Nickname", Type: generator.TypeRef{Kind: "pointer", Elem: &generator.TypeRef{Kind: "primitive", Name: "string"}}},
+			{Name: "LastName", Type: generator.TypeRef{Kind: "primitive", Name: "string"}},
+		},
+	}
+
+	if err := generator.ApplySample(m, "User", []byte(`{"first_name": "Grace", "age": 40, "nickname": "gh"}`)); err != nil {
+		t.Fatalf("ApplySample() error = %v", err)
+	}
+
+	got := generator.GenerateWithOptions(m, "fixtures", generator.GenerateOptions{})
+	for _, want := range []string{`FirstName: "Grace"`, "Age: 40", `Nickname: ptr[string]("gh")`, `LastName: "LastName"`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q\nGot:\n%s", want, got)
+		}
+	}
+}
+
+func TestAnonymizeJSON(t *testing.T) {
+	sample := `{"id": "u-1", "first_name": "Ada", "email": "ada@corp.io", "managerId": 7, "created_at": "2024-03-10T12:00:00Z", "status": "active",
+		"friends": [{"userId": "u-1", "name": "Ada Lovelace", "since": "2024-03-12T12:00:00Z"}]}`
+
+	out, err := generator.AnonymizeJSON([]byte(sample), "salt")
+	if err != nil {
+		t.Fatalf("AnonymizeJSON() error = %v", err)
+	}
+	for _, leaked := range []string{"u-1", "Ada", "ada@corp.io", "2024-03-10", ": 7"} {
+		if strings.Contains(string(out), leaked) {
+			t.Errorf("anonymized sample still contains %q:\n%s", leaked, out)
+		}
+	}
+	var got struct {
+		ID        string `json:"id"`
+		Email     string `json:"email"`
+		CreatedAt string `json:"created_at"`
+		Status    string `json:"status"`
+		Friends   []struct {
+			UserID string `json:"userId"`
+			Since  string `json:"since"`
+		} `json:"friends"`
+	}
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatalf("anonymized sample is no valid JSON: %v\n%s", err, out)
+	}
+	if got.ID != got.Friends[0].UserID || len(got.ID) != len("u-1") {
+		t.Errorf("IDs %q and %q should be hashed consistently", got.ID, got.Friends[0].UserID)
+	}
+	if !strings.HasSuffix(got.Email, "@example.com") || got.Status != "active" {
+		t.Errorf("email = %q, status = %q", got.Email, got.Status)
+	}
+	created, _ := time.Parse(time.RFC3339, got.CreatedAt)
+	since, _ := time.Parse(time.RFC3339, got.Friends[0].Since)
+	if since.Sub(created) != 48*time.Hour || created.Hour() != 12 {
+		t.Errorf("dates %s and %s should be shifted by whole days", got.CreatedAt, got.Friends[0].Since)
+	}
+
+	again, _ := generator.AnonymizeJSON([]byte(sample), "salt")
+	other, _ := generator.AnonymizeJSON([]byte(sample), "pepper")
+	if !bytes.Equal(out, again) || bytes.Equal(out, other) {
+		t.Error("AnonymizeJSON() should be deterministic for a salt and differ between salts")
+	}
+	if _, err := generator.InferJSON("User", out); err != nil {
+		t.Errorf("InferJSON() of the anonymized sample: %v", err)
+	}
+}
+
+func TestParseDDL(t *testing.T) {
+	ddl := `
+CREATE TYPE user_status AS ENUM ('active', 'suspended');
+-- accounts
+CREATE TABLE IF NOT EXISTS public.users (
+	id uuid PRIMARY KEY,
+	email varchar(255) NOT NULL,
+	nickname text,
+	status user_status NOT NULL DEFAULT 'active',
+	created_at timestamptz NOT NULL DEFAULT now()
+);
+CREATE TABLE ` + "`orders`" + ` (
+	` + "`id`" + ` bigint unsigned NOT NULL AUTO_INCREMENT,
+	` + "`state`" + ` ENUM('open','closed') NOT NULL,
+	` + "`paid`" + ` tinyint(1) NOT NULL DEFAULT 0,
+	PRIMARY KEY (` + "`id`" + `)
+) ENGINE=InnoDB;`
+
+	m, err := generator.ParseDDL(ddl)
+	if err != nil {
+		t.Fatalf("ParseDDL() error = %v", err)
+	}
+
+	user, ok := m.Structs["User"]
+	if !ok {
+		t.Fatal("User struct not found")
+	}
+	if user.Table != "public.users" {
+		t.Errorf("User.Table = %q, want public.users", user.Table)
+	}
+	want := map[string]string{
+		"ID":        "string",
+		"Email":     "string",
+		"Nickname":  "*string",
+		"Status":    "UserStatus",
+		"CreatedAt": "Time",
+	}
+	for _, f := range user.Fields {
+		if got := generator.TypeName(f.Type); got != want[f.Name] {
+			t.Errorf("User.%s type = %s, want %s", f.Name, got, want[f.Name])
+		}
+	}
+
+	order := m.Structs["Order"]
+	if order == nil || len(order.Fields) != 3 {
+		t.Fatalf("Order = %+v, want 3 fields", order)
+	}
+	if got := generator.TypeName(order.Fields[0].Type); got != "uint64" {
+		t.Errorf("Order.ID type = %s, want uint64", got)
+	}
+	if e := m.Enums["OrderState"]; e == nil || len(e.Values) != 2 || e.Labels[1] != "closed" {
+		t.Errorf("OrderState enum = %+v", e)
+	}
+
+	seeds := generator.GenerateSQLSeeds(m)
+	if !strings.Contains(seeds, "INSERT INTO public.users (id, email, nickname, status, created_at) VALUES (") ||
+		!strings.Contains(seeds, "'active'") {
+		t.Errorf("GenerateSQLSeeds() = %s", seeds)
+	}
+
+	// Columns of types with a format get values PostgreSQL accepts
+	m, err = generator.ParseDDL(`CREATE TABLE accounts (
+	id uuid PRIMARY KEY,
+	owner_id uuid NOT NULL,
+	meta jsonb NOT NULL,
+	ip inet,
+	net cidr NOT NULL,
+	mac macaddr NOT NULL,
+	doc xml NOT NULL
+);`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uuid := regexp.MustCompile(`^'[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}'$`)
+	seed := generator.SQLSeeds(m)[0].Insert
+	values := strings.Split(strings.TrimSuffix(seed[strings.Index(seed, "VALUES (")+len("VALUES ("):], ");"), ", ")
+	if len(values) != 7 || !uuid.MatchString(values[0]) || !uuid.MatchString(values[1]) || values[0] == values[1] {
+		t.Errorf("uuid values of %s, want distinct UUIDs", seed)
+	}
+	if got := strings.Join(values[2:], ", "); got != "'{}', '192.0.2.1', '192.0.2.0/24', '00:00:5e:00:53:01', '<Doc/>'" {
+		t.Errorf("values = %s", got)
+	}
+	if out := generator.GenerateWithOptions(m, "fixtures", generator.GenerateOptions{}); !strings.Contains(out, `Meta: "{}"`) || !strings.Contains(out, `Ip: ptr("192.0.2.1")`) {
+		t.Errorf("fixture doesn't match the seed:\n%s", out)
+	}
+}
+
+func TestDeclareTypes(t *testing.T) {
+	dir := t.TempDir()
+	openAPI := filepath.Join(dir, "openapi.json")
+	os.WriteFile(openAPI, []byte(`{"openapi": "3.1.0", "components": {"schemas": {
+		"Status": {"type": "string", "enum": ["active", "in-active"]},
+		"UserID": {"type": "string"},
+		"Cat": {"type": "object", "properties": {"name": {"type": "string"}}},
+		"Dog": {"type": "object", "properties": {"bark": {"type": "boolean"}}},
+		"Pet": {"oneOf": [{"$ref": "#/components/schemas/Cat"}, {"$ref": "#/components/schemas/Dog"}]},
+		"User": {"type": "object", "required": ["id", "cat"], "properties": {
+			"id": {"$ref": "#/components/schemas/UserID"},
+			"status": {"$ref": "#/components/schemas/Status"},
+			"pet": {"$ref": "#/components/schemas/Pet"},
+			"cat": {"$ref": "#/components/schemas/Cat"},
+			"created": {"type": "string", "format": "date-time"},
+			"tags": {"type": "array", "items": {"type": "string"}}
+		}}
+	}}}`), 0644)
+	sql := filepath.Join(dir, "schema.sql")
+	os.WriteFile(sql, []byte(`CREATE TYPE mood AS ENUM ('happy', 'sad');
+CREATE TABLE users (id uuid PRIMARY KEY, mood mood, data jsonb, created_at timestamptz NOT NULL);`), 0644)
+
+	// The types of OpenAPI documents and SQL schemas are declared with their fixtures, which compile
+	// on their own
+	for _, tg := range []target{{OpenAPI: openAPI}, {SQL: sql}} {
+		m, _, err := tg.model(1, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		opts, err := tg.options(m, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := generator.GenerateFormattedWithOptions(m, "api", opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, "fixtures.go", out, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := (&types.Config{Importer: importer.ForCompiler(fset, "source", nil)}).Check("api", fset, []*ast.File{f}, nil); err != nil {
+			t.Errorf("%+v: fixtures don't compile: %v\n%s", tg, err, out)
+		}
+		if tg.SQL != "" && !strings.Contains(out, "`db:\"created_at\"`") {
+			t.Errorf("fields aren't tagged with their columns:\n%s", out)
+		}
+	}
+
+	// With -typeprefix the types are declared in the package it names
+	tg := target{OpenAPI: openAPI, TypePrefix: "api"}
+	m, _, err := tg.model(1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts, err := tg.options(m, nil); err != nil || opts.DeclareTypes {
+		t.Errorf("options() = %+v, %v, want the types left undeclared", opts, err)
+	}
+}
+
+func TestKubernetesTypes(t *testing.T) {
+	src := `package v1
+
+type CronJob struct {
+	metav1.TypeMeta   ` + "`json:\",inline\"`" + `
+	metav1.ObjectMeta ` + "`json:\"metadata,omitempty\"`" + `
+	CPU resource.Quantity
+}
+`
+	m, err := generator.ParseSource(src)
+	if err != nil {
+		t.Fatalf("ParseSource() error = %v", err)
+	}
+	m.Structs["CronJob"].APIVersion = "batch.example.com/v1"
+
+	got := generator.GenerateWithOptions(m, "v1", generator.GenerateOptions{ModStyle: false})
+	for _, want := range []string{
+		`metav1.TypeMeta{Kind: "CronJob", APIVersion: "batch.example.com/v1"}`,
+		`metav1.ObjectMeta{Name: "cron-job", Namespace: "default"`,
+		`resource.MustParse("100m")`,
+		`metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %s:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, `"time"`) {
+		t.Errorf("output imports time without using it:\n%s", got)
+	}
+
+	pkg := &packages.Package{PkgPath: "example.com/api/batch/v1"}
+	file, err := parser.ParseFile(token.NewFileSet(), "doc.go", "// +groupName=batch.example.com\npackage v1\n", parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg.Syntax = append(pkg.Syntax, file)
+	if got := generator.APIVersion(pkg); got != "batch.example.com/v1" {
+		t.Errorf("APIVersion() = %q, want batch.example.com/v1", got)
+	}
+}
+
+func TestFixturePackages(t *testing.T) {
+	m := generator.NewModel()
+	m.Structs["Order"] = &generator.Struct{Name: "Order", Fields: []generator.Field{
+		{Name: "Item", Type: generator.TypeRef{Kind: "struct", Name: "Item", Pkg: "example.com/shop/item"}},
+		{Name: "Items", Type: generator.TypeRef{Kind: "slice", Elem: &generator.TypeRef{Kind: "struct", Name: "Item", Pkg: "example.com/shop/item"}}},
+	}}
+	opts := generator.GenerateOptions{
+		ModStyle:        false,
+		FixturePackages: map[string]string{"example.com/shop/item": "example.com/shop/item/fixtures"},
+	}
+
+	got, err := generator.GenerateFormattedWithOptions(m, "fixtures", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`itemfixtures "example.com/shop/item/fixtures"`,
+		`item "example.com/shop/item"`,
+		"Item:  itemfixtures.FixtureItem(),",
+		"Items: []item.Item{itemfixtures.FixtureItem()},",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "func FixtureItem") {
+		t.Errorf("output regenerates a fixture of another package:\n%s", got)
+	}
+
+	dir := t.TempDir()
+	if hasFixtures(dir) {
+		t.Error("hasFixtures() = true for an empty directory")
+	}
+	if err := os.WriteFile(filepath.Join(dir, "fixtures_gen.go"), []byte("package fixtures\n\nfunc FixtureItem() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if !hasFixtures(dir) {
+		t.Error("hasFixtures() = false for a generated fixtures package")
+	}
+}
+
+func TestBuildGraph(t *testing.T) {
+	m := generator.NewModel()
+	m.Structs["Node"] = &generator.Struct{Name: "Node", Fields: []generator.Field{
+		{Name: "Children", Type: generator.TypeRef{Kind: "slice", Elem: &generator.TypeRef{Kind: "pointer", Elem: &generator.TypeRef{Kind: "struct", Name: "Node"}}}},
+		{Name: "Status", Type: generator.TypeRef{Kind: "enum", Name: "Status"}},
+	}}
+	m.Structs["Tree"] = &generator.Struct{Name: "Tree", Fields: []generator.Field{
+		{Name: "Root", Type: generator.TypeRef{Kind: "pointer", Elem: &generator.TypeRef{Kind: "struct", Name: "Node"}}},
+	}}
+	m.Enums["Status"] = &generator.Enum{Name: "Status", Values: []string{"StatusActive"}}
+
+	g := generator.BuildGraph(m)
+	if len(g.Nodes) != 3 || len(g.Edges) != 3 {
+		t.Fatalf("BuildGraph() = %d nodes, %d edges, want 3 and 3", len(g.Nodes), len(g.Edges))
+	}
+	if len(g.Cycles) != 1 || g.Cycles[0][0] != "Node" {
+		t.Errorf("Cycles = %v, want [[Node]]", g.Cycles)
+	}
+	for _, n := range g.Nodes {
+		if n.Name == "Tree" && n.FanOut != 2 {
+			t.Errorf("Tree fan-out = %d, want 2", n.FanOut)
+		}
+	}
+
+	dot := g.DOT()
+	for _, want := range []string{`"Node" -> "Node" [label="Children", color=red];`, `"Tree" -> "Node" [label="Root"];`, `"Status" [shape=ellipse`} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("DOT() missing %s:\n%s", want, dot)
+		}
+	}
+}
+
+func TestSeedFuncs(t *testing.T) {
+	src := "package db\n\ntype UserAccount struct {\n" +
+		"\tID     int64  `db:\"id\"`\n" +
+		"\tEmail  string `gorm:\"column:email_address;uniqueIndex\"`\n" +
+		"\tNick   string `gorm:\"size:32\"`\n" +
+		"\tSecret string `db:\"-\"`\n" +
+		"\tNotes  string\n" +
+		"}\n"
+	m, err := generator.ParseSource(src)
+	if err != nil {
+		t.Fatalf("ParseSource() error = %v", err)
+	}
+
+	tests := []struct {
+		opts generator.GenerateOptions
+		want []string
+	}{
+		{
+			opts: generator.GenerateOptions{ModStyle: true, Seed: true},
+			want: []string{
+				"func SeedUserAccount(ctx context.Context, db *sql.DB, mods ...func(*UserAccount)) (*UserAccount, error) {",
+				"value := FixtureUserAccount(mods...)",
+				`db.ExecContext(ctx, "INSERT INTO user_accounts (id, email_address, nick) VALUES ($1, $2, $3)", value.ID, value.Email, value.Nick)`,
+				`"database/sql"`,
+			},
+		},
+		{
+			opts: generator.GenerateOptions{ModStyle: false, Seed: true, SeedPlaceholder: "?", TypePrefix: "db"},
+			want: []string{
+				"func SeedUserAccount(ctx context.Context, conn *sql.DB) (db.UserAccount, error) {",
+				"VALUES (?, ?, ?)",
+			},
+		},
+	}
+	for _, tt := range tests {
+		got := generator.GenerateWithOptions(m, "fixtures", tt.opts)
+		for _, want := range tt.want {
+			if !strings.Contains(got, want) {
+				t.Errorf("output missing %s:\n%s", want, got)
+			}
+		}
+	}
+
+	if got := generator.GenerateWithOptions(m, "fixtures", generator.GenerateOptions{ModStyle: true}); strings.Contains(got, "Seed") || strings.Contains(got, "database/sql") {
+		t.Errorf("Seed helpers generated without Seed option:\n%s", got)
+	}
+}
+
+func TestHTTPStubs(t *testing.T) {
+	m := generator.NewModel()
+	m.Structs["User"] = &generator.Struct{Name: "User", Fields: []generator.Field{
+		{Name: "Name", Type: generator.TypeRef{Kind: "primitive", Name: "string"}},
+	}}
+	opts := generator.GenerateOptions{
+		ModStyle: true,
+		Routes:   map[string]string{"GET /users/{id}": "User", "GET /missing": "Missing"},
+	}
+
+	got, err := generator.GenerateFormattedWithOptions(m, "fixtures", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`mux.HandleFunc("GET /users/{id}", fixtureRoute("GET /users/{id}", overrides, func(*http.Request) any { return FixtureUser() }))`,
+		"func NewFixtureServer(t testing.TB, overrides map[string]func(*http.Request) any) *httptest.Server {",
+		`"net/http/httptest"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %s:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "/missing") {
+		t.Errorf("route for a type without fixture generated:\n%s", got)
+	}
+	if generator.HasFixture(m, "Missing") || !generator.HasFixture(m, "User") {
+		t.Error("HasFixture() reports wrong types")
+	}
+}
+
+func TestWriteSeedFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "000004_create_users.up.sql"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	seeds := []generator.SQLSeed{
+		{Table: "users", Insert: "INSERT INTO users (id) VALUES (1);", Delete: "DELETE FROM users WHERE id = 1;"},
+		{Table: "orders", Insert: "INSERT INTO orders (id) VALUES (1);", Delete: "DELETE FROM orders WHERE id = 1;"},
+	}
+
+	// A second run must reuse the numbers instead of adding new migrations
+	for i := 0; i < 2; i++ {
+		if err := writeSeedFiles(dir, "migrate", seeds); err != nil {
+			t.Fatalf("writeSeedFiles() error = %v", err)
+		}
+	}
+	entries, _ := os.ReadDir(dir)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	want := []string{
+		"000004_create_users.up.sql",
+		"000005_seed_users.down.sql",
+		"000005_seed_users.up.sql",
+		"000006_seed_orders.down.sql",
+		"000006_seed_orders.up.sql",
+	}
+	if strings.Join(names, " ") != strings.Join(want, " ") {
+		t.Errorf("files = %v, want %v", names, want)
+	}
+
+	gooseDir := t.TempDir()
+	if err := writeSeedFiles(gooseDir, "goose", seeds[:1]); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(gooseDir, "00001_seed_users.sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "-- +goose Up\nINSERT INTO users") || !strings.Contains(string(data), "-- +goose Down\nDELETE FROM users") {
+		t.Errorf("goose file = %s", data)
+	}
+}
+
+func TestSnapshots(t *testing.T) {
+	m := generator.NewModel()
+	m.Structs["User"] = &generator.Struct{Name: "User", Fields: []generator.Field{
+		{Name: "Name", Type: generator.TypeRef{Kind: "primitive", Name: "string"}},
+	}}
+	hash := generator.TypeHash(m, "User")
+	if hash == "" || generator.TypeHash(m, "Missing") != "" {
+		t.Fatalf("TypeHash() = %q", hash)
+	}
+
+	got, err := generator.GenerateFormattedWithOptions(m, "fixtures", generator.GenerateOptions{ModStyle: false, Snapshots: "protojson"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := format.Source([]byte(got)); err != nil {
+		t.Fatalf("generated code does not parse: %v\n%s", err, got)
+	}
+	for _, want := range []string{
+		`{"User", "` + hash + `", func() any { v := FixtureUser(); return &v }},`,
+		"func WriteSnapshots(dir string) error {",
+		"if msg, ok := value.(proto.Message); ok {",
+		`"google.golang.org/protobuf/encoding/protojson"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %s:\n%s", want, got)
+		}
+	}
+
+	m.Structs["User"].Fields = append(m.Structs["User"].Fields, generator.Field{Name: "Age", Type: generator.TypeRef{Kind: "primitive", Name: "int"}})
+	if generator.TypeHash(m, "User") == hash {
+		t.Error("TypeHash() unchanged after adding a field")
+	}
+}
+
+func TestYAMLFixtures(t *testing.T) {
+	m := generator.NewModel()
+	m.Structs["Audit"] = &generator.Struct{Name: "Audit", Fields: []generator.Field{
+		{Name: "CreatedAt", Type: generator.TypeRef{Kind: "external", Name: "Time", Pkg: "time"}},
+	}}
+	m.Structs["OrderLine"] = &generator.Struct{Name: "OrderLine", Fields: []generator.Field{
+		{Name: "Audit", Type: generator.TypeRef{Kind: "struct", Name: "Audit"}, Embedded: true},
+		{Name: "ID", Type: generator.TypeRef{Kind: "primitive", Name: "string"}, JSONName: "id"},
+		{Name: "Note", Type: generator.TypeRef{Kind: "pointer", Elem: &generator.TypeRef{Kind: "primitive", Name: "string"}}, JSONName: "note-text"},
+		{Name: "Tags", Type: generator.TypeRef{Kind: "slice", Elem: &generator.TypeRef{Kind: "primitive", Name: "string"}}},
+		{Name: "Status", Type: generator.TypeRef{Kind: "enum", Name: "Status"}},
+		{Name: "Parent", Type: generator.TypeRef{Kind: "pointer", Elem: &generator.TypeRef{Kind: "struct", Name: "OrderLine"}}},
+	}}
+	m.Enums["Status"] = &generator.Enum{Name: "Status", Values: []string{"StatusOpen"}}
+
+	files, err := generator.YAMLFiles(m, generator.GenerateOptions{ModStyle: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The embedded Audit is flattened, the enum and the recursive Parent keep the fixture's value
+	want := `CreatedAt: "2000-01-01T00:00:00Z"
+id: "OrderLineID"
+"note-text": "Note"
+Tags:
+  - "Tags"
+`
+	if got := string(files["order_line.yaml"]); got != want {
+		t.Errorf("order_line.yaml = %q, want %q", got, want)
+	}
+	if len(files) != 2 {
+		t.Errorf("YAMLFiles() wrote %d files, want one per struct", len(files))
+	}
+
+	out, err := generator.GenerateFormattedWithOptions(m, "fixtures", generator.GenerateOptions{ModStyle: true, TypePrefix: "orders", YAMLLoaders: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "fixtures.go", out, 0); err != nil {
+		t.Fatalf("generated code does not parse: %v\n%s", err, out)
+	}
+	for _, want := range []string{
+		"func LoadOrderLineFixture(t testing.TB, name string) *orders.OrderLine {",
+		"loadYAMLFixture(t, name, value)",
+		`"` + generator.YAMLImport + `"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %s:\n%s", want, out)
+		}
+	}
+	value := generator.GenerateWithOptions(m, "fixtures", generator.GenerateOptions{YAMLLoaders: true})
+	if !strings.Contains(value, "loadYAMLFixture(t, name, &value)") {
+		t.Errorf("loaders of value fixtures should decode into their address:\n%s", value)
+	}
+
+	// The files agree with the fixtures under the value options
+	m.Structs["User"] = &generator.Struct{Name: "User", Fields: []generator.Field{
+		{Name: "Email", Type: generator.TypeRef{Kind: "primitive", Name: "string"}},
+		{Name: "Tags", Type: generator.TypeRef{Kind: "slice", Elem: &generator.TypeRef{Kind: "primitive", Name: "string"}}},
+		{Name: "Scores", Type: generator.TypeRef{Kind: "slice", Elem: &generator.TypeRef{Kind: "primitive", Name: "int"}}},
+	}}
+	opts := generator.GenerateOptions{
+		ModStyle:       true,
+		FieldOverrides: map[string]string{"User.Email": `"ada@example.com"`},
+		SliceLen:       3,
+		SliceLens:      map[string]int{"User.Scores": 2},
+	}
+	files, err = generator.YAMLFiles(m, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = `Email: "ada@example.com"
+Tags:
+  - "Tags1"
+  - "Tags2"
+  - "Tags3"
+Scores:
+  - 1
+  - 2
+`
+	if got := string(files["user.yaml"]); got != want {
+		t.Errorf("user.yaml = %q, want %q", got, want)
+	}
+	fixture, err := generator.GenerateFormattedWithOptions(m, "fixtures", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`Email:  "ada@example.com"`, `Tags:   []string{"Tags1", "Tags2", "Tags3"}`, "Scores: []int{1, 2}"} {
+		if !strings.Contains(fixture, want) {
+			t.Errorf("fixture lacks %s matching user.yaml:\n%s", want, fixture)
+		}
+	}
+
+	dir := t.TempDir()
+	if err := writeYAMLFiles(filepath.Join(dir, "testdata"), m, generator.GenerateOptions{ModStyle: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "testdata", "audit.yaml")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWireMockMapping(t *testing.T) {
+	m, err := generator.ParseOpenAPI([]byte(`{"openapi": "3.0.0", "components": {"schemas": {
+		"CreateUserRequest": {"type": "object", "required": ["role"], "properties": {"role": {"type": "string", "enum": ["admin", "member"]}}},
+		"User": {"type": "object", "required": ["user_id"], "properties": {"user_id": {"type": "string"}, "parent": {"$ref": "#/components/schemas/User"}}}
+	}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := generator.ParseEndpoint("post /v1/orgs/{org}/users", "CreateUserRequest:User")
+	if err != nil {
+		t.Fatalf("ParseEndpoint() error = %v", err)
+	}
+	if e.Method != "POST" || e.Request != "CreateUserRequest" || e.Response != "User" || e.Name() != "post-v1-orgs-org-users" {
+		t.Errorf("ParseEndpoint() = %+v (name %s)", e, e.Name())
+	}
+	if _, err := generator.ParseEndpoint("/users", "User"); err == nil {
+		t.Error("ParseEndpoint() without method: want error")
+	}
+
+	data, err := generator.WireMockMapping(m, e, generator.GenerateOptions{})
+	if err != nil {
+		t.Fatalf("WireMockMapping() error = %v", err)
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, data); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`"urlPathPattern":"/v1/orgs/[^/]+/users"`,
+		`"equalToJson":{"role":"admin"}`,
+		// The recursive parent reference ends in null
+		`"jsonBody":{"user_id":"UserID","parent":null}`,
+	} {
+		if !strings.Contains(compact.String(), want) {
+			t.Errorf("mapping missing %s:\n%s", want, data)
+		}
+	}
+}
+
+func TestJSONValueOptions(t *testing.T) {
+	m, err := generator.ParseSource(`package models
+
+type User struct {
+	Email string
+	Nick  string
+	Tags  []string
+	Raw   []byte
+}
+`)
+	if err != nil {
+		t.Fatalf("ParseSource() error = %v", err)
+	}
+	opts := generator.GenerateOptions{
+		ValueStrategy:  "faker",
+		FieldOverrides: map[string]string{"User.Nick": `"ada"`},
+		SliceLen:       2,
+		ByteLength:     4,
+	}
+	out, err := generator.GenerateFormattedWithOptions(m, "fixtures", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := generator.JSONValue(m, "User", opts)
+	if err != nil {
+		t.Fatalf("JSONValue() error = %v", err)
+	}
+	data, _ := json.Marshal(value)
+	var user struct {
+		Email, Nick string
+		Tags        []string
+		Raw         []byte
+	}
+	if err := json.Unmarshal(data, &user); err != nil {
+		t.Fatal(err)
+	}
+	// The JSON holds what the fixture does
+	if !strings.Contains(out, strconv.Quote(user.Email)) || user.Nick != "ada" || len(user.Tags) != 2 || len(user.Raw) != 4 {
+		t.Errorf("JSONValue() = %s, doesn't match the fixture:\n%s", data, out)
+	}
+}
+
+func TestJSONValueEmbedded(t *testing.T) {
+	m, err := generator.ParseSource(`package models
+
+type Base struct {
+	ID   string
+	Name string
+}
+
+type User struct {
+	Base
+	Name  string
+	Audit Base ` + "`json:\"audit\"`" + `
+	Owner *Base ` + "`json:\"owner\"`" + `
+}
+`)
+	if err != nil {
+		t.Fatalf("ParseSource() error = %v", err)
+	}
+	value, err := generator.JSONValue(m, "User", generator.GenerateOptions{FieldOverrides: map[string]string{"User.Name": `"ada"`}})
+	if err != nil {
+		t.Fatalf("JSONValue() error = %v", err)
+	}
+	data, _ := json.Marshal(value)
+	// The fields of Base are promoted like encoding/json does, and Name is the one of User
+	want := `{"ID":"BaseID","Name":"ada","audit":{"ID":"BaseID","Name":"Name"},"owner":{"ID":"BaseID","Name":"Name"}}`
+	if string(data) != want {
+		t.Errorf("JSONValue() = %s, want %s", data, want)
+	}
+}
+
+func TestAddOpenAPIExamples(t *testing.T) {
+	doc := `{"openapi": "3.1.0", "info": {"title": "Users & <Groups>"}, "components": {"schemas": {
+		"user": {"type": "object", "properties": {"name": {"type": "string"}, "age": {"type": "integer"}}},
+		"Other": {"type": "object"}
+	}}}`
+	m := generator.NewModel()
+	m.Structs["User"] = &generator.Struct{Name: "User", Fields: []generator.Field{
+		{Name: "Name", JSONName: "name", Type: generator.TypeRef{Kind: "primitive", Name: "string"}},
+		{Name: "Age", JSONName: "age", Type: generator.TypeRef{Kind: "pointer", Elem: &generator.TypeRef{Kind: "primitive", Name: "int32"}}},
+	}}
+
+	out, schemas, err := generator.AddOpenAPIExamples([]byte(doc), m, generator.GenerateOptions{})
+	if err != nil {
+		t.Fatalf("AddOpenAPIExamples() error = %v", err)
+	}
+	if len(schemas) != 1 || schemas[0] != "user" {
+		t.Errorf("schemas = %v, want [user]", schemas)
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, out); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"openapi":"3.1.0","info":{"title":"Users & <Groups>"},"components":{"schemas":{` +
+		`"user":{"type":"object","properties":{"name":{"type":"string"},"age":{"type":"integer"}},"examples":[{"name":"Name","age":1}]},` +
+		`"Other":{"type":"object"}}}}`
+	if compact.String() != want {
+		t.Errorf("AddOpenAPIExamples() =\n%s\nwant\n%s", compact.String(), want)
+	}
+}
+
+func TestHTTPRequestSamples(t *testing.T) {
+	m := generator.NewModel()
+	m.Structs["CreateOrder"] = &generator.Struct{Name: "CreateOrder", Fields: []generator.Field{
+		{Name: "Quantity", JSONName: "quantity", Type: generator.TypeRef{Kind: "primitive", Name: "int"}},
+	}}
+	m.Structs["Order"] = &generator.Struct{Name: "Order", Fields: []generator.Field{
+		{Name: "OrderID", JSONName: "order_id", Default: `"order 7"`, Type: generator.TypeRef{Kind: "primitive", Name: "string"}},
+	}}
+	var eps []generator.Endpoint
+	for route, types := range map[string]string{"GET /orders/{orderId}": "Order", "POST /orders": "CreateOrder:Order"} {
+		e, err := generator.ParseEndpoint(route, types)
+		if err != nil {
+			t.Fatal(err)
+		}
+		eps = append(eps, e)
+	}
+	sort.Slice(eps, func(i, j int) bool { return eps[i].Path < eps[j].Path })
+
+	httpFile, err := generator.HTTPRequests(m, eps, "http://localhost:9000", generator.GenerateOptions{})
+	if err != nil {
+		t.Fatalf("HTTPRequests() error = %v", err)
+	}
+	want := "@baseUrl = http://localhost:9000\n\n" +
+		"### POST /orders\nPOST {{baseUrl}}/orders\nContent-Type: application/json\n\n{\n  \"quantity\": 1\n}\n\n" +
+		"### GET /orders/{orderId}\nGET {{baseUrl}}/orders/order%207\n"
+	if httpFile != want {
+		t.Errorf("HTTPRequests() =\n%s\nwant\n%s", httpFile, want)
+	}
+
+	script, err := generator.CurlScript(m, eps, "http://localhost:9000", generator.GenerateOptions{})
+	if err != nil {
+		t.Fatalf("CurlScript() error = %v", err)
+	}
+	for _, want := range []string{
+		`BASE_URL="${BASE_URL:-http://localhost:9000}"`,
+		`curl -sS -X POST "$BASE_URL/orders" \`,
+		`curl -sS -X GET "$BASE_URL/orders/order%207"`,
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("CurlScript() missing %s:\n%s", want, script)
+		}
+	}
+}
+
+func TestAnyPayloads(t *testing.T) {
+	src := `package events
+
+type Envelope struct {
+	Payload  *anypb.Any
+	Metadata *anypb.Any
+}
+
+type OrderCreated struct {
+	OrderId string
+}
+`
+	m, err := generator.ParseSource(src)
+	if err != nil {
+		t.Fatalf("ParseSource() error = %v", err)
+	}
+
+	got := generator.GenerateWithOptions(m, "fixtures", generator.GenerateOptions{
+		ModStyle:    true,
+		AnyPayloads: map[string]string{"Envelope.Payload": "OrderCreated"},
+	})
+	for _, want := range []string{
+		"Payload: PackFixtureAny(FixtureOrderCreated()),",
+		"Metadata: &anypb.Any{},",
+		"func PackFixtureAny[T proto.Message](msg T) *anypb.Any {",
+		`anypb "google.golang.org/protobuf/types/known/anypb"`,
+		`"google.golang.org/protobuf/proto"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %s:\n%s", want, got)
+		}
+	}
+
+	got = generator.GenerateWithOptions(m, "fixtures", generator.GenerateOptions{AnyPayloads: map[string]string{"Metadata": "OrderCreated"}})
+	if !strings.Contains(got, "Metadata: PackFixtureAny(ptr(FixtureOrderCreated())),") {
+		t.Errorf("classic style output missing packed Metadata:\n%s", got)
+	}
+}
+
+func TestPicker(t *testing.T) {
+	m := generator.NewModel()
+	m.Structs["Order"] = &generator.Struct{Name: "Order", Fields: []generator.Field{
+		{Name: "Status", Type: generator.TypeRef{Kind: "enum", Name: "Status"}},
+	}}
+	m.Structs["User"] = &generator.Struct{Name: "User"}
+	m.Enums["Status"] = &generator.Enum{Name: "Status", Values: []string{"StatusActive"}}
+
+	p := newPicker(m)
+	for _, key := range []string{"j", " ", "\x1b[B", "\x1b[B", " "} {
+		if action := p.handleKey(key, 24); action != pickNone {
+			t.Fatalf("handleKey(%q) = %v", key, action)
+		}
+	}
+	if got := strings.Join(p.picked(), ","); got != "Status,User" {
+		t.Errorf("picked() = %s, want Status,User", got)
+	}
+	if p.handleKey("\r", 24) != pickWrite || p.handleKey("q", 24) != pickQuit {
+		t.Error("enter and q should write and quit")
+	}
+
+	// Picking Order pulls in the enum its fixture needs
+	sub := m.Subset([]string{"Order"})
+	if len(sub.Structs) != 1 || sub.Enums["Status"] == nil {
+		t.Errorf("Subset(Order) = %d structs, enums %v", len(sub.Structs), sub.Enums)
+	}
+	if preview := fixturePreview(m, "Order", generator.GenerateOptions{}); !strings.HasPrefix(preview, "func FixtureOrder() Order {") {
+		t.Errorf("fixturePreview() = %s", preview)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	m := generator.NewModel()
+	m.Structs["Order"] = &generator.Struct{Name: "Order", Fields: []generator.Field{
+		{Name: "ID", Type: generator.TypeRef{Kind: "primitive", Name: "string"}},
+		{Name: "CreatedAt", Type: generator.TypeRef{Kind: "external", Name: "Time"}},
+		{Name: "Tags", Type: generator.TypeRef{Kind: "unknown"}},
+		{Name: "Customer", Type: generator.TypeRef{Kind: "pointer", Elem: &generator.TypeRef{Kind: "struct", Name: "Customer"}}},
+		{Name: "Payment", Type: generator.TypeRef{Kind: "oneof", Name: "isOrder_Payment"}},
+	}}
+	m.Enums["Status"] = &generator.Enum{Name: "Status"}
+
+	s := generator.Summarize(m, generator.GenerateOptions{ModStyle: true})
+	if s.Structs != 1 || s.Enums != 0 || s.Fields != 5 {
+		t.Errorf("counts = %d structs, %d enums, %d fields", s.Structs, s.Enums, s.Fields)
+	}
+	want := []generator.Skipped{
+		{Type: "Status", Reason: "enum has no values"},
+		{Type: "Order", Field: "Tags", Reason: "unsupported type"},
+		{Type: "Order", Field: "Payment", Reason: "no implementation of oneof isOrder_Payment"},
+	}
+	if len(s.Skipped) != len(want) {
+		t.Fatalf("Skipped = %+v", s.Skipped)
+	}
+	for i := range want {
+		if s.Skipped[i] != want[i] {
+			t.Errorf("Skipped[%d] = %+v, want %+v", i, s.Skipped[i], want[i])
+		}
+	}
+	wantMissing := []generator.Skipped{{Type: "Order", Field: "Customer", Reason: "references missing fixture FixtureCustomer()"}}
+	if !slices.Equal(s.Missing, wantMissing) {
+		t.Errorf("Missing = %+v, want %+v", s.Missing, wantMissing)
+	}
+	if strings.Join(s.Imports, ",") != "time" {
+		t.Errorf("Imports = %v", s.Imports)
+	}
+	if !strings.Contains(s.String(), "  Order.Tags: unsupported type\n") || !strings.Contains(s.String(), "missing fixtures 1:\n  Order.Customer: references missing fixture FixtureCustomer()\n") {
+		t.Errorf("String() = %s", s.String())
+	}
+}
+
+func TestLint(t *testing.T) {
+	m := generator.NewModel()
+	m.Structs["Node"] = &generator.Struct{Name: "Node", Fields: []generator.Field{
+		{Name: "Next", Type: generator.TypeRef{Kind: "pointer", Elem: &generator.TypeRef{Kind: "struct", Name: "Node"}}},
+	}}
+	m.Structs["cursor"] = &generator.Struct{Name: "cursor", Fields: []generator.Field{
+		{Name: "offset", Type: generator.TypeRef{Kind: "primitive", Name: "int"}},
+		{Name: "ch", Type: generator.TypeRef{Kind: "unknown"}},
+	}}
+	m.OneOfs["isEvent_Payload"] = ""
+
+	var got []string
+	for _, issue := range lint(m, nil) {
+		got = append(got, issue.String())
+	}
+	want := []string{
+		"Node: warning: reference cycle Node -> Node, its fixtures end it with nil after -maxdepth levels",
+		"cursor: struct has only unexported fields, its fixture is always empty",
+		"cursor.ch: unsupported type",
+		"isEvent_Payload: oneof has no implementation",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("lint() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestCommands(t *testing.T) {
+	flags := commandFlags()
+	for _, c := range commands {
+		if c.name != "completion" && len(flags[c.name]) == 0 {
+			t.Errorf("command %s has no flags", c.name)
+		}
+	}
+	// check verifies what generate writes, so it takes the same flags
+	checkFlags := " " + flagNames(flags["check"]) + " "
+	for _, name := range strings.Fields(flagNames(flags["generate"])) {
+		if name != "-interactive" && name != "-check" && !strings.Contains(checkFlags, " "+name+" ") {
+			t.Errorf("check lacks generate flag %s", name)
+		}
+	}
+
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		script, err := completion(shell)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range []string{"lint", "seedfuncs", "format"} {
+			if !strings.Contains(script, want) {
+				t.Errorf("%s completion lacks %s", shell, want)
+			}
+		}
+	}
+	if _, err := completion("tcsh"); err == nil {
+		t.Error("completion(tcsh) should fail")
+	}
+	if code := run([]string{"nope"}); code != 2 {
+		t.Errorf("run(nope) = %d, want 2", code)
+	}
+}
+
+func TestCheckTarget(t *testing.T) {
+	dir := t.TempDir()
+	sql := filepath.Join(dir, "schema.sql")
+	os.WriteFile(sql, []byte("CREATE TABLE users (id BIGINT PRIMARY KEY, name TEXT);"), 0644)
+	tg := target{SQL: sql, Out: filepath.Join(dir, "fixtures.go")}
+
+	if old, new, err := checkTarget(&tg, 1, load); err != nil || old != nil || len(new) == 0 {
+		t.Fatalf("checkTarget() before generating = %q, %d bytes, %v", old, len(new), err)
+	}
+	if _, err := generateTarget(tg, 1, load); err != nil {
+		t.Fatal(err)
+	}
+	if old, new, err := checkTarget(&tg, 1, load); err != nil || !bytes.Equal(old, new) {
+		t.Errorf("checkTarget() after generating differs: %v", err)
+	}
+	os.WriteFile(sql, []byte("CREATE TABLE users (id BIGINT PRIMARY KEY, email TEXT);"), 0644)
+	old, new, _ := checkTarget(&tg, 1, load)
+	if bytes.Equal(old, new) {
+		t.Fatal("checkTarget() should report a changed schema")
+	}
+
+	var diff bytes.Buffer
+	if !writeDiff(&diff, "fixtures.go", old, new, false) {
+		t.Fatal("writeDiff() found no difference")
+	}
+	for _, want := range []string{"--- fixtures.go (on disk)\n", "@@ ", " type User\n", "-\t\tName: ", "+\t\tEmail: ", "changed: User, FixtureUser\n"} {
+		if !strings.Contains(diff.String(), want) {
+			t.Errorf("diff lacks %q:\n%s", want, diff.String())
+		}
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	a := strings.Split("a b c d e f g", " ")
+	b := strings.Split("a c d x e f g h", " ")
+	var ops []string
+	for _, l := range diffLines(a, b) {
+		ops = append(ops, string(l.Op)+l.Text)
+	}
+	if got := strings.Join(ops, " "); got != " a -b  c  d +x  e  f  g +h" {
+		t.Errorf("diffLines() = %s", got)
+	}
+	if len(hunks(diffLines(a, a), 3)) != 0 {
+		t.Error("equal inputs should have no hunks")
+	}
+	var colored bytes.Buffer
+	writeDiff(&colored, "f.go", []byte("a\nb\n"), []byte("a\nc\n"), true)
+	if !strings.Contains(colored.String(), "\x1b[31m-b\x1b[0m") || !strings.Contains(colored.String(), "\x1b[32m+c\x1b[0m") {
+		t.Errorf("colored diff = %q", colored.String())
+	}
+}
+
+func TestVersionHeader(t *testing.T) {
+	m := generator.NewModel()
+	m.Structs["User"] = &generator.Struct{Name: "User"}
+	out := generator.GenerateWithOptions(m, "fixtures", generator.GenerateOptions{Version: "v1.2.0", Incremental: true})
+	if !strings.HasPrefix(out, generator.VersionPrefix+"v1.2.0\n"+generator.HashPrefix) {
+		t.Errorf("output header = %q", out[:80])
+	}
+	if got := generator.ReadVersion([]byte(out)); got != "v1.2.0" {
+		t.Errorf("ReadVersion() = %q", got)
+	}
+	if generator.ReadHash([]byte(out)) == "" {
+		t.Error("hash not found after version line")
+	}
+	other := generator.GenerateWithOptions(m, "fixtures", generator.GenerateOptions{Version: "v1.3.0"})
+	plain := generator.GenerateWithOptions(m, "fixtures", generator.GenerateOptions{Version: "v1.2.0"})
+	if !bytes.Equal(withoutHeader([]byte(other)), withoutHeader([]byte(plain))) {
+		t.Error("withoutHeader() should ignore the generator version")
+	}
+	if version() == "" {
+		t.Error("version() is empty")
+	}
+}
+
+func TestGeneratedHeader(t *testing.T) {
+	invocation = []string{"-pkg", "./orders", "-funcprefix", "My Orders"}
+	defer func() { invocation = nil }()
+	tg := target{}
+	want := `Code generated by fixture-generator -pkg ./orders -funcprefix "My Orders". DO NOT EDIT.`
+	if got := tg.header(); got != want {
+		t.Errorf("header() = %q, want %q", got, want)
+	}
+
+	m := generator.NewModel()
+	m.Structs["User"] = &generator.Struct{Name: "User", Fields: []generator.Field{
+		{Name: "Name", Type: generator.TypeRef{Kind: "primitive", Name: "string"}},
+	}}
+	out, err := generator.GenerateFormattedWithOptions(m, "fixtures", generator.GenerateOptions{Header: tg.header(), Version: "v1.2.0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := parser.ParseFile(token.NewFileSet(), "fixtures.go", out, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ast.IsGenerated(f) || f.Doc != nil {
+		t.Errorf("output isn't marked as generated, or the header became the package doc:\n%s", out)
+	}
+	if got := generator.ReadVersion([]byte(out)); got != "v1.2.0" {
+		t.Errorf("ReadVersion() = %q after the header", got)
+	}
+
+	custom, err := generator.GenerateFormattedWithOptions(m, "fixtures", generator.GenerateOptions{Header: "Copyright Example\n\nCode generated by hand. DO NOT EDIT."})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(custom, "// Copyright Example\n//\n// Code generated by hand. DO NOT EDIT.\n\npackage fixtures") {
+		t.Errorf("custom header = %q", custom[:80])
+	}
+	if !bytes.Equal(withoutHeader([]byte(custom)), withoutHeader([]byte(out))) {
+		t.Error("withoutHeader() should ignore the header")
+	}
+	commented, err := generator.GenerateFormattedWithOptions(m, "fixtures", generator.GenerateOptions{Header: "// Copyright Example\n//\n// Code generated by hand. DO NOT EDIT."})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if commented != custom {
+		t.Errorf("header given as comments = %q, want the same as without the slashes", commented[:80])
+	}
+
+	tests, err := generator.GenerateRoundTripTests(m, "fixtures", generator.GenerateOptions{Header: want}, "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(tests), "// "+want+"\n\npackage fixtures") {
+		t.Errorf("round-trip tests lack the header:\n%s", tests)
+	}
+}
+
+func TestExitPolicy(t *testing.T) {
+	if !(target{}).fatal(condDrift) || (target{}).fatal(condSkipped) {
+		t.Error("by default only drift should be fatal")
+	}
+	strict := target{FailOn: "all", WarnOn: "format"}
+	for _, cond := range []string{condLoad, condSkipped, condDrift} {
+		if !strict.fatal(cond) {
+			t.Errorf("fail-on all: %s should be fatal", cond)
+		}
+	}
+	if strict.fatal(condFormat) || (target{WarnOn: "drift"}).fatal(condDrift) {
+		t.Error("warn-on should win over fail-on and the defaults")
+	}
+	if err := (target{FailOn: "load,typos"}).validatePolicy(); err == nil {
+		t.Error("unknown conditions should be rejected")
+	}
+	if err := (target{FailOn: "skipped"}).condition(condSkipped, "2 fields were skipped"); err == nil || !strings.Contains(err.Error(), "(skipped)") {
+		t.Errorf("condition() = %v", err)
+	}
+
+	// Declarations gofmt rejects are reported instead of silently written unformatted
+	m := generator.NewModel()
+	m.Structs["User"] = &generator.Struct{Name: "User", Fields: []generator.Field{
+		{Name: "Name", Type: generator.TypeRef{Kind: "primitive", Name: "string"}, Default: `"unterminated`},
+	}}
+	var formatErrors []error
+	opts := generator.GenerateOptions{FormatError: func(err error) { formatErrors = append(formatErrors, err) }}
+	var out bytes.Buffer
+	if err := generator.GenerateTo(&out, m, "fixtures", opts); err != nil {
+		t.Fatal(err)
+	}
+	if len(formatErrors) != 1 {
+		t.Fatalf("FormatError called %d times, want 1", len(formatErrors))
+	}
+	var fe *generator.FormatError
+	if !errors.As(formatErrors[0], &fe) || !errors.Is(fe, generator.ErrFormatFailed) {
+		t.Fatalf("FormatError called with %v, want a *FormatError", formatErrors[0])
+	}
+	if lines := strings.Split(out.String(), "\n"); fe.Line < 1 || fe.Line > len(lines) || !strings.Contains(lines[fe.Line-1], "unterminated") {
+		t.Errorf("FormatError at line %d, want the line of the unterminated string in:\n%s", fe.Line, out.String())
+	}
+	if _, err := generator.GenerateFormattedWithOptions(m, "fixtures", generator.GenerateOptions{}); !errors.Is(err, generator.ErrFormatFailed) {
+		t.Errorf("GenerateFormattedWithOptions() error = %v, want ErrFormatFailed", err)
+	}
+
+	if code := exitCode(fmt.Errorf("loading: %w", generator.ErrNoPackages)); code != exitLoad {
+		t.Errorf("exitCode(ErrNoPackages) = %d, want %d", code, exitLoad)
+	}
+	if code := exitCode((target{FailOn: "format"}).condition(condFormat, "1 declaration could not be formatted")); code != exitFormat {
+		t.Errorf("exitCode(format) = %d, want %d", code, exitFormat)
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	fs := flag.NewFlagSet("generate", flag.ContinueOnError)
+	tg, _ := generateFlags(fs)
+	if err := fs.Parse([]string{"-profile", "proto", "-pkg", "./pb", "-funcprefix", "Proto"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyProfile(fs); err != nil {
+		t.Fatal(err)
+	}
+	if tg.Snapshots != "protojson" || !tg.Deep || tg.FailOn != "skipped" {
+		t.Errorf("proto profile not applied: %+v", tg)
+	}
+	if tg.FuncPrefix != "Proto" {
+		t.Errorf("FuncPrefix = %q, explicit flags should win over the profile", tg.FuncPrefix)
+	}
+
+	fs = flag.NewFlagSet("generate", flag.ContinueOnError)
+	tg, _ = generateFlags(fs)
+	fs.Parse([]string{"-profile", "api-json"})
+	applyProfile(fs)
+	if *tg.ModStyle || tg.Snapshots != "json" {
+		t.Errorf("api-json profile not applied: modstyle %v, snapshots %q", *tg.ModStyle, tg.Snapshots)
+	}
+
+	// Every profile only sets flags that exist
+	for name := range profiles {
+		fs := flag.NewFlagSet("generate", flag.ContinueOnError)
+		generateFlags(fs)
+		fs.Parse([]string{"-profile", name})
+		if err := applyProfile(fs); err != nil {
+			t.Errorf("profile %s: %v", name, err)
+		}
+	}
+	fs = flag.NewFlagSet("generate", flag.ContinueOnError)
+	generateFlags(fs)
+	fs.Parse([]string{"-profile", "nope"})
+	if applyProfile(fs) == nil {
+		t.Error("unknown profiles should be rejected")
+	}
+}
+
+func TestEnvConfig(t *testing.T) {
+	t.Setenv("FIXTUREGEN_TYPEPREFIX", "orders")
+	t.Setenv("FIXTUREGEN_FAIL_ON", "skipped")
+	t.Setenv("FIXTUREGEN_ROUTE", "GET /users=User; GET /orders=Order")
+	t.Setenv("FIXTUREGEN_INCLUDE", "*Request;^(User|Order){1,2}$")
+	t.Setenv("FIXTUREGEN_PROFILE", "api-json")
+
+	fs := flag.NewFlagSet("generate", flag.ContinueOnError)
+	tg, _ := generateFlags(fs)
+	fs.Parse([]string{"-typeprefix", "cli"})
+	if err := applyEnv(fs); err != nil {
+		t.Fatal(err)
+	}
+	applyProfile(fs)
+	if tg.TypePrefix != "cli" {
+		t.Errorf("TypePrefix = %q, flags should win over the environment", tg.TypePrefix)
+	}
+	if tg.FailOn != "skipped" || len(tg.Routes) != 2 || tg.Routes["GET /orders"] != "Order" {
+		t.Errorf("environment not applied: fail-on %q, routes %v", tg.FailOn, tg.Routes)
+	}
+	if tg.Snapshots != "json" {
+		t.Errorf("Snapshots = %q, FIXTUREGEN_PROFILE should select the profile", tg.Snapshots)
+	}
+	if !slices.Equal(tg.Include, []string{"*Request", "^(User|Order){1,2}$"}) {
+		t.Errorf("Include = %q, want the patterns of FIXTUREGEN_INCLUDE split on ';'", tg.Include)
+	}
+
+	// Batch targets take the environment, overridden by their config
+	path := filepath.Join(t.TempDir(), "batch.json")
+	os.WriteFile(path, []byte(`{"failOn": "load", "targets": [{"pkg": "a"}, {"pkg": "b", "typeprefix": "b"}]}`), 0644)
+	cfg, err := readBatchConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Targets[0].TypePrefix != "orders" || cfg.Targets[1].TypePrefix != "b" {
+		t.Errorf("TypePrefix = %q, %q", cfg.Targets[0].TypePrefix, cfg.Targets[1].TypePrefix)
+	}
+	if cfg.Targets[0].FailOn != "load" {
+		t.Errorf("FailOn = %q, the config file should win over the environment", cfg.Targets[0].FailOn)
+	}
+
+	// -out-dir is -outdir under another name, and wins over FIXTUREGEN_OUTDIR like it
+	t.Setenv("FIXTUREGEN_OUTDIR", "env")
+	fs = flag.NewFlagSet("generate", flag.ContinueOnError)
+	tg, _ = generateFlags(fs)
+	fs.Parse([]string{"-out-dir", "cli"})
+	if err := applyEnv(fs); err != nil {
+		t.Fatal(err)
+	}
+	if tg.OutDir != "cli" {
+		t.Errorf("OutDir = %q, -out-dir should set -outdir and win over the environment", tg.OutDir)
+	}
+
+	t.Setenv("FIXTUREGEN_J", "many")
+	fs = flag.NewFlagSet("generate", flag.ContinueOnError)
+	generateFlags(fs)
+	if err := applyEnv(fs); err == nil || !strings.Contains(err.Error(), "FIXTUREGEN_J") {
+		t.Errorf("applyEnv() = %v, want an error naming the variable", err)
+	}
+}
+
+func TestPlugin(t *testing.T) {
+	dir := t.TempDir()
+	plugin := filepath.Join(dir, "plugin.sh")
+	script := "#!/bin/sh\ncat > " + filepath.Join(dir, "request.json") + "\n" +
+		`printf '%s\n' '{"files": [{"name": "docs/types.md", "content": "# Types\n"}]}'` + "\n"
+	if err := os.WriteFile(plugin, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	m := generator.NewModel()
+	m.Structs["User"] = &generator.Struct{Name: "User"}
+	out := filepath.Join(dir, "out")
+	if err := runPlugin(plugin, "lang=md", out, m, "fixtures", generator.GenerateOptions{ModStyle: true}); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(filepath.Join(out, "docs", "types.md")); err != nil || string(data) != "# Types\n" {
+		t.Errorf("plugin file = %q, %v", data, err)
+	}
+	var req generator.PluginRequest
+	data, _ := os.ReadFile(filepath.Join(dir, "request.json"))
+	if err := json.Unmarshal(data, &req); err != nil {
+		t.Fatal(err)
+	}
+	if req.Version != generator.PluginProtocolVersion || req.Model.Structs["User"] == nil || req.Parameter != "lang=md" || !req.Options.ModStyle {
+		t.Errorf("request = %+v", req)
+	}
+
+	escape := filepath.Join(dir, "escape.sh")
+	os.WriteFile(escape, []byte("#!/bin/sh\ncat >/dev/null\necho '{\"files\": [{\"name\": \"../x.go\"}]}'\n"), 0755)
+	if err := runPlugin(escape, "", out, m, "fixtures", generator.GenerateOptions{}); err == nil {
+		t.Error("files outside the output directory should be rejected")
+	}
+	failing := filepath.Join(dir, "failing.sh")
+	os.WriteFile(failing, []byte("#!/bin/sh\ncat >/dev/null\necho '{\"error\": \"unsupported model\"}'\n"), 0755)
+	if err := runPlugin(failing, "", out, m, "fixtures", generator.GenerateOptions{}); err == nil || !strings.Contains(err.Error(), "unsupported model") {
+		t.Errorf("runPlugin() = %v, want the plugin's error", err)
+	}
+}
+
+func TestNoGenerics(t *testing.T) {
+	m := generator.NewModel()
+	m.Structs["Order"] = &generator.Struct{Name: "Order", Fields: []generator.Field{
+		{Name: "Note", Type: generator.TypeRef{Kind: "pointer", Elem: &generator.TypeRef{Kind: "primitive", Name: "string"}}},
+		{Name: "Count", Type: generator.TypeRef{Kind: "pointer", Elem: &generator.TypeRef{Kind: "primitive", Name: "int64"}}, Default: "ptr[int64](5)"},
+		{Name: "Customer", Type: generator.TypeRef{Kind: "pointer", Elem: &generator.TypeRef{Kind: "struct", Name: "Customer"}}},
+		{Name: "Meta", Type: generator.TypeRef{Kind: "pointer", Elem: &generator.TypeRef{Kind: "external", Name: "ObjectMeta"}}},
+	}}
+	m.Structs["Customer"] = &generator.Struct{Name: "Customer"}
+
+	out, err := generator.GenerateFormattedWithOptions(m, "fixtures", generator.GenerateOptions{TypePrefix: "orders", GoVersion: "1.17"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "fixtures.go", out, 0); err != nil {
+		t.Fatalf("generated code does not parse: %v\n%s", err, out)
+	}
+	for _, want := range []string{
+		"func ptrString(v string) *string { return &v }",
+		"func ptrInt64(v int64) *int64 { return &v }",
+		"func ptrOrdersCustomer(v orders.Customer) *orders.Customer { return &v }",
+		"func ptrMetav1ObjectMeta(v metav1.ObjectMeta) *metav1.ObjectMeta { return &v }",
+		"Note:     ptrString(\"Note\")",
+		"Count:    ptrInt64(5)",
+		"Customer: ptrOrdersCustomer(FixtureCustomer())",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "[T any]") {
+		t.Error("output uses generics")
+	}
+
+	if withGenerics := generator.GenerateWithOptions(m, "fixtures", generator.GenerateOptions{GoVersion: "1.21"}); !strings.Contains(withGenerics, "func ptr[T any]") {
+		t.Error("Go 1.21 output should use the generic ptr helper")
+	}
+	if err := (&target{Pkg: ".", Placeholder: "$", GoVersion: "1.17", Snapshots: "json"}).validate(); err == nil {
+		t.Error("-snapshots should be rejected before Go 1.18")
+	}
+}
+
+func TestModSignature(t *testing.T) {
+	m := generator.NewModel()
+	m.Structs["User"] = &generator.Struct{Name: "User", Fields: []generator.Field{
+		{Name: "Name", Type: generator.TypeRef{Kind: "primitive", Name: "string"}},
+		{Name: "Role", Type: generator.TypeRef{Kind: "enum", Name: "Role"}},
+	}}
+	m.Enums["Role"] = &generator.Enum{Name: "Role", Values: []string{"RoleAdmin"}}
+	m.TypeDefs["UserID"] = &generator.TypeDef{Name: "UserID", Underlying: generator.TypeRef{Kind: "primitive", Name: "string"}}
+
+	out, err := generator.GenerateFormattedWithOptions(m, "fixtures", generator.GenerateOptions{ModStyle: true, ModSignature: "value"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"func FixtureUser(mods ...func(User) User) *User {",
+		"*value = mod(*value)",
+		"func FixtureRole(mods ...func(Role) Role) *Role {",
+		"value = mod(value)",
+		"func FixtureUserID(mods ...func(UserID) UserID) *UserID {",
+		"*result = mod(*result)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+
+	pointer := generator.GenerateWithOptions(m, "fixtures", generator.GenerateOptions{ModStyle: true})
+	if !strings.Contains(pointer, "func FixtureUser(mods ...func(*User)) *User {") || !strings.Contains(pointer, "mod(value)") {
+		t.Errorf("default mods should be pointer mutators:\n%s", pointer)
+	}
+	if err := (&target{Pkg: ".", Placeholder: "$", ModSignature: "transform"}).validate(); err == nil {
+		t.Error("unknown -modsig should be rejected")
+	}
+}
+
+func TestDocComments(t *testing.T) {
+	m := generator.NewModel()
+	m.Structs["User"] = &generator.Struct{Name: "User", Source: &generator.Source{Pkg: "example.com/shop/users", File: "user.go", Line: 12}}
+	m.Structs["Schema"] = &generator.Struct{Name: "Schema"}
+
+	out := generator.GenerateWithOptions(m, "fixtures", generator.GenerateOptions{TypePrefix: "users", FuncPrefix: "PB"})
+	want := "// FixturePBUser returns a fixture of [users.User], declared in example.com/shop/users at user.go:12.\nfunc FixturePBUser() users.User {"
+	if !strings.Contains(out, want) {
+		t.Errorf("output lacks %q:\n%s", want, out)
+	}
+	if strings.Contains(out, "FixturePBSchema returns") {
+		t.Errorf("types without a Go declaration should have no doc comment:\n%s", out)
+	}
+}
+
+func TestTypeDefConstants(t *testing.T) {
+	m := generator.NewModel()
+	m.TypeDefs["TenantID"] = &generator.TypeDef{Name: "TenantID", Underlying: generator.TypeRef{Kind: "primitive", Name: "string"}}
+	m.TypeDefs["Weight"] = &generator.TypeDef{Name: "Weight", Underlying: generator.TypeRef{Kind: "primitive", Name: "float64"}}
+	m.TypeDefs["Handle"] = &generator.TypeDef{Name: "Handle", Underlying: generator.TypeRef{Kind: "primitive", Name: "uintptr"}}
+
+	for _, modStyle := range []bool{true, false} {
+		out, err := generator.GenerateFormattedWithOptions(m, "fixtures", generator.GenerateOptions{TypePrefix: "orders", ModStyle: modStyle})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range []string{
+			`const FixtureTenantIDValue orders.TenantID = "TenantID"`,
+			`const FixtureWeightValue orders.Weight = 1`,
+		} {
+			if !strings.Contains(out, want) {
+				t.Errorf("modstyle=%v: output lacks %q:\n%s", modStyle, want, out)
+			}
+		}
+		if strings.Contains(out, "FixtureHandleValue") {
+			t.Errorf("modstyle=%v: typedefs without a constant value should get no constant:\n%s", modStyle, out)
+		}
+	}
+}
+
+func TestSparse(t *testing.T) {
+	m := generator.NewModel()
+	m.Structs["User"] = &generator.Struct{Name: "User", Fields: []generator.Field{
+		{Name: "Name", Type: generator.TypeRef{Kind: "primitive", Name: "string"}},
+		{Name: "Nickname", Type: generator.TypeRef{Kind: "pointer", Elem: &generator.TypeRef{Kind: "primitive", Name: "string"}}},
+		{Name: "Address", Type: generator.TypeRef{Kind: "pointer", Elem: &generator.TypeRef{Kind: "struct", Name: "Address"}}},
+	}}
+	m.Structs["Address"] = &generator.Struct{Name: "Address", Fields: []generator.Field{
+		{Name: "City", Type: generator.TypeRef{Kind: "primitive", Name: "string"}},
+	}}
+
+	out, err := generator.GenerateFormattedWithOptions(m, "fixtures", generator.GenerateOptions{TypePrefix: "users", ModStyle: true, Sparse: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "fixtures.go", out, 0); err != nil {
+		t.Fatalf("generated code does not parse: %v\n%s", err, out)
+	}
+	for _, want := range []string{
+		"func WithUserNickname() func(*users.User) {",
+		`value.Nickname = ptr("Nickname")`,
+		"func WithUserAddress(mods ...func(*users.Address)) func(*users.User) {",
+		"value.Address = FixtureAddress(mods...)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "Nickname: ") || strings.Contains(out, "Address: ") {
+		t.Errorf("sparse fixtures should leave pointer fields nil:\n%s", out)
+	}
+
+	value := generator.GenerateWithOptions(m, "fixtures", generator.GenerateOptions{ModStyle: true, Sparse: true, ModSignature: "value"})
+	if !strings.Contains(value, "func WithUserAddress(mods ...func(Address) Address) func(User) User {") {
+		t.Errorf("With helpers should follow the mod signature:\n%s", value)
+	}
+	modStyle := false
+	if err := (&target{Pkg: ".", Placeholder: "$", Sparse: true, ModStyle: &modStyle}).validate(); err == nil {
+		t.Error("-sparse should be rejected without -modstyle")
+	}
+}
+
+func TestOptions(t *testing.T) {
+	m := generator.NewModel()
+	m.Structs["User"] = &generator.Struct{Name: "User", Fields: []generator.Field{
+		{Name: "Email", Type: generator.TypeRef{Kind: "primitive", Name: "string"}},
+		{Name: "Status", Type: generator.TypeRef{Kind: "enum", Name: "Status"}},
+		{Name: "Nickname", Type: generator.TypeRef{Kind: "pointer", Elem: &generator.TypeRef{Kind: "primitive", Name: "string"}}},
+		{Name: "Contact", Type: generator.TypeRef{Kind: "oneof", Name: "isUser_Contact"}},
+	}}
+	m.Enums["Status"] = &generator.Enum{Name: "Status", Values: []string{"StatusActive"}}
+
+	out, err := generator.GenerateFormattedWithOptions(m, "fixtures", generator.GenerateOptions{TypePrefix: "users", ModStyle: true, Options: true, Sparse: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "fixtures.go", out, 0); err != nil {
+		t.Fatalf("generated code does not parse: %v\n%s", err, out)
+	}
+	for _, want := range []string{
+		"func WithUserEmail(v string) func(*users.User) {",
+		"func WithUserStatus(v users.Status) func(*users.User) {",
+		"value.Email = v",
+		// The sparse mod populating the pointer field is kept
+		"func WithUserNickname() func(*users.User) {",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "WithUserContact") {
+		t.Errorf("oneof fields shouldn't get an option:\n%s", out)
+	}
+
+	value := generator.GenerateWithOptions(m, "fixtures", generator.GenerateOptions{ModStyle: true, Options: true, ModSignature: "value"})
+	if !strings.Contains(value, "func WithUserNickname(v *string) func(User) User {") {
+		t.Errorf("options should follow the mod signature:\n%s", value)
+	}
+	modStyle := false
+	if err := (&target{Pkg: ".", Placeholder: "$", Options: true, ModStyle: &modStyle}).validate(); err == nil {
+		t.Error("-options should be rejected without -modstyle")
+	}
+}
+
+func TestSelfPackage(t *testing.T) {
+	pkgs := []*packages.Package{{Name: "orders", PkgPath: "example.com/shop/orders", GoFiles: []string{"/src/shop/orders/order.go"}}}
+	tg := target{Pkg: "./orders", Self: true}
+	if err := tg.selfPackage(pkgs); err != nil {
+		t.Fatal(err)
+	}
+	if tg.OutPkg != "orders" || tg.Out != filepath.Join("/src/shop/orders", "orders_fixtures.go") {
+		t.Errorf("selfPackage() = package %s, out %s", tg.OutPkg, tg.Out)
+	}
+
+	tg = target{Pkg: "./orders", Self: true, Out: "custom.go"}
+	if err := tg.selfPackage(pkgs); err != nil || tg.Out != "custom.go" {
+		t.Errorf("selfPackage() should keep -out, got %s, %v", tg.Out, err)
+	}
+	if err := tg.selfPackage(append(pkgs, pkgs[0])); err == nil {
+		t.Error("selfPackage() should reject several packages")
+	}
+	if err := (&target{Pkg: "./orders", Placeholder: "$", Self: true, TypePrefix: "orders"}).validate(); err == nil {
+		t.Error("-self should be rejected with -typeprefix")
+	}
+
+	// The package of the types may declare a ptr of its own
+	m := generator.NewModel()
+	m.Structs["Order"] = &generator.Struct{Name: "Order", Fields: []generator.Field{
+		{Name: "Note", Type: generator.TypeRef{Kind: "pointer", Elem: &generator.TypeRef{Kind: "primitive", Name: "string"}}},
+		{Name: "Qty", Type: generator.TypeRef{Kind: "pointer", Elem: &generator.TypeRef{Kind: "primitive", Name: "int64"}}, Default: "ptr[int64](5)"},
+	}}
+	opts, err := tg.options(m, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := generator.GenerateWithOptions(m, "orders", opts)
+	for _, want := range []string{"func fixturePtr[T any](v T) *T", `fixturePtr("Note")`, "fixturePtr[int64](5)"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "func ptr[") || strings.Contains(out, " ptr(") {
+		t.Errorf("-self output uses the ptr helper name:\n%s", out)
+	}
+}
+
+func TestModReturnValue(t *testing.T) {
+	m := generator.NewModel()
+	m.Structs["Money"] = &generator.Struct{Name: "Money", Fields: []generator.Field{
+		{Name: "Amount", Type: generator.TypeRef{Kind: "primitive", Name: "int64"}},
+		{Name: "Currency", Type: generator.TypeRef{Kind: "enum", Name: "Currency"}},
+		{Name: "Rate", Type: generator.TypeRef{Kind: "pointer", Elem: &generator.TypeRef{Kind: "struct", Name: "Rate"}}},
+	}}
+	m.Structs["Rate"] = &generator.Struct{Name: "Rate"}
+	m.Enums["Currency"] = &generator.Enum{Name: "Currency", Values: []string{"CurrencyEUR"}}
+
+	out, err := generator.GenerateFormattedWithOptions(m, "fixtures", generator.GenerateOptions{TypePrefix: "billing", ModStyle: true, ModReturn: "value"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"func FixtureMoney(mods ...func(*billing.Money)) billing.Money {",
+		"value := billing.Money{",
+		"Currency: FixtureCurrency(),",
+		"Rate:     ptr(FixtureRate()),",
+		"mod(&value)",
+		"func FixtureCurrency(mods ...func(*billing.Currency)) billing.Currency {",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "*Fixture") {
+		t.Errorf("value fixtures should not be dereferenced:\n%s", out)
+	}
+
+	transform := generator.GenerateWithOptions(m, "fixtures", generator.GenerateOptions{ModStyle: true, ModReturn: "value", ModSignature: "value"})
+	if !strings.Contains(transform, "value = mod(value)") {
+		t.Errorf("value mods should transform the local copy:\n%s", transform)
+	}
+	if err := (&target{Pkg: ".", Placeholder: "$", ModReturn: "copy"}).validate(); err == nil {
+		t.Error("unknown -modreturn should be rejected")
+	}
+}
+
+func TestTypeDefFixtureCompiles(t *testing.T) {
+	src := `package p
+
+type TenantID string
+`
+	m, err := generator.ParseSource(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, modReturn := range []string{"", "value"} {
+		out, err := generator.GenerateFormattedWithOptions(m, "p", generator.GenerateOptions{ModStyle: true, ModReturn: modReturn})
+		if err != nil {
+			t.Fatal(err)
+		}
+		fset := token.NewFileSet()
+		var files []*ast.File
+		for name, code := range map[string]string{"p.go": src, "fixtures.go": out} {
+			f, err := parser.ParseFile(fset, name, code, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			files = append(files, f)
+		}
+		if _, err := new(types.Config).Check("p", fset, files, nil); err != nil {
+			t.Errorf("ModReturn %q: fixtures don't compile: %v\n%s", modReturn, err, out)
+		}
+	}
+}
+
+func TestDumpHelpers(t *testing.T) {
+	m := generator.NewModel()
+	m.Structs["User"] = &generator.Struct{Name: "User", Fields: []generator.Field{
+		{Name: "Name", Type: generator.TypeRef{Kind: "primitive", Name: "string"}},
+	}}
+
+	out, err := generator.GenerateFormattedWithOptions(m, "fixtures", generator.GenerateOptions{TypePrefix: "users", ModStyle: true, Dump: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"func DumpUser(v *users.User) string {",
+		"return dumpFixture(v)",
+		"func dumpFixture(v interface{}) string {",
+		`"reflect"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+
+	classic := generator.GenerateWithOptions(m, "fixtures", generator.GenerateOptions{Dump: true})
+	if !strings.Contains(classic, "func DumpUser(v User) string {") {
+		t.Errorf("Dump helpers should take what the fixture returns:\n%s", classic)
+	}
+	if plain := generator.GenerateWithOptions(m, "fixtures", generator.GenerateOptions{}); strings.Contains(plain, "dumpFixture") {
+		t.Error("Dump helpers should only be generated with Dump set")
+	}
+}
+
+func TestRoundTripTests(t *testing.T) {
+	m := generator.NewModel()
+	m.Structs["User"] = &generator.Struct{Name: "User", Fields: []generator.Field{
+		{Name: "Name", Type: generator.TypeRef{Kind: "primitive", Name: "string"}},
+	}}
+
+	out, err := generator.GenerateRoundTripTests(m, "fixtures", generator.GenerateOptions{TypePrefix: "users", ModStyle: true}, "protojson")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"package fixtures",
+		`t.Run("User", func(t *testing.T) { roundTripFixture(t, FixtureUser(), new(users.User)) })`,
+		"protojson.Unmarshal(data, got.(proto.Message))",
+		"reflect.DeepEqual(want, got)",
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("round-trip tests lack %q:\n%s", want, out)
+		}
+	}
+
+	classic, err := generator.GenerateRoundTripTests(m, "fixtures", generator.GenerateOptions{}, "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(classic), "roundTripFixture(t, &want, new(User))") || strings.Contains(string(classic), "protojson") {
+		t.Errorf("unexpected JSON round-trip tests of value fixtures:\n%s", classic)
+	}
+}
+
+func TestValidationTests(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "models.go", `package models
+
+type Order struct{}
+
+func (o *Order) Validate() error    { return nil }
+func (o *Order) ValidateAll() error { return nil }
+
+type Item struct{}
+
+func (i Item) Validate() bool { return true }
+`, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := &types.Info{Types: make(map[ast.Expr]types.TypeAndValue), Defs: make(map[*ast.Ident]types.Object)}
+	tpkg, err := new(types.Config).Check("example.com/models", fset, []*ast.File{f}, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	extracted := generator.ExtractPackage(&packages.Package{PkgPath: "example.com/models", Fset: fset, Syntax: []*ast.File{f}, Types: tpkg, TypesInfo: info})
+	if got := extracted.Structs["Order"].Validator; got != "ValidateAll" {
+		t.Errorf("Order validator = %q, want ValidateAll", got)
+	}
+	if got := extracted.Structs["Item"].Validator; got != "" {
+		t.Errorf("Item validator = %q, want none for a method not returning error", got)
+	}
+
+	m := generator.NewModel()
+	m.Structs["Order"] = &generator.Struct{Name: "Order", Validator: "ValidateAll"}
+	m.Structs["Item"] = &generator.Struct{Name: "Item"}
+	out, err := generator.GenerateValidationTests(m, "fixtures", generator.GenerateOptions{ModStyle: true}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "fixture.ValidateAll(); err != nil") || strings.Contains(string(out), `"Item"`) {
+		t.Errorf("unexpected validation tests:\n%s", out)
+	}
+
+	out, err = generator.GenerateValidationTests(m, "fixtures", generator.GenerateOptions{}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"buf.build/go/protovalidate"`, "validateFixture(t, &fixture)", "protovalidate.Validate(msg)"} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("protovalidate tests lack %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestCgoFiles(t *testing.T) {
+	dir := t.TempDir()
+	native := filepath.Join(dir, "native.go")
+	plain := filepath.Join(dir, "plain.go")
+	os.WriteFile(native, []byte("package p\n\n// #include <stdint.h>\nimport \"C\"\n\ntype Native struct{ Handle C.int32_t }\n\ntype Shared struct{}\n"), 0644)
+	os.WriteFile(plain, []byte("package p\n\ntype Shared struct{}\n"), 0644)
+
+	if got := cgoFiles([]string{native, plain}); len(got) != 1 || got[0] != native {
+		t.Errorf("cgoFiles() = %v, want [%s]", got, native)
+	}
+
+	tpkg := types.NewPackage("example.com/p", "p")
+	tpkg.Scope().Insert(types.NewTypeName(token.NoPos, tpkg, "Shared", types.NewStruct(nil, nil)))
+	pkg := &packages.Package{Types: tpkg, GoFiles: []string{plain}, IgnoredFiles: []string{native}}
+	if got := cgoUnavailable(pkg)[native]; len(got) != 1 || got[0] != "Native" {
+		t.Errorf("cgoUnavailable() = %v, want [Native] (Shared has a fallback)", got)
+	}
+	if cgoBroken([]*packages.Package{pkg}) {
+		t.Error("cgoBroken() without load errors")
+	}
+	pkg.GoFiles = append(pkg.GoFiles, native)
+	pkg.Errors = []packages.Error{{Msg: "could not import C"}}
+	if !cgoBroken([]*packages.Package{pkg}) {
+		t.Error("cgoBroken() should report a failed package with cgo files")
+	}
+}
+
+func TestIncludeTests(t *testing.T) {
+	// The shape of packages.Load(".") with Tests set for a package with internal and external tests
+	pkgs := []*packages.Package{
+		{ID: "example.com/shop", PkgPath: "example.com/shop"},
+		{ID: "example.com/shop [example.com/shop.test]", PkgPath: "example.com/shop"},
+		{ID: "example.com/shop_test [example.com/shop.test]", PkgPath: "example.com/shop_test"},
+		{ID: "example.com/shop.test", PkgPath: "example.com/shop.test"},
+		{ID: "example.com/other", PkgPath: "example.com/other"},
+	}
+	var ids []string
+	for _, pkg := range testVariants(pkgs) {
+		ids = append(ids, pkg.ID)
+	}
+	want := []string{"example.com/shop [example.com/shop.test]", "example.com/other"}
+	if strings.Join(ids, ",") != strings.Join(want, ",") {
+		t.Errorf("testVariants() = %v, want %v", ids, want)
+	}
+}
+
+func TestPlatformFiles(t *testing.T) {
+	dir := t.TempDir()
+	windows := filepath.Join(dir, "handle_windows.go")
+	tagged := filepath.Join(dir, "handle_bsd.go")
+	ignored := filepath.Join(dir, "gen.go")
+	os.WriteFile(windows, []byte("package p\n\ntype Handle struct{ Value uintptr }\n"), 0644)
+	os.WriteFile(tagged, []byte("//go:build freebsd || openbsd\n\npackage p\n\ntype Handle struct{ Value int }\n"), 0644)
+	os.WriteFile(ignored, []byte("//go:build ignore\n\npackage main\n\ntype Generator struct{}\n"), 0644)
+
+	got := platformFiles([]string{windows, tagged, ignored})
+	if len(got) != 2 || got[0] != windows || got[1] != tagged {
+		t.Errorf("platformFiles() = %v, want [%s %s]", got, windows, tagged)
+	}
+
+	pkg := &packages.Package{Types: types.NewPackage("example.com/p", "p"), IgnoredFiles: []string{windows}}
+	if got := unavailableTypes(pkg, platformFiles(pkg.IgnoredFiles))[windows]; len(got) != 1 || got[0] != "Handle" {
+		t.Errorf("unavailableTypes() = %v, want [Handle]", got)
+	}
+
+	if env := platformEnv("", ""); env != nil {
+		t.Errorf("platformEnv() for the host = %v, want nil", env)
+	}
+	env := platformEnv("windows", "arm64")
+	if env[len(env)-2] != "GOOS=windows" || env[len(env)-1] != "GOARCH=arm64" {
+		t.Errorf("platformEnv() ends with %v", env[len(env)-2:])
+	}
+}
+
+func TestPromotedFields(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "models.go", `package models
+
+type Audit struct {
+	CreatedBy string `+"`db:\"created_by\"`"+`
+}
+
+type Base struct {
+	Audit
+	ID   string `+"`db:\"id\"`"+`
+	Note string `+"`db:\"note\"`"+`
+}
+
+type Order struct {
+	Base
+	ID    int    `+"`db:\"order_id\"`"+`
+	Total int64  `+"`db:\"total\"`"+`
+}
+`, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := &types.Info{Types: make(map[ast.Expr]types.TypeAndValue), Defs: make(map[*ast.Ident]types.Object)}
+	tpkg, err := new(types.Config).Check("example.com/models", fset, []*ast.File{f}, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := &packages.Package{PkgPath: "example.com/models", Fset: fset, Syntax: []*ast.File{f}, Types: tpkg, TypesInfo: info}
+
+	m := generator.ExtractPackage(pkg)
+	order := m.Structs["Order"]
+	if len(order.Fields) != 3 || order.Fields[0].Name != "Base" || !order.Fields[0].Embedded {
+		t.Fatalf("Order fields = %+v, want the embedded Base first", order.Fields)
+	}
+	var promoted []string
+	for _, f := range order.Promoted {
+		promoted = append(promoted, f.Name)
+	}
+	// Base.ID is shadowed by Order.ID
+	want := []string{"Base.Audit", "Base.Audit.CreatedBy", "Base.Note"}
+	if strings.Join(promoted, ",") != strings.Join(want, ",") {
+		t.Errorf("Order promoted = %v, want %v", promoted, want)
+	}
+
+	out, err := generator.GenerateFormattedWithOptions(m, "fixtures", generator.GenerateOptions{ModStyle: true, TypePrefix: "models", Seed: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(out, "\tID:") != 2 || !strings.Contains(out, "Base:  *FixtureBase(),") {
+		t.Errorf("unexpected literals:\n%s", out)
+	}
+	if !strings.Contains(out, `"INSERT INTO orders (order_id, total, created_by, note) VALUES ($1, $2, $3, $4)", value.ID, value.Total, value.Base.Audit.CreatedBy, value.Base.Note`) {
+		t.Errorf("unexpected SeedOrder:\n%s", out)
+	}
+}
+
+func TestParseSourceEmbedded(t *testing.T) {
+	m, err := generator.ParseSource(`package models
+
+type Base struct {
+	ID string
+}
+
+type Order struct {
+	Base
+	*Audit
+	base
+	Total int64
+}
+
+type Audit struct {
+	CreatedBy string
+}
+
+type base struct{}
+`)
+	if err != nil {
+		t.Fatalf("ParseSource() error = %v", err)
+	}
+	order := m.Structs["Order"]
+	if len(order.Fields) != 3 || !order.Fields[0].Embedded || order.Fields[1].Name != "Audit" || order.Fields[1].Type.Kind != "pointer" {
+		t.Fatalf("Order fields = %+v, want the embedded Base and *Audit", order.Fields)
+	}
+
+	got := generator.GenerateWithOptions(m, "fixtures", generator.GenerateOptions{})
+	for _, want := range []string{"Base: FixtureBase()", "Audit: ptr(FixtureAudit())"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %s:\n%s", want, got)
+		}
+	}
+}
+
+func TestGenericStructs(t *testing.T) {
+	m, err := generator.ParseSource(`package models
+
+type Page[T any, K comparable] struct {
+	Items []T
+	Next  *K
+	Total int
+}
+
+type User struct {
+	Name string
+}
+
+type Result struct {
+	Users Page[User, string]
+}
+`)
+	if err != nil {
+		t.Fatalf("ParseSource() error = %v", err)
+	}
+	page := m.Structs["Page"]
+	if len(page.TypeParams) != 2 || page.TypeParams[1].Constraint != "comparable" || page.Fields[0].Type.Elem.Kind != "typeparam" {
+		t.Fatalf("Page = %+v, want type parameters T and K", page)
+	}
+
+	got, err := generator.GenerateFormattedWithOptions(m, "fixtures", generator.GenerateOptions{})
+	if err != nil {
+		t.Fatalf("GenerateFormattedWithOptions() error = %v", err)
+	}
+	for _, want := range []string{
+		"func FixturePage[T any, K comparable](t T, k K) Page[T, K] {",
+		"Items: []T{t},",
+		"Next:  ptr(k),",
+		`Users: FixturePage[User, string](FixtureUser(), "Users"),`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %s:\n%s", want, got)
+		}
+	}
+
+	got = generator.GenerateWithOptions(m, "fixtures", generator.GenerateOptions{ModStyle: true, TypePrefix: "models", Dump: true})
+	if want := "func FixturePage[T any, K comparable](t T, k K, mods ...func(*models.Page[T, K])) *models.Page[T, K] {"; !strings.Contains(got, want) {
+		t.Errorf("output missing %s:\n%s", want, got)
+	}
+	if strings.Contains(got, "DumpPage") {
+		t.Errorf("generic struct got a DumpX helper:\n%s", got)
+	}
+}
+
+func TestTypeDocs(t *testing.T) {
+	m, err := generator.ParseSource(`package models
+
+// User is a registered account.
+//
+// +kubebuilder:object:root=true
+type User struct {
+	Name string
+}
+
+type (
+	// A tenant of the platform
+	TenantID string
+	Plain    string
+)
+`)
+	if err != nil {
+		t.Fatalf("ParseSource() error = %v", err)
+	}
+
+	got := generator.GenerateWithOptions(m, "fixtures", generator.GenerateOptions{TypePrefix: "models"})
+	for _, want := range []string{
+		"// FixtureUser returns a fixture of [models.User].\n//\n// User is a registered account.\nfunc FixtureUser()",
+		"// FixtureTenantID returns a fixture of [models.TenantID].\n//\n// TenantID: A tenant of the platform\nfunc FixtureTenantID()",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "kubebuilder") || strings.Contains(got, "[models.Plain]") {
+		t.Errorf("output has markers or a doc for an undocumented type:\n%s", got)
+	}
+}
+
+func TestTypeFilters(t *testing.T) {
+	m, err := generator.ParseSource(`package models
+
+type CreateUserRequest struct {
+	User *User
+}
+
+type CreateUserResponse struct {
+	ID string
+}
+
+type User struct {
+	Name string
+}
+
+type CacheEntry struct {
+	Key string
+}
+`)
+	if err != nil {
+		t.Fatalf("ParseSource() error = %v", err)
+	}
+	got, err := generator.GenerateFormattedWithOptions(m, "fixtures", generator.GenerateOptions{
+		Include: []string{"*Request", "Response$"},
+		Exclude: []string{"User"},
+	})
+	if err != nil {
+		t.Fatalf("GenerateFormattedWithOptions() error = %v", err)
+	}
+	for _, want := range []string{"func FixtureCreateUserRequest()", "func FixtureCreateUserResponse()", "func FixtureUser()"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %s (User is needed by the request):\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "FixtureCacheEntry") {
+		t.Errorf("output has a fixture of a type not included:\n%s", got)
+	}
+
+	exclude, _ := generator.CompileTypePatterns([]string{"Cache*"})
+	if sub := m.Filter(nil, exclude); sub.Structs["CacheEntry"] != nil || len(sub.Structs) != 3 {
+		t.Errorf("Filter(exclude Cache*) = %d structs, want CreateUserRequest, CreateUserResponse and User", len(sub.Structs))
+	}
+	if err := (&target{Pkg: ".", Placeholder: "$", Include: []string{"User("}}).validate(); err == nil || !strings.Contains(err.Error(), "-include") {
+		t.Errorf("validate() = %v, want an error for the invalid -include pattern", err)
+	}
+
+	src := filepath.Join(t.TempDir(), "models.go")
+	os.WriteFile(src, []byte("package models\n\ntype User struct{ Name string }\n"), 0644)
+	if _, _, err := (target{Src: src, Include: []string{"User", "Usr*"}}).model(1, nil); err == nil || !strings.Contains(err.Error(), `"Usr*"`) {
+		t.Errorf("model() = %v, want an error for the -include pattern matching nothing", err)
+	}
+}
+
+func TestTypeImport(t *testing.T) {
+	m, err := generator.ParseSource(`package account
+
+type User struct {
+	Name string
+}
+`)
+	if err != nil {
+		t.Fatalf("ParseSource() error = %v", err)
+	}
+	opts := generator.GenerateOptions{TypePrefix: "account", TypeImport: "example.com/app/account"}
+	got, err := generator.GenerateFormattedWithOptions(m, "fixtures", opts)
+	if err != nil {
+		t.Fatalf("GenerateFormattedWithOptions() error = %v", err)
+	}
+	if want := `account "example.com/app/account"`; !strings.Contains(got, want) {
+		t.Errorf("output missing %s:\n%s", want, got)
+	}
+	tests, err := generator.GenerateRoundTripTests(m, "fixtures", opts, "json")
+	if err != nil {
+		t.Fatalf("GenerateRoundTripTests() error = %v", err)
+	}
+	if !strings.Contains(string(tests), `account "example.com/app/account"`) {
+		t.Errorf("round-trip tests missing the type import:\n%s", tests)
+	}
+
+	pkgs := []*packages.Package{{PkgPath: "example.com/app/account_test"}, {PkgPath: "example.com/app/account"}}
+	if got := (target{TypePrefix: "account"}).typeImport(pkgs); got != "example.com/app/account" {
+		t.Errorf("typeImport() = %q, want the path of the loaded package", got)
+	}
+	if got := (target{TypePrefix: "account", TypeImport: "example.com/other"}).typeImport(pkgs); got != "example.com/other" {
+		t.Errorf("typeImport() = %q, want -typeimport", got)
+	}
+}
+
+func TestArrays(t *testing.T) {
+	m, err := generator.ParseSource(`package models
+
+type Device struct {
+	ID     [16]byte
+	Octets [4]uint8
+	Tags   [2]string
+	Empty  [0]int
+	Owners [2]*Owner
+}
+
+type Owner struct {
+	Name string
+}
+`)
+	if err != nil {
+		t.Fatalf("ParseSource() error = %v", err)
+	}
+	if id := m.Structs["Device"].Fields[0].Type; id.Kind != "array" || id.Len != 16 {
+		t.Fatalf("ID type = %+v, want an array of length 16", id)
+	}
+
+	got, err := generator.GenerateFormattedWithOptions(m, "fixtures", generator.GenerateOptions{Rapid: true})
+	if err != nil {
+		t.Fatalf("GenerateFormattedWithOptions() error = %v", err)
+	}
+	for _, want := range []string{
+		"ID:     [16]byte{",
+		`Tags:   [2]string{"Tags"},`,
+		"Empty:  [0]int{},",
+		"Owners: [2]*Owner{ptr(FixtureOwner())},",
+		"rapid.SliceOfN(rapid.Byte(), 16, 16), func(v []byte) (a [16]byte)",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %s:\n%s", want, got)
+		}
+	}
+
+	value, err := generator.JSONValue(m, "Device", generator.GenerateOptions{})
+	if err != nil {
+		t.Fatalf("JSONValue() error = %v", err)
+	}
+	data, _ := json.Marshal(value)
+	if !strings.Contains(string(data), `"Tags":["Tags",""]`) {
+		t.Errorf("JSONValue() = %s, want the arrays at their length", data)
+	}
+}
+
+func TestForeignStructs(t *testing.T) {
+	m, err := generator.ParseSource(`package shop
+
+import (
+	"example.com/shop/orders"
+	billing "example.com/shop/billing/v2"
+)
+
+type Cart struct {
+	Item    orders.Item
+	Items   []*orders.Item
+	Invoice *billing.Invoice
+}
+`)
+	if err != nil {
+		t.Fatalf("ParseSource() error = %v", err)
+	}
+	if item := m.Structs["Cart"].Fields[0].Type; item.Pkg != "example.com/shop/orders" {
+		t.Fatalf("Item type = %+v, want the import path of orders", item)
+	}
+
+	got, err := generator.GenerateFormattedWithOptions(m, "fixtures", generator.GenerateOptions{
+		FixturePackages: map[string]string{"example.com/shop/billing/v2": "example.com/shop/billing/v2/fixtures"},
+	})
+	if err != nil {
+		t.Fatalf("GenerateFormattedWithOptions() error = %v", err)
+	}
+	for _, want := range []string{
+		`orders "example.com/shop/orders"`,
+		"Item:    orders.Item{}, /* TODO: no fixture for example.com/shop/orders.Item */",
+		"Items:   []*orders.Item{&orders.Item{} /* TODO: no fixture for example.com/shop/orders.Item */},",
+		"Invoice: ptr(billingv2fixtures.FixtureInvoice()),",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %s:\n%s", want, got)
+		}
+	}
+	if summary := generator.Summarize(m, generator.GenerateOptions{}); len(summary.Skipped) != 3 {
+		t.Errorf("Summarize() skipped %+v, want the orders.Item fields and the Invoice without fixtures package", summary.Skipped)
+	}
+}
+
+// importerFunc adapts a function to types.Importer
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) { return f(path) }
+
+func TestForeignEmbedded(t *testing.T) {
+	fset := token.NewFileSet()
+	check := func(path, src string, imp types.Importer) (*ast.File, *types.Package, *types.Info) {
+		f, err := parser.ParseFile(fset, filepath.Base(path)+".go", src, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		info := &types.Info{Types: make(map[ast.Expr]types.TypeAndValue), Defs: make(map[*ast.Ident]types.Object)}
+		tpkg, err := (&types.Config{Importer: imp}).Check(path, fset, []*ast.File{f}, info)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return f, tpkg, info
+	}
+	_, base, _ := check("example.com/base", `package base
+
+type Entity struct {
+	ID       string
+	Owner    *string
+	Revision int64
+	internal int
+}
+`, nil)
+	f, tpkg, info := check("example.com/models", `package models
+
+import "example.com/base"
+
+type Order struct {
+	base.Entity
+	Total int64
+}
+
+type Item struct {
+	*base.Entity
+	Name string
+}
+`, importerFunc(func(string) (*types.Package, error) { return base, nil }))
+	pkg := &packages.Package{PkgPath: "example.com/models", Fset: fset, Syntax: []*ast.File{f}, Types: tpkg, TypesInfo: info}
+
+	m := generator.ExtractPackage(pkg)
+	opts := generator.GenerateOptions{ModStyle: true, TypePrefix: "models"}
+	out, err := generator.GenerateFormattedWithOptions(m, "fixtures", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"value.Entity.ID = \"EntityID\"", "value.Entity.Owner = ptr(\"Owner\")", "value.Entity.Revision = 1"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "internal") || strings.Contains(out, "Entity:") {
+		t.Errorf("foreign embedded set in the literal or through unexported fields:\n%s", out)
+	}
+
+	out, err = generator.GenerateFormattedWithOptions(m, "fixtures", generator.GenerateOptions{TypePrefix: "models"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "value := models.Order{\n\t\tTotal: 1,\n\t}\n\tvalue.Entity.ID = \"EntityID\"") {
+		t.Errorf("unexpected classic Order fixture:\n%s", out)
+	}
+
+	opts.FixturePackages = map[string]string{"example.com/base": "example.com/base/fixtures"}
+	out, err = generator.GenerateFormattedWithOptions(m, "fixtures", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "Entity: *basefixtures.FixtureEntity(),") || !strings.Contains(out, `basefixtures "example.com/base/fixtures"`) || strings.Contains(out, "value.Entity.ID") {
+		t.Errorf("embedded Entity not set through its fixtures package:\n%s", out)
+	}
+}
+
+func TestSkipFields(t *testing.T) {
+	var names listFlag
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Var(&names, "skipfields", "")
+	if err := fs.Parse([]string{"-skipfields", "config, selectValues", "-skipfields", "Order.Edges,Base.Hidden"}); err != nil {
+		t.Fatal(err)
+	}
+
+	m := generator.NewModel()
+	m.Structs["Order"] = &generator.Struct{
+		Name: "Order",
+		Fields: []generator.Field{
+			{Name: "ID", Type: generator.TypeRef{Kind: "primitive", Name: "int"}},
+			{Name: "Edges", Type: generator.TypeRef{Kind: "struct", Name: "OrderEdges"}},
+			{Name: "config", Type: generator.TypeRef{Kind: "unknown"}},
+		},
+		Promoted: []generator.Field{
+			{Name: "Base.Hidden", Type: generator.TypeRef{Kind: "primitive", Name: "string"}},
+			{Name: "Base.Note", Type: generator.TypeRef{Kind: "primitive", Name: "string"}},
+		},
+	}
+	m.Structs["Item"] = &generator.Struct{
+		Name: "Item",
+		Fields: []generator.Field{
+			{Name: "Edges", Type: generator.TypeRef{Kind: "struct", Name: "ItemEdges"}},
+			{Name: "selectValues", Type: generator.TypeRef{Kind: "unknown"}},
+		},
+	}
+	m.SkipFields(names)
+
+	if f := m.Structs["Order"].Fields; len(f) != 1 || f[0].Name != "ID" {
+		t.Errorf("Order fields = %+v, want only ID", f)
+	}
+	if f := m.Structs["Order"].Promoted; len(f) != 1 || f[0].Name != "Base.Note" {
+		t.Errorf("Order promoted = %+v, want only Base.Note", f)
+	}
+	if f := m.Structs["Item"].Fields; len(f) != 1 || f[0].Name != "Edges" {
+		t.Errorf("Item fields = %+v, want only Edges (Order.Edges is specific to Order)", f)
+	}
+}
+
+func TestExcludeConstants(t *testing.T) {
+	tgt := target{ExcludeConsts: []string{"_minVersion$", "^file_.*_rawDesc$"}}
+	patterns, err := tgt.excludedConstants()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (target{ExcludeConsts: []string{"("}}).excludedConstants(); err == nil {
+		t.Error("excludedConstants() accepted an invalid pattern")
+	}
+
+	m := generator.NewModel()
+	m.Enums["Status"] = &generator.Enum{Name: "Status", Values: []string{"Status_minVersion", "StatusActive", "StatusClosed"}, Labels: []string{"min", "active", "closed"}}
+	m.Enums["Version"] = &generator.Enum{Name: "Version", Values: []string{"file_orders_proto_rawDesc"}}
+	m.ExcludeConstants(patterns)
+
+	if e := m.Enums["Status"]; strings.Join(e.Values, ",") != "StatusActive,StatusClosed" || strings.Join(e.Labels, ",") != "active,closed" {
+		t.Errorf("Status = %v %v, want the excluded value and its label removed", e.Values, e.Labels)
+	}
+	if _, ok := m.Enums["Version"]; ok {
+		t.Error("enum of only excluded constants should be removed")
+	}
+	if !generator.InternalConstant("EnforceVersion") || !generator.InternalConstant("_") || generator.InternalConstant("StatusActive") {
+		t.Error("InternalConstant() doesn't match the defaults")
+	}
+}
+
+func TestOpaqueBuilders(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "order.pb.go", `package orderpb
+
+type case_Order_Payment int32
+
+const (
+	Order_Payment_not_set_case case_Order_Payment = 0
+	Order_Card_case            case_Order_Payment = 3
+)
+
+type Order struct {
+	xxx_hidden_Id   *string
+	XXX_presence    [1]uint32
+	XXX_raceDetectHookData struct{}
+}
+
+type Order_builder struct {
+	_  [0]func()
+	Id *string
+}
+
+func (b0 Order_builder) Build() *Order { return &Order{xxx_hidden_Id: b0.Id} }
+
+type Report_builder struct {
+	Title string
+}
+
+type Customer struct {
+	Name string
+}
+
+type Customer_builder struct {
+	Name string
+}
+
+func (b0 Customer_builder) Build() *Customer { return &Customer{Name: b0.Name} }
+`, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := &types.Info{Types: make(map[ast.Expr]types.TypeAndValue), Defs: make(map[*ast.Ident]types.Object)}
+	tpkg, err := new(types.Config).Check("example.com/orderpb", fset, []*ast.File{f}, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := &packages.Package{PkgPath: "example.com/orderpb", Fset: fset, Syntax: []*ast.File{f}, Types: tpkg, TypesInfo: info}
+
+	m := generator.ExtractPackage(pkg)
+	if _, ok := m.Structs["Order_builder"]; ok {
+		t.Error("builder Order_builder extracted as a struct")
+	}
+	if _, ok := m.Structs["Report_builder"]; !ok {
+		t.Error("Report_builder has no Build method and is a plain struct")
+	}
+	if order := m.Structs["Order"]; order.Builder != "Order_builder" || len(order.Fields) != 1 || order.Fields[0].Name != "Id" {
+		t.Errorf("Order = %+v, want the fields of its builder instead of the hidden ones", order)
+	}
+	if len(m.Enums) != 0 {
+		t.Errorf("enums = %v, want none for oneof case constants", m.Enums)
+	}
+	if m.Structs["Customer"].Builder != "" {
+		t.Error("hybrid API message Customer built through its builder without -opaque")
+	}
+
+	generator.UseBuilders([]*packages.Package{pkg}, m)
+	out, err := generator.GenerateFormattedWithOptions(m, "fixtures", generator.GenerateOptions{ModStyle: true, TypePrefix: "orderpb"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"value := orderpb.Order_builder{\n\t\tId: ptr(\"OrderID\"),\n\t}.Build()",
+		"value := orderpb.Customer_builder{\n\t\tName: \"Name\",\n\t}.Build()",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	out, err = generator.GenerateFormattedWithOptions(m, "fixtures", generator.GenerateOptions{TypePrefix: "orderpb"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "return *orderpb.Order_builder{") {
+		t.Errorf("classic fixture doesn't dereference the built message:\n%s", out)
+	}
+}
+
+func TestPairwise(t *testing.T) {
+	m := generator.NewModel()
+	m.Enums["Status"] = &generator.Enum{Name: "Status", Values: []string{"StatusOpen", "StatusPaid", "StatusClosed"}}
+	m.Structs["Order"] = &generator.Struct{
+		Name: "Order",
+		Fields: []generator.Field{
+			{Name: "Status", Type: generator.TypeRef{Kind: "enum", Name: "Status"}},
+			{Name: "Express", Type: generator.TypeRef{Kind: "primitive", Name: "bool"}},
+			{Name: "Note", Type: generator.TypeRef{Kind: "pointer", Elem: &generator.TypeRef{Kind: "primitive", Name: "string"}}},
+			{Name: "Total", Type: generator.TypeRef{Kind: "primitive", Name: "int64"}},
+		},
+	}
+	tgt := target{Pairwise: map[string]string{"Order": "Status,Express,Note,Total"}}
+	opts, err := tgt.options(m, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.ModStyle = true
+	out, err := generator.GenerateFormattedWithOptions(m, "fixtures", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "// PairwiseOrder returns fixtures of [Order] covering every pair of values of Status, Express and Note.") {
+		t.Fatalf("no PairwiseOrder for the interesting fields:\n%s", out)
+	}
+	// 3 statuses times 2 bools is the least any covering set needs
+	if !strings.Contains(out, "fixtures := make([]*Order, 6)") {
+		t.Errorf("want 6 fixtures:\n%s", out)
+	}
+
+	// Rebuild the rows from the assignments, fields not assigned keep the fixture value
+	rows := make([]map[string]string, 6)
+	for i := range rows {
+		rows[i] = map[string]string{"Status": "StatusOpen", "Express": "true", "Note": "set"}
+	}
+	for _, line := range strings.Split(out, "\n") {
+		var r int
+		var field, value string
+		if n, _ := fmt.Sscanf(strings.TrimSpace(line), "fixtures[%d].%s = %s", &r, &field, &value); n == 3 {
+			rows[r][field] = value
+		}
+	}
+	values := map[string][]string{"Status": {"StatusOpen", "StatusPaid", "StatusClosed"}, "Express": {"true", "false"}, "Note": {"set", "nil"}}
+	fields := []string{"Status", "Express", "Note"}
+	for i, a := range fields {
+		for _, b := range fields[i+1:] {
+			for _, va := range values[a] {
+				for _, vb := range values[b] {
+					found := false
+					for _, row := range rows {
+						found = found || row[a] == va && row[b] == vb
+					}
+					if !found {
+						t.Errorf("no fixture with %s=%s and %s=%s", a, va, b, vb)
+					}
+				}
+			}
+		}
+	}
+
+	if _, err := (target{Pairwise: map[string]string{"Missing": "A"}}).options(m, nil); err == nil {
+		t.Error("options() accepted -pairwise for an unknown struct")
+	}
+}
+
+func TestRapidGenerators(t *testing.T) {
+	m := generator.NewModel()
+	m.Enums["Status"] = &generator.Enum{Name: "Status", Values: []string{"StatusOpen", "StatusPaid"}}
+	m.TypeDefs["Currency"] = &generator.TypeDef{Name: "Currency", Underlying: generator.TypeRef{Kind: "primitive", Name: "string"}}
+	m.Structs["Customer"] = &generator.Struct{Name: "Customer", Fields: []generator.Field{
+		{Name: "Name", Type: generator.TypeRef{Kind: "primitive", Name: "string"}},
+	}}
+	m.Structs["Order"] = &generator.Struct{Name: "Order", Fields: []generator.Field{
+		{Name: "Status", Type: generator.TypeRef{Kind: "enum", Name: "Status"}},
+		{Name: "Currency", Type: generator.TypeRef{Kind: "typedef", Name: "Currency"}},
+		{Name: "Customer", Type: generator.TypeRef{Kind: "pointer", Elem: &generator.TypeRef{Kind: "struct", Name: "Customer"}}},
+		{Name: "Lines", Type: generator.TypeRef{Kind: "slice", Elem: &generator.TypeRef{Kind: "primitive", Name: "int64"}}},
+		{Name: "CreatedAt", Type: generator.TypeRef{Kind: "external", Name: "Time"}},
+	}}
+
+	out, err := generator.GenerateFormattedWithOptions(m, "fixtures", generator.GenerateOptions{ModStyle: true, TypePrefix: "shop", Rapid: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`"pgregory.net/rapid"`,
+		"func RapidOrder(pin ...string) *rapid.Generator[*shop.Order] {",
+		`value.Status = rapid.SampledFrom([]shop.Status{shop.StatusOpen, shop.StatusPaid}).Draw(t, "Status")`,
+		`value.Currency = rapid.Map(rapid.String(), func(v string) shop.Currency { return shop.Currency(v) }).Draw(t, "Currency")`,
+		`value.Customer = rapid.OneOf(rapid.Just[*shop.Customer](nil), RapidCustomer()).Draw(t, "Customer")`,
+		`value.Lines = rapid.SliceOfN(rapid.Int64(), 0, 3).Draw(t, "Lines")`,
+		`if !rapidPinned(pin, "Status") {`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, `Draw(t, "CreatedAt")`) {
+		t.Error("external field drawn instead of keeping its fixture value")
+	}
+
+	out, err = generator.GenerateFormattedWithOptions(m, "fixtures", generator.GenerateOptions{ModStyle: true, ModReturn: "value", Rapid: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, `value.Customer = rapid.Ptr(RapidCustomer(), true).Draw(t, "Customer")`) {
+		t.Errorf("value fixtures should wrap the generator with rapid.Ptr:\n%s", out)
+	}
+
+	if err := (&target{Pkg: ".", Placeholder: "$", GoVersion: "1.17", Rapid: true}).validate(); err == nil {
+		t.Error("validate() accepted -rapid with -go 1.17")
+	}
+}
+
+func TestFixtureDiff(t *testing.T) {
+	m := generator.NewModel()
+	m.Structs["User"] = &generator.Struct{Name: "User", Fields: []generator.Field{
+		{Name: "Name", Type: generator.TypeRef{Kind: "primitive", Name: "string"}},
+		{Name: "Age", Type: generator.TypeRef{Kind: "primitive", Name: "int"}},
+	}}
+	m.Structs["Legacy"] = &generator.Struct{Name: "Legacy", Fields: []generator.Field{
+		{Name: "ID", Type: generator.TypeRef{Kind: "primitive", Name: "string"}},
+	}}
+	var before bytes.Buffer
+	if err := generator.GenerateTo(&before, m, "fixtures", generator.GenerateOptions{ModStyle: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	delete(m.Structs, "Legacy")
+	m.Structs["User"].Fields = []generator.Field{
+		{Name: "Age", Type: generator.TypeRef{Kind: "primitive", Name: "int"}, Default: "42"},
+		{Name: "Email", Type: generator.TypeRef{Kind: "primitive", Name: "string"}},
+	}
+	m.Structs["Order"] = &generator.Struct{Name: "Order", Fields: []generator.Field{
+		{Name: "Total", Type: generator.TypeRef{Kind: "primitive", Name: "int"}},
+	}}
+	var after bytes.Buffer
+	if err := generator.GenerateTo(&after, m, "fixtures", generator.GenerateOptions{ModStyle: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	old, err := parseFixtureFile("old.go", before.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	new, err := parseFixtureFile("new.go", after.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if !writeFixtureDiff(&out, old, new, false) {
+		t.Fatal("writeFixtureDiff() found no difference")
+	}
+	want := "- FixtureLegacy\n" +
+		"~ FixtureUser\n" +
+		"    - Name: \"Name\"\n" +
+		"    ~ Age: 1 -> 42\n" +
+		"    + Email: \"Email\"\n" +
+		"+ FixtureOrder\n"
+	if out.String() != want {
+		t.Errorf("writeFixtureDiff() =\n%s\nwant\n%s", out.String(), want)
+	}
+
+	// Reformatting alone is no change
+	reformatted, err := parseFixtureFile("old.go", bytes.ReplaceAll(before.Bytes(), []byte("\t"), []byte("  ")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if writeFixtureDiff(&out, old, reformatted, false) {
+		t.Error("writeFixtureDiff() reported a reformatted file as changed")
+	}
+}
+
+func TestClock(t *testing.T) {
+	m := generator.NewModel()
+	m.Structs["Event"] = &generator.Struct{Name: "Event", Fields: []generator.Field{
+		{Name: "At", Type: generator.TypeRef{Kind: "external", Name: "Time"}},
+		{Name: "Deleted", Type: generator.TypeRef{Kind: "pointer", Elem: &generator.TypeRef{Kind: "external", Name: "Time"}}},
+		{Name: "Created", Type: generator.TypeRef{Kind: "pointer", Elem: &generator.TypeRef{Kind: "external", Name: "Timestamp"}}},
+	}}
+
+	out, err := generator.GenerateFormattedWithOptions(m, "fixtures", generator.GenerateOptions{ModStyle: true, FuncPrefix: "PB", Clock: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"var FixturePBNow = func() time.Time { return time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC) }",
+		"At:      FixturePBNow(),",
+		"Deleted: ptr(FixturePBNow()),",
+		"Created: timestamppb.New(FixturePBNow()),",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "fixtures.go", out, 0); err != nil {
+		t.Errorf("output does not parse: %v", err)
+	}
+
+	plain := generator.GenerateWithOptions(m, "fixtures", generator.GenerateOptions{ModStyle: true})
+	if strings.Contains(plain, "FixtureNow") || !strings.Contains(plain, "Created: timestamppb.New(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC))") {
+		t.Errorf("without a clock time fields should be literals:\n%s", plain)
+	}
+	delete(m.Structs, "Event")
+	if out := generator.GenerateWithOptions(m, "fixtures", generator.GenerateOptions{Clock: true}); strings.Contains(out, "FixtureNow") {
+		t.Error("the clock func should only be generated for models with time fields")
+	}
+}
+
+func TestAggregates(t *testing.T) {
+	m := generator.NewModel()
+	m.TypeDefs["OrderID"] = &generator.TypeDef{Name: "OrderID", Underlying: generator.TypeRef{Kind: "primitive", Name: "string"}}
+	m.Structs["Order"] = &generator.Struct{Name: "Order", Fields: []generator.Field{
+		{Name: "ID", Type: generator.TypeRef{Kind: "typedef", Name: "OrderID"}},
+		{Name: "CustomerID", Type: generator.TypeRef{Kind: "primitive", Name: "string"}},
+		{Name: "Lines", Type: generator.TypeRef{Kind: "slice", Name: "OrderLine", Elem: &generator.TypeRef{Kind: "pointer", Elem: &generator.TypeRef{Kind: "struct", Name: "OrderLine"}}}},
+	}}
+	m.Structs["OrderLine"] = &generator.Struct{Name: "OrderLine", Fields: []generator.Field{
+		{Name: "OrderID", Type: generator.TypeRef{Kind: "typedef", Name: "OrderID"}},
+		{Name: "Order", Type: generator.TypeRef{Kind: "pointer", Elem: &generator.TypeRef{Kind: "struct", Name: "Order"}}},
+	}}
+	m.Structs["Customer"] = &generator.Struct{Name: "Customer", Fields: []generator.Field{
+		{Name: "ID", Type: generator.TypeRef{Kind: "primitive", Name: "string"}},
+		{Name: "Favorite", Type: generator.TypeRef{Kind: "struct", Name: "Order"}},
+	}}
+	aggregates := map[string][]string{"Order": {"OrderLine", "Customer"}}
+
+	out, err := generator.GenerateFormattedWithOptions(m, "fixtures", generator.GenerateOptions{ModStyle: true, TypePrefix: "shop", Aggregates: aggregates})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"type OrderAggregate struct {\n\tOrder     *shop.Order\n\tOrderLine *shop.OrderLine\n\tCustomer  *shop.Customer\n}",
+		"// ArrangeOrderAggregate returns fixtures of [shop.Order], [shop.OrderLine] and [shop.Customer] wired together",
+		"func ArrangeOrderAggregate() *OrderAggregate {",
+		"OrderLine: FixtureOrderLine(),",
+		"a.Order.CustomerID = a.Customer.ID\n\ta.OrderLine.OrderID = a.Order.ID\n\ta.OrderLine.Order = a.Order\n",
+		"a.Order.Lines = []*shop.OrderLine{a.OrderLine}\n\ta.Customer.Favorite = *a.Order\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+
+	value := generator.GenerateWithOptions(m, "fixtures", generator.GenerateOptions{ModStyle: true, ModReturn: "value", Aggregates: aggregates})
+	for _, want := range []string{"\tOrder Order\n", "a.OrderLine.Order = &a.Order\n", "a.Order.Lines = []*OrderLine{&a.OrderLine}\n", "a.Customer.Favorite = a.Order\n"} {
+		if !strings.Contains(value, want) {
+			t.Errorf("value output lacks %q:\n%s", want, value)
+		}
+	}
+
+	tg := target{Aggregates: map[string]string{"Order": "Invoice"}}
+	if _, err := tg.options(m, nil); err == nil || !strings.Contains(err.Error(), "no struct Invoice") {
+		t.Errorf("options() with an unknown aggregate member = %v", err)
+	}
+}
+
+func TestWeights(t *testing.T) {
+	m := generator.NewModel()
+	m.Enums["Status"] = &generator.Enum{Name: "Status", Values: []string{"StatusActive", "StatusArchived", "StatusDeleted"}}
+	m.OneOfs["isOrder_Payment"] = "Order_Card"
+	m.Structs["Order_Card"] = &generator.Struct{Name: "Order_Card", Fields: []generator.Field{
+		{Name: "Card", Type: generator.TypeRef{Kind: "primitive", Name: "string"}},
+	}}
+	m.Structs["Order"] = &generator.Struct{Name: "Order", Fields: []generator.Field{
+		{Name: "Status", Type: generator.TypeRef{Kind: "enum", Name: "Status"}},
+		{Name: "Payment", Type: generator.TypeRef{Kind: "oneof", Name: "isOrder_Payment"}},
+	}}
+
+	tg := target{Rapid: true, Weights: map[string]string{
+		"Status":          "StatusActive:80, StatusArchived:20",
+		"isOrder_Payment": "Order_Card:3,Order_Cash:1",
+	}}
+	opts, err := tg.options(m, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.ModStyle, opts.TypePrefix = true, "shop"
+	out, err := generator.GenerateFormattedWithOptions(m, "fixtures", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"func rapidWeighted(weights ...int) *rapid.Generator[int] {",
+		`value.Status = rapid.Map(rapidWeighted(80, 20), func(i int) shop.Status { return []shop.Status{shop.StatusActive, shop.StatusArchived}[i] }).Draw(t, "Status")`,
+		`switch rapidWeighted(3, 1).Draw(t, "Payment") {`,
+		"case 1:\n\t\t\t\tvalue.Payment = &shop.Order_Cash{}",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+
+	for weights, want := range map[string]string{
+		"StatusGone:1":      "StatusGone is no value of Status",
+		"StatusActive:high": "non-negative weight",
+		"StatusActive:0":    "all weights are 0",
+	} {
+		tg.Weights = map[string]string{"Status": weights}
+		if _, err := tg.options(m, nil); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("options() with weights %q = %v, want %q", weights, err, want)
+		}
+	}
+	tg.Weights = map[string]string{"Priority": "PriorityHigh:1"}
+	if _, err := tg.options(m, nil); err == nil {
+		t.Error("options() accepted weights of an unknown type")
+	}
+	if err := (&target{Pkg: ".", Placeholder: "$", Weights: map[string]string{"Status": "StatusActive:1"}}).validate(); err == nil {
+		t.Error("validate() accepted -weights without -rapid")
+	}
+}
+
+func TestHooks(t *testing.T) {
+	m := generator.NewModel()
+	m.TypeDefs["TenantID"] = &generator.TypeDef{Name: "TenantID", Underlying: generator.TypeRef{Kind: "primitive", Name: "string"}}
+	m.Structs["Address"] = &generator.Struct{Name: "Address", Fields: []generator.Field{
+		{Name: "City", Type: generator.TypeRef{Kind: "primitive", Name: "string"}},
+	}}
+	m.Structs["User"] = &generator.Struct{Name: "User", Fields: []generator.Field{
+		{Name: "Tenant", Type: generator.TypeRef{Kind: "typedef", Name: "TenantID"}},
+		{Name: "BaseURL", Type: generator.TypeRef{Kind: "primitive", Name: "string"}, Default: `"https://staging.example.com"`},
+		{Name: "Address", Type: generator.TypeRef{Kind: "pointer", Elem: &generator.TypeRef{Kind: "struct", Name: "Address"}}},
+	}}
+
+	out, err := generator.GenerateFormattedWithOptions(m, "fixtures", generator.GenerateOptions{ModStyle: true, TypePrefix: "acct", FuncPrefix: "PB", Hooks: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"type PBUserDefaultsFuncs struct {\n\tTenant  func() acct.TenantID\n\tBaseURL func() string\n}",
+		"var PBUserDefaults = PBUserDefaultsFuncs{\n\tTenant:  func() acct.TenantID { return *FixturePBTenantID() },\n\tBaseURL: func() string { return \"https://staging.example.com\" },\n}",
+		"Tenant:  PBUserDefaults.Tenant(),",
+		"Address: *FixturePBAddress(),",
+		"var PBAddressDefaults = PBAddressDefaultsFuncs{",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	if plain := generator.GenerateWithOptions(m, "fixtures", generator.GenerateOptions{ModStyle: true}); strings.Contains(plain, "Defaults") {
+		t.Errorf("hooks generated without Hooks:\n%s", plain)
+	}
+}
+
+func TestProviders(t *testing.T) {
+	m := generator.NewModel()
+	m.Structs["User"] = &generator.Struct{Name: "User", Fields: []generator.Field{
+		{Name: "Name", Type: generator.TypeRef{Kind: "primitive", Name: "string"}},
+	}}
+	m.Structs["Repo"] = &generator.Struct{Name: "Repo", Fields: []generator.Field{
+		{Name: "DSN", Type: generator.TypeRef{Kind: "primitive", Name: "string"}},
+	}}
+
+	wire, err := generator.GenerateFormattedWithOptions(m, "fixtures", generator.GenerateOptions{ModStyle: true, TypePrefix: "app", Providers: "wire"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`"github.com/google/wire"`,
+		"func ProvideFixtureUser() *app.User {\n\treturn FixtureUser()\n}",
+		"var FixtureProviders = wire.NewSet(\n\tProvideFixtureRepo,\n\tProvideFixtureUser,\n)",
+	} {
+		if !strings.Contains(wire, want) {
+			t.Errorf("wire output lacks %q:\n%s", want, wire)
+		}
+	}
+
+	fx := generator.GenerateWithOptions(m, "fixtures", generator.GenerateOptions{FuncPrefix: "PB", Providers: "fx"})
+	for _, want := range []string{`"go.uber.org/fx"`, "func ProvideFixturePBUser() User {", "var FixturePBProviders = fx.Provide("} {
+		if !strings.Contains(fx, want) {
+			t.Errorf("fx output lacks %q:\n%s", want, fx)
+		}
+	}
+	if err := (&target{Pkg: ".", Placeholder: "$", Providers: "dig"}).validate(); err == nil {
+		t.Error("validate() accepted -providers dig")
+	}
+}
+
+func TestRelations(t *testing.T) {
+	m := generator.NewModel()
+	m.Structs["Customer"] = &generator.Struct{Name: "Customer", Fields: []generator.Field{
+		{Name: "ID", Type: generator.TypeRef{Kind: "primitive", Name: "string"}, Column: "id", Default: `"cus_1"`},
+		{Name: "Name", Type: generator.TypeRef{Kind: "primitive", Name: "string"}},
+	}}
+	m.Structs["Order"] = &generator.Struct{Name: "Order", Fields: []generator.Field{
+		{Name: "ID", Type: generator.TypeRef{Kind: "primitive", Name: "string"}},
+		{Name: "CustomerID", Type: generator.TypeRef{Kind: "primitive", Name: "string"}, Column: "customer_id"},
+		{Name: "Total", Type: generator.TypeRef{Kind: "primitive", Name: "int"}},
+	}}
+	if err := m.Relate(map[string]string{"Order.CustomerID": "Customer.ID"}); err != nil {
+		t.Fatal(err)
+	}
+
+	out := generator.GenerateWithOptions(m, "fixtures", generator.GenerateOptions{ModStyle: true})
+	if !strings.Contains(out, "\t\tCustomerID: \"cus_1\",\n") {
+		t.Errorf("Order fixture doesn't share the customer ID:\n%s", out)
+	}
+	if seeds := generator.GenerateSQLSeeds(m); strings.Count(seeds, "'cus_1'") != 2 {
+		t.Errorf("seeds don't share the customer ID:\n%s", seeds)
+	}
+	if sub := m.Subset([]string{"Order"}); sub.Structs["Customer"] == nil {
+		t.Error("Subset() dropped the struct a relation refers to")
+	}
+
+	for want, relations := range map[string]map[string]string{
+		"is not of the form Struct.Field": {"Order": "Customer.ID"},
+		"no struct Invoice":               {"Order.CustomerID": "Invoice.ID"},
+		"Customer has no field Email":     {"Order.CustomerID": "Customer.Email"},
+		"Order.Total is a int":            {"Order.Total": "Customer.ID"},
+		"refers back to it":               {"Order.CustomerID": "Order.ID", "Order.ID": "Order.CustomerID"},
+	} {
+		if err := m.Relate(relations); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Relate(%v) = %v, want error containing %q", relations, err, want)
+		}
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	m := generator.NewModel()
+	category := generator.TypeRef{Kind: "pointer", Elem: &generator.TypeRef{Kind: "struct", Name: "Category"}}
+	m.Structs["Category"] = &generator.Struct{Name: "Category", Fields: []generator.Field{
+		{Name: "Name", Type: generator.TypeRef{Kind: "primitive", Name: "string"}},
+		{Name: "Parent", Type: category},
+		{Name: "Children", Type: generator.TypeRef{Kind: "slice", Elem: &category}},
+	}}
+	// Expr -> isExpr_Kind -> Expr_Not -> Expr
+	m.OneOfs["isExpr_Kind"] = "Expr_Not"
+	m.Structs["Expr_Not"] = &generator.Struct{Name: "Expr_Not", Fields: []generator.Field{
+		{Name: "Not", Type: generator.TypeRef{Kind: "pointer", Elem: &generator.TypeRef{Kind: "struct", Name: "Expr"}}},
+	}}
+	m.Structs["Expr"] = &generator.Struct{Name: "Expr", Fields: []generator.Field{
+		{Name: "Kind", Type: generator.TypeRef{Kind: "oneof", Name: "isExpr_Kind"}},
+		{Name: "Category", Type: generator.TypeRef{Kind: "struct", Name: "Category"}},
+	}}
+
+	out, err := generator.GenerateFormattedWithOptions(m, "fixtures", generator.GenerateOptions{ModStyle: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"\t\tParent:   nil,\n\t\tChildren: nil,\n",
+		"\t\tKind:     nil,\n\t\tCategory: *FixtureCategory(),\n",
+		"\t\tNot: nil,\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+
+	out, err = generator.GenerateFormattedWithOptions(m, "fixtures", generator.GenerateOptions{ModStyle: true, MaxDepth: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The fixture's Parent and Children each nest a category holding another Parent and Children
+	if n := strings.Count(out, "&Category{"); n != 1+2*3 {
+		t.Errorf("output has %d nested categories:\n%s", n, out)
+	}
+	for _, want := range []string{
+		"\t\tParent: &Category{\n\t\t\tName: \"Name\",\n\t\t\tParent: &Category{\n\t\t\t\tName:     \"Name\",\n\t\t\t\tParent:   nil,\n",
+		"\t\tChildren: []*Category{&Category{\n",
+		"\t\tKind: &Expr_Not{\n\t\t\tNot: &Expr{\n\t\t\t\tKind:     nil,\n\t\t\t\tCategory: *FixtureCategory(),\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("MaxDepth 2 output lacks %q:\n%s", want, out)
+		}
+	}
+
+	tg := target{Pkg: ".", MaxDepth: -1}
+	if err := tg.validate(); err == nil {
+		t.Error("validate() accepted a negative -maxdepth")
+	}
+}
+
+func TestArrayLengthConstants(t *testing.T) {
+	src := `package p
+
+const N = 4
+
+const (
+	A = iota
+	B
+	C
+)
+
+type T struct {
+	Counts [N]int
+	Names  [2 * C]string
+	Flags  [3]bool
+}
+`
+	m, err := generator.ParseSource(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := generator.GenerateFormattedWithOptions(m, "p", generator.GenerateOptions{ModStyle: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Counts: [4]int{1},", `Names:  [4]string{"Names"},`, "Flags:  [3]bool{true},"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for name, code := range map[string]string{"p.go": src, "fixtures.go": out} {
+		f, err := parser.ParseFile(fset, name, code, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, f)
+	}
+	if _, err := new(types.Config).Check("p", fset, files, nil); err != nil {
+		t.Errorf("fixtures don't compile: %v\n%s", err, out)
+	}
+}
+
+func TestValueCycles(t *testing.T) {
+	// The cycles run through a struct value and an array, which can't be nil, and end at the pointers
+	src := `package p
+
+type A struct {
+	B     B
+	Peers [2]*A
+}
+
+type B struct {
+	A *A
+}
+`
+	m, err := generator.ParseSource(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, maxDepth := range []int{0, 1} {
+		out, err := generator.GenerateFormattedWithOptions(m, "p", generator.GenerateOptions{ModStyle: true, MaxDepth: maxDepth})
+		if err != nil {
+			t.Fatal(err)
+		}
+		fset := token.NewFileSet()
+		var files []*ast.File
+		for name, code := range map[string]string{"p.go": src, "fixtures.go": out} {
+			f, err := parser.ParseFile(fset, name, code, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			files = append(files, f)
+		}
+		if _, err := new(types.Config).Check("p", fset, files, nil); err != nil {
+			t.Errorf("MaxDepth %d: fixtures don't compile: %v\n%s", maxDepth, err, out)
+		}
+		if maxDepth == 0 && (!strings.Contains(out, "B:     *FixtureB(),") || !strings.Contains(out, "Peers: [2]*A{},") || !strings.Contains(out, "A: nil,")) {
+			t.Errorf("cycles not broken at the pointers:\n%s", out)
+		}
+	}
+}
+
+func TestPackageTargets(t *testing.T) {
+	dir := t.TempDir()
+	for name, src := range map[string]string{
+		"go.mod":               "module example.com/shop\n\ngo 1.24\n",
+		"user/user.go":         "package user\n\ntype User struct{ ID string }\n",
+		"order/order.go":       "package order\n\nimport \"example.com/shop/user\"\n\ntype Order struct{ Buyer *user.User }\n",
+		"user/fixtures/gen.go": "package fixtures\n\nfunc FixtureUser() {}\n",
+		"cmd/shop/main.go":     "package main\n\nfunc main() {}\n",
+		"internal/doc.go":      "// Package internal holds nothing to generate fixtures for.\npackage internal\n\nfunc Helper() {}\n",
+		"rfix/fixtures.go":     "// Code generated by fixture-generator -outpkg rfix. DO NOT EDIT.\n\npackage rfix\n\ntype UserDefaultsFuncs struct{ ID func() string }\n",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Chdir(dir)
+
+	if !multiPackage("./...") || !multiPackage("./user,./order") || multiPackage("./user") {
+		t.Error("multiPackage() doesn't tell lists and wildcards from single packages")
+	}
+	targets, err := packageTargets(target{Pkg: "./...", FixturePackages: map[string]string{"example.com/other": "example.com/other/testing"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(targets) != 2 {
+		t.Fatalf("packageTargets() = %d targets, want order and user without the main, fixtures, typeless and generated packages", len(targets))
+	}
+	if _, err := os.Stat(filepath.Join(dir, "order", "fixtures")); !os.IsNotExist(err) {
+		t.Error("packageTargets() created the output directory before anything was generated")
+	}
+	order, user := targets[0], targets[1]
+	if order.TypePrefix != "order" || order.Out != filepath.Join(dir, "order", "fixtures", "fixtures.go") {
+		t.Errorf("order target = prefix %q, out %q", order.TypePrefix, order.Out)
+	}
+	if got := order.FixturePackages["example.com/shop/user"]; got != "example.com/shop/user/fixtures" {
+		t.Errorf("order reuses user fixtures from %q", got)
+	}
+	if _, ok := user.FixturePackages["example.com/shop/user"]; ok || user.FixturePackages["example.com/other"] == "" {
+		t.Errorf("user fixture packages = %v, want the other packages and explicit mappings", user.FixturePackages)
+	}
+
+	targets, err = packageTargets(target{Pkg: "./order, ./user", Self: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(targets) != 2 || targets[0].Out != "" || targets[0].FixturePackages["example.com/shop/user"] != "example.com/shop/user" {
+		t.Errorf("-self targets = %+v", targets)
+	}
+
+	if err := (&target{Pkg: "./...", Out: "fixtures.go"}).validate(); err == nil {
+		t.Error("validate() accepted -out with several packages")
+	}
+}
+
+func TestRandomValues(t *testing.T) {
+	m := generator.NewModel()
+	m.TypeDefs["TenantID"] = &generator.TypeDef{Name: "TenantID", Underlying: generator.TypeRef{Kind: "primitive", Name: "string"}}
+	m.Enums["Status"] = &generator.Enum{Name: "Status", Values: []string{"StatusActive", "StatusArchived"}}
+	m.Structs["User"] = &generator.Struct{Name: "User", Fields: []generator.Field{
+		{Name: "ID", Type: generator.TypeRef{Kind: "primitive", Name: "string"}},
+		{Name: "Age", Type: generator.TypeRef{Kind: "primitive", Name: "int32"}},
+		{Name: "Score", Type: generator.TypeRef{Kind: "primitive", Name: "float64"}},
+		{Name: "Admin", Type: generator.TypeRef{Kind: "primitive", Name: "bool"}},
+		{Name: "Email", Type: generator.TypeRef{Kind: "primitive", Name: "string"}, Default: `"ada@example.com"`},
+	}}
+
+	out, err := generator.GenerateFormattedWithOptions(m, "fixtures", generator.GenerateOptions{ModStyle: true, FuncPrefix: "PB", ValueStrategy: "random"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`"math/rand"`,
+		"func SeedPBFixtures(seed int64) {",
+		`ID:    fixtureRandomString("UserID"),`,
+		"Age:   int32(1 + fixtureRandom(100)),",
+		"Score: float64(1+fixtureRandom(10000)) / 100,",
+		"Admin: fixtureRandom(2) == 1,",
+		`Email: "ada@example.com",`,
+		`TenantID(fixtureRandomString("TenantID"))`,
+		"value := []Status{StatusActive, StatusArchived}[fixtureRandom(2)]",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "FixturePBTenantIDValue") {
+		t.Errorf("random output has a constant value:\n%s", out)
+	}
+
+	static := generator.GenerateWithOptions(m, "fixtures", generator.GenerateOptions{ModStyle: true})
+	if strings.Contains(static, "fixtureRandom") || strings.Contains(static, "math/rand") {
+		t.Errorf("static output draws random values:\n%s", static)
+	}
+
+	if err := (&target{Pkg: ".", Values: "fuzzy"}).validate(); err == nil {
+		t.Error("validate() accepted -values fuzzy")
+	}
+	if err := (&target{Pkg: ".", Values: "random", Relations: map[string]string{"Order.UserID": "User.ID"}}).validate(); err == nil {
+		t.Error("validate() accepted -relation with random values")
+	}
+}
+
+func TestExternalTypes(t *testing.T) {
+	m := generator.NewModel()
+	m.Structs["Invoice"] = &generator.Struct{Name: "Invoice", Fields: []generator.Field{
+		{Name: "Total", Type: generator.TypeRef{Kind: "struct", Name: "Decimal", Pkg: "github.com/shopspring/decimal"}},
+		{Name: "Discount", Type: generator.TypeRef{Kind: "pointer", Elem: &generator.TypeRef{Kind: "struct", Name: "Decimal", Pkg: "github.com/shopspring/decimal"}}},
+		{Name: "Lines", Type: generator.TypeRef{Kind: "slice", Elem: &generator.TypeRef{Kind: "struct", Name: "Decimal", Pkg: "github.com/shopspring/decimal"}}},
+		{Name: "TTL", Type: generator.TypeRef{Kind: "external", Name: "Duration", Pkg: "google.golang.org/protobuf/types/known/durationpb"}},
+		{Name: "Due", Type: generator.TypeRef{Kind: "struct", Name: "Time", Pkg: "example.com/calendar"}},
+	}}
+	builtin := generator.ExternalTypes["google.golang.org/protobuf/types/known/durationpb.Duration"]
+	generator.RegisterExternalType("google.golang.org/protobuf/types/known/durationpb", "Duration", generator.ExternalType{Value: "durationpb.New(time.Hour)", Requires: []string{`"time"`}, Pointer: true})
+	defer func() {
+		generator.ExternalTypes["google.golang.org/protobuf/types/known/durationpb.Duration"] = builtin
+	}()
+
+	tg := target{ExternalTypes: map[string]string{"github.com/shopspring/decimal.Decimal": "decimal.NewFromInt(100)"}}
+	opts, err := tg.options(m, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.ModStyle = true
+	out, err := generator.GenerateFormattedWithOptions(m, "fixtures", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`"github.com/shopspring/decimal"`,
+		`"google.golang.org/protobuf/types/known/durationpb"`,
+		`"time"`,
+		"Total:    decimal.NewFromInt(100),",
+		"Discount: ptr(decimal.NewFromInt(100)),",
+		"Lines:    []decimal.Decimal{decimal.NewFromInt(100)},",
+		"TTL:      durationpb.New(time.Hour),",
+		// A type of another package named like time.Time isn't mistaken for it, and without a fixture
+		// it gets a zero value
+		`calendar "example.com/calendar"`,
+		"Due:      calendar.Time{}, /* TODO: no fixture for example.com/calendar.Time */",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+
+	if ext := generator.NewExternalType("k8s.io/api/core/v1", "corev1.ResourceList{}"); ext.Import != `corev1 "k8s.io/api/core/v1"` {
+		t.Errorf("NewExternalType() import = %s", ext.Import)
+	}
+	tg = target{ExternalTypes: map[string]string{"Decimal": "decimal.Zero"}}
+	if _, err := tg.options(m, nil); err == nil {
+		t.Error("options() accepted an external type without import path")
+	}
+}
+
+func TestOneOfVariants(t *testing.T) {
+	m, err := generator.ParseSource(`package pb
+
+type User struct {
+	Name    string
+	Contact isUser_Contact
+	Role    isUser_Role
+}
+
+type isUser_Contact interface {
+	isUser_Contact()
+}
+
+type User_Email struct {
+	Email string
+}
+
+type User_Phone struct {
+	Phone string
+}
+
+type isUser_Role interface {
+	isUser_Role()
+}
+
+type User_Admin struct {
+	Admin bool
+}
+
+func (*User_Email) isUser_Contact() {}
+
+func (*User_Phone) isUser_Contact() {}
+
+func (*User_Admin) isUser_Role() {}
+`)
+	if err != nil {
+		t.Fatalf("ParseSource() error = %v", err)
+	}
+	if got := m.Variants["isUser_Contact"]; !slices.Equal(got, []string{"User_Email", "User_Phone"}) {
+		t.Errorf("isUser_Contact variants = %v, want User_Email and User_Phone", got)
+	}
+	if got := m.Variants["isUser_Role"]; !slices.Equal(got, []string{"User_Admin"}) {
+		t.Errorf("isUser_Role variants = %v, want only User_Admin", got)
+	}
+	if m.OneOfs["isUser_Contact"] != "User_Email" {
+		t.Errorf("isUser_Contact default = %q, want User_Email", m.OneOfs["isUser_Contact"])
+	}
+
+	out, err := generator.GenerateFormattedWithOptions(m, "pb", generator.GenerateOptions{ModStyle: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"func FixtureUserWithEmail(mods ...func(*User)) *User {",
+		"func FixtureUserWithPhone(mods ...func(*User)) *User {",
+		"value.Contact = &User_Phone{\n\t\tPhone: \"Phone\",\n\t}",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %s:\n%s", want, out)
+		}
+	}
+	// A oneof with a single implementation has nothing to choose from
+	if strings.Contains(out, "FixtureUserWithAdmin") {
+		t.Errorf("unexpected variant of a single implementation:\n%s", out)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "fixtures.go", out, 0); err != nil {
+		t.Errorf("output doesn't parse: %v\n%s", err, out)
+	}
+
+	// Fields sharing an interface get variants of their own
+	m.Structs["User"].Fields = append(m.Structs["User"].Fields, generator.Field{Name: "Backup", Type: m.Structs["User"].Fields[1].Type})
+	out, err = generator.GenerateFormattedWithOptions(m, "pb", generator.GenerateOptions{ModStyle: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"func FixtureUserWithContactEmail(", "func FixtureUserWithBackupEmail(", "value.Backup = &User_Email{"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %s:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "func FixtureUserWithEmail(") {
+		t.Errorf("variants of fields sharing an interface aren't told apart:\n%s", out)
+	}
+}
+
+func TestParseDescriptorSet(t *testing.T) {
+	label := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+	field := func(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type, typeName string) *descriptorpb.FieldDescriptorProto {
+		f := &descriptorpb.FieldDescriptorProto{Name: proto.String(name), Number: proto.Int32(number), Label: label, Type: typ.Enum()}
+		if typeName != "" {
+			f.TypeName = proto.String(typeName)
+		}
+		return f
+	}
+	card := field("card", 3, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".shop.v1.Card")
+	card.OneofIndex = proto.Int32(0)
+	voucher := field("voucher_code", 4, descriptorpb.FieldDescriptorProto_TYPE_STRING, "")
+	voucher.OneofIndex = proto.Int32(0)
+	priority := field("priority", 7, descriptorpb.FieldDescriptorProto_TYPE_INT32, "")
+	priority.OneofIndex, priority.Proto3Optional = proto.Int32(1), proto.Bool(true)
+	items := field("items", 8, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".shop.v1.Order.Item")
+	items.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	counts := field("counts", 5, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".shop.v1.Order.CountsEntry")
+	counts.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+
+	set := &descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{{
+		Name:        proto.String("google/protobuf/timestamp.proto"),
+		Package:     proto.String("google.protobuf"),
+		MessageType: []*descriptorpb.DescriptorProto{{Name: proto.String("Timestamp")}},
+	}, {
+		Name:       proto.String("shop/v1/order.proto"),
+		Package:    proto.String("shop.v1"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/protobuf/timestamp.proto"},
+		Options:    &descriptorpb.FileOptions{GoPackage: proto.String("example.com/shop/shoppb;shoppb")},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Order"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				field("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+				field("status", 2, descriptorpb.FieldDescriptorProto_TYPE_ENUM, ".shop.v1.Order.Status"),
+				card, voucher, counts,
+				field("created_at", 6, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".google.protobuf.Timestamp"),
+				priority, items,
+			},
+			OneofDecl: []*descriptorpb.OneofDescriptorProto{{Name: proto.String("payment")}, {Name: proto.String("_priority")}},
+			NestedType: []*descriptorpb.DescriptorProto{
+				{Name: proto.String("Item"), Field: []*descriptorpb.FieldDescriptorProto{field("sku", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, "")}},
+				{Name: proto.String("CountsEntry"), Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)}},
+			},
+			EnumType: []*descriptorpb.EnumDescriptorProto{{
+				Name:  proto.String("Status"),
+				Value: []*descriptorpb.EnumValueDescriptorProto{{Name: proto.String("STATUS_UNSPECIFIED"), Number: proto.Int32(0)}, {Name: proto.String("STATUS_OPEN"), Number: proto.Int32(1)}},
+			}},
+		}, {
+			Name:  proto.String("Card"),
+			Field: []*descriptorpb.FieldDescriptorProto{field("number", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, "")},
+		}},
+		SourceCodeInfo: &descriptorpb.SourceCodeInfo{Location: []*descriptorpb.SourceCodeInfo_Location{
+			{Path: []int32{4, 0}, Span: []int32{11, 0, 20, 1}, LeadingComments: proto.String(" Order is a customer order.\n")},
+		}},
+	}}}
+	data, err := proto.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := generator.ParseDescriptorSet(data)
+	if err != nil {
+		t.Fatalf("ParseDescriptorSet() error = %v", err)
+	}
+
+	if _, ok := m.Structs["Timestamp"]; ok {
+		t.Error("imported well-known type Timestamp got a fixture")
+	}
+	if _, ok := m.Structs["Order_CountsEntry"]; ok {
+		t.Error("map entry Order_CountsEntry got a fixture")
+	}
+	if got := m.Enums["Order_Status"]; got == nil || strings.Join(got.Values, " ") != "Order_STATUS_UNSPECIFIED Order_STATUS_OPEN" {
+		t.Errorf("Order_Status enum = %+v, want values [Order_STATUS_UNSPECIFIED Order_STATUS_OPEN]", got)
+	}
+	if got := m.Variants["isOrder_Payment"]; strings.Join(got, " ") != "Order_Card Order_VoucherCode" {
+		t.Errorf("isOrder_Payment variants = %v, want [Order_Card Order_VoucherCode]", got)
+	}
+
+	var fields []string
+	for _, f := range m.Structs["Order"].Fields {
+		fields = append(fields, f.Name+" "+generator.TypeName(f.Type)+" "+f.Type.Kind)
+	}
+	want := []string{
+		"Id string primitive",
+		"Status Order_Status enum",
+		"Payment isOrder_Payment oneof",
+		"Counts interface{} unknown",
+		"CreatedAt *timestamppb.Timestamp pointer",
+		"Priority *int32 pointer",
+		"Items []*Order_Item slice",
+	}
+	if strings.Join(fields, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Order fields =\n%s\nwant\n%s", strings.Join(fields, "\n"), strings.Join(want, "\n"))
+	}
+
+	code, err := generator.GenerateFormattedWithOptions(m, "fixtures", generator.GenerateOptions{TypePrefix: "shoppb"})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	for _, want := range []string{
+		`shoppb "example.com/shop/shoppb"`,
+		"// FixtureOrder returns a fixture of [shoppb.Order], declared in example.com/shop/shoppb at shop/v1/order.proto:12.\n//\n// Order is a customer order.",
+		"Payment: &shoppb.Order_Card{",
+		"CreatedAt: timestamppb.New(",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("generated code lacks %q:\n%s", want, code)
+		}
+	}
+}
+
+func TestFieldOverrides(t *testing.T) {
+	m, err := generator.ParseSource(`package models
+
+type User struct {
+	Email string
+	Age   int
+	Name  string
+}
+`)
+	if err != nil {
+		t.Fatalf("ParseSource() error = %v", err)
+	}
+	overrides := map[string]string{"User.Email": `"user@example.com"`, "User.Age": "30"}
+	got, err := generator.GenerateFormattedWithOptions(m, "fixtures", generator.GenerateOptions{FieldOverrides: overrides})
+	if err != nil {
+		t.Fatalf("GenerateFormattedWithOptions() error = %v", err)
+	}
+	for _, want := range []string{`Email: "user@example.com",`, "Age:   30,", `Name:  "Name",`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %s:\n%s", want, got)
+		}
+	}
+
+	if _, err := (target{FieldOverrides: map[string]string{"User.Mail": `""`}}).options(m, nil); err == nil || !strings.Contains(err.Error(), "no field Mail of struct User") {
+		t.Errorf("options() = %v, want an error for the unknown field", err)
+	}
+}
+
+func TestFakerValues(t *testing.T) {
+	m, err := generator.ParseSource(`package models
+
+type Customer struct {
+	ID        string
+	Email     string
+	FirstName string
+	Phone     string
+	IBAN      string
+	Notes     string
+}
+`)
+	if err != nil {
+		t.Fatalf("ParseSource() error = %v", err)
+	}
+	generate := func(seed int64) string {
+		t.Helper()
+		got, err := generator.GenerateFormattedWithOptions(m, "fixtures", generator.GenerateOptions{ValueStrategy: "faker", FakerSeed: seed})
+		if err != nil {
+			t.Fatalf("GenerateFormattedWithOptions() error = %v", err)
+		}
+		return got
+	}
+	got := generate(1)
+	for _, want := range []string{`@example.com",`, `Phone:     "+1-202-555-01`, `ID:        "CustomerID",`, `Notes:     "Notes",`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %s:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, `Email:     "Email"`) || strings.Contains(got, `FirstName: "FirstName"`) {
+		t.Errorf("faker kept the static values:\n%s", got)
+	}
+	if generate(1) != got {
+		t.Error("faker values differ between runs with the same seed")
+	}
+	if generate(2) == got {
+		t.Error("faker values are the same for seeds 1 and 2")
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	m, err := generator.ParseSource(`package models
+
+type Status int
+
+const (
+	StatusActive Status = iota
+)
+
+type UserID string
+
+type User struct {
+	ID     UserID
+	Status Status
+}
+`)
+	if err != nil {
+		t.Fatalf("ParseSource() error = %v", err)
+	}
+	got, err := generator.GenerateFormattedWithOptions(m, "fixtures", generator.GenerateOptions{Registry: true, FuncPrefix: "PB"})
+	if err != nil {
+		t.Fatalf("GenerateFormattedWithOptions() error = %v", err)
+	}
+	for _, want := range []string{
+		"func PBFixtures() map[string]func() any {",
+		`"UserID": func() any { v := FixturePBUserID(); return &v },`,
+		`"Status": func() any { v := FixturePBStatus(); return &v },`,
+		`"User":   func() any { v := FixturePBUser(); return &v },`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %s:\n%s", want, got)
+		}
+	}
+
+	got, err = generator.GenerateFormattedWithOptions(m, "fixtures", generator.GenerateOptions{Registry: true, ModStyle: true})
+	if err != nil {
+		t.Fatalf("GenerateFormattedWithOptions() error = %v", err)
+	}
+	if !strings.Contains(got, `"User":   func() any { return FixtureUser() },`) {
+		t.Errorf("mod style registry doesn't return the fixture pointers:\n%s", got)
+	}
+}
+
+func TestTypeCheck(t *testing.T) {
+	src := `package models
+
+type User struct {
+	Name string
+	Age  int
+}
+`
+	m, err := generator.ParseSource(src)
+	if err != nil {
+		t.Fatalf("ParseSource() error = %v", err)
+	}
+	check := func(opts generator.GenerateOptions) error {
+		t.Helper()
+		var code bytes.Buffer
+		if err := generator.GenerateTo(&code, m, "models", opts); err != nil {
+			t.Fatal(err)
+		}
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, "models.go", src, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return generator.TypeCheck(fset, code.Bytes(), []*ast.File{f}, newLoadedImporter(fset, nil))
+	}
+	if err := check(generator.GenerateOptions{ModStyle: true}); err != nil {
+		t.Errorf("TypeCheck() = %v, want no errors", err)
+	}
+
+	err = check(generator.GenerateOptions{ModStyle: true, FieldOverrides: map[string]string{"User.Age": `"thirty"`}})
+	if err == nil || !strings.Contains(err.Error(), "fixtures.go:") || !strings.Contains(err.Error(), `Age:  "thirty",`) {
+		t.Errorf("TypeCheck() = %v, want the error with its position and line", err)
+	}
+
+	// The types are missing without the files declaring them
+	if err := generator.Validate(m, "models", generator.GenerateOptions{}); err == nil || !strings.Contains(err.Error(), "undefined: User") {
+		t.Errorf("Validate() = %v, want undefined: User", err)
+	}
+}
+
+func TestEnumDefaults(t *testing.T) {
+	m := generator.NewModel()
+	m.Enums["Order_Status"] = &generator.Enum{Name: "Order_Status", Values: []string{"Order_STATUS_UNSPECIFIED", "Order_STATUS_OPEN", "Order_STATUS_CLOSED"}}
+	m.Enums["Color"] = &generator.Enum{Name: "Color", Values: []string{"ColorUnknown", "ColorRed", "ColorBlue"}, Labels: []string{"unknown", "red", "blue"}}
+	if err := m.PickEnumDefaults("specified", map[string]string{"Color": "blue"}); err != nil {
+		t.Fatalf("PickEnumDefaults() error = %v", err)
+	}
+	if got := m.Enums["Order_Status"].Values[0]; got != "Order_STATUS_OPEN" {
+		t.Errorf("Order_Status default = %s, want Order_STATUS_OPEN", got)
+	}
+	if c := m.Enums["Color"]; c.Values[0] != "ColorBlue" || c.Labels[0] != "blue" {
+		t.Errorf("Color default = %s (%s), want the picked ColorBlue (blue)", c.Values[0], c.Labels[0])
+	}
+	if err := m.PickEnumDefaults("first", map[string]string{"Color": "green"}); err == nil {
+		t.Error("PickEnumDefaults() picked a value the enum doesn't have")
+	}
+
+	got, err := generator.GenerateFormattedWithOptions(m, "fixtures", generator.GenerateOptions{EnumConstructors: true, TypePrefix: "pb"})
+	if err != nil {
+		t.Fatalf("GenerateFormattedWithOptions() error = %v", err)
+	}
+	for _, want := range []string{
+		"func FixtureOrder_Status() pb.Order_Status {\n\treturn pb.Order_STATUS_OPEN\n}",
+		"func FixtureOrder_STATUS_UNSPECIFIED() pb.Order_Status {\n\treturn pb.Order_STATUS_UNSPECIFIED\n}",
+		"func FixtureColorRed() pb.Color {",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %s:\n%s", want, got)
+		}
+	}
+}
+
+func TestEnumDeclarationOrder(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{"go.mod": "module kinds\n\ngo 1.22\n", "kinds.go": `package kinds
+
+type Kind int
+
+const (
+	KindA Kind = iota + 1
+	KindB
+	KindC
+	KindD
+	KindE
+)
+`}
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{"KindA", "KindB", "KindC", "KindD", "KindE"}
+	pkgs, err := load(dir, loadOptions{})
+	if err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	// The values come from a map, so a single extraction could be in order by chance
+	for range 5 {
+		m := generator.ExtractFromPackages(pkgs)
+		if err := m.PickEnumDefaults("first", nil); err != nil {
+			t.Fatal(err)
+		}
+		if got := m.Enums["Kind"].Values; !slices.Equal(got, want) {
+			t.Fatalf("Kind values = %v, want the declaration order %v", got, want)
+		}
+	}
+}
+
+func TestInterfaceStrategy(t *testing.T) {
+	m, err := generator.ParseSource(`package pb
+
+import (
+	"context"
+	"io"
+)
+
+type Store interface {
+	Get(ctx context.Context, id string) (*User, error)
+	Log(format string, args ...any)
+}
+
+type User struct {
+	ID    string
+	Store Store
+	Body  io.Reader
+	Err   error
+}
+`)
+	if err != nil {
+		t.Fatalf("ParseSource() error = %v", err)
+	}
+	if got := m.Structs["User"].Fields[1].Type.Kind; got != "interface" {
+		t.Fatalf("Store field kind = %s, want interface", got)
+	}
+
+	tests := []struct {
+		strategy string
+		want     []string
+		notWant  []string
+	}{
+		{"nil", []string{"Store: nil,", "Body:  nil,"}, []string{"StubStore", `"bytes"`}},
+		{"defaults", []string{"Store: nil,", "Body:  bytes.NewReader(nil),", `"bytes"`}, []string{"StubStore"}},
+		{"stub", []string{
+			"type StubStore struct{}",
+			"func (StubStore) Get(context.Context, string) (_ *pb.User, _ error) { return }",
+			"func (StubStore) Log(string, ...interface{}) {}",
+			"Store: StubStore{},",
+			"Err:   nil,",
+		}, nil},
+	}
+	for _, tt := range tests {
+		got, err := generator.GenerateFormattedWithOptions(m, "fixtures", generator.GenerateOptions{TypePrefix: "pb", InterfaceStrategy: tt.strategy})
+		if err != nil {
+			t.Fatalf("%s: GenerateFormattedWithOptions() error = %v", tt.strategy, err)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(got, want) {
+				t.Errorf("%s: output missing %s:\n%s", tt.strategy, want, got)
+			}
+		}
+		for _, notWant := range tt.notWant {
+			if strings.Contains(got, notWant) {
+				t.Errorf("%s: output contains %s:\n%s", tt.strategy, notWant, got)
+			}
+		}
+	}
+}
+
+func TestOptionalFields(t *testing.T) {
+	m, err := generator.ParseSource(`package pb
+
+type Status int32
+
+const (
+	Status_UNSPECIFIED Status = 0
+	Status_ACTIVE      Status = 1
+)
+
+type User struct {
+	Nickname *string
+	Age      *int32
+	Score    *float64
+	Status   *Status
+}
+`)
+	if err != nil {
+		t.Fatalf("ParseSource() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		opts generator.GenerateOptions
+		want []string
+	}{
+		{"mod style", generator.GenerateOptions{ModStyle: true}, []string{
+			`Nickname: ptr("Nickname"),`,
+			"Age:      ptr(int32(1)),",
+			"Score:    ptr(float64(1)),",
+			"Status:   FixtureStatus(),",
+		}},
+		{"classic", generator.GenerateOptions{}, []string{"Age:      ptr(int32(1)),", "Status:   ptr(FixtureStatus()),"}},
+		{"go1.17", generator.GenerateOptions{GoVersion: "1.17"}, []string{"Age:      ptrInt32(1),"}},
+		{"nil optionals", generator.GenerateOptions{ModStyle: true, NilOptionals: true, FieldOverrides: map[string]string{"User.Age": "ptr(int32(30))"}}, []string{"value := &User{\n\t\tAge: ptr(int32(30)),\n\t}"}},
+	}
+	for _, tt := range tests {
+		got, err := generator.GenerateFormattedWithOptions(m, "pb", tt.opts)
+		if err != nil {
+			t.Fatalf("%s: GenerateFormattedWithOptions() error = %v", tt.name, err)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(got, want) {
+				t.Errorf("%s: output missing %s:\n%s", tt.name, want, got)
+			}
+		}
+	}
+}
+
+func TestOutDir(t *testing.T) {
+	m, err := generator.ParseSource(`package pb
+
+import "time"
+
+type User struct {
+	Name      string
+	CreatedAt time.Time
+}
+
+type Order struct {
+	ID   string
+	User *User
+}
+`)
+	if err != nil {
+		t.Fatalf("ParseSource() error = %v", err)
+	}
+	m.Structs["User"].Source = &generator.Source{File: "proto/user.pb.go"}
+	m.Structs["Order"].Source = &generator.Source{File: "proto/order.pb.go"}
+
+	files, err := generator.GenerateFiles(m, "pb", generator.GenerateOptions{ModStyle: true, Incremental: true}, generator.FileLayout(m, 0))
+	if err != nil {
+		t.Fatalf("GenerateFiles() error = %v", err)
+	}
+	if len(files) != 3 || files["user_fixtures.go"] == nil || files["order_fixtures.go"] == nil || files[generator.SharedFile] == nil {
+		t.Fatalf("GenerateFiles() = %d files, want user_fixtures.go, order_fixtures.go and fixtures.go", len(files))
+	}
+	user, order, shared := string(files["user_fixtures.go"]), string(files["order_fixtures.go"]), string(files[generator.SharedFile])
+	if !strings.Contains(user, "func FixtureUser(") || !strings.Contains(user, `"time"`) || strings.Contains(user, "FixtureOrder") {
+		t.Errorf("user_fixtures.go holds the wrong declarations or imports:\n%s", user)
+	}
+	if !strings.Contains(order, "func FixtureOrder(") || strings.Contains(order, `"time"`) {
+		t.Errorf("order_fixtures.go holds the wrong declarations or imports:\n%s", order)
+	}
+	if !strings.Contains(shared, "func ptr[") || strings.Contains(user, "func ptr[") {
+		t.Errorf("fixtures.go doesn't hold the shared helpers:\n%s", shared)
+	}
+	if generator.ReadHash(files["user_fixtures.go"]) == "" || generator.ReadHash(files["user_fixtures.go"]) == generator.ReadHash(files["order_fixtures.go"]) {
+		t.Errorf("the files don't record the hashes of their own content:\n%s\n%s", user, order)
+	}
+
+	// A type added to one file leaves the hashes of the others alone
+	added := generator.NewModel()
+	for name, s := range m.Structs {
+		added.Structs[name] = s
+	}
+	added.Structs["Account"] = &generator.Struct{Name: "Account", Source: &generator.Source{File: "proto/account.pb.go"}}
+	opts := generator.GenerateOptions{ModStyle: true, Incremental: true, Unchanged: func(file, hash string) bool {
+		return files[file] != nil && generator.ReadHash(files[file]) == hash
+	}}
+	regenerated, err := generator.GenerateFiles(added, "pb", opts, generator.FileLayout(added, 0))
+	if err != nil {
+		t.Fatalf("GenerateFiles() error = %v", err)
+	}
+	if _, ok := regenerated["user_fixtures.go"]; !ok || regenerated["user_fixtures.go"] != nil || regenerated["order_fixtures.go"] != nil || regenerated["account_fixtures.go"] == nil {
+		t.Errorf("GenerateFiles() didn't regenerate only the new file: %v", slices.Sorted(maps.Keys(regenerated)))
+	}
+
+	if layout := generator.FileLayout(m, 1); layout["Order"] != "fixtures_1.go" || layout["User"] != "fixtures_2.go" {
+		t.Errorf("FileLayout(1) = %v", layout)
+	}
+
+	dir := t.TempDir()
+	stale := filepath.Join(dir, "account_fixtures.go")
+	kept := filepath.Join(dir, "fixtures_test.go")
+	for _, path := range []string{stale, kept} {
+		if err := os.WriteFile(path, []byte("package pb\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := writeOutputFiles(dir, files); err != nil {
+		t.Fatalf("writeOutputFiles() error = %v", err)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Error("writeOutputFiles() kept the fixtures of a removed source file")
+	}
+	if _, err := os.Stat(kept); err != nil {
+		t.Error("writeOutputFiles() removed a file it didn't generate")
+	}
+	old, new, err := checkOutDir(dir, files)
+	if err != nil || !bytes.Equal(old, new) {
+		t.Errorf("checkOutDir() reports drift right after writing, err = %v", err)
+	}
+
+	if err := (&target{Pkg: "./pb", Out: "fixtures.go", OutDir: "fixtures"}).validate(); err == nil {
+		t.Error("validate() accepted -out with -outdir")
+	}
+	if err := (&target{Pkg: "./pb", TypesPerFile: 10}).validate(); err == nil {
+		t.Error("validate() accepted -typesperfile without -outdir")
+	}
+}
+
+func TestCustomFixtures(t *testing.T) {
+	dir := t.TempDir()
+	for name, src := range map[string]string{
+		"fixtures_custom.go": "package fixtures\n\nfunc FixtureUser(mods ...func(*pb.User)) *pb.User { return nil }\n\nfunc newUser() {}\n",
+		"fixtures.go":        "// Code generated by fixture-generator. DO NOT EDIT.\n\npackage fixtures\n\nfunc FixtureOrder() {}\n",
+		"custom_test.go":     "package fixtures\n\nfunc FixtureTest() {}\n",
+		"other.go":           "package other\n\nfunc FixtureOther() {}\n",
+		"tool.go":            "//go:build ignore\n\npackage main\n\nfunc FixtureTool( {}\n",
+		"editing.go":         "package fixtures\n\nfunc FixtureEditing( {}\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := target{Out: filepath.Join(dir, "fixtures.go")}.customFixtures()
+	if err != nil {
+		t.Fatalf("customFixtures() error = %v", err)
+	}
+	if !slices.Equal(got, []string{"FixtureUser"}) {
+		t.Errorf("customFixtures() = %v, want the hand-written FixtureUser only", got)
+	}
+
+	m, err := generator.ParseSource(`package pb
+
+import "time"
+
+type User struct {
+	Name      string
+	CreatedAt time.Time
+}
+
+type Order struct {
+	ID   string
+	User *User
+}
+`)
+	if err != nil {
+		t.Fatalf("ParseSource() error = %v", err)
+	}
+	out, err := generator.GenerateFormattedWithOptions(m, "fixtures", generator.GenerateOptions{ModStyle: true, TypePrefix: "pb", CustomFixtures: got})
+	if err != nil {
+		t.Fatalf("GenerateFormattedWithOptions() error = %v", err)
+	}
+	if strings.Contains(out, "func FixtureUser(") || !strings.Contains(out, "func FixtureOrder(") || !strings.Contains(out, "FixtureUser(),") {
+		t.Errorf("output regenerates the custom fixture or doesn't call it:\n%s", out)
+	}
+	if strings.Contains(out, `"time"`) {
+		t.Errorf("output imports time, which only the custom fixture used:\n%s", out)
+	}
+}
+
+func TestSourceInput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "types.go")
+	src := "package pb\n\ntype User struct {\n\tName  string\n\tEmail string\n}\n"
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m, pkgs, err := target{Src: path, SkipFields: []string{"User.Email"}}.model(1, nil)
+	if err != nil {
+		t.Fatalf("model() error = %v", err)
+	}
+	if pkgs != nil || m.Structs["User"] == nil || len(m.Structs["User"].Fields) != 1 {
+		t.Errorf("model() of -src = %+v, want User without the skipped Email", m.Structs["User"])
+	}
+
+	if err := (&target{Src: "-", Placeholder: "$"}).validate(); err != nil {
+		t.Errorf("validate() rejected -src - on its own: %v", err)
+	}
+	if err := (&target{Src: "-", Pkg: "./pb", Placeholder: "$"}).validate(); err == nil {
+		t.Error("validate() accepted -src with -pkg")
+	}
+}
+
+func TestParseSources(t *testing.T) {
+	m, err := generator.ParseSources(map[string]string{
+		"user.pb.go": `package pb
+
+import "time"
+
+type User struct {
+	Name      string
+	CreatedAt time.Time
+	Status    Status
+}
+`,
+		"order.pb.go": `package pb
+
+type Status int32
+
+const (
+	Status_UNSPECIFIED Status = 0
+	Status_ACTIVE      Status = 1
+)
+
+type Order struct {
+	Buyer   *User
+	Payment isOrder_Payment
+}
+
+type isOrder_Payment interface{ isOrder_Payment() }
+
+type Order_Card struct{ Card string }
+
+func (*Order_Card) isOrder_Payment() {}
+`,
+	})
+	if err != nil {
+		t.Fatalf("ParseSources() error = %v", err)
+	}
+	if m.Structs["User"] == nil || m.Structs["Order"] == nil || m.OneOfs["isOrder_Payment"] == "" {
+		t.Fatalf("ParseSources() = %+v, want the types and oneofs of both files", m)
+	}
+	out, err := generator.GenerateFormattedWithOptions(m, "fixtures", generator.GenerateOptions{ModStyle: true, TypePrefix: "pb"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"FixtureUser()", "time.Date(", "&pb.Order_Card{"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %s:\n%s", want, out)
+		}
+	}
+
+	if _, err := generator.ParseSources(map[string]string{"a.go": "package pb", "b.go": "package"}); err == nil || !strings.Contains(err.Error(), "b.go") {
+		t.Errorf("ParseSources() error = %v, want the file that failed to parse", err)
+	}
+}
+
+func TestParseSourceEnums(t *testing.T) {
+	m, err := generator.ParseSource(`package pb
+
+type Status int32
+
+const (
+	Status_UNSPECIFIED Status = iota
+	Status_ACTIVE
+	_
+	Status_BANNED
+)
+
+type Level string
+
+const LevelHigh Level = "high"
+
+const (
+	Untyped   = 1
+	Converted = Level("low")
+)
+
+type TenantID string
+
+type User struct {
+	Status Status
+	Level  *Level
+	Tenant TenantID
+}
+`)
+	if err != nil {
+		t.Fatalf("ParseSource() error = %v", err)
+	}
+	if e := m.Enums["Status"]; e == nil || !slices.Equal(e.Values, []string{"Status_UNSPECIFIED", "Status_ACTIVE", "Status_BANNED"}) {
+		t.Errorf("Status enum = %+v, want the values of the iota block", e)
+	}
+	if e := m.Enums["Level"]; e == nil || !slices.Equal(e.Values, []string{"LevelHigh"}) {
+		t.Errorf("Level enum = %+v, want the typed constant only", e)
+	}
+	if _, ok := m.TypeDefs["Status"]; ok || m.TypeDefs["TenantID"] == nil {
+		t.Errorf("typedefs = %v, want TenantID without the enums", m.TypeDefs)
+	}
+	if kind := m.Structs["User"].Fields[0].Type.Kind; kind != "enum" {
+		t.Errorf("Status field kind = %q, want enum", kind)
+	}
+
+	out, err := generator.GenerateFormattedWithOptions(m, "pb", generator.GenerateOptions{ModStyle: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"func FixtureStatus(", "value := Status_UNSPECIFIED", "Status: *FixtureStatus(),", "Level:  FixtureLevel(),"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %s:\n%s", want, out)
+		}
+	}
+}
+
+func TestByteSlices(t *testing.T) {
+	m, err := generator.ParseSource(`package models
+
+type Blob struct {
+	Data []byte
+	Raw  []uint8
+	Opt  *[]byte
+	List [][]byte
+}
+`)
+	if err != nil {
+		t.Fatalf("ParseSource() error = %v", err)
+	}
+	got, err := generator.GenerateFormattedWithOptions(m, "fixtures", generator.GenerateOptions{})
+	if err != nil {
+		t.Fatalf("GenerateFormattedWithOptions() error = %v", err)
+	}
+	for _, want := range []string{`Data: []byte("Data"),`, `Raw:  []byte("Raw"),`, `Opt:  ptr([]byte("Opt")),`, `List: [][]byte{[]byte("List")},`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %s:\n%s", want, got)
+		}
+	}
+
+	got, err = generator.GenerateFormattedWithOptions(m, "fixtures", generator.GenerateOptions{ByteLength: 40})
+	if err != nil {
+		t.Fatalf("GenerateFormattedWithOptions() error = %v", err)
+	}
+	bytes := func(field string) string {
+		_, rest, _ := strings.Cut(got, field+"[]byte{")
+		value, _, _ := strings.Cut(rest, "}")
+		return value
+	}
+	if n := len(strings.Split(bytes("Data: "), ",")); n != 40 {
+		t.Fatalf("Data has %d bytes, want 40:\n%s", n, got)
+	}
+	if bytes("Data: ") == bytes("Raw:  ") {
+		t.Error("Data and Raw got the same bytes")
+	}
+	again, _ := generator.GenerateFormattedWithOptions(m, "fixtures", generator.GenerateOptions{ByteLength: 40})
+	if again != got {
+		t.Error("byte data differs between runs")
+	}
+
+	// The JSON of the fixture decodes to the same bytes
+	value, err := generator.JSONValue(m, "Blob", generator.GenerateOptions{})
+	if err != nil {
+		t.Fatalf("JSONValue() error = %v", err)
+	}
+	var blob struct{ Data, Opt []byte }
+	data, _ := json.Marshal(value)
+	if err := json.Unmarshal(data, &blob); err != nil || string(blob.Data) != "Data" || string(blob.Opt) != "Opt" {
+		t.Errorf("JSONValue() = %s, want the bytes of []byte(\"Data\") and []byte(\"Opt\")", data)
+	}
+}
+
+func TestWellKnownTypes(t *testing.T) {
+	m, err := generator.ParseSource(`package pb
+
+import (
+	"time"
+
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+	fieldmaskpb "google.golang.org/protobuf/types/known/fieldmaskpb"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	wrapperspb "google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type Job struct {
+	Timeout  *durationpb.Duration
+	Wait     time.Duration
+	Name     *wrapperspb.StringValue
+	Count    *wrapperspb.Int64Value
+	Data     *wrapperspb.BytesValue
+	Meta     *structpb.Struct
+	Mask     *fieldmaskpb.FieldMask
+	Nothing  *emptypb.Empty
+	Timeouts []*durationpb.Duration
+}
+`)
+	if err != nil {
+		t.Fatalf("ParseSource() error = %v", err)
+	}
+	out, err := generator.GenerateFormattedWithOptions(m, "pb", generator.GenerateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`durationpb "google.golang.org/protobuf/types/known/durationpb"`,
+		`wrapperspb "google.golang.org/protobuf/types/known/wrapperspb"`,
+		"Timeout:  durationpb.New(time.Minute),",
+		`Name:     wrapperspb.String("Name"),`,
+		"Count:    wrapperspb.Int64(1),",
+		`Data:     wrapperspb.Bytes([]byte("Data")),`,
+		`Meta:     &structpb.Struct{Fields: map[string]*structpb.Value{"Meta": structpb.NewStringValue("Meta")}},`,
+		"Mask:     &fieldmaskpb.FieldMask{},",
+		"Nothing:  &emptypb.Empty{},",
+		"Timeouts: []*durationpb.Duration{durationpb.New(time.Minute)},",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	// time.Duration is named like durationpb.Duration but isn't one
+	if strings.Contains(out, "Wait:     durationpb") {
+		t.Errorf("time.Duration got a durationpb value:\n%s", out)
+	}
+}
+
+func TestNestedMessages(t *testing.T) {
+	// Without the marker methods protoc-gen-go declares, oneof wrappers are told by name and shape
+	m, err := generator.ParseSource(`package pb
+
+import protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+
+type Order struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Items      []*Order_LineItem
+	Payment    isOrder_Payment
+	Attributes map[string]string
+}
+
+type isOrder_Payment interface{ isOrder_Payment() }
+
+type Order_Card struct{ Card string }
+
+type Order_LineItem struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name string
+	Kind isOrder_LineItem_Kind
+}
+
+type isOrder_LineItem_Kind interface{ isOrder_LineItem_Kind() }
+
+type Order_LineItem_Sku struct{ Sku string }
+
+type Order_AttributesEntry struct {
+	Key   string
+	Value string
+}
+`)
+	if err != nil {
+		t.Fatalf("ParseSource() error = %v", err)
+	}
+	if got := m.Variants["isOrder_Payment"]; !slices.Equal(got, []string{"Order_Card"}) {
+		t.Errorf("isOrder_Payment variants = %v, want only Order_Card", got)
+	}
+	if got := m.Variants["isOrder_LineItem_Kind"]; !slices.Equal(got, []string{"Order_LineItem_Sku"}) {
+		t.Errorf("isOrder_LineItem_Kind variants = %v, want only Order_LineItem_Sku", got)
+	}
+	if _, ok := m.Structs["Order_AttributesEntry"]; ok {
+		t.Error("map entry Order_AttributesEntry got a struct")
+	}
+
+	out, err := generator.GenerateFormattedWithOptions(m, "pb", generator.GenerateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"func FixtureOrder_LineItem(", "Items: []*Order_LineItem{ptr(FixtureOrder_LineItem())},", "Kind: &Order_LineItem_Sku{"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "AttributesEntry") {
+		t.Errorf("output has the map entry:\n%s", out)
+	}
+}
+
+func TestSliceLen(t *testing.T) {
+	m, err := generator.ParseSource(`package models
+
+type Post struct {
+	Tags    []string
+	Scores  []int
+	Flags   []bool
+	Authors []Author
+	Links   []string ` + "`fixture:\"len=2\"`" + `
+	Data    []byte
+}
+
+type Author struct {
+	Name string
+}
+`)
+	if err != nil {
+		t.Fatalf("ParseSource() error = %v", err)
+	}
+	tg := target{SliceLen: 3, SliceLens: map[string]string{"Post.Scores": "0"}}
+	opts, err := tg.options(m, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := generator.GenerateFormattedWithOptions(m, "fixtures", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`Tags:   []string{"Tags1", "Tags2", "Tags3"},`,
+		"Scores: []int{},",
+		"Flags:  []bool{true, false, true},",
+		"Authors: []Author{\n\t\t\t*FixtureAuthor(func(v *Author) { v.Name += \"1\" }),\n\t\t\t*FixtureAuthor(func(v *Author) { v.Name += \"2\" }),",
+		`Links: []string{"Links1", "Links2"},`,
+		`Data:  []byte("Data"),`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+
+	value, err := generator.JSONValue(m, "Post", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(value)
+	if !strings.Contains(string(data), `"Flags":[true,false,true],"Authors":[{"Name":"Name1"},{"Name":"Name2"},{"Name":"Name3"}]`) {
+		t.Errorf("JSONValue() = %s, want the elements of the fixture", data)
+	}
+
+	opts.ModSignature = "value"
+	out = generator.GenerateWithOptions(m, "fixtures", opts)
+	if !strings.Contains(out, `*FixtureAuthor(func(v Author) Author { v.Name += "2"; return v })`) {
+		t.Errorf("elements don't take value mods:\n%s", out)
+	}
+
+	for _, lens := range []map[string]string{{"Post.Tags": "-1"}, {"Post.Name": "2"}, {"Author.Name": "2"}} {
+		tg := target{SliceLens: lens}
+		if _, err := tg.options(m, nil); err == nil {
+			t.Errorf("options() accepted -len %v", lens)
+		}
+	}
+
+	// Only the key field is suffixed, unless it refers to another struct, is overridden or faked
+	m, err = generator.ParseSource(`package models
+
+type Order struct {
+	ID    string
+	Lines []Line
+}
+
+type Line struct {
+	OrderID string
+	Name    string
+}
+
+type Tag struct {
+	Label string
+}
+
+type Post struct {
+	Tags []Tag
+}
+`)
+	if err != nil {
+		t.Fatalf("ParseSource() error = %v", err)
+	}
+	if err := m.Relate(map[string]string{"Line.OrderID": "Order.ID"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		tg         target
+		want, lack string
+	}{
+		{target{SliceLen: 2}, `*FixtureTag(func(v *Tag) { v.Label += "2" })`, "v.Name +="},
+		{target{SliceLen: 2}, "[]Line{*FixtureLine(), *FixtureLine()}", "v.OrderID +="},
+		{target{SliceLen: 2, FieldOverrides: map[string]string{"Tag.Label": `"go"`}}, "[]Tag{*FixtureTag(), *FixtureTag()}", "+="},
+		{target{SliceLen: 2, Values: "faker"}, "[]Tag{*FixtureTag(), *FixtureTag()}", "+="},
+	} {
+		opts, err := tc.tg.options(m, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := generator.GenerateWithOptions(m, "fixtures", opts)
+		if !strings.Contains(out, tc.want) || strings.Contains(out, tc.lack) {
+			t.Errorf("%+v: output lacks %q or has %q:\n%s", tc.tg, tc.want, tc.lack, out)
+		}
+	}
+}
+
+func TestCallPairs(t *testing.T) {
+	m, err := generator.ParseSource(`package pb
+
+type CreateUserRequest struct {
+	Name string
+}
+
+type CreateUserResponse struct {
+	ID string
+}
+
+type DeleteUserRequest struct {
+	ID string
+}
+
+type GetUserRequest struct {
+	ID string
+}
+
+type GetUserResponse struct {
+	Name string
+}
+
+type GetUserCall struct {
+	Name string
+}
+`)
+	if err != nil {
+		t.Fatalf("ParseSource() error = %v", err)
+	}
+	out, err := generator.GenerateFormattedWithOptions(m, "pb", generator.GenerateOptions{ModStyle: true, Calls: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "func FixtureCreateUserCall() (*CreateUserRequest, *CreateUserResponse) {\n\treturn FixtureCreateUserRequest(), FixtureCreateUserResponse()\n}"
+	if !strings.Contains(out, want) {
+		t.Errorf("output lacks %q:\n%s", want, out)
+	}
+	// Requests without a response and pairs named like the fixture of another struct get no helper
+	if strings.Contains(out, "FixtureDeleteUserCall") || strings.Count(out, "func FixtureGetUserCall(") != 1 {
+		t.Errorf("unexpected call helpers:\n%s", out)
+	}
+
+	out, err = generator.GenerateFormattedWithOptions(m, "pb", generator.GenerateOptions{Calls: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "req, resp := FixtureCreateUserRequest(), FixtureCreateUserResponse()\n\treturn &req, &resp"; !strings.Contains(out, want) {
+		t.Errorf("output lacks %q:\n%s", want, out)
+	}
+}
+
+func TestAssertHelpers(t *testing.T) {
+	m, err := generator.ParseSource(`package models
+
+type User struct {
+	Name string
+}
+`)
+	if err != nil {
+		t.Fatalf("ParseSource() error = %v", err)
+	}
+	out, err := generator.GenerateFormattedWithOptions(m, "fixtures", generator.GenerateOptions{TypePrefix: "models", Asserts: "cmp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`"github.com/google/go-cmp/cmp"`,
+		"func AssertUserEqual(t assertT, expected, actual *models.User) bool {",
+		"cmp.Diff(expected, actual, assertOptions...)",
+		"return ok && !token.IsExported(f.Name())",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "protocmp") {
+		t.Errorf("cmp assertions use protocmp:\n%s", out)
+	}
+
+	out, err = generator.GenerateFormattedWithOptions(m, "fixtures", generator.GenerateOptions{TypePrefix: "models", Asserts: "protocmp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, `"google.golang.org/protobuf/testing/protocmp"`) || !strings.Contains(out, "protocmp.Transform(),") {
+		t.Errorf("protocmp assertions don't transform messages:\n%s", out)
+	}
+
+	if err := (&target{Pkg: ".", Placeholder: "$", Asserts: "testify"}).validate(); err == nil || !strings.Contains(err.Error(), "-asserts") {
+		t.Errorf("validate() error = %v, want one about -asserts", err)
+	}
+}
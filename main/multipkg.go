@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"fixture-generator/pkg/generator"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// multiPackage reports whether the -pkg pattern names several packages: a comma-separated list or a
+// pattern like ./...
+func multiPackage(pattern string) bool {
+	return strings.Contains(pattern, ",") || strings.Contains(pattern, "...")
+}
+
+// packageTargets expands t, whose -pkg names several packages, into a target per package. Each writes
+// fixtures/fixtures.go below its package, or with -self <package>_fixtures.go next to its types, and
+// calls the fixtures of the other packages for their types, so FixtureOrder can use FixtureUser.
+// Main packages, fixtures packages matched by the pattern and packages without types to generate
+// fixtures for, apart from those in fixture output, are left out.
+func packageTargets(t target) ([]target, error) {
+	var patterns []string
+	for _, p := range strings.Split(t.Pkg, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			patterns = append(patterns, p)
+		}
+	}
+	// Only file names are needed to find the package directories, not type information
+	cfg := &packages.Config{Mode: packages.NeedName | packages.NeedFiles, Env: platformEnv(t.GOOS, t.GOARCH)}
+	pkgs, err := packages.Load(cfg, patterns...)
+	if err != nil {
+		return nil, err
+	}
+	var matched []*packages.Package
+	for _, pkg := range pkgs {
+		if len(pkg.GoFiles) == 0 || path.Base(pkg.PkgPath) == fixtureSubpackage || pkg.Name == "main" && !t.Self {
+			continue
+		}
+		ok, err := hasTypes(pkg.GoFiles)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			matched = append(matched, pkg)
+		}
+	}
+	if len(matched) == 0 {
+		return nil, fmt.Errorf("%w with Go files in %s", generator.ErrNoPackages, t.Pkg)
+	}
+
+	fixturePkgs := make(map[string]string)
+	for _, pkg := range matched {
+		fixturePkgs[pkg.PkgPath] = pkg.PkgPath + "/" + fixtureSubpackage
+		if t.Self {
+			fixturePkgs[pkg.PkgPath] = pkg.PkgPath
+		}
+	}
+
+	targets := make([]target, 0, len(matched))
+	for _, pkg := range matched {
+		pt := t
+		pt.Pkg = filepath.Dir(pkg.GoFiles[0])
+		pt.onePackageOf = true
+		pt.FixturePackages = make(map[string]string)
+		for typePkg, fixturePkg := range fixturePkgs {
+			if typePkg != pkg.PkgPath {
+				pt.FixturePackages[typePkg] = fixturePkg
+			}
+		}
+		// Explicit mappings win over the generated packages
+		maps.Copy(pt.FixturePackages, t.FixturePackages)
+		// Tests load testdata relative to their package, so each package gets its own directory
+		if t.Testdata != "" && !filepath.IsAbs(t.Testdata) {
+			pt.Testdata = filepath.Join(pt.Pkg, t.Testdata)
+		}
+		if !t.Self {
+			dir := filepath.Join(pt.Pkg, fixtureSubpackage)
+			pt.TypePrefix = pkg.Name
+			pt.TypeImport = pkg.PkgPath
+			pt.Out = filepath.Join(dir, "fixtures.go")
+		}
+		targets = append(targets, pt)
+	}
+	return targets, nil
+}
+
+// hasTypes reports whether the model of the Go files holds a type to generate a fixture for. Files
+// generated by fixture-generator, the output of an earlier run named other than fixtures, don't
+// count, or every run would add fixtures of the fixtures. Files that don't parse are left to the
+// target to report.
+func hasTypes(files []string) (bool, error) {
+	sources := make(map[string]string)
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			return false, err
+		}
+		if !fixtureOutput(src) {
+			sources[file] = string(src)
+		}
+	}
+	if len(sources) == 0 {
+		return false, nil
+	}
+	model, err := generator.ParseSources(sources)
+	if err != nil {
+		return true, nil
+	}
+	return len(model.Structs)+len(model.Enums)+len(model.TypeDefs) > 0, nil
+}
+
+// fixtureOutput reports whether src was generated by fixture-generator or protoc-gen-gofixtures:
+// it has a Code generated header, and the version line or a header naming the generator
+func fixtureOutput(src []byte) bool {
+	f, err := parser.ParseFile(token.NewFileSet(), "", src, parser.PackageClauseOnly|parser.ParseComments)
+	if err != nil || !ast.IsGenerated(f) {
+		return false
+	}
+	return generator.ReadVersion(src) != "" || bytes.Contains(src[:f.Package-1], []byte("by fixture-generator")) || bytes.Contains(src[:f.Package-1], []byte("by protoc-gen-gofixtures"))
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/build"
+	"io"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// platformEnv returns the environment loading packages for goos and goarch, nil for the host platform
+func platformEnv(goos, goarch string) []string {
+	if goos == "" && goarch == "" {
+		return nil
+	}
+	env := os.Environ()
+	if goos != "" {
+		env = append(env, "GOOS="+goos)
+	}
+	if goarch != "" {
+		env = append(env, "GOARCH="+goarch)
+	}
+	return env
+}
+
+// knownOS and knownArch are the platforms tried on files left out of a package, as listed by
+// `go tool dist list`
+var (
+	knownOS = []string{"aix", "android", "darwin", "dragonfly", "freebsd", "illumos", "ios", "js", "linux",
+		"netbsd", "openbsd", "plan9", "solaris", "wasip1", "windows"}
+	knownArch = []string{"386", "amd64", "arm", "arm64", "loong64", "mips", "mips64", "mips64le", "mipsle",
+		"ppc64", "ppc64le", "riscv64", "s390x", "wasm"}
+)
+
+// platformFiles returns the files built only for other platforms than the one they were left out for,
+// ignoring files no platform builds such as those constrained by "ignore"
+func platformFiles(files []string) []string {
+	var result []string
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		if otherPlatform(file, data) {
+			result = append(result, file)
+		}
+	}
+	return result
+}
+
+// otherPlatform reports whether the file with the given content is built for some known platform.
+// cgo is disabled, files needing it are reported by reportCgo.
+func otherPlatform(file string, data []byte) bool {
+	ctxt := build.Default
+	ctxt.CgoEnabled = false
+	ctxt.OpenFile = func(string) (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(data)), nil }
+	for _, goos := range knownOS {
+		for _, goarch := range knownArch {
+			ctxt.GOOS, ctxt.GOARCH = goos, goarch
+			if ok, err := ctxt.MatchFile(filepath.Dir(file), filepath.Base(file)); err == nil && ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// reportPlatform warns about the types of pkgs that got no fixtures because they are declared only
+// in files for other platforms
+func reportPlatform(pkgs []*packages.Package) {
+	for _, pkg := range pkgs {
+		unavailable := unavailableTypes(pkg, platformFiles(pkg.IgnoredFiles))
+		for _, file := range sortedNames(unavailable) {
+			for _, name := range unavailable[file] {
+				fmt.Fprintf(os.Stderr, "warning: %s: type %s is unavailable, it is declared for other platforms, set -goos/-goarch to generate it\n", file, name)
+			}
+		}
+	}
+}
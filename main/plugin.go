@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"fixture-generator/pkg/generator"
+)
+
+// runPlugin sends the model to the plugin executable and writes the files it returns into dir
+func runPlugin(plugin, parameter, dir string, model *generator.Model, pkgName string, opts generator.GenerateOptions) error {
+	req, err := json.Marshal(generator.PluginRequest{
+		Version:   generator.PluginProtocolVersion,
+		Model:     model,
+		Options:   opts,
+		Package:   pkgName,
+		Parameter: parameter,
+	})
+	if err != nil {
+		return err
+	}
+
+	var stdout bytes.Buffer
+	cmd := exec.Command(plugin)
+	cmd.Stdin = bytes.NewReader(req)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("plugin %s: %w", plugin, err)
+	}
+	var resp generator.PluginResponse
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return fmt.Errorf("plugin %s: invalid response: %w", plugin, err)
+	}
+	if resp.Error != "" {
+		return fmt.Errorf("plugin %s: %s", plugin, resp.Error)
+	}
+
+	// Check every name before writing anything, so a bad response leaves no partial output
+	for _, f := range resp.Files {
+		if !filepath.IsLocal(filepath.FromSlash(f.Name)) {
+			return fmt.Errorf("plugin %s: file name %q is not relative to the output directory", plugin, f.Name)
+		}
+	}
+	for _, f := range resp.Files {
+		path := filepath.Join(dir, filepath.FromSlash(f.Name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(f.Content), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pluginDir is where plugin files go: -plugin-out, else the directory of the output file
+func (t target) pluginDir() string {
+	if t.PluginOut != "" {
+		return t.PluginOut
+	}
+	if t.Out != "" {
+		return filepath.Dir(t.Out)
+	}
+	return "."
+}
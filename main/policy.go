@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"fixture-generator/pkg/generator"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Conditions a run can hit. The exit policy of a target decides which of them fail it and which are
+// only reported as warnings.
+const (
+	// condLoad is a package with load or type errors
+	condLoad = "load"
+	// condSkipped is a type or field that got no fixture value
+	condSkipped = "skipped"
+	// condFormat is a generated declaration gofmt rejects
+	condFormat = "format"
+	// condDrift is an output file check finds out of date
+	condDrift = "drift"
+)
+
+var conditions = []string{condLoad, condSkipped, condFormat, condDrift}
+
+// fatalByDefault are the conditions that fail a run unless listed in WarnOn
+var fatalByDefault = map[string]bool{condDrift: true}
+
+// parseConditions splits a comma-separated list of conditions, where "all" stands for every one
+func parseConditions(list string) ([]string, error) {
+	var conds []string
+	for _, c := range strings.Split(list, ",") {
+		c = strings.TrimSpace(c)
+		switch {
+		case c == "":
+		case c == "all":
+			conds = append(conds, conditions...)
+		case contains(conditions, c):
+			conds = append(conds, c)
+		default:
+			return nil, fmt.Errorf("unknown condition %q (want %s or all)", c, strings.Join(conditions, ", "))
+		}
+	}
+	return conds, nil
+}
+
+func contains(items []string, s string) bool {
+	for _, item := range items {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// validatePolicy checks the conditions listed in FailOn and WarnOn
+func (t target) validatePolicy() error {
+	if _, err := parseConditions(t.FailOn); err != nil {
+		return fmt.Errorf("fail-on: %w", err)
+	}
+	if _, err := parseConditions(t.WarnOn); err != nil {
+		return fmt.Errorf("warn-on: %w", err)
+	}
+	return nil
+}
+
+// fatal reports whether cond fails t. WarnOn wins over FailOn, so "all" can be combined with exceptions.
+func (t target) fatal(cond string) bool {
+	warn, _ := parseConditions(t.WarnOn)
+	if contains(warn, cond) {
+		return false
+	}
+	fail, _ := parseConditions(t.FailOn)
+	return contains(fail, cond) || fatalByDefault[cond]
+}
+
+// condition returns an error if cond fails t and otherwise prints msg as a warning
+func (t target) condition(cond, msg string) error {
+	if t.fatal(cond) {
+		return &conditionError{cond: cond, msg: msg}
+	}
+	fmt.Fprintf(os.Stderr, "warning: %s\n", msg)
+	return nil
+}
+
+// conditionError is a condition failing a target
+type conditionError struct {
+	cond, msg string
+}
+
+func (e *conditionError) Error() string {
+	return fmt.Sprintf("%s (%s)", e.msg, e.cond)
+}
+
+// Exit codes of failed runs, telling apart inputs that can't be loaded and output that can't be
+// formatted from other failures. Invalid command lines exit with 2 like the flag package does.
+const (
+	exitFailed = 1
+	exitLoad   = 3
+	exitFormat = 4
+)
+
+// exitCode returns the exit code of a run failed by err
+func exitCode(err error) int {
+	var cond *conditionError
+	switch {
+	case errors.Is(err, generator.ErrNoPackages):
+		return exitLoad
+	case errors.As(err, &cond) && cond.cond == condLoad:
+		return exitLoad
+	case errors.As(err, &cond) && cond.cond == condFormat:
+		return exitFormat
+	}
+	return exitFailed
+}
+
+// loadErrors returns the number of load and type errors of pkgs
+func loadErrors(pkgs []*packages.Package) int {
+	n := 0
+	for _, pkg := range pkgs {
+		n += len(pkg.Errors)
+	}
+	return n
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// profile is a named bundle of flag values for a common scenario
+type profile struct {
+	summary string
+	flags   map[string]string
+}
+
+// profiles are selected with -profile. Their values only apply to flags not given on the command line.
+var profiles = map[string]profile{
+	"proto": {
+		summary: "protobuf messages: mod style, protojson snapshots, PB function prefix, cross-package messages loaded, skipped fields fatal",
+		flags: map[string]string{
+			"modstyle":   "true",
+			"snapshots":  "protojson",
+			"deep":       "true",
+			"funcprefix": "PB",
+			"fail-on":    "skipped",
+		},
+	},
+	"domain": {
+		summary: "plain Go domain types: mod style and Seed helpers for structs with db or gorm tags",
+		flags: map[string]string{
+			"modstyle":  "true",
+			"seedfuncs": "true",
+		},
+	},
+	"api-json": {
+		summary: "JSON API payloads: value-returning fixtures and JSON snapshots",
+		flags: map[string]string{
+			"modstyle":  "false",
+			"snapshots": "json",
+		},
+	},
+}
+
+// profileFlag registers -profile on fs
+func profileFlag(fs *flag.FlagSet) {
+	var names []string
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fs.String("profile", "", "preset bundling options for a common scenario: "+strings.Join(names, ", ")+" (flags given explicitly win)")
+}
+
+// applyProfile sets the flags of the profile selected with -profile that weren't set explicitly
+func applyProfile(fs *flag.FlagSet) error {
+	f := fs.Lookup("profile")
+	if f == nil || f.Value.String() == "" {
+		return nil
+	}
+	p, ok := profiles[f.Value.String()]
+	if !ok {
+		return fmt.Errorf("unknown -profile %q", f.Value.String())
+	}
+	explicit := explicitFlags(fs)
+	for name, value := range p.flags {
+		if explicit[name] {
+			continue
+		}
+		if err := fs.Set(name, value); err != nil {
+			return fmt.Errorf("profile %s: -%s: %w", f.Value.String(), name, err)
+		}
+	}
+	return nil
+}
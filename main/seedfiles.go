@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"fixture-generator/pkg/generator"
+)
+
+// migrationName matches sequentially numbered migration files of golang-migrate and goose
+var migrationName = regexp.MustCompile(`^([0-9]+)_(.+?)(\.up\.sql|\.down\.sql|\.sql)$`)
+
+// writeSeedFiles writes a numbered seed migration per table into dir, in golang-migrate
+// ("migrate": NNNNNN_seed_<table>.up.sql and .down.sql) or goose ("goose": NNNNN_seed_<table>.sql) format.
+// Seed files written by an earlier run keep their number; new ones are numbered after the
+// highest existing migration.
+func writeSeedFiles(dir, format string, seeds []generator.SQLSeed) error {
+	if format != "migrate" && format != "goose" {
+		return fmt.Errorf("unknown seed format %q (want migrate or goose)", format)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	width := 6
+	if format == "goose" {
+		width = 5
+	}
+	var last int
+	numbers := make(map[string]int)
+	for _, e := range entries {
+		match := migrationName.FindStringSubmatch(e.Name())
+		if match == nil {
+			continue
+		}
+		n, err := strconv.Atoi(match[1])
+		if err != nil {
+			continue
+		}
+		width = len(match[1])
+		last = max(last, n)
+		numbers[match[2]] = n
+	}
+
+	for _, seed := range seeds {
+		name := "seed_" + seed.Table
+		n, ok := numbers[name]
+		if !ok {
+			last++
+			n = last
+		}
+		prefix := filepath.Join(dir, fmt.Sprintf("%0*d_%s", width, n, name))
+
+		if format == "goose" {
+			content := "-- +goose Up\n" + seed.Insert + "\n\n-- +goose Down\n" + seed.Delete + "\n"
+			if err := os.WriteFile(prefix+".sql", []byte(content), 0644); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := os.WriteFile(prefix+".up.sql", []byte(seed.Insert+"\n"), 0644); err != nil {
+			return err
+		}
+		if err := os.WriteFile(prefix+".down.sql", []byte(seed.Delete+"\n"), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"go/ast"
+	"go/importer"
+	"go/token"
+	"go/types"
+	"path/filepath"
+
+	"fixture-generator/pkg/generator"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// selfTest type-checks the fixtures generated for model against the loaded packages, so -selftest
+// fails with the type errors before an uncompilable file is written. With -self the fixtures are
+// checked together with the other files of the package, leaving out the output file they replace.
+func (t target) selfTest(model *generator.Model, pkgs []*packages.Package, outPkg string, opts generator.GenerateOptions) error {
+	// Declarations that don't format are reported by the generation itself
+	opts.FormatError = func(error) {}
+	var b bytes.Buffer
+	if err := generator.GenerateTo(&b, model, outPkg, opts); err != nil {
+		return err
+	}
+	fset := token.NewFileSet()
+	var files []*ast.File
+	if t.Self && len(pkgs) == 1 && pkgs[0].Fset != nil {
+		fset = pkgs[0].Fset
+		out, _ := filepath.Abs(t.Out)
+		for i, f := range pkgs[0].Syntax {
+			if i < len(pkgs[0].CompiledGoFiles) && pkgs[0].CompiledGoFiles[i] == out {
+				continue
+			}
+			files = append(files, f)
+		}
+	}
+	return generator.TypeCheck(fset, b.Bytes(), files, newLoadedImporter(fset, pkgs))
+}
+
+// loadedImporter resolves imports to the packages type-checked while loading, so the fixtures are
+// checked against the very types the model was extracted from, and imports other packages from source
+type loadedImporter struct {
+	loaded   map[string]*types.Package
+	fallback types.Importer
+}
+
+func newLoadedImporter(fset *token.FileSet, pkgs []*packages.Package) types.Importer {
+	imp := loadedImporter{loaded: make(map[string]*types.Package), fallback: importer.ForCompiler(fset, "source", nil)}
+	var add func(p *types.Package)
+	add = func(p *types.Package) {
+		if p == nil || imp.loaded[p.Path()] != nil || !p.Complete() {
+			return
+		}
+		imp.loaded[p.Path()] = p
+		for _, dep := range p.Imports() {
+			add(dep)
+		}
+	}
+	for _, pkg := range pkgs {
+		add(pkg.Types)
+	}
+	return imp
+}
+
+func (imp loadedImporter) Import(path string) (*types.Package, error) {
+	if p, ok := imp.loaded[path]; ok {
+		return p, nil
+	}
+	return imp.fallback.Import(path)
+}
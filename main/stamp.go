@@ -0,0 +1,79 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"fixture-generator/pkg/generator"
+)
+
+// Stamps remember the content hash of the last generation for an output file, so a rerun that would
+// produce the same file can skip formatting and writing it. They live in the user cache directory
+// rather than next to the output to keep generated packages clean. With -incremental the hash is
+// recorded in the output file itself as well, so it survives a cleared cache and other machines.
+
+// stampPath returns where the stamp for the output file out is stored
+func stampPath(out string) (string, bool) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", false
+	}
+	abs, err := filepath.Abs(out)
+	if err != nil {
+		return "", false
+	}
+	sum := sha256.Sum256([]byte(abs))
+	return filepath.Join(dir, "fixture-generator", hex.EncodeToString(sum[:8])), true
+}
+
+// upToDate reports whether out was last generated from content and has not been modified since,
+// or with -incremental whether out records content as its hash
+func (t target) upToDate(out, content string) bool {
+	if t.Incremental {
+		if existing, err := os.ReadFile(out); err == nil && generator.ReadHash(existing) == content {
+			return true
+		}
+	}
+	path, ok := stampPath(out)
+	if !ok {
+		return false
+	}
+	stamp, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	parts := strings.Fields(string(stamp))
+	if len(parts) != 2 || parts[0] != content {
+		return false
+	}
+	fileHash, err := hashFile(out)
+	return err == nil && fileHash == parts[1]
+}
+
+// writeStamp records that out was generated from content. Failures only cost the next run its fast path.
+func writeStamp(out, content string) {
+	path, ok := stampPath(out)
+	if !ok {
+		return
+	}
+	fileHash, err := hashFile(out)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+	os.WriteFile(path, []byte(content+" "+fileHash+"\n"), 0644)
+}
+
+func hashFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
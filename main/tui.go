@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"fixture-generator/pkg/generator"
+)
+
+// pickTypes shows the types of t in an interactive terminal UI and returns the ones picked.
+// It returns no types if the user quits without writing.
+func pickTypes(t target, jobs int, loader loaderFunc) ([]string, error) {
+	m, pkgs, err := t.model(jobs, loader)
+	if err != nil {
+		return nil, err
+	}
+	p := newPicker(m)
+	if len(p.items) == 0 {
+		return nil, fmt.Errorf("no types found")
+	}
+
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("interactive mode needs a terminal: %w", err)
+	}
+	defer tty.Close()
+	restore, err := rawMode(tty)
+	if err != nil {
+		return nil, fmt.Errorf("interactive mode needs a terminal: %w", err)
+	}
+	defer restore()
+
+	rows, cols := termSize(tty)
+	opts := generator.GenerateOptions{
+		TypePrefix: t.TypePrefix,
+		TypeImport: t.typeImport(pkgs),
+		FuncPrefix: t.FuncPrefix,
+		ModStyle:   t.ModStyle == nil || *t.ModStyle,
+
+		ModSignature: t.ModSignature,
+		ModReturn:    t.ModReturn,
+		Sparse:       t.Sparse,
+	}
+	previews := make(map[string]string)
+	preview := func(name string) string {
+		if _, ok := previews[name]; !ok {
+			previews[name] = fixturePreview(m, name, opts)
+		}
+		return previews[name]
+	}
+
+	// Alternate screen and hidden cursor, undone on exit
+	fmt.Fprint(tty, "\x1b[?1049h\x1b[?25l")
+	defer fmt.Fprint(tty, "\x1b[?25h\x1b[?1049l")
+
+	buf := make([]byte, 16)
+	for {
+		var frame bytes.Buffer
+		p.render(&frame, rows, cols, preview(p.items[p.cursor].Name))
+		tty.Write(frame.Bytes())
+
+		n, err := tty.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		switch p.handleKey(string(buf[:n]), rows) {
+		case pickWrite:
+			return p.picked(), nil
+		case pickQuit:
+			return nil, nil
+		}
+	}
+}
+
+// pickItem is a type listed by the picker
+type pickItem struct {
+	Name string
+	Kind string
+}
+
+// picker is the state of the interactive type selection
+type picker struct {
+	items    []pickItem
+	selected map[string]bool
+	cursor   int
+	offset   int // first item shown
+}
+
+type pickAction int
+
+const (
+	pickNone pickAction = iota
+	pickWrite
+	pickQuit
+)
+
+func newPicker(m *generator.Model) *picker {
+	p := &picker{selected: make(map[string]bool)}
+	for _, n := range generator.BuildGraph(m).Nodes {
+		// Only types that get a fixture function can be picked
+		if n.Kind == "struct" || n.Kind == "enum" || n.Kind == "typedef" {
+			if generator.HasFixture(m, n.Name) {
+				p.items = append(p.items, pickItem{Name: n.Name, Kind: n.Kind})
+			}
+		}
+	}
+	return p
+}
+
+// handleKey applies a key press: arrows or j/k move, space toggles, a toggles all,
+// enter or w writes and q, Esc or Ctrl-C quits
+func (p *picker) handleKey(key string, rows int) pickAction {
+	switch key {
+	case "\x1b[A", "k":
+		if p.cursor > 0 {
+			p.cursor--
+		}
+	case "\x1b[B", "j":
+		if p.cursor < len(p.items)-1 {
+			p.cursor++
+		}
+	case " ":
+		name := p.items[p.cursor].Name
+		p.selected[name] = !p.selected[name]
+	case "a":
+		all := len(p.picked()) < len(p.items)
+		for _, item := range p.items {
+			p.selected[item.Name] = all
+		}
+	case "\r", "\n", "w":
+		return pickWrite
+	case "q", "\x1b", "\x03":
+		return pickQuit
+	}
+
+	// Keep the cursor inside the visible part of the list
+	visible := listRows(rows)
+	if p.cursor < p.offset {
+		p.offset = p.cursor
+	}
+	if p.cursor >= p.offset+visible {
+		p.offset = p.cursor - visible + 1
+	}
+	return pickNone
+}
+
+// picked returns the selected types in list order
+func (p *picker) picked() []string {
+	var names []string
+	for _, item := range p.items {
+		if p.selected[item.Name] {
+			names = append(names, item.Name)
+		}
+	}
+	return names
+}
+
+// listRows is how many list entries fit above the preview
+func listRows(rows int) int {
+	return max(3, rows/3)
+}
+
+// render draws the list with the preview of the highlighted type below it
+func (p *picker) render(w io.Writer, rows, cols int, preview string) {
+	fmt.Fprint(w, "\x1b[H\x1b[2J")
+	fmt.Fprintf(w, "Select types (%d of %d)  ↑/↓ move  space toggle  a all  enter write  q quit\n\n", len(p.picked()), len(p.items))
+
+	visible := listRows(rows)
+	for i := p.offset; i < len(p.items) && i < p.offset+visible; i++ {
+		cursor, box := "  ", "[ ]"
+		if i == p.cursor {
+			cursor = "> "
+		}
+		if p.selected[p.items[i].Name] {
+			box = "[x]"
+		}
+		line := fmt.Sprintf("%s%s %s (%s)", cursor, box, p.items[i].Name, p.items[i].Kind)
+		if i == p.cursor {
+			line = "\x1b[7m" + line + "\x1b[0m"
+		}
+		fmt.Fprintf(w, "%s\n", line)
+	}
+
+	fmt.Fprintf(w, "\n%s\n", strings.Repeat("─", max(1, cols-1)))
+	previewRows := rows - visible - 5
+	for i, line := range strings.Split(preview, "\n") {
+		if i >= previewRows {
+			break
+		}
+		line = strings.ReplaceAll(line, "\t", "    ")
+		if len(line) > cols-1 {
+			line = line[:max(0, cols-1)]
+		}
+		fmt.Fprintf(w, "%s\n", line)
+	}
+}
+
+// fixturePreview returns the generated fixture function of the named type
+func fixturePreview(m *generator.Model, name string, opts generator.GenerateOptions) string {
+	code, err := generator.GenerateFormattedWithOptions(m.Subset([]string{name}), "fixtures", opts)
+	if err != nil {
+		return err.Error()
+	}
+	start := strings.Index(code, "func Fixture"+opts.FuncPrefix+name+"(")
+	if start < 0 {
+		return code
+	}
+	code = code[start:]
+	if end := strings.Index(code, "\n}\n"); end >= 0 {
+		code = code[:end+2]
+	}
+	return code
+}
+
+// rawMode switches the terminal to unbuffered input without echo and returns a function restoring it.
+// Signal keys are read as input too, so Ctrl-C quits through handleKey and the terminal is restored.
+func rawMode(tty *os.File) (func(), error) {
+	saved, err := stty(tty, "-g")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := stty(tty, "-icanon", "-echo", "-isig", "min", "1"); err != nil {
+		return nil, err
+	}
+	return func() { stty(tty, strings.TrimSpace(saved)) }, nil
+}
+
+// termSize returns the rows and columns of the terminal, or 24x80 if unknown
+func termSize(tty *os.File) (rows, cols int) {
+	out, err := stty(tty, "size")
+	if err == nil {
+		if _, err := fmt.Sscan(out, &rows, &cols); err == nil && rows > 0 && cols > 0 {
+			return rows, cols
+		}
+	}
+	return 24, 80
+}
+
+func stty(tty *os.File, args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = tty
+	out, err := cmd.Output()
+	return string(out), err
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"runtime/debug"
+	"strings"
+)
+
+// version returns the module version the binary was built as, with the VCS revision it was built
+// from when known, e.g. "v1.4.0" or "(devel) 1a2b3c4d5e6f-dirty"
+func version() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	v := info.Main.Version
+	if v == "" {
+		v = "(devel)"
+	}
+	var revision string
+	var modified bool
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			revision = s.Value
+		case "vcs.modified":
+			modified = s.Value == "true"
+		}
+	}
+	// Versions resolved by the go command already carry the revision (v0.0.0-20240101-1a2b3c4d5e6f)
+	if revision == "" || len(revision) >= 12 && strings.Contains(v, revision[:12]) {
+		return v
+	}
+	if len(revision) > 12 {
+		revision = revision[:12]
+	}
+	if modified {
+		revision += "-dirty"
+	}
+	return v + " " + revision
+}
@@ -0,0 +1,146 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// aggregateFuncs renders an XAggregate struct and ArrangeXAggregate function per configured aggregate
+func aggregateFuncs(m *Model, opts GenerateOptions) string {
+	var b strings.Builder
+	for _, root := range sortedKeys(opts.Aggregates) {
+		b.WriteString(aggregateFunc(m, root, opts.Aggregates[root], opts))
+	}
+	return b.String()
+}
+
+// aggregateFunc renders the aggregate of root and its related members. Their fixtures are wired
+// together: fields named after another member's ID (OrderLine.OrderID) get its ID, and fields
+// referencing another member (OrderLine.Order, Order.Lines) get that member's fixture.
+func aggregateFunc(m *Model, root string, related []string, opts GenerateOptions) string {
+	members := []*Struct{m.Structs[root]}
+	for _, name := range related {
+		if s := m.Structs[name]; s != nil && name != root {
+			members = append(members, s)
+		}
+	}
+	if members[0] == nil {
+		return ""
+	}
+
+	name := opts.FuncPrefix + root + "Aggregate"
+	var names, links []string
+	for _, s := range members {
+		names = append(names, "["+typeName(TypeRef{Kind: "struct", Name: s.Name}, opts)+"]")
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "// %s holds the fixtures of the %s aggregate, see Arrange%s.\n", name, root, name)
+	fmt.Fprintf(&b, "type %s struct {\n", name)
+	for _, s := range members {
+		fmt.Fprintf(&b, "\t%s %s\n", s.Name, fixtureResult(typeName(TypeRef{Kind: "struct", Name: s.Name}, opts), opts))
+	}
+	b.WriteString("}\n\n")
+
+	// IDs first, so the copies taken for value references carry them
+	for _, s := range members {
+		for _, f := range s.Fields {
+			for _, other := range members {
+				if other != s {
+					if id := idField(other, f); id != nil {
+						links = append(links, setField(s, f, "a."+other.Name+"."+getField(other, *id)))
+					}
+				}
+			}
+		}
+	}
+	for _, pass := range []string{"pointer", "value"} {
+		for _, s := range members {
+			for _, f := range s.Fields {
+				for _, other := range members {
+					if other == s {
+						continue
+					}
+					if ref := referenceTo(other, f, pass, opts); ref != "" {
+						links = append(links, setField(s, f, ref))
+					}
+				}
+			}
+		}
+	}
+
+	fmt.Fprintf(&b, "// Arrange%s returns fixtures of %s wired together, with matching IDs and references.\n", name, joinNames(names))
+	fmt.Fprintf(&b, "func Arrange%s() *%s {\n", name, name)
+	fmt.Fprintf(&b, "\ta := &%s{\n", name)
+	for _, s := range members {
+		fmt.Fprintf(&b, "\t\t%s: Fixture%s%s(),\n", s.Name, opts.FuncPrefix, s.Name)
+	}
+	b.WriteString("\t}\n")
+	for _, l := range links {
+		fmt.Fprintf(&b, "\t%s\n", l)
+	}
+	b.WriteString("\treturn a\n}\n\n")
+	return b.String()
+}
+
+// idField returns the ID field of other that f refers to by its name (OrderID or OrderId for Order),
+// if both have the same type
+func idField(other *Struct, f Field) *Field {
+	if f.Name != other.Name+"ID" && f.Name != other.Name+"Id" {
+		return nil
+	}
+	for i, id := range other.Fields {
+		if (id.Name == "ID" || id.Name == "Id") && TypeName(id.Type) == TypeName(f.Type) {
+			return &other.Fields[i]
+		}
+	}
+	return nil
+}
+
+// referenceTo returns the expression f is set to to reference the fixture of other, or "" if f doesn't
+// hold one. The pointer pass links pointers, the value pass copies and slices of the fixture.
+func referenceTo(other *Struct, f Field, pass string, opts GenerateOptions) string {
+	t := f.Type
+	slice := t.Kind == "slice" && t.Elem != nil
+	if slice {
+		t = *t.Elem
+	}
+	pointer := t.Kind == "pointer" && t.Elem != nil
+	if pointer {
+		t = *t.Elem
+	}
+	if t.Kind != "struct" || t.Name != other.Name || t.Pkg != "" && opts.FixturePackages[t.Pkg] != "" {
+		return ""
+	}
+	if (pointer && !slice) != (pass == "pointer") {
+		return ""
+	}
+
+	ref := "a." + other.Name
+	switch {
+	case pointer && !returnsPointer(opts):
+		ref = "&" + ref
+	case !pointer && returnsPointer(opts):
+		ref = "*" + ref
+	}
+	if slice {
+		return typeName(f.Type, opts) + "{" + ref + "}"
+	}
+	return ref
+}
+
+// setField renders the statement setting f of the member s, through its setter for opaque API messages
+func setField(s *Struct, f Field, value string) string {
+	if s.Builder != "" {
+		return fmt.Sprintf("a.%s.Set%s(%s)", s.Name, f.Name, value)
+	}
+	return fmt.Sprintf("a.%s.%s = %s", s.Name, f.Name, value)
+}
+
+// getField renders reading f of the member s, through its getter for opaque API messages
+func getField(s *Struct, f Field) string {
+	if s.Builder != "" {
+		return "Get" + f.Name + "()"
+	}
+	return f.Name
+}
@@ -0,0 +1,190 @@
+package generator
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Pseudonyms are picked from these by the hash of the real value, so a name maps to the same pseudonym
+// wherever it appears in a sample
+var (
+	pseudoFirstNames = []string{"Alex", "Blake", "Casey", "Dana", "Eden", "Frankie", "Gray", "Harper", "Indigo", "Jordan", "Kai", "Logan", "Morgan", "Noel", "Oakley", "Parker", "Quinn", "Riley", "Sage", "Taylor"}
+	pseudoLastNames  = []string{"Adler", "Brooks", "Carter", "Dalton", "Ellis", "Fisher", "Garner", "Hayes", "Irving", "Jensen", "Keller", "Lowell", "Mercer", "Nolan", "Olsen", "Porter", "Quincy", "Reed", "Sutton", "Turner"}
+)
+
+// AnonymizeJSON rewrites the personal data of a sample JSON payload so production-shaped data can become
+// committed fixtures: names get pseudonyms, emails and phone numbers are replaced, IDs are hashed and
+// dates are shifted into the past by the same number of days. Every replacement is derived from salt
+// and the real value only, so equal values stay equal (IDs still match across objects, intervals
+// between dates are kept) and regenerating from the same sample gives the same fixtures.
+func AnonymizeJSON(data []byte, salt string) ([]byte, error) {
+	root, err := decodeJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("json parse error: %w", err)
+	}
+	a := anonymizer{salt: salt}
+	a.shift = -time.Duration(1+a.hash("date shift")%365) * 24 * time.Hour
+	a.node("", root)
+	var b bytes.Buffer
+	encodeJSON(&b, root)
+	return b.Bytes(), nil
+}
+
+type anonymizer struct {
+	salt  string
+	shift time.Duration
+}
+
+// hash returns a number derived from the salt and value
+func (a anonymizer) hash(value string) uint64 {
+	sum := sha256.Sum256([]byte(a.salt + "\x00" + value))
+	return binary.BigEndian.Uint64(sum[:8])
+}
+
+// hexHash returns n hex digits derived from the salt and value
+func (a anonymizer) hexHash(value string, n int) string {
+	sum := sha256.Sum256([]byte(a.salt + "\x00" + value))
+	return hex.EncodeToString(sum[:])[:n]
+}
+
+// node anonymizes n in place, key being the object key it is the value of (or the key of the array
+// holding it)
+func (a anonymizer) node(key string, n *jsonNode) {
+	switch n.Kind {
+	case "object":
+		for _, k := range n.Keys {
+			a.node(k, n.Fields[k])
+		}
+	case "array":
+		for _, item := range n.Items {
+			a.node(key, item)
+		}
+	case "string":
+		n.Value = a.string(key, n.Value)
+	case "number":
+		if isIDKey(key) {
+			if _, err := strconv.ParseInt(n.Value, 10, 64); err == nil {
+				n.Value = strconv.FormatUint(1+a.hash(n.Value)%1_000_000_000, 10)
+			}
+		}
+	}
+}
+
+// string returns the replacement of the string v held by key
+func (a anonymizer) string(key, v string) string {
+	if v == "" {
+		return v
+	}
+	if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+		return t.Add(a.shift).Format(time.RFC3339Nano)
+	}
+	if t, err := time.Parse(time.DateOnly, v); err == nil {
+		return t.Add(a.shift).Format(time.DateOnly)
+	}
+	h := a.hash(v)
+	idKey := isIDKey(key)
+	key = normalizeKey(key)
+	switch {
+	case strings.Contains(key, "email") || strings.Contains(v, "@") && strings.Contains(v[strings.Index(v, "@"):], "."):
+		return "user-" + a.hexHash(v, 8) + "@example.com"
+	case strings.Contains(key, "phone"):
+		return fmt.Sprintf("+1555%07d", h%10_000_000)
+	case idKey && isUUID(v):
+		x := a.hexHash(v, 32)
+		return x[:8] + "-" + x[8:12] + "-" + x[12:16] + "-" + x[16:20] + "-" + x[20:]
+	case idKey:
+		return a.hexHash(v, min(len(v), 64))
+	case key == "firstname" || key == "givenname":
+		return pseudoFirstNames[h%uint64(len(pseudoFirstNames))]
+	case key == "lastname" || key == "surname" || key == "familyname":
+		return pseudoLastNames[h%uint64(len(pseudoLastNames))]
+	case key == "name" || strings.HasSuffix(key, "fullname") || key == "displayname" || key == "username":
+		first := pseudoFirstNames[h%uint64(len(pseudoFirstNames))]
+		last := pseudoLastNames[h/uint64(len(pseudoFirstNames))%uint64(len(pseudoLastNames))]
+		if key == "username" {
+			return strings.ToLower(first + "." + last)
+		}
+		return first + " " + last
+	}
+	return v
+}
+
+// isIDKey reports whether a JSON key names an identifier: "id", "uuid", "userId", "UserID" or "order_id"
+func isIDKey(key string) bool {
+	lower := strings.ToLower(key)
+	return lower == "id" || lower == "uuid" || strings.HasSuffix(key, "Id") || strings.HasSuffix(key, "ID") ||
+		strings.HasSuffix(lower, "_id") || strings.HasSuffix(lower, "-id")
+}
+
+// isUUID reports whether v is formatted as a UUID, which hashed IDs keep
+func isUUID(v string) bool {
+	if len(v) != 36 {
+		return false
+	}
+	for i, r := range v {
+		if i == 8 || i == 13 || i == 18 || i == 23 {
+			if r != '-' {
+				return false
+			}
+		} else if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// encodeJSON writes n as indented JSON, keeping the key order of the document it was decoded from
+func encodeJSON(b *bytes.Buffer, n *jsonNode) {
+	encodeJSONIndent(b, n, "")
+	b.WriteString("\n")
+}
+
+func encodeJSONIndent(b *bytes.Buffer, n *jsonNode, indent string) {
+	switch n.Kind {
+	case "object":
+		if len(n.Keys) == 0 {
+			b.WriteString("{}")
+			return
+		}
+		b.WriteString("{\n")
+		for i, k := range n.Keys {
+			key, _ := json.Marshal(k)
+			fmt.Fprintf(b, "%s  %s: ", indent, key)
+			encodeJSONIndent(b, n.Fields[k], indent+"  ")
+			if i < len(n.Keys)-1 {
+				b.WriteString(",")
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString(indent + "}")
+	case "array":
+		if len(n.Items) == 0 {
+			b.WriteString("[]")
+			return
+		}
+		b.WriteString("[\n")
+		for i, item := range n.Items {
+			b.WriteString(indent + "  ")
+			encodeJSONIndent(b, item, indent+"  ")
+			if i < len(n.Items)-1 {
+				b.WriteString(",")
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString(indent + "]")
+	case "string":
+		s, _ := json.Marshal(n.Value)
+		b.Write(s)
+	case "number", "bool":
+		b.WriteString(n.Value)
+	default:
+		b.WriteString("null")
+	}
+}
@@ -0,0 +1,61 @@
+package generator
+
+// Fields of type *anypb.Any get an empty Any by default, or the fixture of the message configured
+// for them in GenerateOptions.AnyPayloads, packed with the generated PackFixtureAny.
+func init() {
+	ExternalTypes["Any"] = ExternalType{
+		Import:    `anypb "google.golang.org/protobuf/types/known/anypb"`,
+		Requires:  []string{`"google.golang.org/protobuf/proto"`},
+		PkgPath:   "google.golang.org/protobuf/types/known/anypb",
+		ValueFunc: anyValue,
+		Pointer:   true,
+	}
+}
+
+const anyHelper = `// PackFixtureAny packs a fixture message into an anypb.Any
+func PackFixtureAny[T proto.Message](msg T) *anypb.Any {
+	packed, err := anypb.New(msg)
+	if err != nil {
+		panic(err)
+	}
+	return packed
+}
+
+`
+
+// anyHelperNoGenerics is anyHelper for Go versions before 1.18
+const anyHelperNoGenerics = `// PackFixtureAny packs a fixture message into an anypb.Any
+func PackFixtureAny(msg proto.Message) *anypb.Any {
+	packed, err := anypb.New(msg)
+	if err != nil {
+		panic(err)
+	}
+	return packed
+}
+
+`
+
+func anyValue(m *Model, fieldName, structName string, opts GenerateOptions) string {
+	payload, ok := opts.AnyPayloads[structName+"."+fieldName]
+	if !ok {
+		payload = opts.AnyPayloads[fieldName]
+	}
+	if payload == "" {
+		return "&anypb.Any{}"
+	}
+	if returnsPointer(opts) {
+		return "PackFixtureAny(Fixture" + opts.FuncPrefix + payload + "())"
+	}
+	return "PackFixtureAny(" + ptrFunc(TypeRef{Kind: "struct", Name: payload}, opts) + "(Fixture" + opts.FuncPrefix + payload + "()))"
+}
+
+// usesExternal reports whether a struct field of m has the named external type
+func usesExternal(m *Model, name string) bool {
+	used := make(map[string]bool)
+	for _, s := range m.Structs {
+		for _, f := range s.Fields {
+			collectExternalTypes(f.Type, used)
+		}
+	}
+	return used[name]
+}
@@ -0,0 +1,35 @@
+package generator
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"strconv"
+)
+
+// resolveArrayLens replaces the constant expressions giving the length of arrays, like [N]int or
+// [2 * N]int, by their value, as exprToTypeRef only reads literals. The files are type-checked
+// without their imports, so the lengths taken from constants of other packages stay unresolved.
+func resolveArrayLens(fset *token.FileSet, files []*ast.File) {
+	info := &types.Info{Types: make(map[ast.Expr]types.TypeAndValue)}
+	conf := types.Config{Error: func(error) {}}
+	conf.Check("", fset, files, info)
+	for _, f := range files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			array, ok := n.(*ast.ArrayType)
+			if !ok || array.Len == nil {
+				return true
+			}
+			if _, ok := array.Len.(*ast.BasicLit); ok {
+				return true
+			}
+			if tv, ok := info.Types[array.Len]; ok && tv.Value != nil {
+				if v, ok := constant.Int64Val(constant.ToInt(tv.Value)); ok {
+					array.Len = &ast.BasicLit{ValuePos: array.Len.Pos(), Kind: token.INT, Value: strconv.FormatInt(v, 10)}
+				}
+			}
+			return true
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// assertImports returns the imports of assertFuncs
+func assertImports(opts GenerateOptions) []string {
+	imports := []string{`"go/token"`, `"github.com/google/go-cmp/cmp"`}
+	if opts.Asserts == "protocmp" {
+		imports = append(imports, `"google.golang.org/protobuf/testing/protocmp"`)
+	}
+	return imports
+}
+
+// assertFuncs renders an AssertXEqual(t, expected, actual) per struct reporting a go-cmp diff of the
+// two to t, for tests comparing against fixtures. Like testify's assertions they take anything with
+// an Errorf and return whether the values are equal. Unexported fields are left out of the
+// comparison; with opts.Asserts == "protocmp" protobuf messages are compared with protocmp, which
+// knows their internal state.
+func assertFuncs(m *Model, opts GenerateOptions) string {
+	var b strings.Builder
+	b.WriteString("// assertT is the part of *testing.T the assertions use, like the TestingT of testify's assert\n")
+	b.WriteString("type assertT interface {\n\tErrorf(format string, args ...interface{})\n}\n\n")
+	b.WriteString("// assertOptions compare fixtures by their exported fields\n")
+	b.WriteString("var assertOptions = []cmp.Option{\n")
+	if opts.Asserts == "protocmp" {
+		b.WriteString("\tprotocmp.Transform(),\n")
+	}
+	b.WriteString("\tcmp.FilterPath(func(p cmp.Path) bool {\n")
+	b.WriteString("\t\tf, ok := p.Last().(cmp.StructField)\n")
+	b.WriteString("\t\treturn ok && !token.IsExported(f.Name())\n")
+	b.WriteString("\t}, cmp.Ignore()),\n")
+	b.WriteString("}\n\n")
+	for _, name := range fixtureStructs(m) {
+		typ := typeName(TypeRef{Kind: "struct", Name: name}, opts)
+		fmt.Fprintf(&b, "// Assert%s%sEqual reports the differences between expected and actual to t, returning whether there are none\n", opts.FuncPrefix, name)
+		fmt.Fprintf(&b, "func Assert%s%sEqual(t assertT, expected, actual *%s) bool {\n", opts.FuncPrefix, name, typ)
+		b.WriteString("\tif h, ok := t.(interface{ Helper() }); ok {\n\t\th.Helper()\n\t}\n")
+		b.WriteString("\tif diff := cmp.Diff(expected, actual, assertOptions...); diff != \"\" {\n")
+		fmt.Fprintf(&b, "\t\tt.Errorf(\"%s differs (-expected +actual):\\n%%s\", diff)\n", name)
+		b.WriteString("\t\treturn false\n\t}\n\treturn true\n}\n\n")
+	}
+	return b.String()
+}
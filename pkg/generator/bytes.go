@@ -0,0 +1,40 @@
+package generator
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+)
+
+// byteSlice reports whether t is []byte, or []uint8 which is the same type
+func byteSlice(t TypeRef) bool {
+	return t.Kind == "slice" && t.Elem != nil && t.Elem.Kind == "primitive" && (t.Elem.Name == "byte" || t.Elem.Name == "uint8")
+}
+
+// byteSliceValue renders the value of a []byte field: []byte("Field"), or with ByteLength that many
+// bytes hashed from the struct and field name, which stay the same for every run
+func byteSliceValue(fieldName, structName string, opts GenerateOptions) string {
+	if opts.ByteLength == 0 {
+		return fmt.Sprintf("[]byte(%q)", fieldName)
+	}
+	data := byteSliceData(fieldName, structName, opts)
+	values := make([]string, len(data))
+	for i, v := range data {
+		values[i] = fmt.Sprintf("0x%02x", v)
+	}
+	return "[]byte{" + strings.Join(values, ", ") + "}"
+}
+
+// byteSliceData returns the bytes byteSliceValue renders
+func byteSliceData(fieldName, structName string, opts GenerateOptions) []byte {
+	if opts.ByteLength == 0 {
+		return []byte(fieldName)
+	}
+	data := make([]byte, 0, opts.ByteLength)
+	sum := sha256.Sum256([]byte(structName + "." + fieldName))
+	for len(data) < opts.ByteLength {
+		data = append(data, sum[:min(len(sum), opts.ByteLength-len(data))]...)
+		sum = sha256.Sum256(sum[:])
+	}
+	return data
+}
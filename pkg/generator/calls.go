@@ -0,0 +1,46 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// callPairs returns the names X of the request and response structs of m named XRequest and
+// XResponse, like the messages of the gRPC method X of a service, sorted. Methods whose helper would
+// be named like the fixture of a struct XCall are left out.
+func callPairs(m *Model) []string {
+	var calls []string
+	for _, name := range fixtureStructs(m) {
+		call, ok := strings.CutSuffix(name, "Request")
+		if !ok || call == "" {
+			continue
+		}
+		if resp := m.Structs[call+"Response"]; resp == nil || len(resp.TypeParams) > 0 || m.Structs[call+"Call"] != nil {
+			continue
+		}
+		calls = append(calls, call)
+	}
+	return calls
+}
+
+// callFuncs renders a FixtureXCall() per request and response pair X of m returning both fixtures,
+// so tests of the handler of a gRPC method get a matching pair in one call. Like the registry they
+// return pointers, which protobuf messages are passed as.
+func callFuncs(m *Model, opts GenerateOptions) string {
+	var b strings.Builder
+	for _, call := range callPairs(m) {
+		req := typeName(TypeRef{Kind: "struct", Name: call + "Request"}, opts)
+		resp := typeName(TypeRef{Kind: "struct", Name: call + "Response"}, opts)
+		reqFixture := "Fixture" + opts.FuncPrefix + call + "Request()"
+		respFixture := "Fixture" + opts.FuncPrefix + call + "Response()"
+		fmt.Fprintf(&b, "// Fixture%s%sCall returns the fixtures of the request and response of %s\n", opts.FuncPrefix, call, call)
+		fmt.Fprintf(&b, "func Fixture%s%sCall() (*%s, *%s) {\n", opts.FuncPrefix, call, req, resp)
+		if returnsPointer(opts) {
+			fmt.Fprintf(&b, "\treturn %s, %s\n", reqFixture, respFixture)
+		} else {
+			fmt.Fprintf(&b, "\treq, resp := %s, %s\n\treturn &req, &resp\n", reqFixture, respFixture)
+		}
+		b.WriteString("}\n\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,38 @@
+package generator
+
+import "fmt"
+
+// timeLiteralDefault is the time fixtures are set to without a clock
+const timeLiteralDefault = "time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)"
+
+// nowFunc returns the name of the settable func time fields read from with GenerateOptions.Clock
+func nowFunc(opts GenerateOptions) string {
+	return "Fixture" + opts.FuncPrefix + "Now"
+}
+
+// timeValue is the default of time.Time fields: the literal, or a call of the clock func with Clock
+func timeValue(m *Model, fieldName, structName string, opts GenerateOptions) string {
+	if opts.Clock {
+		return nowFunc(opts) + "()"
+	}
+	return timeLiteralDefault
+}
+
+// timestampValue is the default of *timestamppb.Timestamp fields, taken from timeValue
+func timestampValue(m *Model, fieldName, structName string, opts GenerateOptions) string {
+	return "timestamppb.New(" + timeValue(m, fieldName, structName, opts) + ")"
+}
+
+// hasClock reports whether the clock func is generated: with Clock, for models with time fields
+func hasClock(m *Model, opts GenerateOptions) bool {
+	return opts.Clock && (usesExternal(m, "Time") || usesExternal(m, "Timestamp"))
+}
+
+// clockFunc renders the package-level func time fields read from. Test suites freezing time point it
+// at their clock (FixtureNow = clock.Now), and every fixture built afterwards uses that time.
+func clockFunc(opts GenerateOptions) string {
+	name := nowFunc(opts)
+	return fmt.Sprintf("// %s returns the time the time fields of fixtures are set to. Point it at the clock of\n"+
+		"// a test suite that freezes time to build fixtures at that time.\n"+
+		"var %s = func() time.Time { return %s }\n\n", name, name, timeLiteralDefault)
+}
@@ -0,0 +1,47 @@
+package generator
+
+import (
+	"bytes"
+	"slices"
+)
+
+// customFixture reports whether the fixture of the type name is written by hand, so it isn't generated
+func customFixture(name string, opts GenerateOptions) bool {
+	return slices.Contains(opts.CustomFixtures, "Fixture"+opts.FuncPrefix+name)
+}
+
+// generateCustomDecls is generateDecls for output leaving out CustomFixtures. Since a hand-written
+// fixture may have been the only one using an import, the declarations are held until the imports
+// the rest of them use are known.
+func generateCustomDecls(m *Model, pkgName string, opts GenerateOptions, emit func(decl []byte) error) error {
+	var header []byte
+	var decls [][]byte
+	var body bytes.Buffer
+	err := generateOwnedDecls(m, pkgName, opts, func(owner string, decl []byte) error {
+		if owner == headerOwner {
+			header = bytes.Clone(decl)
+			return nil
+		}
+		decls = append(decls, bytes.Clone(decl))
+		body.Write(decl)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	comments, imports, err := parseHeader(header)
+	if err != nil {
+		return err
+	}
+	var b bytes.Buffer
+	writeFileHeader(&b, comments, pkgName, imports, body.Bytes())
+	if err := emit(b.Bytes()); err != nil {
+		return err
+	}
+	for _, decl := range decls {
+		if err := emit(decl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package generator
+
+import (
+	"go/ast"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// ExtractDeps extracts the packages imported by pkgs whose import path is one of paths, keyed by
+// it, for Model.Deps. The packages have to be loaded with their syntax and type information, by
+// packages.NeedDeps or linked into the Imports of pkgs; those that aren't are left out.
+func ExtractDeps(pkgs []*packages.Package, paths []string) map[string]*Model {
+	want := make(map[string]bool)
+	for _, path := range paths {
+		want[path] = true
+	}
+	deps := make(map[string]*Model)
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		if want[pkg.PkgPath] && deps[pkg.PkgPath] == nil && pkg.TypesInfo != nil {
+			deps[pkg.PkgPath] = ExtractPackage(pkg)
+		}
+	})
+	if len(deps) == 0 {
+		return nil
+	}
+	return deps
+}
+
+// depValue renders a value of the foreign type t from the model of its package in Deps: a literal
+// setting the exported fields of a struct that are of basic types or of types of the same package,
+// or the first constant of an enum. It returns false if the type wasn't loaded. seen holds the
+// structs being rendered, whose references back are left zero.
+func depValue(m *Model, t TypeRef, opts GenerateOptions, seen map[string]bool) (string, bool) {
+	dep := m.Deps[t.Pkg]
+	if dep == nil {
+		return "", false
+	}
+	switch t.Kind {
+	case "enum":
+		if e, ok := dep.Enums[t.Name]; ok {
+			for _, v := range e.Values {
+				if ast.IsExported(v) {
+					return packageAlias(t.Pkg) + "." + v, true
+				}
+			}
+		}
+	case "struct":
+		s, ok := dep.Structs[t.Name]
+		if !ok || len(s.TypeParams) > 0 || seen[t.Name] {
+			return "", false
+		}
+		seen[t.Name] = true
+		defer delete(seen, t.Name)
+		var fields []string
+		for _, f := range s.Fields {
+			if !ast.IsExported(f.Name) || f.Embedded {
+				continue
+			}
+			if v, ok := depFieldValue(m, f.Type, t.Pkg, f.Name, s.Name, opts, seen); ok {
+				fields = append(fields, f.Name+": "+v)
+			}
+		}
+		return typeName(t, opts) + "{" + strings.Join(fields, ", ") + "}", true
+	}
+	return "", false
+}
+
+// depFieldValue renders the value of the field fieldName of type t of a struct of the package pkg
+// in Deps, or returns false for types whose value would need other imports
+func depFieldValue(m *Model, t TypeRef, pkg, fieldName, structName string, opts GenerateOptions, seen map[string]bool) (string, bool) {
+	switch t.Kind {
+	case "primitive":
+		v := genPrimitiveValue(t.Name, fieldName, structName)
+		return v, v != "nil"
+	case "struct", "enum":
+		if t.Pkg == pkg {
+			return depValue(m, t, opts, seen)
+		}
+	case "pointer":
+		if t.Elem != nil && t.Elem.Kind == "struct" && t.Elem.Pkg == pkg {
+			v, ok := depValue(m, *t.Elem, opts, seen)
+			return "&" + v, ok
+		}
+	case "slice":
+		if t.Elem == nil {
+			return "", false
+		}
+		if byteSlice(t) {
+			return byteSliceValue(fieldName, structName, opts), true
+		}
+		if v, ok := depFieldValue(m, *t.Elem, pkg, fieldName, structName, opts, seen); ok && t.Elem.Kind != "pointer" && t.Elem.Kind != "slice" {
+			return "[]" + typeName(*t.Elem, opts) + "{" + v + "}", true
+		}
+	}
+	return "", false
+}
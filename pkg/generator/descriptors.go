@@ -0,0 +1,361 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// wellKnownPackages are the Go packages of the well-known types, which declare no go_package
+var wellKnownPackages = map[string]string{
+	"google/protobuf/any.proto":        "google.golang.org/protobuf/types/known/anypb",
+	"google/protobuf/duration.proto":   "google.golang.org/protobuf/types/known/durationpb",
+	"google/protobuf/empty.proto":      "google.golang.org/protobuf/types/known/emptypb",
+	"google/protobuf/field_mask.proto": "google.golang.org/protobuf/types/known/fieldmaskpb",
+	"google/protobuf/struct.proto":     "google.golang.org/protobuf/types/known/structpb",
+	"google/protobuf/timestamp.proto":  "google.golang.org/protobuf/types/known/timestamppb",
+	"google/protobuf/wrappers.proto":   "google.golang.org/protobuf/types/known/wrapperspb",
+}
+
+// protoScalars are the Go types protoc-gen-go generates for scalar fields
+var protoScalars = map[descriptorpb.FieldDescriptorProto_Type]string{
+	descriptorpb.FieldDescriptorProto_TYPE_DOUBLE:   "float64",
+	descriptorpb.FieldDescriptorProto_TYPE_FLOAT:    "float32",
+	descriptorpb.FieldDescriptorProto_TYPE_INT64:    "int64",
+	descriptorpb.FieldDescriptorProto_TYPE_SINT64:   "int64",
+	descriptorpb.FieldDescriptorProto_TYPE_SFIXED64: "int64",
+	descriptorpb.FieldDescriptorProto_TYPE_UINT64:   "uint64",
+	descriptorpb.FieldDescriptorProto_TYPE_FIXED64:  "uint64",
+	descriptorpb.FieldDescriptorProto_TYPE_INT32:    "int32",
+	descriptorpb.FieldDescriptorProto_TYPE_SINT32:   "int32",
+	descriptorpb.FieldDescriptorProto_TYPE_SFIXED32: "int32",
+	descriptorpb.FieldDescriptorProto_TYPE_UINT32:   "uint32",
+	descriptorpb.FieldDescriptorProto_TYPE_FIXED32:  "uint32",
+	descriptorpb.FieldDescriptorProto_TYPE_BOOL:     "bool",
+	descriptorpb.FieldDescriptorProto_TYPE_STRING:   "string",
+}
+
+// protoType is a message or enum of a descriptor set with the Go names protoc-gen-go gives it
+type protoType struct {
+	GoName string
+	Pkg    string
+	Enum   bool
+	// MapEntry is set for the synthetic entry messages of map fields
+	MapEntry bool
+}
+
+// ParseDescriptorSet reads a FileDescriptorSet (protoc --descriptor_set_out) into a Model of the Go
+// types protoc-gen-go generates for it, so oneofs, enums and maps come from the descriptors instead of
+// naming conventions. Fixtures are generated for the files of the Go package of the last file, the one
+// protoc was run on; messages of other files are referenced as types of their go_package. Comments
+// become type docs with --include_source_info.
+func ParseDescriptorSet(data []byte) (*Model, error) {
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("descriptor set: %w", err)
+	}
+	if len(set.File) == 0 {
+		return nil, fmt.Errorf("descriptor set has no files")
+	}
+	return ParseDescriptors(set.File, goPackage(set.File[len(set.File)-1])), nil
+}
+
+// ParseDescriptors builds the Model of the files of files whose go_package is pkg, as ParseDescriptorSet
+// does for the package of its last file. files must include the files they import.
+func ParseDescriptors(files []*descriptorpb.FileDescriptorProto, pkg string) *Model {
+	types := make(map[string]protoType)
+	for _, fd := range files {
+		declared := goPackage(fd)
+		prefix := ""
+		if fd.GetPackage() != "" {
+			prefix = "." + fd.GetPackage()
+		}
+		var walk func(scope string, msgs []*descriptorpb.DescriptorProto, enums []*descriptorpb.EnumDescriptorProto)
+		walk = func(scope string, msgs []*descriptorpb.DescriptorProto, enums []*descriptorpb.EnumDescriptorProto) {
+			for _, e := range enums {
+				full := scope + "." + e.GetName()
+				types[full] = protoType{GoName: protoGoName(fd, full), Pkg: declared, Enum: true}
+			}
+			for _, msg := range msgs {
+				full := scope + "." + msg.GetName()
+				types[full] = protoType{GoName: protoGoName(fd, full), Pkg: declared, MapEntry: msg.GetOptions().GetMapEntry()}
+				walk(full, msg.NestedType, msg.EnumType)
+			}
+		}
+		walk(prefix, fd.MessageType, fd.EnumType)
+	}
+
+	m := NewModel()
+	for _, fd := range files {
+		if goPackage(fd) != pkg {
+			continue
+		}
+		p := &protoFile{fd: fd, types: types, pkg: pkg, m: m, comments: protoComments(fd)}
+		for i, e := range fd.EnumType {
+			p.enum(e, "", []int32{5, int32(i)})
+		}
+		for i, msg := range fd.MessageType {
+			p.message(msg, "", []int32{4, int32(i)})
+		}
+	}
+	return m
+}
+
+// protoFile adds the types of one file of a descriptor set to m
+type protoFile struct {
+	fd       *descriptorpb.FileDescriptorProto
+	types    map[string]protoType
+	pkg      string
+	m        *Model
+	comments map[string]*descriptorpb.SourceCodeInfo_Location
+}
+
+// scope returns the full name of the message or enum name declared in parent, or at the top level
+func (p *protoFile) scope(parent, name string) string {
+	if parent != "" {
+		return parent + "." + name
+	}
+	if p.fd.GetPackage() != "" {
+		return "." + p.fd.GetPackage() + "." + name
+	}
+	return "." + name
+}
+
+// source returns the declaration of the element at path and its leading comment
+func (p *protoFile) source(path []int32) (*Source, string) {
+	src := &Source{Pkg: p.pkg, File: p.fd.GetName()}
+	loc := p.comments[pathKey(path)]
+	if loc == nil {
+		return src, ""
+	}
+	if len(loc.Span) > 0 {
+		src.Line = int(loc.Span[0]) + 1
+	}
+	var lines []string
+	for _, line := range strings.Split(loc.GetLeadingComments(), "\n") {
+		lines = append(lines, strings.TrimPrefix(line, " "))
+	}
+	return src, strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+func (p *protoFile) enum(e *descriptorpb.EnumDescriptorProto, parent string, path []int32) {
+	full := p.scope(parent, e.GetName())
+	enum := &Enum{Name: p.types[full].GoName}
+	enum.Source, enum.Doc = p.source(path)
+	// Values of nested enums are prefixed with the name of the message, not of the enum
+	valuePrefix := enum.Name
+	if parent != "" {
+		valuePrefix = p.types[parent].GoName
+	}
+	for _, v := range e.Value {
+		enum.Values = append(enum.Values, valuePrefix+"_"+v.GetName())
+		enum.Labels = append(enum.Labels, v.GetName())
+	}
+	p.m.Enums[enum.Name] = enum
+}
+
+func (p *protoFile) message(msg *descriptorpb.DescriptorProto, parent string, path []int32) {
+	full := p.scope(parent, msg.GetName())
+	if p.types[full].MapEntry {
+		return
+	}
+	s := &Struct{Name: p.types[full].GoName}
+	s.Source, s.Doc = p.source(path)
+
+	// protoc-gen-go renames fields clashing with the methods of messages or the getters of other fields
+	used := map[string]bool{
+		"Reset": true, "String": true, "ProtoMessage": true, "Marshal": true, "Unmarshal": true,
+		"ExtensionRangeArray": true, "ExtensionMap": true, "Descriptor": true,
+	}
+	unique := func(name string, getter bool) string {
+		for used[name] || getter && used["Get"+name] {
+			name += "_"
+		}
+		used[name] = true
+		used["Get"+name] = getter
+		return name
+	}
+	names := make([]string, len(msg.Field))
+	for i, f := range msg.Field {
+		names[i] = unique(goCamelCase(f.GetName()), true)
+	}
+	oneofNames := make([]string, len(msg.OneofDecl))
+	for i, o := range msg.OneofDecl {
+		if !syntheticOneof(msg, int32(i)) {
+			oneofNames[i] = unique(goCamelCase(o.GetName()), false)
+		}
+	}
+
+	seen := make(map[int32]bool)
+	for i, f := range msg.Field {
+		field := Field{Name: names[i], Type: p.fieldType(f, false)}
+		if f.GetName() != field.Name {
+			field.JSONName = f.GetName()
+		}
+		if f.OneofIndex == nil || f.GetProto3Optional() {
+			s.Fields = append(s.Fields, field)
+			continue
+		}
+		// A oneof is a field of the interface type its wrapper structs implement, in place of the first of its fields
+		idx := f.GetOneofIndex()
+		iface := "is" + s.Name + "_" + oneofNames[idx]
+		if !seen[idx] {
+			seen[idx] = true
+			s.Fields = append(s.Fields, Field{Name: oneofNames[idx], Type: TypeRef{Kind: "oneof", Name: iface}})
+			p.m.OneOfs[iface] = ""
+		}
+		wrapper := s.Name + "_" + field.Name
+		for p.declared(wrapper) {
+			wrapper += "_"
+		}
+		field.Type = p.fieldType(f, true)
+		src, _ := p.source(append(path[:len(path):len(path)], 2, int32(i)))
+		p.m.Structs[wrapper] = &Struct{Name: wrapper, Fields: []Field{field}, Source: src}
+		p.m.AddOneOfVariant(iface, wrapper)
+	}
+	p.m.Structs[s.Name] = s
+
+	for i, e := range msg.EnumType {
+		p.enum(e, full, append(path[:len(path):len(path)], 4, int32(i)))
+	}
+	for i, nested := range msg.NestedType {
+		p.message(nested, full, append(path[:len(path):len(path)], 3, int32(i)))
+	}
+}
+
+// declared reports whether a message or enum of the file has the Go name name
+func (p *protoFile) declared(name string) bool {
+	for _, t := range p.types {
+		if t.GoName == name && t.Pkg == p.pkg {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldType returns the Go type of field f, as a field of a oneof wrapper with inOneof
+func (p *protoFile) fieldType(f *descriptorpb.FieldDescriptorProto, inOneof bool) TypeRef {
+	var t TypeRef
+	switch f.GetType() {
+	case descriptorpb.FieldDescriptorProto_TYPE_BYTES:
+		t = TypeRef{Kind: "slice", Name: "byte", Elem: &TypeRef{Kind: "primitive", Name: "byte"}}
+	case descriptorpb.FieldDescriptorProto_TYPE_ENUM:
+		ref := p.types[f.GetTypeName()]
+		t = TypeRef{Kind: "enum", Name: ref.GoName}
+		if ref.Pkg != p.pkg {
+			t.Pkg = ref.Pkg
+		}
+	case descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, descriptorpb.FieldDescriptorProto_TYPE_GROUP:
+		ref := p.types[f.GetTypeName()]
+		if ref.MapEntry {
+			// Maps have no fixture values, like map fields of Go code
+			return TypeRef{Kind: "unknown"}
+		}
+		elem := TypeRef{Kind: "struct", Name: ref.GoName}
+		if ext, ok := ExternalTypes[ref.GoName]; ok && ext.PkgPath == ref.Pkg {
+			elem = TypeRef{Kind: "external", Name: ref.GoName, Pkg: ref.Pkg}
+		} else if ref.Pkg != p.pkg {
+			elem.Pkg = ref.Pkg
+		}
+		t = TypeRef{Kind: "pointer", Elem: &elem}
+	default:
+		t = TypeRef{Kind: "primitive", Name: protoScalars[f.GetType()]}
+	}
+	if f.GetLabel() == descriptorpb.FieldDescriptorProto_LABEL_REPEATED {
+		return TypeRef{Kind: "slice", Name: t.Name, Elem: &t}
+	}
+	if !inOneof && t.Kind != "pointer" && t.Kind != "slice" && p.explicitPresence(f) {
+		return TypeRef{Kind: "pointer", Elem: &t}
+	}
+	return t
+}
+
+// explicitPresence reports whether the scalar field f is generated as a pointer to tell unset from zero
+func (p *protoFile) explicitPresence(f *descriptorpb.FieldDescriptorProto) bool {
+	if f.GetProto3Optional() {
+		return true
+	}
+	switch p.fd.GetSyntax() {
+	case "proto3":
+		return false
+	case "editions":
+		presence := p.fd.GetOptions().GetFeatures().GetFieldPresence()
+		if fp := f.GetOptions().GetFeatures().GetFieldPresence(); fp != descriptorpb.FeatureSet_FIELD_PRESENCE_UNKNOWN {
+			presence = fp
+		}
+		return presence != descriptorpb.FeatureSet_IMPLICIT
+	}
+	return true
+}
+
+// syntheticOneof reports whether the oneof at index of msg only marks a proto3 optional field
+func syntheticOneof(msg *descriptorpb.DescriptorProto, index int32) bool {
+	for _, f := range msg.Field {
+		if f.OneofIndex != nil && f.GetOneofIndex() == index {
+			return f.GetProto3Optional()
+		}
+	}
+	return false
+}
+
+// goPackage returns the import path of the Go package generated for fd
+func goPackage(fd *descriptorpb.FileDescriptorProto) string {
+	if path, ok := wellKnownPackages[fd.GetName()]; ok {
+		return path
+	}
+	path, _, _ := strings.Cut(fd.GetOptions().GetGoPackage(), ";")
+	return path
+}
+
+// protoGoName returns the Go name protoc-gen-go gives the message or enum full declared in fd
+func protoGoName(fd *descriptorpb.FileDescriptorProto, full string) string {
+	name := strings.TrimPrefix(full, ".")
+	if fd.GetPackage() != "" {
+		name = strings.TrimPrefix(name, fd.GetPackage()+".")
+	}
+	return goCamelCase(name)
+}
+
+// goCamelCase converts a protobuf name to the Go name protoc-gen-go derives from it: words are
+// capitalized, underscores before lowercase letters dropped and dots of nested names become underscores
+func goCamelCase(s string) string {
+	lower := func(c byte) bool { return 'a' <= c && c <= 'z' }
+	var b []byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '.' && i+1 < len(s) && lower(s[i+1]):
+		case c == '.':
+			b = append(b, '_')
+		case c == '_' && (i == 0 || s[i-1] == '.'):
+			b = append(b, 'X')
+		case c == '_' && i+1 < len(s) && lower(s[i+1]):
+		case '0' <= c && c <= '9':
+			b = append(b, c)
+		default:
+			if lower(c) {
+				c -= 'a' - 'A'
+			}
+			b = append(b, c)
+			for ; i+1 < len(s) && lower(s[i+1]); i++ {
+				b = append(b, s[i+1])
+			}
+		}
+	}
+	return string(b)
+}
+
+// protoComments indexes the source locations of fd by their path
+func protoComments(fd *descriptorpb.FileDescriptorProto) map[string]*descriptorpb.SourceCodeInfo_Location {
+	locs := make(map[string]*descriptorpb.SourceCodeInfo_Location)
+	for _, loc := range fd.GetSourceCodeInfo().GetLocation() {
+		locs[pathKey(loc.Path)] = loc
+	}
+	return locs
+}
+
+func pathKey(path []int32) string {
+	return fmt.Sprint(path)
+}
@@ -0,0 +1,139 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// dumpFuncs renders a DumpX helper per struct rendering a fixture for failure messages, all sharing
+// dumpHelper
+func dumpFuncs(m *Model, opts GenerateOptions) string {
+	var b strings.Builder
+	b.WriteString(dumpHelper)
+	for _, name := range fixtureStructs(m) {
+		typ := typeName(TypeRef{Kind: "struct", Name: name}, opts)
+		if returnsPointer(opts) {
+			typ = "*" + typ
+		}
+		fmt.Fprintf(&b, "// Dump%s%s renders v with one field per line, for failure messages and debugging.\n", opts.FuncPrefix, name)
+		fmt.Fprintf(&b, "func Dump%s%s(v %s) string {\n\treturn dumpFixture(v)\n}\n\n", opts.FuncPrefix, name, typ)
+	}
+	return b.String()
+}
+
+// dumpImports are the imports of dumpHelper
+var dumpImports = []string{`"fmt"`, `"reflect"`, `"sort"`, `"strconv"`, `"strings"`}
+
+// dumpHelper renders values through reflection without a dependency on go-spew or litter. It sticks to
+// APIs of Go 1.17 and before, so it also builds with GenerateOptions.GoVersion set.
+const dumpHelper = `// dumpFixture renders v as an indented literal with one field per line, map keys sorted and
+// unexported fields left out, so the output is stable across runs
+func dumpFixture(v interface{}) string {
+	var b strings.Builder
+	dumpFixtureValue(&b, reflect.ValueOf(v), 0, make(map[uintptr]bool))
+	return b.String()
+}
+
+func dumpFixtureValue(b *strings.Builder, v reflect.Value, depth int, seen map[uintptr]bool) {
+	indent := strings.Repeat("\t", depth)
+	switch v.Kind() {
+	case reflect.Invalid:
+		b.WriteString("nil")
+	case reflect.Ptr:
+		if v.IsNil() {
+			b.WriteString("nil")
+			return
+		}
+		if seen[v.Pointer()] {
+			b.WriteString("<cycle>")
+			return
+		}
+		seen[v.Pointer()] = true
+		defer delete(seen, v.Pointer())
+		b.WriteString("&")
+		dumpFixtureValue(b, v.Elem(), depth, seen)
+	case reflect.Interface:
+		if v.IsNil() {
+			b.WriteString("nil")
+			return
+		}
+		dumpFixtureValue(b, v.Elem(), depth, seen)
+	case reflect.Struct:
+		t := v.Type()
+		var fields []int
+		for i := 0; i < t.NumField(); i++ {
+			if t.Field(i).PkgPath == "" {
+				fields = append(fields, i)
+			}
+		}
+		// Opaque values such as time.Time are rendered through their String method
+		if s, ok := v.Interface().(fmt.Stringer); ok && len(fields) == 0 {
+			b.WriteString(t.String() + "(" + strconv.Quote(s.String()) + ")")
+			return
+		}
+		b.WriteString(t.String() + "{")
+		if len(fields) > 0 {
+			b.WriteString("\n")
+			for _, i := range fields {
+				b.WriteString(indent + "\t" + t.Field(i).Name + ": ")
+				dumpFixtureValue(b, v.Field(i), depth+1, seen)
+				b.WriteString(",\n")
+			}
+			b.WriteString(indent)
+		}
+		b.WriteString("}")
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			b.WriteString("nil")
+			return
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 && v.Kind() == reflect.Slice {
+			b.WriteString(v.Type().String() + "(" + strconv.Quote(string(v.Bytes())) + ")")
+			return
+		}
+		b.WriteString(v.Type().String() + "{")
+		if v.Len() > 0 {
+			b.WriteString("\n")
+			for i := 0; i < v.Len(); i++ {
+				b.WriteString(indent + "\t")
+				dumpFixtureValue(b, v.Index(i), depth+1, seen)
+				b.WriteString(",\n")
+			}
+			b.WriteString(indent)
+		}
+		b.WriteString("}")
+	case reflect.Map:
+		if v.IsNil() {
+			b.WriteString("nil")
+			return
+		}
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+		b.WriteString(v.Type().String() + "{")
+		if len(keys) > 0 {
+			b.WriteString("\n")
+			for _, k := range keys {
+				b.WriteString(indent + "\t")
+				dumpFixtureValue(b, k, depth+1, seen)
+				b.WriteString(": ")
+				dumpFixtureValue(b, v.MapIndex(k), depth+1, seen)
+				b.WriteString(",\n")
+			}
+			b.WriteString(indent)
+		}
+		b.WriteString("}")
+	case reflect.String:
+		b.WriteString(strconv.Quote(v.String()))
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		// Addresses would make the output differ between runs
+		if v.IsNil() {
+			b.WriteString("nil")
+		} else {
+			b.WriteString("<" + v.Type().String() + ">")
+		}
+	default:
+		fmt.Fprint(b, v)
+	}
+}
+
+`
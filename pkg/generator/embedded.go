@@ -0,0 +1,97 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// foreignEmbedded reports whether f embeds a type of another package without a fixture to set it from.
+// Such fields are left out of the struct literal and their promoted fields are assigned instead.
+func foreignEmbedded(s *Struct, f Field, opts GenerateOptions) bool {
+	if !f.Embedded {
+		return false
+	}
+	t := f.Type
+	if t.Kind == "pointer" && t.Elem != nil {
+		t = *t.Elem
+	}
+	if t.Pkg == "" || s.Source == nil || t.Pkg == s.Source.Pkg {
+		return false
+	}
+	_, ok := fixtureCall(t, opts)
+	return !ok
+}
+
+// promotedAssignments returns the promoted fields of s that are assigned after its literal: those
+// reached through value embeddings of foreign types and whose values need no fixture of that package.
+// Fields behind an embedded pointer are skipped, it is left nil.
+func promotedAssignments(s *Struct, opts GenerateOptions) []Field {
+	embeds := make(map[string]Field)
+	for _, f := range s.Fields {
+		if f.Embedded {
+			embeds[f.Name] = f
+		}
+	}
+	for _, f := range s.Promoted {
+		if f.Embedded {
+			embeds[f.Name] = f
+		}
+	}
+
+	var result []Field
+	for _, f := range s.Promoted {
+		if f.Embedded || !assignable(f.Type, opts) {
+			continue
+		}
+		segments := strings.Split(f.Name, ".")
+		if !foreignEmbedded(s, embeds[segments[0]], opts) {
+			continue
+		}
+		direct := true
+		for i := 1; i < len(segments); i++ {
+			if embeds[strings.Join(segments[:i], ".")].Type.Kind == "pointer" {
+				direct = false
+				break
+			}
+		}
+		if direct {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+// assignable reports whether a value of t can be rendered without a fixture of the package declaring it
+func assignable(t TypeRef, opts GenerateOptions) bool {
+	switch t.Kind {
+	case "primitive":
+		return true
+	case "external":
+		_, ok := lookupExternal(t, opts)
+		return ok
+	case "slice", "array":
+		return t.Elem != nil && assignable(*t.Elem, opts)
+	case "pointer":
+		return t.Elem != nil && (t.Elem.Kind == "primitive" || t.Elem.Kind == "external") && assignable(*t.Elem, opts)
+	case "struct", "enum", "typedef":
+		if _, ok := lookupExternal(t, opts); ok {
+			return true
+		}
+		_, ok := fixtureCall(t, opts)
+		return ok
+	}
+	return false
+}
+
+// promotedStatements renders the assignments of the promoted fields of s to value, named after the
+// struct embedding them so IDs read "BaseID" rather than the outer struct's
+func promotedStatements(m *Model, s *Struct, opts GenerateOptions, cache *valueCache) string {
+	var b strings.Builder
+	for _, f := range promotedAssignments(s, opts) {
+		segments := strings.Split(f.Name, ".")
+		leaf := f
+		leaf.Name = segments[len(segments)-1]
+		fmt.Fprintf(&b, "\tvalue.%s = %s\n", f.Name, genFieldValue(m, leaf, segments[len(segments)-2], opts, cache))
+	}
+	return b.String()
+}
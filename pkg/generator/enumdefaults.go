@@ -0,0 +1,94 @@
+package generator
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// unspecifiedEnumValue matches the names of enum values standing for no value, like protobuf's
+// STATUS_UNSPECIFIED zero values, after the enum name they are prefixed with
+var unspecifiedEnumValue = regexp.MustCompile(`(?i)(^|_)(unspecified|unknown|unset|invalid|none|undefined)$`)
+
+// PickEnumDefaults moves the value the fixture of each enum returns to the front of its values, so
+// fixtures and the JSON, YAML and SQL values agree on it. With policy "first" (or "") that is the
+// declared first value, with "specified" the first one not named like an unspecified value
+// (STATUS_UNSPECIFIED, StatusUnknown, ...), which often fail validation. picks names the value of
+// single enums, by constant or label, over the policy.
+func (m *Model) PickEnumDefaults(policy string, picks map[string]string) error {
+	if policy != "" && policy != "first" && policy != "specified" {
+		return fmt.Errorf("enum default policy must be 'first' or 'specified', not %q", policy)
+	}
+	for name, pick := range picks {
+		e := m.Enums[name]
+		if e == nil {
+			return fmt.Errorf("enum value %s: no enum %s", pick, name)
+		}
+		i := enumValueIndex(e, pick)
+		if i < 0 {
+			return fmt.Errorf("enum value %s: not a value of %s", pick, name)
+		}
+		moveEnumValue(e, i)
+	}
+	if policy != "specified" {
+		return nil
+	}
+	for name, e := range m.Enums {
+		if _, ok := picks[name]; ok {
+			continue
+		}
+		for i, v := range e.Values {
+			if !InternalConstant(v) && !unspecifiedEnumValue.MatchString(strings.TrimPrefix(v, e.Name)) {
+				moveEnumValue(e, i)
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// enumValueIndex returns the index of the value of e named by its constant or label, or -1
+func enumValueIndex(e *Enum, name string) int {
+	for i, v := range e.Values {
+		if v == name || len(e.Labels) == len(e.Values) && e.Labels[i] == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// moveEnumValue moves the value at i to the front of the values of e, together with its label
+func moveEnumValue(e *Enum, i int) {
+	move := func(s []string) {
+		v := s[i]
+		copy(s[1:i+1], s[:i])
+		s[0] = v
+	}
+	move(e.Values)
+	if len(e.Labels) == len(e.Values) {
+		move(e.Labels)
+	}
+}
+
+// enumConstructors renders a FixtureX per value X of e returning that value, for tests needing a
+// particular one, unless its name is taken by another fixture
+func enumConstructors(m *Model, e *Enum, opts GenerateOptions) string {
+	typ := typeName(TypeRef{Kind: "enum", Name: e.Name}, opts)
+	var b strings.Builder
+	for _, v := range e.Values {
+		if InternalConstant(v) || HasFixture(m, v) {
+			continue
+		}
+		value := v
+		if opts.TypePrefix != "" {
+			value = opts.TypePrefix + "." + v
+		}
+		fmt.Fprintf(&b, "// Fixture%s%s returns %s.\n", opts.FuncPrefix, v, value)
+		if returnsPointer(opts) {
+			fmt.Fprintf(&b, "func Fixture%s%s() *%s {\n\tvalue := %s\n\treturn &value\n}\n\n", opts.FuncPrefix, v, typ, value)
+		} else {
+			fmt.Fprintf(&b, "func Fixture%s%s() %s {\n\treturn %s\n}\n\n", opts.FuncPrefix, v, typ, value)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,53 @@
+package generator
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// parseEnums turns the typedefs of m with constants declared in files into enums, with the constants
+// as values in declaration order. Without type information only constants declared with the type,
+// or repeating the type of the previous constant of an iota block, are known to be of it.
+func parseEnums(files []*ast.File, m *Model) {
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok || gd.Tok != token.CONST {
+				continue
+			}
+			typ := ""
+			for _, spec := range gd.Specs {
+				vs, ok := spec.(*ast.ValueSpec)
+				if !ok {
+					continue
+				}
+				switch {
+				case vs.Type != nil:
+					typ = ""
+					if ident, ok := vs.Type.(*ast.Ident); ok {
+						typ = ident.Name
+					}
+				case len(vs.Values) > 0:
+					// An untyped constant, or one converted to its type, which only type checking tells
+					typ = ""
+				}
+				if m.TypeDefs[typ] == nil && m.Enums[typ] == nil {
+					continue
+				}
+				for _, name := range vs.Names {
+					if name.Name == "_" || InternalConstant(name.Name) {
+						continue
+					}
+					e := m.Enums[typ]
+					if e == nil {
+						td := m.TypeDefs[typ]
+						e = &Enum{Name: td.Name, Source: td.Source, Doc: td.Doc}
+						m.Enums[typ] = e
+						delete(m.TypeDefs, typ)
+					}
+					e.Values = append(e.Values, name.Name)
+				}
+			}
+		}
+	}
+}
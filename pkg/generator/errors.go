@@ -0,0 +1,57 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"go/scanner"
+)
+
+// ErrNoPackages is returned when a package pattern matches no packages to generate fixtures for
+var ErrNoPackages = errors.New("no packages found")
+
+// ErrFormatFailed is matched by the FormatErrors of generated code gofmt rejects, with errors.Is
+var ErrFormatFailed = errors.New("generated code could not be formatted")
+
+// FormatError is generated code gofmt rejects, at the position of its first syntax error in the
+// output. The code is written unformatted; the error usually points at a value a GenerateOptions
+// field such as FieldOverrides put into it.
+type FormatError struct {
+	// File is the output file, "" if not known
+	File   string
+	Line   int
+	Column int
+	// Err is the error of go/format
+	Err error
+}
+
+func (e *FormatError) Error() string {
+	pos := e.File
+	if pos == "" {
+		pos = "output"
+	}
+	if e.Line > 0 {
+		pos = fmt.Sprintf("%s:%d:%d", pos, e.Line, e.Column)
+	}
+	msg := e.Err.Error()
+	var list scanner.ErrorList
+	if errors.As(e.Err, &list) && len(list) > 0 {
+		msg = list[0].Msg
+	}
+	return fmt.Sprintf("%s: %v: %s", pos, ErrFormatFailed, msg)
+}
+
+func (e *FormatError) Unwrap() error { return e.Err }
+
+// Is reports whether target is ErrFormatFailed
+func (e *FormatError) Is(target error) bool { return target == ErrFormatFailed }
+
+// newFormatError returns the FormatError of err from go/format, for code starting at line offset+1
+// of file
+func newFormatError(file string, offset int, err error) *FormatError {
+	fe := &FormatError{File: file, Err: err}
+	var list scanner.ErrorList
+	if errors.As(err, &list) && len(list) > 0 {
+		fe.Line, fe.Column = offset+list[0].Pos.Line, list[0].Pos.Column
+	}
+	return fe
+}
@@ -0,0 +1,67 @@
+package generator
+
+import (
+	"strconv"
+	"strings"
+)
+
+// RegisterExternalType teaches the generator the default value of the type name of the package at
+// pkgPath, for types without fixtures such as uuid.UUID or decimal.Decimal. Unlike the simple names
+// ExternalTypes is keyed by, it only matches the type of that package. An empty ext.Import imports
+// the package as NewExternalType does. Register types from an init func: the registry isn't
+// guarded against concurrent generation.
+func RegisterExternalType(pkgPath, name string, ext ExternalType) {
+	ext.PkgPath = pkgPath
+	if ext.Import == "" {
+		ext.Import = externalImport(pkgPath)
+	}
+	ExternalTypes[pkgPath+"."+name] = ext
+}
+
+// NewExternalType returns an external type of the package at pkgPath defaulting to the Go expression
+// value. The package is imported by its last path element, or for versioned paths like
+// "k8s.io/api/core/v1" by the alias "corev1", which value refers to it by.
+func NewExternalType(pkgPath, value string) ExternalType {
+	return ExternalType{Import: externalImport(pkgPath), Value: value, PkgPath: pkgPath}
+}
+
+// externalImport returns the import spec of the package at path, aliased like packageAlias names it
+func externalImport(path string) string {
+	alias := packageAlias(path)
+	if alias == path[strings.LastIndex(path, "/")+1:] {
+		return strconv.Quote(path)
+	}
+	return alias + " " + strconv.Quote(path)
+}
+
+// lookupExternal returns the external type t refers to. Types of a known package are looked up by
+// import path and name, in GenerateOptions.ExternalTypes and then the registered ones, which also
+// turns types extracted as structs or enums of other packages into external types. Otherwise the
+// simple name decides, for types of the package the entry names or of any package if it names none.
+func lookupExternal(t TypeRef, opts GenerateOptions) (ExternalType, bool) {
+	if t.Pkg != "" && t.Name != "" {
+		key := t.Pkg + "." + t.Name
+		if ext, ok := opts.ExternalTypes[key]; ok {
+			return ext, true
+		}
+		if ext, ok := ExternalTypes[key]; ok {
+			return ext, true
+		}
+	}
+	if t.Kind != "external" {
+		return ExternalType{}, false
+	}
+	ext, ok := ExternalTypes[t.Name]
+	if !ok || t.Pkg != "" && ext.PkgPath != "" && ext.PkgPath != t.Pkg {
+		return ExternalType{}, false
+	}
+	return ext, true
+}
+
+// externalValue renders the default of a field of the external type ext
+func externalValue(ext ExternalType, m *Model, fieldName, structName string, opts GenerateOptions) string {
+	if ext.ValueFunc != nil {
+		return ext.ValueFunc(m, fieldName, structName, opts)
+	}
+	return ext.Value
+}
@@ -0,0 +1,92 @@
+package generator
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+)
+
+// Word lists the faker strategy picks realistic values from. Phone numbers are in the 555-01xx range
+// and IP addresses in 192.0.2.0/24, which are reserved for examples.
+var (
+	fakeFirstNames = []string{"Ada", "Grace", "Alan", "Linus", "Margaret", "Dennis", "Barbara", "Ken", "Frances", "Niklaus"}
+	fakeLastNames  = []string{"Lovelace", "Hopper", "Turing", "Torvalds", "Hamilton", "Ritchie", "Liskov", "Thompson", "Allen", "Wirth"}
+	fakeCities     = []string{"Berlin", "Lisbon", "Toronto", "Melbourne", "Osaka", "Nairobi", "Austin", "Oslo"}
+	fakeCountries  = []string{"Germany", "Portugal", "Canada", "Australia", "Japan", "Kenya", "United States", "Norway"}
+	fakeCodes      = []string{"DE", "PT", "CA", "AU", "JP", "KE", "US", "NO"}
+	fakeStreets    = []string{"Main Street", "Elm Street", "Park Avenue", "Oak Lane", "Harbour Road", "Mill Road"}
+	fakeCompanies  = []string{"Acme Corp", "Globex", "Initech", "Umbrella Ltd", "Stark Industries", "Wayne Enterprises"}
+	fakeCurrencies = []string{"EUR", "USD", "GBP", "JPY", "CAD", "CHF"}
+	fakeIBANs      = []string{"DE89370400440532013000", "GB29NWBK60161331926819", "FR1420041010050500013M02606", "NL91ABNA0417164300"}
+	fakeTimezones  = []string{"Europe/Berlin", "America/New_York", "Asia/Tokyo", "Australia/Sydney", "UTC"}
+	fakeLocales    = []string{"en-US", "de-DE", "fr-FR", "ja-JP", "pt-PT"}
+	fakeColors     = []string{"#1e88e5", "#43a047", "#e53935", "#fdd835", "#8e24aa"}
+	fakeSentences  = []string{
+		"Lorem ipsum dolor sit amet, consectetur adipiscing elit.",
+		"Sed do eiusmod tempor incididunt ut labore et dolore magna aliqua.",
+		"Ut enim ad minim veniam, quis nostrud exercitation ullamco.",
+	}
+)
+
+// fakeValue returns a realistic string literal for a field whose name tells what it holds (Email,
+// Phone, City, IBAN...), or "" for others. Values are picked by a hash of seed, struct and field, so
+// the same seed always generates the same fixtures while fields of different structs vary.
+func fakeValue(fieldName, structName string, seed int64) string {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%d.%s.%s", seed, structName, fieldName)
+	n := h.Sum64()
+	pick := func(words []string) string {
+		return words[n%uint64(len(words))]
+	}
+	first, last := pick(fakeFirstNames), fakeLastNames[n/7%uint64(len(fakeLastNames))]
+
+	var v string
+	switch name := strings.ToLower(strings.ReplaceAll(fieldName, "_", "")); {
+	case strings.HasSuffix(name, "email"):
+		v = strings.ToLower(first+"."+last) + "@example.com"
+	case strings.HasSuffix(name, "phone") || strings.HasSuffix(name, "phonenumber") || name == "mobile":
+		v = fmt.Sprintf("+1-202-555-01%02d", n%100)
+	case name == "url" || strings.HasSuffix(name, "url") || name == "website" || name == "homepage":
+		v = "https://example.com/" + strings.ToLower(structName)
+	case name == "firstname" || name == "givenname":
+		v = first
+	case name == "lastname" || name == "surname" || name == "familyname":
+		v = last
+	case name == "fullname" || name == "displayname":
+		v = first + " " + last
+	case name == "username" || name == "login":
+		v = strings.ToLower(first[:1] + last)
+	case name == "city":
+		v = pick(fakeCities)
+	case name == "country":
+		v = pick(fakeCountries)
+	case name == "countrycode":
+		v = pick(fakeCodes)
+	case name == "street" || name == "address" || name == "addressline1" || name == "streetaddress":
+		v = strconv.FormatUint(1+n%200, 10) + " " + pick(fakeStreets)
+	case name == "zip" || name == "zipcode" || name == "postcode" || name == "postalcode":
+		v = fmt.Sprintf("%05d", n%100000)
+	case name == "company" || name == "companyname" || name == "organization":
+		v = pick(fakeCompanies)
+	case name == "currency" || name == "currencycode":
+		v = pick(fakeCurrencies)
+	case name == "iban":
+		v = pick(fakeIBANs)
+	case name == "ip" || name == "ipaddress":
+		v = fmt.Sprintf("192.0.2.%d", 1+n%254)
+	case name == "uuid" || name == "guid":
+		v = fmt.Sprintf("%08x-%04x-4%03x-8%03x-%012x", uint32(n), uint16(n>>32), n>>48&0xfff, n>>8&0xfff, n&0xffffffffffff)
+	case name == "timezone" || name == "tz":
+		v = pick(fakeTimezones)
+	case name == "locale" || name == "language":
+		v = pick(fakeLocales)
+	case name == "color" || name == "colour":
+		v = pick(fakeColors)
+	case name == "description" || name == "bio" || name == "comment" || name == "summary":
+		v = pick(fakeSentences)
+	default:
+		return ""
+	}
+	return strconv.Quote(v)
+}
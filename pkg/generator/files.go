@@ -0,0 +1,184 @@
+package generator
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"path"
+	"strings"
+)
+
+// SharedFile is the file GenerateFiles writes the declarations shared by all types to: the ptr helper,
+// the registry, snapshots and other helpers covering every fixture
+const SharedFile = "fixtures.go"
+
+// FileLayout assigns the types of m to the files their fixtures are split into by GenerateFiles. With
+// perFile 0 the files mirror the source files of the types, the fixtures of types declared in
+// user.pb.go going to user_fixtures.go, and types without a source file go to the SharedFile.
+// Otherwise the types are cut into files of perFile types each, fixtures_1.go, fixtures_2.go...
+func FileLayout(m *Model, perFile int) map[string]string {
+	var names []string
+	sources := make(map[string]*Source)
+	for _, name := range sortedKeys(m.TypeDefs) {
+		names = append(names, name)
+		sources[name] = m.TypeDefs[name].Source
+	}
+	for _, name := range sortedKeys(m.Enums) {
+		names = append(names, name)
+		sources[name] = m.Enums[name].Source
+	}
+	for _, name := range sortedKeys(m.Structs) {
+		names = append(names, name)
+		sources[name] = m.Structs[name].Source
+	}
+
+	layout := make(map[string]string)
+	for i, name := range names {
+		switch src := sources[name]; {
+		case perFile > 0:
+			layout[name] = fmt.Sprintf("fixtures_%d.go", i/perFile+1)
+		case src != nil && src.File != "":
+			base, _, _ := strings.Cut(path.Base(src.File), ".")
+			layout[name] = base + "_fixtures.go"
+		default:
+			layout[name] = SharedFile
+		}
+	}
+	return layout
+}
+
+// GenerateFiles renders the fixtures of m like GenerateTo, split into files by layout, which maps type
+// names to file names (see FileLayout). The fixture of a type goes to its file together with its
+// helpers; declarations shared by all types, and types layout leaves out, go to the SharedFile. Every
+// file gets the header and the imports its declarations use, and with Incremental the hash of its
+// own content. Files opts.Unchanged reports as unchanged are in the result without content.
+func GenerateFiles(m *Model, pkgName string, opts GenerateOptions, layout map[string]string) (map[string][]byte, error) {
+	var header []byte
+	bodies := map[string]*bytes.Buffer{SharedFile: {}}
+	// Each file records the hash of its own content instead of one for the model
+	incremental := opts.Incremental
+	opts.Incremental = false
+	err := generateOwnedDecls(m, pkgName, opts, func(owner string, decl []byte) error {
+		if owner == headerOwner {
+			header = append([]byte(nil), decl...)
+			return nil
+		}
+		file := layout[owner]
+		if file == "" {
+			file = SharedFile
+		}
+		if bodies[file] == nil {
+			bodies[file] = &bytes.Buffer{}
+		}
+		bodies[file].Write(decl)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	comments, imports, err := parseHeader(header)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string][]byte, len(bodies))
+	for name, body := range bodies {
+		if name != SharedFile && body.Len() == 0 {
+			continue
+		}
+		var b bytes.Buffer
+		writeFileHeader(&b, comments, pkgName, imports, body.Bytes())
+		b.Write(body.Bytes())
+
+		sum := sha256.Sum256(b.Bytes())
+		hash := hex.EncodeToString(sum[:])
+		if opts.Unchanged != nil && opts.Unchanged(name, hash) {
+			files[name] = nil
+			continue
+		}
+		if incremental {
+			b.Reset()
+			writeFileHeader(&b, withHash(comments, hash), pkgName, imports, body.Bytes())
+			b.Write(body.Bytes())
+		}
+
+		formatted, err := format.Source(b.Bytes())
+		if err != nil {
+			formatted = b.Bytes()
+			if opts.FormatError != nil {
+				opts.FormatError(newFormatError(name, 0, err))
+			}
+		}
+		files[name] = formatted
+	}
+	return files, nil
+}
+
+// withHash adds the line recording hash to the header comments of a file, after the version
+func withHash(comments, hash string) string {
+	return strings.TrimSuffix(comments, "\n") + HashPrefix + hash + "\n\n"
+}
+
+// parseHeader splits the header generateOwnedDecls emits into its comments and imports
+func parseHeader(header []byte) (string, []*ast.ImportSpec, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", header, parser.ImportsOnly|parser.ParseComments)
+	if err != nil {
+		return "", nil, err
+	}
+	return string(header[:fset.Position(f.Package).Offset]), f.Imports, nil
+}
+
+// writeFileHeader writes the header of a file holding the declarations body to b: the comments, the
+// package clause and those of imports body uses
+func writeFileHeader(b *bytes.Buffer, comments, pkgName string, imports []*ast.ImportSpec, body []byte) {
+	b.WriteString(comments)
+	b.WriteString("package " + pkgName + "\n\n")
+	if specs := usedImports(imports, body); len(specs) > 0 {
+		b.WriteString("import (\n")
+		for _, spec := range specs {
+			fmt.Fprintf(b, "\t%s\n", spec)
+		}
+		b.WriteString(")\n\n")
+	}
+}
+
+// usedImports returns the import specs of imports the declarations in body refer to
+func usedImports(imports []*ast.ImportSpec, body []byte) []string {
+	f, err := parser.ParseFile(token.NewFileSet(), "", append([]byte("package p\n\n"), body...), 0)
+	if err != nil {
+		// Keep every import of unformattable output rather than guess
+		f = nil
+	}
+	used := make(map[string]bool)
+	if f != nil {
+		ast.Inspect(f, func(n ast.Node) bool {
+			// Package names aren't declared in the file, locals of the same name are
+			if sel, ok := n.(*ast.SelectorExpr); ok {
+				if x, ok := sel.X.(*ast.Ident); ok && x.Obj == nil {
+					used[x.Name] = true
+				}
+			}
+			return true
+		})
+	}
+
+	var specs []string
+	for _, imp := range imports {
+		spec := imp.Path.Value
+		if imp.Name != nil {
+			spec = imp.Name.Name + " " + spec
+		}
+		// Packages named unlike their path (gopkg.in/yaml.v3) are kept, their name is unknown
+		if name := importName(spec); f == nil || used[name] || !token.IsIdentifier(name) {
+			specs = append(specs, spec)
+		}
+	}
+	return specs
+}
@@ -0,0 +1,72 @@
+package generator
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// globPattern matches type patterns written as globs, e.g. "*Request" or "User?"; any other pattern
+// is a regular expression
+var globPattern = regexp.MustCompile(`^[A-Za-z0-9_*?]+$`)
+
+// CompileTypePatterns compiles the patterns of Include or Exclude. Globs of type names and wildcards
+// match whole names, regular expressions anywhere in the name like the -excludeconst ones.
+func CompileTypePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	var compiled []*regexp.Regexp
+	for _, pattern := range patterns {
+		expr := pattern
+		if globPattern.MatchString(pattern) {
+			expr = "^" + strings.NewReplacer("*", ".*", "?", ".").Replace(pattern) + "$"
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("type pattern %q: %w", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// Filter returns a model with the structs, enums and typedefs of m matching any of include (all if it
+// is empty) and none of exclude, and every type their fixtures need: an excluded type referenced by a
+// field of an included one is kept, or its fixture wouldn't compile. Without patterns m is returned.
+func (m *Model) Filter(include, exclude []*regexp.Regexp) *Model {
+	if len(include) == 0 && len(exclude) == 0 {
+		return m
+	}
+	matches := func(patterns []*regexp.Regexp, name string) bool {
+		for _, re := range patterns {
+			if re.MatchString(name) {
+				return true
+			}
+		}
+		return false
+	}
+	var names []string
+	for _, name := range m.TypeNames() {
+		if (len(include) == 0 || matches(include, name)) && !matches(exclude, name) {
+			names = append(names, name)
+		}
+	}
+	return m.Subset(names)
+}
+
+// TypeNames returns the names of the structs, enums and typedefs of m, the types fixtures are
+// generated for
+func (m *Model) TypeNames() []string {
+	return append(append(sortedKeys(m.Structs), sortedKeys(m.Enums)...), sortedKeys(m.TypeDefs)...)
+}
+
+// filterTypes applies the Include and Exclude patterns of opts to m
+func filterTypes(m *Model, opts GenerateOptions) (*Model, error) {
+	include, err := CompileTypePatterns(opts.Include)
+	if err != nil {
+		return nil, err
+	}
+	exclude, err := CompileTypePatterns(opts.Exclude)
+	if err != nil {
+		return nil, err
+	}
+	return m.Filter(include, exclude), nil
+}
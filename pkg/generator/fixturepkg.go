@@ -0,0 +1,58 @@
+package generator
+
+import (
+	"strings"
+	"unicode"
+)
+
+// fixtureCall renders a value of t as a call into the fixtures package registered for t's package
+// in opts.FixturePackages. Those fixtures are assumed to use the same style and function prefix.
+func fixtureCall(t TypeRef, opts GenerateOptions) (string, bool) {
+	if t.Pkg == "" || opts.FixturePackages[t.Pkg] == "" {
+		return "", false
+	}
+	call := fixtureAlias(t.Pkg) + ".Fixture" + opts.FuncPrefix + t.Name + "()"
+	if returnsPointer(opts) {
+		return "*" + call, true
+	}
+	return call, true
+}
+
+// packageAlias returns the name a package is imported as in generated code: its last path element,
+// prefixed with the parent element for versioned paths ("k8s.io/api/apps/v1" -> "appsv1")
+func packageAlias(path string) string {
+	parts := strings.Split(path, "/")
+	alias := parts[len(parts)-1]
+	if len(parts) > 1 && isVersion(alias) {
+		alias = parts[len(parts)-2] + alias
+	}
+	alias = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, alias)
+	if alias == "" || unicode.IsDigit(rune(alias[0])) {
+		alias = "pkg" + alias
+	}
+	return alias
+}
+
+// fixtureAlias returns the import name of the fixtures package for the package at path
+func fixtureAlias(path string) string {
+	return packageAlias(path) + "fixtures"
+}
+
+// isVersion reports whether s looks like an API version such as "v1" or "v2beta1"
+func isVersion(s string) bool {
+	if len(s) < 2 || s[0] != 'v' || !unicode.IsDigit(rune(s[1])) {
+		return false
+	}
+	rest := strings.TrimLeft(s[1:], "0123456789")
+	for _, stage := range []string{"alpha", "beta"} {
+		if after, ok := strings.CutPrefix(rest, stage); ok {
+			rest = strings.TrimLeft(after, "0123456789")
+		}
+	}
+	return rest == ""
+}
@@ -0,0 +1,94 @@
+package generator
+
+import (
+	"go/ast"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// foreignType reports whether t is a named type of a package other than that of the types, TypeImport,
+// with no fixture to call: neither an external type nor one of FixturePackages. Fields of such types are
+// set to a zero value marked TODO, since calling a fixture of the same name would not compile, unless
+// their package was loaded into Model.Deps.
+func foreignType(t TypeRef, opts GenerateOptions) bool {
+	if t.Kind != "struct" && t.Kind != "enum" && t.Kind != "typedef" {
+		return false
+	}
+	if t.Pkg == "" || t.Pkg == opts.TypeImport || t.Name == "" || strings.HasPrefix(t.Name, "is") {
+		return false
+	}
+	if _, ok := lookupExternal(t, opts); ok {
+		return false
+	}
+	_, ok := fixtureCall(t, opts)
+	return !ok
+}
+
+// modelPackage returns the import path of the package the types of m are declared in, taken from
+// their Source, or "" for models not taken from Go code. It is the TypeImport unless one is given.
+func modelPackage(m *Model) string {
+	for _, name := range sortedKeys(m.Structs) {
+		if src := m.Structs[name].Source; src != nil {
+			return src.Pkg
+		}
+	}
+	for _, name := range sortedKeys(m.Enums) {
+		if src := m.Enums[name].Source; src != nil {
+			return src.Pkg
+		}
+	}
+	for _, name := range sortedKeys(m.TypeDefs) {
+		if src := m.TypeDefs[name].Source; src != nil {
+			return src.Pkg
+		}
+	}
+	return ""
+}
+
+// foreignValue renders the zero value of the foreign type t, or with pointer a pointer to it
+func foreignValue(t TypeRef, pointer bool, opts GenerateOptions) string {
+	todo := " /* TODO: no fixture for " + t.Pkg + "." + t.Name + " */"
+	typ := typeName(t, opts)
+	switch {
+	case t.Kind == "struct" && pointer:
+		return "&" + typ + "{}" + todo
+	case t.Kind == "struct":
+		return typ + "{}" + todo
+	case pointer:
+		return "new(" + typ + ")" + todo
+	}
+	return "*new(" + typ + ")" + todo
+}
+
+// fileImports returns the import paths of the imports of f by the name the file refers to them by.
+// Unnamed imports are assumed to be named after the last path element that isn't a major version.
+func fileImports(f *ast.File) map[string]string {
+	imports := make(map[string]string)
+	for _, spec := range f.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		name := path.Base(importPath)
+		if isMajorVersion(name) && path.Dir(importPath) != "." {
+			name = path.Base(path.Dir(importPath))
+		}
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		if name != "_" && name != "." {
+			imports[name] = importPath
+		}
+	}
+	return imports
+}
+
+// isMajorVersion reports whether s is a module major version suffix such as "v2"
+func isMajorVersion(s string) bool {
+	if len(s) < 2 || s[0] != 'v' {
+		return false
+	}
+	_, err := strconv.Atoi(s[1:])
+	return err == nil
+}
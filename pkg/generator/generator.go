@@ -2,11 +2,18 @@ package generator
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"go/ast"
 	"go/format"
 	"go/parser"
 	"go/token"
+	"io"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -16,15 +23,55 @@ type Model struct {
 	Enums    map[string]*Enum
 	TypeDefs map[string]*TypeDef
 	OneOfs   map[string]string // interface name -> first implementation name
+	// Interfaces are the other interface types, which fields can be set to stubs of
+	Interfaces map[string]*Interface `json:",omitempty"`
+	// Variants maps oneof interface names to all their implementations in declaration order, the
+	// first of which is the default in OneOfs
+	Variants map[string][]string `json:",omitempty"`
+	// Deps are the models of the other packages struct fields reference, keyed by import path, see
+	// ExtractDeps. Fields of their types get values instead of the zero value of foreign types.
+	Deps map[string]*Model `json:",omitempty"`
 }
 
 // NewModel creates an empty Model
 func NewModel() *Model {
 	return &Model{
-		Structs:  make(map[string]*Struct),
-		Enums:    make(map[string]*Enum),
-		TypeDefs: make(map[string]*TypeDef),
-		OneOfs:   make(map[string]string),
+		Structs:    make(map[string]*Struct),
+		Enums:      make(map[string]*Enum),
+		TypeDefs:   make(map[string]*TypeDef),
+		OneOfs:     make(map[string]string),
+		Variants:   make(map[string][]string),
+		Interfaces: make(map[string]*Interface),
+	}
+}
+
+// Merge copies all types from other into m, keeping already resolved oneof implementations
+func (m *Model) Merge(other *Model) {
+	for name, s := range other.Structs {
+		m.Structs[name] = s
+	}
+	for name, e := range other.Enums {
+		m.Enums[name] = e
+	}
+	for name, td := range other.TypeDefs {
+		m.TypeDefs[name] = td
+	}
+	for name, i := range other.Interfaces {
+		m.Interfaces[name] = i
+	}
+	for path, dep := range other.Deps {
+		if m.Deps == nil {
+			m.Deps = make(map[string]*Model)
+		}
+		m.Deps[path] = dep
+	}
+	for name, impl := range other.OneOfs {
+		if m.OneOfs[name] == "" {
+			m.OneOfs[name] = impl
+			for _, variant := range other.Variants[name] {
+				m.AddOneOfVariant(name, variant)
+			}
+		}
 	}
 }
 
@@ -32,189 +79,446 @@ func NewModel() *Model {
 type Struct struct {
 	Name   string
 	Fields []Field
+	// Source is where the struct is declared, if it comes from Go code
+	Source *Source `json:",omitempty"`
+	// Doc is the doc comment of the struct, carried into the doc comment of its fixture
+	Doc string `json:",omitempty"`
+	// Table is the database table the struct is stored in, if known
+	Table string `json:",omitempty"`
+	// APIVersion is the Kubernetes group/version of the struct (e.g. "apps/v1"), if known
+	APIVersion string `json:",omitempty"`
+	// Validator is the method validating the struct, "ValidateAll" or "Validate" as generated by
+	// protoc-gen-validate, if it has one
+	Validator string `json:",omitempty"`
+	// Builder is the Message_builder type of the protobuf opaque API the fixture is built with, whose
+	// fields are the Fields of the struct. Set for messages whose fields are hidden.
+	Builder string `json:",omitempty"`
+	// Promoted are the fields promoted from embedded structs, named by their selector through the
+	// embedded fields (e.g. "Base.CreatedAt"). Fields shadowed by a shallower field of the same name
+	// aren't promoted. They are set through the embedded fixture, not in the struct literal.
+	Promoted []Field `json:",omitempty"`
+	// TypeParams are the type parameters of a generic struct, whose fixture is generic over them
+	TypeParams []TypeParam `json:",omitempty"`
 }
 
 // Field represents a struct field
 type Field struct {
 	Name string
 	Type TypeRef
+	// Default is a Go expression used instead of the generated default value (e.g. taken from a sample payload)
+	Default string `json:",omitempty"`
+	// Column is the database column the field is stored in, if known
+	Column string `json:",omitempty"`
+	// JSONName is the key of the field in JSON ("-" if it is left out), if it differs from Name
+	JSONName string `json:",omitempty"`
+	// Embedded is set for embedded fields, which are named by their type
+	Embedded bool `json:",omitempty"`
+	// References is the "Struct.Field" whose value the field takes, set by Model.Relate
+	References string `json:",omitempty"`
+	// SliceLen is the number of elements of a slice field from its fixture:"len=N" tag, 0 without one
+	SliceLen int `json:",omitempty"`
 }
 
 // Enum represents a Go enum type (constants of the same type)
 type Enum struct {
 	Name   string
 	Values []string
+	// Labels are the external representations of Values (e.g. database enum labels), if known
+	Labels []string `json:",omitempty"`
+	// Source is where the enum type is declared, if it comes from Go code
+	Source *Source `json:",omitempty"`
+	// Doc is the doc comment of the enum type
+	Doc string `json:",omitempty"`
 }
 
 // TypeDef represents a type alias like `type TenantID string`
 type TypeDef struct {
 	Name       string
 	Underlying TypeRef
+	// Source is where the type is declared, if it comes from Go code
+	Source *Source `json:",omitempty"`
+	// Doc is the doc comment of the type
+	Doc string `json:",omitempty"`
+}
+
+// Source is the declaration of a type in Go code, referenced from the doc comment of its fixture
+type Source struct {
+	// Pkg is the import path of the declaring package
+	Pkg  string
+	File string
+	Line int
 }
 
 // TypeRef represents a type reference
 type TypeRef struct {
-	Kind string // "primitive", "struct", "enum", "oneof", "pointer", "slice", "external", "typedef", "unknown"
+	Kind string // "primitive", "struct", "enum", "oneof", "interface", "pointer", "slice", "array", "external", "typedef", "typeparam", "unknown"
 	Name string
 	Elem *TypeRef
+	// Len is the length of an array
+	Len int64 `json:",omitempty"`
+	// Pkg is the import path of the package declaring a named type, if known
+	Pkg string `json:",omitempty"`
+	// TypeArgs are the type arguments of an instantiated generic struct, User of Page[User]
+	TypeArgs []TypeRef `json:",omitempty"`
 }
 
-// ProtoInternalFields are protobuf-generated fields to skip
+// ProtoInternalFields are protobuf-generated fields to skip, including those of gogo/protobuf and
+// the legacy golang/protobuf API. Fields of other generators are added with Model.SkipFields.
 var ProtoInternalFields = map[string]bool{
-	"state":          true,
-	"unknownFields":  true,
-	"sizeCache":      true,
-	"EnforceVersion": true,
+	"state":                true,
+	"unknownFields":        true,
+	"sizeCache":            true,
+	"EnforceVersion":       true,
+	"XXX_NoUnkeyedLiteral": true,
+	"XXX_unrecognized":     true,
+	"XXX_sizecache":        true,
+	// The opaque API tracks presence and races in these
+	"XXX_raceDetectHookData": true,
+	"XXX_presence":           true,
+}
+
+// ProtoInternalField reports whether a field is protobuf-internal: one of ProtoInternalFields or a
+// field hidden by the opaque API (xxx_hidden_Name), which is only accessed through getters and setters
+func ProtoInternalField(name string) bool {
+	return ProtoInternalFields[name] || strings.HasPrefix(name, "xxx_hidden_")
+}
+
+// InternalConstants match the names of generator-internal constants that are no enum values: the blank
+// identifier and protoc-gen-go's EnforceVersion. Further patterns are applied with Model.ExcludeConstants.
+var InternalConstants = []*regexp.Regexp{
+	regexp.MustCompile(`^_$`),
+	regexp.MustCompile(`^EnforceVersion$`),
+}
+
+// InternalConstant reports whether the constant name matches one of InternalConstants
+func InternalConstant(name string) bool {
+	for _, re := range InternalConstants {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExcludeConstants removes the enum values of m matching any of patterns, together with their labels.
+// Enums left without values only had generator-internal constants and are removed as well.
+func (m *Model) ExcludeConstants(patterns []*regexp.Regexp) {
+	if len(patterns) == 0 {
+		return
+	}
+	excluded := func(name string) bool {
+		for _, re := range patterns {
+			if re.MatchString(name) {
+				return true
+			}
+		}
+		return false
+	}
+	for name, e := range m.Enums {
+		var values, labels []string
+		for i, v := range e.Values {
+			if excluded(v) {
+				continue
+			}
+			values = append(values, v)
+			if len(e.Labels) == len(e.Values) {
+				labels = append(labels, e.Labels[i])
+			}
+		}
+		if len(values) == len(e.Values) {
+			continue
+		}
+		if len(values) == 0 {
+			delete(m.Enums, name)
+			continue
+		}
+		e.Values, e.Labels = values, labels
+	}
+}
+
+// SkipFields removes fields from the structs of m, named either "Field" for every struct or
+// "Type.Field" for one. Promoted fields are matched by their own name and their selector.
+func (m *Model) SkipFields(names []string) {
+	if len(names) == 0 {
+		return
+	}
+	skip := make(map[string]bool, len(names))
+	for _, name := range names {
+		skip[name] = true
+	}
+	keep := func(s *Struct, fields []Field) []Field {
+		var kept []Field
+		for _, f := range fields {
+			name := f.Name[strings.LastIndex(f.Name, ".")+1:]
+			if !skip[name] && !skip[s.Name+"."+name] && !skip[f.Name] {
+				kept = append(kept, f)
+			}
+		}
+		return kept
+	}
+	for _, s := range m.Structs {
+		s.Fields = keep(s, s.Fields)
+		s.Promoted = keep(s, s.Promoted)
+	}
 }
 
 // ExternalType defines an external type with its import and default value
 type ExternalType struct {
 	Import string
 	Value  string
+	// Requires lists further imports used by Value
+	Requires []string
+	// PkgPath restricts the type to one package, so a local type of the same name isn't mistaken for it
+	PkgPath string
+	// ValueFunc computes the default from the field and struct it is used in, instead of Value
+	ValueFunc func(m *Model, fieldName, structName string, opts GenerateOptions) string `json:"-"`
+	// Pointer is set for types only used through a pointer, whose Value already is one
+	Pointer bool
 }
 
-// ExternalTypes maps type names to their import and default value
+// ExternalTypes maps type names to their import and default value. Types registered with
+// RegisterExternalType are keyed by import path and name instead ("github.com/google/uuid.UUID").
 var ExternalTypes = map[string]ExternalType{
 	"Timestamp": {
-		Import: `timestamppb "google.golang.org/protobuf/types/known/timestamppb"`,
-		Value:  "timestamppb.New(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC))",
+		Import:    `timestamppb "google.golang.org/protobuf/types/known/timestamppb"`,
+		Requires:  []string{`"time"`},
+		PkgPath:   "google.golang.org/protobuf/types/known/timestamppb",
+		ValueFunc: timestampValue,
+		Pointer:   true,
 	},
 	"Time": {
-		Import: `"time"`,
-		Value:  "time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)",
+		Import:    `"time"`,
+		PkgPath:   "time",
+		ValueFunc: timeValue,
 	},
 }
 
-// RequiredImports are always included when external types are used
-var RequiredImports = []string{
-	`"time"`,
-}
-
 // ParseSource parses Go source code and extracts type information into a Model
 func ParseSource(source string) (*Model, error) {
+	return ParseSources(map[string]string{"input.go": source})
+}
+
+// ParseSources parses the Go source files of one package, keyed by file name, into a single Model,
+// like ParseSource does with one file. Types may refer to the types of the other files, as the
+// several .pb.go files protoc generates for a package do; each file resolves other packages through
+// its own imports.
+func ParseSources(sources map[string]string) (*Model, error) {
 	fset := token.NewFileSet()
-	f, err := parser.ParseFile(fset, "input.go", source, parser.ParseComments)
-	if err != nil {
-		return nil, fmt.Errorf("parse error: %w", err)
+	var files []*ast.File
+	for _, name := range sortedKeys(sources) {
+		f, err := parser.ParseFile(fset, name, sources[name], parser.ParseComments)
+		if err != nil {
+			return nil, fmt.Errorf("parse error: %w", err)
+		}
+		files = append(files, f)
 	}
+	resolveArrayLens(fset, files)
 
 	m := NewModel()
+	docs := TypeDocs(files)
 
 	// First pass: find oneof interfaces
-	for _, decl := range f.Decls {
-		genDecl, ok := decl.(*ast.GenDecl)
-		if !ok || genDecl.Tok != token.TYPE {
-			continue
-		}
-
-		for _, spec := range genDecl.Specs {
-			typeSpec, ok := spec.(*ast.TypeSpec)
-			if !ok {
+	for _, f := range files {
+		imports := fileImports(f)
+		for _, decl := range f.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.TYPE {
 				continue
 			}
 
-			name := typeSpec.Name.Name
+			for _, spec := range genDecl.Specs {
+				typeSpec, ok := spec.(*ast.TypeSpec)
+				if !ok {
+					continue
+				}
+
+				name := typeSpec.Name.Name
 
-			// Look for oneof interfaces (start with "is") first - these can be lowercase
-			if _, ok := typeSpec.Type.(*ast.InterfaceType); ok {
-				if len(name) > 2 && name[:2] == "is" {
-					m.OneOfs[name] = ""
-					continue // Don't skip oneof interfaces
+				// Look for oneof interfaces (start with "is") first - these can be lowercase
+				if iface, ok := typeSpec.Type.(*ast.InterfaceType); ok {
+					if len(name) > 2 && name[:2] == "is" {
+						m.OneOfs[name] = ""
+						continue // Don't skip oneof interfaces
+					}
+					if ast.IsExported(name) && typeSpec.TypeParams == nil {
+						m.Interfaces[name] = parseInterface(name, iface, imports)
+						m.Interfaces[name].Doc = docs[name]
+					}
 				}
-			}
 
-			// Skip unexported types (except oneof interfaces handled above)
-			if name[0] >= 'a' && name[0] <= 'z' {
-				continue
+				// Skip unexported types (except oneof interfaces handled above)
+				if name[0] >= 'a' && name[0] <= 'z' {
+					continue
+				}
 			}
 		}
 	}
 
 	// Second pass: find struct implementations and build model
-	for _, decl := range f.Decls {
-		genDecl, ok := decl.(*ast.GenDecl)
-		if !ok || genDecl.Tok != token.TYPE {
-			continue
-		}
-
-		for _, spec := range genDecl.Specs {
-			typeSpec, ok := spec.(*ast.TypeSpec)
-			if !ok {
+	for _, f := range files {
+		imports := fileImports(f)
+		for _, decl := range f.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.TYPE {
 				continue
 			}
 
-			name := typeSpec.Name.Name
+			for _, spec := range genDecl.Specs {
+				typeSpec, ok := spec.(*ast.TypeSpec)
+				if !ok {
+					continue
+				}
 
-			// Skip unexported types
-			if name[0] >= 'a' && name[0] <= 'z' {
-				continue
-			}
+				name := typeSpec.Name.Name
 
-			switch t := typeSpec.Type.(type) {
-			case *ast.StructType:
-				s := &Struct{Name: name}
+				// Skip unexported types
+				if name[0] >= 'a' && name[0] <= 'z' {
+					continue
+				}
 
-				for _, field := range t.Fields.List {
-					if len(field.Names) == 0 {
+				switch t := typeSpec.Type.(type) {
+				case *ast.StructType:
+					if mapEntry(name, t) {
 						continue
 					}
+					s := &Struct{Name: name, TypeParams: ParseTypeParams(typeSpec.TypeParams), Doc: docs[name]}
+
+					for _, field := range t.Fields.List {
+						if len(field.Names) == 0 {
+							// Embedded external types such as metav1.TypeMeta are set through their type name
+							if typeRef := exprToTypeRef(field.Type, imports); typeRef.Kind == "external" {
+								s.Fields = append(s.Fields, Field{Name: typeRef.Name, Type: typeRef})
+							} else if embedded, ok := embeddedLocalType(field.Type); ok {
+								// Exported types of the file are set through their fixture; the package of
+								// other embedded types is unknown without type information
+								s.Fields = append(s.Fields, Field{Name: embedded, Type: typeRef, Column: fieldColumn(field, embedded), JSONName: fieldJSONName(field), Embedded: true})
+							}
+							continue
+						}
 
-					fieldName := field.Names[0].Name
+						fieldName := field.Names[0].Name
 
-					if ProtoInternalFields[fieldName] {
-						continue
+						if ProtoInternalField(fieldName) {
+							continue
+						}
+
+						// Skip unexported fields
+						if fieldName[0] >= 'a' && fieldName[0] <= 'z' {
+							continue
+						}
+
+						typeRef := exprToTypeRef(field.Type, imports)
+						s.Fields = append(s.Fields, Field{Name: fieldName, Type: typeRef, Column: fieldColumn(field, fieldName), JSONName: fieldJSONName(field), SliceLen: fieldSliceLen(field)})
 					}
 
-					// Skip unexported fields
-					if fieldName[0] >= 'a' && fieldName[0] <= 'z' {
-						continue
+					// Without type information the type parameters of a generic struct parse as struct names
+					for i := range s.Fields {
+						s.Fields[i].Type = genericRef(s.Fields[i].Type, s.TypeParams)
 					}
 
-					typeRef := exprToTypeRef(field.Type)
-					s.Fields = append(s.Fields, Field{Name: fieldName, Type: typeRef})
-				}
+					if len(s.Fields) > 0 {
+						m.Structs[s.Name] = s
+					}
 
-				if len(s.Fields) > 0 {
-					m.Structs[s.Name] = s
-				}
+					m.addOneOfWrapper(name, t)
 
-				// Check if this struct implements a oneof interface
-				for ifaceName := range m.OneOfs {
-					if m.OneOfs[ifaceName] == "" {
-						parentName := ifaceName[2:] // remove "is" prefix
-						for i := len(parentName) - 1; i >= 0; i-- {
-							if parentName[i] == '_' {
-								prefix := parentName[:i]
-								if len(name) > len(prefix) && name[:len(prefix)] == prefix && name[len(prefix)] == '_' {
-									m.OneOfs[ifaceName] = name
-									break
-								}
-							}
+				case *ast.Ident:
+					// Type alias like `type TenantID string`
+					underlying := exprToTypeRef(t, imports)
+					if underlying.Kind == "primitive" {
+						m.TypeDefs[name] = &TypeDef{
+							Name:       name,
+							Underlying: underlying,
+							Doc:        docs[name],
 						}
 					}
-				}
 
-			case *ast.Ident:
-				// Type alias like `type TenantID string`
-				underlying := exprToTypeRef(t)
-				if underlying.Kind == "primitive" {
-					m.TypeDefs[name] = &TypeDef{
-						Name:       name,
-						Underlying: underlying,
-					}
+				case *ast.InterfaceType:
+					// Already handled in first pass
+					continue
 				}
-
-			case *ast.InterfaceType:
-				// Already handled in first pass
-				continue
 			}
 		}
 	}
+	parseEnums(files, m)
+	m.ResolveOneOfs(files)
+	m.markTypes()
 
 	return m, nil
 }
 
-func exprToTypeRef(expr ast.Expr) TypeRef {
+// markTypes turns the references to interfaces and enums of m, which parse as structs without type
+// information, into references of their kind
+func (m *Model) markTypes() {
+	var mark func(t *TypeRef)
+	mark = func(t *TypeRef) {
+		if t.Kind == "struct" && t.Pkg == "" && m.Interfaces[t.Name] != nil {
+			t.Kind = "interface"
+		}
+		if t.Kind == "struct" && t.Pkg == "" && m.Enums[t.Name] != nil {
+			t.Kind = "enum"
+		}
+		if t.Elem != nil {
+			mark(t.Elem)
+		}
+	}
+	for _, s := range m.Structs {
+		for i := range s.Fields {
+			mark(&s.Fields[i].Type)
+		}
+	}
+	for _, iface := range m.Interfaces {
+		for _, method := range iface.Methods {
+			for i := range method.Params {
+				mark(&method.Params[i])
+			}
+			for i := range method.Results {
+				mark(&method.Results[i])
+			}
+		}
+	}
+}
+
+// embeddedLocalType returns the name of the exported type of the same file an embedded field is of,
+// as T or *T
+func embeddedLocalType(expr ast.Expr) (string, bool) {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	ident, ok := expr.(*ast.Ident)
+	if !ok || !ast.IsExported(ident.Name) {
+		return "", false
+	}
+	return ident.Name, true
+}
+
+// fieldColumn returns the database column of a struct field from its tag
+func fieldColumn(field *ast.Field, fieldName string) string {
+	if field.Tag == nil {
+		return ""
+	}
+	tag, err := strconv.Unquote(field.Tag.Value)
+	if err != nil {
+		return ""
+	}
+	return ColumnFromTag(tag, fieldName)
+}
+
+// fieldJSONName returns the JSON key of a struct field from its json tag
+func fieldJSONName(field *ast.Field) string {
+	if field.Tag == nil {
+		return ""
+	}
+	tag, err := strconv.Unquote(field.Tag.Value)
+	if err != nil {
+		return ""
+	}
+	return JSONNameFromTag(tag)
+}
+
+// exprToTypeRef returns the type expr refers to. Types of other packages get the import path imports
+// maps their package name to.
+func exprToTypeRef(expr ast.Expr, imports map[string]string) TypeRef {
 	switch t := expr.(type) {
 	case *ast.Ident:
 		name := t.Name
@@ -224,26 +528,68 @@ func exprToTypeRef(expr ast.Expr) TypeRef {
 			"uint", "uint8", "uint16", "uint32", "uint64",
 			"float32", "float64", "byte", "rune":
 			return TypeRef{Kind: "primitive", Name: name}
+		case "any":
+			return TypeRef{Kind: "interface"}
+		case "error":
+			return TypeRef{Kind: "interface", Name: name}
 		}
-		if _, ok := ExternalTypes[name]; ok {
+		if ext, ok := ExternalTypes[name]; ok && ext.PkgPath == "" {
 			return TypeRef{Kind: "external", Name: name}
 		}
 		return TypeRef{Kind: "struct", Name: name}
 
 	case *ast.StarExpr:
-		elem := exprToTypeRef(t.X)
+		elem := exprToTypeRef(t.X, imports)
 		return TypeRef{Kind: "pointer", Elem: &elem}
 
 	case *ast.ArrayType:
-		elem := exprToTypeRef(t.Elt)
-		return TypeRef{Kind: "slice", Elem: &elem, Name: elem.Name}
+		elem := exprToTypeRef(t.Elt, imports)
+		if t.Len == nil {
+			return TypeRef{Kind: "slice", Elem: &elem, Name: elem.Name}
+		}
+		// Lengths given by constants are only known with type information, see resolveArrayLens
+		lit, ok := t.Len.(*ast.BasicLit)
+		if !ok || lit.Kind != token.INT {
+			return TypeRef{Kind: "unknown"}
+		}
+		n, err := strconv.ParseInt(lit.Value, 0, 64)
+		if err != nil {
+			return TypeRef{Kind: "unknown"}
+		}
+		return TypeRef{Kind: "array", Elem: &elem, Name: elem.Name, Len: n}
 
 	case *ast.SelectorExpr:
 		typeName := t.Sel.Name
 		if _, ok := ExternalTypes[typeName]; ok {
 			return TypeRef{Kind: "external", Name: typeName}
 		}
-		return TypeRef{Kind: "struct", Name: typeName}
+		var pkg string
+		if x, ok := t.X.(*ast.Ident); ok {
+			pkg = imports[x.Name]
+		}
+		if _, ok := InterfaceDefaults[pkg+"."+typeName]; ok {
+			return TypeRef{Kind: "interface", Name: typeName, Pkg: pkg}
+		}
+		return TypeRef{Kind: "struct", Name: typeName, Pkg: pkg}
+
+	case *ast.InterfaceType:
+		if len(t.Methods.List) == 0 {
+			return TypeRef{Kind: "interface"}
+		}
+		return TypeRef{Kind: "unknown"}
+
+	case *ast.IndexExpr:
+		// An instantiated generic type like Page[User]
+		base := exprToTypeRef(t.X, imports)
+		base.TypeArgs = []TypeRef{exprToTypeRef(t.Index, imports)}
+		return base
+
+	case *ast.IndexListExpr:
+		base := exprToTypeRef(t.X, imports)
+		for _, index := range t.Indices {
+			base.TypeArgs = append(base.TypeArgs, exprToTypeRef(index, imports))
+		}
+		return base
 
 	default:
 		return TypeRef{Kind: "unknown"}
@@ -254,10 +600,171 @@ func exprToTypeRef(expr ast.Expr) TypeRef {
 type GenerateOptions struct {
 	// TypePrefix is prepended to type names (e.g., "productionorderbase" -> "productionorderbase.Operation")
 	TypePrefix string
+	// TypeImport is the import path of the package of the types, imported as TypePrefix. Named types
+	// of other packages are qualified. Defaults to the package of the Source of the types.
+	TypeImport string `json:",omitempty"`
 	// FuncPrefix is inserted into fixture function names (e.g., "PB" -> "FixturePBOperation")
 	FuncPrefix string
+	// Include limits the fixtures to the types matching any of these globs ("*Request") or regular
+	// expressions, plus the types their fixtures need. See CompileTypePatterns.
+	Include []string `json:",omitempty"`
+	// Exclude leaves out the fixtures of the types matching any of these patterns, unless an
+	// included type needs them
+	Exclude []string `json:",omitempty"`
+	// DeclareTypes also declares the types of the model in the output (structs, enums, typedefs and
+	// oneofs), for models read from a JSON sample, OpenAPI document or SQL schema whose types aren't
+	// declared anywhere else
+	DeclareTypes bool `json:",omitempty"`
 	// ModStyle generates fixtures with functional options pattern (default: true)
 	ModStyle bool
+	// Incremental embeds the content hash of the output so unchanged output can be skipped
+	Incremental bool
+	// Seed adds a SeedX helper inserting the fixture into a database for structs with column mappings
+	Seed bool
+	// SeedPlaceholder is the bind parameter style of Seed helpers: "$" for $1, $2 (default) or "?"
+	SeedPlaceholder string `json:",omitempty"`
+	// Routes maps HTTP route patterns (e.g. "GET /users/{id}") to the type served on them by a
+	// generated FixtureHandler
+	Routes map[string]string `json:",omitempty"`
+	// YAMLLoaders generates a LoadXFixture(t, name) per struct decoding a YAML file of the test's
+	// testdata directory, like the ones YAMLFiles writes, over the fixture
+	YAMLLoaders bool `json:",omitempty"`
+	// Snapshots adds a WriteSnapshots function serializing every fixture: "json", or "protojson" to
+	// write protobuf messages with protojson
+	Snapshots string `json:",omitempty"`
+	// FieldOverrides maps "Struct.Field" to the Go expression the field is set to instead of its
+	// generated value, e.g. {"User.Email": `"user@example.com"`, "User.Age": "30"}
+	FieldOverrides map[string]string `json:",omitempty"`
+	// SliceLen is the number of elements of slices, one if not set. Strings and numbers differ
+	// between the elements ("Tags1", "Tags2"), as many bugs only show with several.
+	SliceLen int `json:",omitempty"`
+	// SliceLens maps "Struct.Field" to the number of elements of that slice field, 0 for an empty
+	// slice, taking precedence over a fixture:"len=N" tag of the field and SliceLen
+	SliceLens map[string]int `json:",omitempty"`
+	// AnyPayloads maps "Struct.Field" (or just "Field") to the message packed into that anypb.Any field
+	AnyPayloads map[string]string `json:",omitempty"`
+	// FixturePackages maps the import path of a type's package to a package already holding its
+	// fixtures, so values of those types call into it instead of being generated here
+	FixturePackages map[string]string `json:",omitempty"`
+	// Header is the comment opening generated files, e.g. "Code generated by fixture-generator. DO NOT
+	// EDIT." on one line, which marks them as generated for linters and code review tools. Lines
+	// may be given with or without their leading "//".
+	Header string `json:",omitempty"`
+	// Version of the generator, stamped into the header of the output so a fixture file can be traced
+	// back to the generator that produced it
+	Version string `json:",omitempty"`
+	// ModSignature is the signature of mods in ModStyle: "pointer" for func(*T) mutators (default) or
+	// "value" for func(T) T transforms
+	ModSignature string `json:",omitempty"`
+	// ModReturn is what ModStyle fixtures return: "pointer" for *T (default) or "value" for T, with
+	// the mods applied to a local copy
+	ModReturn string `json:",omitempty"`
+	// CustomFixtures are the fixture functions written by hand in the output package, like FixtureUser
+	// in fixtures_custom.go. Their fixtures aren't generated, the rest of the output calls them instead.
+	CustomFixtures []string `json:",omitempty"`
+	// Dump generates a DumpX(v) string helper per struct rendering a fixture one field per line
+	Dump bool `json:",omitempty"`
+	// InterfaceStrategy is what fields of interface types other than oneofs are set to: "nil"
+	// (default), "defaults" for standard implementations of the well-known InterfaceDefaults like
+	// bytes.NewReader(nil) for an io.Reader, or "stub" for those and a generated StubX doing nothing
+	// for the interfaces X of the model
+	InterfaceStrategy string `json:",omitempty"`
+	// EnumConstructors generates a FixtureX per enum value X returning it, next to the enum fixture
+	// returning the first value (see Model.PickEnumDefaults)
+	EnumConstructors bool `json:",omitempty"`
+	// Registry generates a Fixtures() func returning a constructor of every fixture by type name
+	Registry bool `json:",omitempty"`
+	// Calls generates a FixtureXCall() per pair of XRequest and XResponse structs, the messages of
+	// the gRPC method X, returning the fixtures of both
+	Calls bool `json:",omitempty"`
+	// Asserts generates an AssertXEqual(t, expected, actual) per struct reporting a go-cmp diff,
+	// ignoring unexported fields: "cmp", or "protocmp" to compare protobuf messages with protocmp
+	Asserts string `json:",omitempty"`
+	// NilOptionals leaves pointers to scalars nil, like the fields of proto3 optional fields, instead
+	// of pointing them to a value
+	NilOptionals bool `json:",omitempty"`
+	// Sparse leaves the pointer fields of ModStyle fixtures nil and generates a WithX mod per field to
+	// populate them instead
+	Sparse bool `json:",omitempty"`
+	// Options generates a WithX(v) mod per field of ModStyle fixtures setting it to v
+	Options bool `json:",omitempty"`
+	// Rapid generates a RapidX generator per struct for property tests with pgregory.net/rapid
+	Rapid bool `json:",omitempty"`
+	// Weights maps enum names to the weights their values are drawn with by Rapid generators, and
+	// oneof interface names (isOrder_Payload) to the weights of their implementations. Values and
+	// implementations without a weight aren't drawn.
+	Weights map[string]map[string]int `json:",omitempty"`
+	// Pairwise maps struct names to fields whose interesting values (enum values, bools, nil or not)
+	// are combined pairwise by a generated PairwiseX function
+	Pairwise map[string][]string `json:",omitempty"`
+	// Aggregates maps root struct names to the related structs whose fixtures a generated
+	// ArrangeXAggregate function returns together with the root's, wired by IDs and references
+	Aggregates map[string][]string `json:",omitempty"`
+	// Hooks generates an XDefaults variable per struct holding a func per field of a basic type, typedef
+	// or enum, which the fixture calls for the field's value, so test suites can re-point them
+	Hooks bool `json:",omitempty"`
+	// Providers generates a ProvideFixtureX constructor per struct and a FixtureProviders set of them:
+	// "wire" for a google/wire provider set or "fx" for an uber/fx option
+	Providers string `json:",omitempty"`
+	// Clock sets time fields from a generated package-level FixtureNow func instead of a literal, so
+	// test suites freezing time can point it at their clock
+	Clock bool `json:",omitempty"`
+	// ValueStrategy is how fixtures get their values: "static" literals (default), "random" values drawn
+	// at runtime from a source reseeded by a generated SeedFixtures func, reproducible by seed, or
+	// "faker" literals that look real for strings named like an email, phone, URL, name or address
+	ValueStrategy string `json:",omitempty"`
+	// FakerSeed picks the "faker" values, which are the same for every run with the same seed
+	FakerSeed int64 `json:",omitempty"`
+	// ByteLength makes []byte fields that many bytes of data, hashed from the struct and field name
+	// so they are the same every run, instead of []byte("Field")
+	ByteLength int `json:",omitempty"`
+	// ExternalTypes maps import path and name of types ("github.com/shopspring/decimal.Decimal") to
+	// their import and default value, in addition to the registered ExternalTypes
+	ExternalTypes map[string]ExternalType `json:",omitempty"`
+	// MaxDepth is how many levels of recursive references (Category.Parent, or through other types)
+	// a fixture populates with nested literals. Deeper references, and all of them at 0, are nil.
+	MaxDepth int `json:",omitempty"`
+	// GoVersion is the Go version the generated code has to build with (e.g. "1.17"). Before 1.18 the
+	// generic ptr helper is replaced by one helper per type.
+	GoVersion string `json:",omitempty"`
+	// PtrFunc names the helper fixtures take the address of values with, "ptr" by default. Fixtures
+	// generated into the package of their types need a name the package doesn't declare itself.
+	PtrFunc string `json:",omitempty"`
+	// FormatError is called with a *FormatError for each declaration gofmt rejects; it is written
+	// unformatted
+	FormatError func(err error) `json:"-"`
+	// Unchanged is asked by GenerateFiles whether each file was last written with the content hash
+	// hash; those it reports as unchanged are neither formatted nor returned with content
+	Unchanged func(file, hash string) bool `json:"-"`
+}
+
+// HashPrefix marks the line holding the content hash in generated output
+const HashPrefix = "// fixture-generator:hash "
+
+// VersionPrefix marks the line holding the generator version in generated output
+const VersionPrefix = "// fixture-generator:version "
+
+// ReadHash returns the content hash stored in previously generated output, or "" if there is none
+func ReadHash(content []byte) string {
+	return readHeader(content, HashPrefix)
+}
+
+// ReadVersion returns the generator version stamped into previously generated output, or "" if there is none
+func ReadVersion(content []byte) string {
+	return readHeader(content, VersionPrefix)
+}
+
+// readHeader returns the value of the header line starting with prefix
+func readHeader(content []byte, prefix string) string {
+	for _, line := range strings.Split(string(content), "\n") {
+		if strings.HasPrefix(line, prefix) {
+			return strings.TrimSpace(strings.TrimPrefix(line, prefix))
+		}
+		if strings.HasPrefix(line, "package ") {
+			break
+		}
+	}
+	return ""
 }
 
 // Generate produces fixture functions from the model
@@ -267,10 +774,102 @@ func Generate(m *Model, pkgName string) string {
 
 // GenerateWithOptions produces fixture functions from the model with optional prefixes
 func GenerateWithOptions(m *Model, pkgName string, opts GenerateOptions) string {
+	var out bytes.Buffer
+	generateDecls(m, pkgName, opts, func(decl []byte) error {
+		out.Write(decl)
+		return nil
+	})
+	return out.String()
+}
+
+// GenerateTo streams formatted fixture functions to w, formatting one declaration at a time
+// so the whole file never has to be held in memory twice
+func GenerateTo(w io.Writer, m *Model, pkgName string, opts GenerateOptions) error {
+	sep := ""
+	lines := 0
+	return generateDecls(m, pkgName, opts, func(decl []byte) error {
+		formatted, err := format.Source(decl)
+		if err != nil {
+			formatted = decl
+			if opts.FormatError != nil {
+				// Positions are relative to the declaration, which follows the lines written so far
+				// without the blank lines it starts with
+				trimmed := bytes.Count(decl[:len(decl)-len(bytes.TrimLeft(decl, " \t\n"))], []byte("\n"))
+				opts.FormatError(newFormatError("", lines+len(sep)-trimmed, err))
+			}
+		}
+		// Declarations are formatted in isolation, so separate them the way gofmt would
+		text := fmt.Sprintf("%s%s\n", sep, bytes.TrimSpace(formatted))
+		lines += strings.Count(text, "\n")
+		sep = "\n"
+		_, err = io.WriteString(w, text)
+		return err
+	})
+}
+
+// ContentHash returns a hash of the unformatted output for m, leaving out the line Incremental
+// records it in, without holding it in memory. Equal hashes mean formatting and writing the output
+// again would produce the same file.
+func ContentHash(m *Model, pkgName string, opts GenerateOptions) string {
+	opts.Incremental = false
+	h := sha256.New()
+	generateDecls(m, pkgName, opts, func(decl []byte) error {
+		h.Write(decl)
+		return nil
+	})
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// generateDecls renders the file header and every fixture function, passing each one to emit as soon as it is complete
+func generateDecls(m *Model, pkgName string, opts GenerateOptions, emit func(decl []byte) error) error {
+	if len(opts.CustomFixtures) > 0 {
+		return generateCustomDecls(m, pkgName, opts, emit)
+	}
+	return generateOwnedDecls(m, pkgName, opts, func(_ string, decl []byte) error {
+		return emit(decl)
+	})
+}
+
+// headerOwner owns the header of the file, its comments, package clause and imports, in generateOwnedDecls
+const headerOwner = "package"
+
+// generateOwnedDecls is generateDecls passing emit the type the declarations belong to as well: the
+// name of a typedef, enum or struct for its fixture and helpers, headerOwner for the header of the
+// file, or "" for the declarations shared by all types
+func generateOwnedDecls(m *Model, pkgName string, opts GenerateOptions, emit func(owner string, decl []byte) error) error {
+	m, err := filterTypes(m, opts)
+	if err != nil {
+		return err
+	}
+	if opts.TypeImport == "" {
+		opts.TypeImport = modelPackage(m)
+	}
 	var b bytes.Buffer
+	cache := newValueCache()
+	owner := headerOwner
+	flush := func() error {
+		// Nothing is left of the fixtures of types written by hand without helpers
+		if b.Len() == 0 {
+			return nil
+		}
+		err := emit(owner, b.Bytes())
+		b.Reset()
+		return err
+	}
+
+	b.WriteString(headerComment(opts))
+	if opts.Version != "" {
+		b.WriteString(VersionPrefix + opts.Version + "\n")
+	}
+	if opts.Incremental {
+		b.WriteString(HashPrefix + ContentHash(m, pkgName, opts) + "\n")
+	}
+	if opts.Header != "" || opts.Version != "" || opts.Incremental {
+		b.WriteString("\n")
+	}
 	b.WriteString("package " + pkgName + "\n\n")
 
-	imports := collectImports(m, opts.TypePrefix)
+	imports := collectImports(m, opts)
 	if len(imports) > 0 {
 		b.WriteString("import (\n")
 		for _, imp := range imports {
@@ -278,8 +877,41 @@ func GenerateWithOptions(m *Model, pkgName string, opts GenerateOptions) string
 		}
 		b.WriteString(")\n\n")
 	}
+	if err := flush(); err != nil {
+		return err
+	}
+	owner = ""
 
-	b.WriteString("func ptr[T any](v T) *T { return &v }\n\n")
+	if noGenerics(opts) {
+		b.WriteString(ptrHelpers(m, opts))
+	} else {
+		b.WriteString("func " + ptrName(opts) + "[T any](v T) *T { return &v }\n\n")
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+	if randomValues(m, opts) {
+		b.WriteString(randomHelper(opts))
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+	if hasClock(m, opts) {
+		b.WriteString(clockFunc(opts))
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+	if usesExternal(m, "Any") {
+		if noGenerics(opts) {
+			b.WriteString(anyHelperNoGenerics)
+		} else {
+			b.WriteString(anyHelper)
+		}
+		if err := flush(); err != nil {
+			return err
+		}
+	}
 
 	// Helper to prefix type names
 	prefixType := func(name string) string {
@@ -289,198 +921,558 @@ func GenerateWithOptions(m *Model, pkgName string, opts GenerateOptions) string
 		return name
 	}
 
+	if opts.DeclareTypes {
+		for _, name := range sortedKeys(m.TypeDefs) {
+			owner = name
+			fmt.Fprintf(&b, "type %s %s\n\n", name, declTypeName(m.TypeDefs[name].Underlying, opts))
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		for _, name := range sortedKeys(m.Enums) {
+			owner = name
+			b.WriteString(enumDecl(m.Enums[name]))
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		for _, name := range sortedKeys(m.OneOfs) {
+			owner = name
+			b.WriteString(oneOfDecl(m, name))
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		for _, name := range sortedKeys(m.Structs) {
+			owner = name
+			b.WriteString(structDecl(m.Structs[name], opts))
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+
 	// Generate typedef fixtures
-	for _, td := range m.TypeDefs {
+	for _, name := range sortedKeys(m.TypeDefs) {
+		td := m.TypeDefs[name]
+		owner = name
+		start := b.Len()
+		b.WriteString(docComment("Fixture"+opts.FuncPrefix+td.Name, prefixType(td.Name), td.Doc, td.Source))
 		if opts.ModStyle {
-			fmt.Fprintf(&b, "func Fixture%s%s(mods ...func(*%s)) *%s {\n", opts.FuncPrefix, td.Name, prefixType(td.Name), prefixType(td.Name))
-			value := fmt.Sprintf("%s(%s)", prefixType(td.Name), genPrimitiveValue(td.Underlying.Name, td.Name, td.Name))
-			fmt.Fprintf(&b, "\tresult := &%s\n", value)
-			fmt.Fprintf(&b, "\tfor _, mod := range mods {\n")
-			fmt.Fprintf(&b, "\t\tmod(result)\n")
-			fmt.Fprintf(&b, "\t}\n")
+			fmt.Fprintf(&b, "func Fixture%s%s(mods ...%s) %s {\n", opts.FuncPrefix, td.Name, modFunc(prefixType(td.Name), opts), fixtureResult(prefixType(td.Name), opts))
+			value := fmt.Sprintf("%s(%s)", prefixType(td.Name), primitiveValue(td.Underlying.Name, td.Name, td.Name, opts))
+			addr, pointer, deref := modOperands("result", opts)
+			// Conversions aren't addressable
+			if addr != "" {
+				fmt.Fprintf(&b, "\tvalue := %s\n", value)
+				value = "value"
+			}
+			fmt.Fprintf(&b, "\tresult := %s%s\n", addr, value)
+			b.WriteString(applyMods(pointer, deref, opts))
 			fmt.Fprintf(&b, "\treturn result\n")
 		} else {
 			fmt.Fprintf(&b, "func Fixture%s%s() %s {\n", opts.FuncPrefix, td.Name, prefixType(td.Name))
-			fmt.Fprintf(&b, "\treturn %s(%s)\n", prefixType(td.Name), genPrimitiveValue(td.Underlying.Name, td.Name, td.Name))
+			fmt.Fprintf(&b, "\treturn %s(%s)\n", prefixType(td.Name), primitiveValue(td.Underlying.Name, td.Name, td.Name, opts))
 		}
 		fmt.Fprintf(&b, "}\n\n")
+		// The canonical value as a constant, so tests can assert against it without calling the fixture.
+		// Random values have none.
+		if value := primitiveValue(td.Underlying.Name, td.Name, td.Name, opts); value != "nil" && opts.ValueStrategy != "random" {
+			fmt.Fprintf(&b, "// Fixture%s%sValue is the value of Fixture%s%s.\n", opts.FuncPrefix, td.Name, opts.FuncPrefix, td.Name)
+			fmt.Fprintf(&b, "const Fixture%s%sValue %s = %s\n\n", opts.FuncPrefix, td.Name, prefixType(td.Name), value)
+		}
+		if customFixture(td.Name, opts) {
+			b.Truncate(start)
+		}
+		if err := flush(); err != nil {
+			return err
+		}
 	}
 
 	// Generate enum fixtures
-	for _, e := range m.Enums {
-		var firstValue string
+	for _, name := range sortedKeys(m.Enums) {
+		e := m.Enums[name]
+		owner = name
+		var values []string
 		for _, v := range e.Values {
-			if v != "_" && v != "EnforceVersion" {
-				firstValue = v
-				break
+			if !InternalConstant(v) {
+				values = append(values, prefixType(v))
 			}
 		}
-		if firstValue == "" {
+		if len(values) == 0 {
 			continue
 		}
+		value := enumValue(prefixType(e.Name), values, opts)
+		start := b.Len()
+		b.WriteString(docComment("Fixture"+opts.FuncPrefix+e.Name, prefixType(e.Name), e.Doc, e.Source))
 		if opts.ModStyle {
-			fmt.Fprintf(&b, "func Fixture%s%s(mods ...func(*%s)) *%s {\n", opts.FuncPrefix, e.Name, prefixType(e.Name), prefixType(e.Name))
-			fmt.Fprintf(&b, "\tvalue := %s\n", prefixType(firstValue))
-			fmt.Fprintf(&b, "\tfor _, mod := range mods {\n")
-			fmt.Fprintf(&b, "\t\tmod(&value)\n")
-			fmt.Fprintf(&b, "\t}\n")
-			fmt.Fprintf(&b, "\treturn &value\n")
+			fmt.Fprintf(&b, "func Fixture%s%s(mods ...%s) %s {\n", opts.FuncPrefix, e.Name, modFunc(prefixType(e.Name), opts), fixtureResult(prefixType(e.Name), opts))
+			fmt.Fprintf(&b, "\tvalue := %s\n", value)
+			b.WriteString(applyMods("&value", "value", opts))
+			if returnsPointer(opts) {
+				fmt.Fprintf(&b, "\treturn &value\n")
+			} else {
+				fmt.Fprintf(&b, "\treturn value\n")
+			}
 		} else {
 			fmt.Fprintf(&b, "func Fixture%s%s() %s {\n", opts.FuncPrefix, e.Name, prefixType(e.Name))
-			fmt.Fprintf(&b, "\treturn %s\n", prefixType(firstValue))
+			fmt.Fprintf(&b, "\treturn %s\n", value)
 		}
 		fmt.Fprintf(&b, "}\n\n")
+		if customFixture(e.Name, opts) {
+			b.Truncate(start)
+		}
+		if opts.EnumConstructors {
+			b.WriteString(enumConstructors(m, e, opts))
+		}
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+
+	owner = ""
+
+	if opts.InterfaceStrategy == "stub" {
+		b.WriteString(interfaceStubs(m, opts))
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+
+	if hasRapid(m, opts) {
+		b.WriteString(rapidHelper)
+		if len(opts.Weights) > 0 {
+			b.WriteString(rapidWeightedHelper)
+		}
+		if err := flush(); err != nil {
+			return err
+		}
 	}
 
 	// Generate struct fixtures
-	for _, s := range m.Structs {
+	for _, name := range sortedKeys(m.Structs) {
+		s := m.Structs[name]
+		owner = name
+		if len(s.TypeParams) > 0 && customFixture(s.Name, opts) {
+			continue
+		}
+		if len(s.TypeParams) > 0 {
+			b.WriteString(genericFixture(m, s, opts, cache))
+			if err := flush(); err != nil {
+				return err
+			}
+			continue
+		}
+		b.WriteString(hookFuncs(m, s, opts, cache))
+		start := b.Len()
+		b.WriteString(docComment("Fixture"+opts.FuncPrefix+s.Name, prefixType(s.Name), s.Doc, s.Source))
 		if opts.ModStyle {
-			fmt.Fprintf(&b, "func Fixture%s%s(mods ...func(*%s)) *%s {\n", opts.FuncPrefix, s.Name, prefixType(s.Name), prefixType(s.Name))
-			fmt.Fprintf(&b, "\tvalue := &%s{\n", prefixType(s.Name))
+			fmt.Fprintf(&b, "func Fixture%s%s(mods ...%s) %s {\n", opts.FuncPrefix, s.Name, modFunc(prefixType(s.Name), opts), fixtureResult(prefixType(s.Name), opts))
+			addr, pointer, deref := modOperands("value", opts)
+			if s.Builder != "" {
+				// Build returns a pointer already
+				star := ""
+				if !returnsPointer(opts) {
+					star = "*"
+				}
+				fmt.Fprintf(&b, "\tvalue := %s%s{\n", star, prefixType(s.Builder))
+			} else {
+				fmt.Fprintf(&b, "\tvalue := %s%s{\n", addr, prefixType(s.Name))
+			}
 			for _, f := range s.Fields {
-				fmt.Fprintf(&b, "\t\t%s: %s,\n", f.Name, genValue(m, f.Type, f.Name, s.Name, opts))
+				if sparseField(f, opts) && s.Builder == "" || foreignEmbedded(s, f, opts) || nilOptional(m, f, s.Name, opts) {
+					continue
+				}
+				fmt.Fprintf(&b, "\t\t%s: %s,\n", f.Name, structFieldValue(m, s, f, opts, cache))
 			}
-			fmt.Fprintf(&b, "\t}\n")
-			fmt.Fprintf(&b, "\tfor _, mod := range mods {\n")
-			fmt.Fprintf(&b, "\t\tmod(value)\n")
-			fmt.Fprintf(&b, "\t}\n")
+			fmt.Fprintf(&b, "\t}%s\n", build(s))
+			b.WriteString(promotedStatements(m, s, opts, cache))
+			b.WriteString(applyMods(pointer, deref, opts))
 			fmt.Fprintf(&b, "\treturn value\n")
 		} else {
 			fmt.Fprintf(&b, "func Fixture%s%s() %s {\n", opts.FuncPrefix, s.Name, prefixType(s.Name))
-			fmt.Fprintf(&b, "\treturn %s{\n", prefixType(s.Name))
+			promoted := promotedStatements(m, s, opts, cache)
+			if s.Builder != "" {
+				fmt.Fprintf(&b, "\treturn *%s{\n", prefixType(s.Builder))
+			} else if promoted == "" {
+				fmt.Fprintf(&b, "\treturn %s{\n", prefixType(s.Name))
+			} else {
+				fmt.Fprintf(&b, "\tvalue := %s{\n", prefixType(s.Name))
+			}
 			for _, f := range s.Fields {
-				fmt.Fprintf(&b, "\t\t%s: %s,\n", f.Name, genValue(m, f.Type, f.Name, s.Name, opts))
+				if foreignEmbedded(s, f, opts) || nilOptional(m, f, s.Name, opts) {
+					continue
+				}
+				fmt.Fprintf(&b, "\t\t%s: %s,\n", f.Name, structFieldValue(m, s, f, opts, cache))
+			}
+			fmt.Fprintf(&b, "\t}%s\n", build(s))
+			if promoted != "" {
+				b.WriteString(promoted)
+				fmt.Fprintf(&b, "\treturn value\n")
 			}
-			fmt.Fprintf(&b, "\t}\n")
 		}
 		fmt.Fprintf(&b, "}\n\n")
+		if customFixture(s.Name, opts) {
+			b.Truncate(start)
+		}
+		b.WriteString(withHelpers(m, s, opts, cache))
+		b.WriteString(optionHelpers(s, opts))
+		b.WriteString(variantFuncs(m, s, opts, cache))
+		if fields := opts.Pairwise[s.Name]; len(fields) > 0 {
+			b.WriteString(pairwiseFunc(m, s, fields, opts, cache))
+		}
+		if opts.Rapid {
+			b.WriteString(rapidFunc(m, s, opts))
+		}
+		if opts.Seed {
+			b.WriteString(seedFunc(s, opts))
+		}
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+
+	owner = ""
+
+	if len(opts.Aggregates) > 0 {
+		b.WriteString(aggregateFuncs(m, opts))
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+
+	if opts.Providers != "" {
+		b.WriteString(providerFuncs(m, opts))
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+
+	if opts.Dump && len(m.Structs) > 0 {
+		b.WriteString(dumpFuncs(m, opts))
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+
+	if opts.Asserts != "" && len(fixtureStructs(m)) > 0 {
+		b.WriteString(assertFuncs(m, opts))
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+
+	if opts.Registry && len(m.Structs)+len(m.Enums)+len(m.TypeDefs) > 0 {
+		b.WriteString(registryFuncs(m, opts))
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+
+	if opts.Calls && len(callPairs(m)) > 0 {
+		b.WriteString(callFuncs(m, opts))
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+
+	if opts.Snapshots != "" {
+		b.WriteString(snapshotFuncs(m, opts))
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+
+	if opts.YAMLLoaders && len(m.Structs) > 0 {
+		b.WriteString(yamlLoaders(m, opts))
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+
+	if len(opts.Routes) > 0 {
+		b.WriteString(httpStubs(m, opts))
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// build returns the call completing the literal of s: Build() for the builder of an opaque API message
+func build(s *Struct) string {
+	if s.Builder != "" {
+		return ".Build()"
+	}
+	return ""
+}
+
+// docComment renders the doc comment of the fixture function fn of typ, linking to the type and naming
+// its declaration so hovers show what the fixture constructs, followed by the doc of the type. Types
+// not taken from Go code get none.
+func docComment(fn, typ, doc string, src *Source) string {
+	if src == nil && doc == "" {
+		return ""
 	}
+	name := typ[strings.LastIndex(typ, ".")+1:]
+	if src == nil {
+		return fmt.Sprintf("// %s returns a fixture of [%s].\n", fn, typ) + typeDocLines(name, doc)
+	}
+	// Types of descriptor sets without source info have no line
+	if src.Line == 0 {
+		return fmt.Sprintf("// %s returns a fixture of [%s], declared in %s at %s.\n", fn, typ, src.Pkg, src.File) + typeDocLines(name, doc)
+	}
+	return fmt.Sprintf("// %s returns a fixture of [%s], declared in %s at %s:%d.\n", fn, typ, src.Pkg, src.File, src.Line) + typeDocLines(name, doc)
+}
+
+// modFunc returns the type of the mods a fixture of typ takes
+func modFunc(typ string, opts GenerateOptions) string {
+	if opts.ModSignature == "value" {
+		return "func(" + typ + ") " + typ
+	}
+	return "func(*" + typ + ")"
+}
 
-	return b.String()
+// returnsPointer reports whether fixtures return *T: in ModStyle unless ModReturn is "value"
+func returnsPointer(opts GenerateOptions) bool {
+	return opts.ModStyle && opts.ModReturn != "value"
+}
+
+// fixtureResult returns the result type of a ModStyle fixture of typ
+func fixtureResult(typ string, opts GenerateOptions) string {
+	if returnsPointer(opts) {
+		return "*" + typ
+	}
+	return typ
+}
+
+// modOperands returns how a ModStyle fixture held in the variable v is declared (the prefix of its
+// literal) and given to applyMods as pointer and value expression
+func modOperands(v string, opts GenerateOptions) (addr, pointer, value string) {
+	if returnsPointer(opts) {
+		return "&", v, "*" + v
+	}
+	return "", "&" + v, v
 }
 
-// GenerateFormatted produces formatted fixture functions
+// applyMods renders the loop applying mods to the fixture, given as pointer and as value expression
+func applyMods(pointer, value string, opts GenerateOptions) string {
+	apply := "mod(" + pointer + ")"
+	if opts.ModSignature == "value" {
+		apply = value + " = mod(" + value + ")"
+	}
+	return "\tfor _, mod := range mods {\n\t\t" + apply + "\n\t}\n"
+}
+
+// GenerateFormatted produces formatted fixture functions. Code gofmt rejects is returned unformatted
+// together with a *FormatError.
 func GenerateFormatted(m *Model, pkgName string) (string, error) {
 	return GenerateFormattedWithOptions(m, pkgName, GenerateOptions{ModStyle: true})
 }
 
-// GenerateFormattedWithOptions produces formatted fixture functions with optional prefixes
+// GenerateFormattedWithOptions produces formatted fixture functions with optional prefixes. Code
+// gofmt rejects is returned unformatted together with a *FormatError.
 func GenerateFormattedWithOptions(m *Model, pkgName string, opts GenerateOptions) (string, error) {
+	if _, err := filterTypes(m, opts); err != nil {
+		return "", err
+	}
 	out := GenerateWithOptions(m, pkgName, opts)
 	formatted, err := format.Source([]byte(out))
 	if err != nil {
-		return out, nil
+		return out, newFormatError("", 0, err)
 	}
 	return string(formatted), nil
 }
 
 // GenValue generates a default value for a type (without prefix support, for backward compatibility)
 func GenValue(m *Model, t TypeRef, fieldName string, structName string) string {
-	return genValue(m, t, fieldName, structName, GenerateOptions{ModStyle: true})
+	return genValue(m, t, fieldName, structName, GenerateOptions{ModStyle: true}, nil)
+}
+
+// valueCache memoizes rendered oneof literals, and which fields are recursive, for the duration of a
+// single generation, where the model and options are fixed. A nil cache disables memoization.
+type valueCache struct {
+	oneOfs    map[string]string
+	recursive map[string]bool
+}
+
+func newValueCache() *valueCache {
+	return &valueCache{oneOfs: make(map[string]string), recursive: make(map[string]bool)}
 }
 
 // genValue generates a default value for a type with optional prefix support
-func genValue(m *Model, t TypeRef, fieldName string, structName string, opts GenerateOptions) string {
-	prefixType := func(name string) string {
-		if opts.TypePrefix != "" {
-			return opts.TypePrefix + "." + name
+func genValue(m *Model, t TypeRef, fieldName string, structName string, opts GenerateOptions, cache *valueCache) string {
+	if foreignType(t, opts) {
+		if v, ok := depValue(m, t, opts, make(map[string]bool)); ok {
+			return v
 		}
-		return name
+		return foreignValue(t, false, opts)
 	}
-
 	switch t.Kind {
 	case "primitive":
-		return genPrimitiveValue(t.Name, fieldName, structName)
+		return primitiveValue(t.Name, fieldName, structName, opts)
 	case "struct":
 		// Check if this is actually a oneof interface (starts with "is")
 		if len(t.Name) > 2 && t.Name[:2] == "is" {
-			// This is a oneof interface, find the first implementation
-			if impl, ok := m.OneOfs[t.Name]; ok && impl != "" {
-				// Check if we have the implementation struct in our model
-				if implStruct, exists := m.Structs[impl]; exists {
-					// Generate populated struct with default values
-					var structFields []string
-					for _, field := range implStruct.Fields {
-						fieldValue := genValue(m, field.Type, field.Name, impl, opts)
-						structFields = append(structFields, fmt.Sprintf("%s: %s", field.Name, fieldValue))
-					}
-					if len(structFields) > 0 {
-						return fmt.Sprintf("&%s{\n\t\t\t%s,\n\t\t}", prefixType(impl), strings.Join(structFields, ",\n\t\t\t"))
-					}
-				}
-				// Fallback to empty struct if no fields found
-				return "&" + prefixType(impl) + "{}"
-			}
-			return "nil"
+			return genOneOfValue(m, t.Name, opts, cache)
+		}
+		if ext, ok := lookupExternal(t, opts); ok {
+			return externalValue(ext, m, fieldName, structName, opts)
+		}
+		if call, ok := fixtureCall(t, opts); ok {
+			return call
 		}
 
 		// Check if it's actually a typedef
 		if _, ok := m.TypeDefs[t.Name]; ok {
-			if opts.ModStyle {
+			if returnsPointer(opts) {
 				return "*Fixture" + opts.FuncPrefix + t.Name + "()"
 			}
 			return "Fixture" + opts.FuncPrefix + t.Name + "()"
 		}
-		if opts.ModStyle {
-			return "*Fixture" + opts.FuncPrefix + t.Name + "()"
+		args := fixtureArgs(m, t, fieldName, structName, opts, cache)
+		if returnsPointer(opts) {
+			return "*Fixture" + opts.FuncPrefix + t.Name + args
 		}
-		return "Fixture" + opts.FuncPrefix + t.Name + "()"
+		return "Fixture" + opts.FuncPrefix + t.Name + args
+	case "typeparam":
+		return typeParamVar(t.Name)
 	case "enum":
-		if opts.ModStyle {
+		if ext, ok := lookupExternal(t, opts); ok {
+			return externalValue(ext, m, fieldName, structName, opts)
+		}
+		if call, ok := fixtureCall(t, opts); ok {
+			return call
+		}
+		if returnsPointer(opts) {
 			return "*Fixture" + opts.FuncPrefix + t.Name + "()"
 		}
 		return "Fixture" + opts.FuncPrefix + t.Name + "()"
 	case "typedef":
-		if opts.ModStyle {
+		if returnsPointer(opts) {
 			return "*Fixture" + opts.FuncPrefix + t.Name + "()"
 		}
 		return "Fixture" + opts.FuncPrefix + t.Name + "()"
 	case "oneof":
-		if impl, ok := m.OneOfs[t.Name]; ok && impl != "" {
-			// Check if we have the implementation struct in our model
-			if implStruct, exists := m.Structs[impl]; exists {
-				// Generate populated struct with default values
-				var structFields []string
-				for _, field := range implStruct.Fields {
-					fieldValue := genValue(m, field.Type, field.Name, impl, opts)
-					structFields = append(structFields, fmt.Sprintf("%s: %s", field.Name, fieldValue))
-				}
-				if len(structFields) > 0 {
-					return fmt.Sprintf("&%s{\n\t\t\t%s,\n\t\t}", prefixType(impl), strings.Join(structFields, ",\n\t\t\t"))
-				}
-			}
-			// Fallback to empty struct if no fields found
-			return "&" + prefixType(impl) + "{}"
+		return genOneOfValue(m, t.Name, opts, cache)
+	case "interface":
+		if ext, ok := lookupExternal(t, opts); ok {
+			return externalValue(ext, m, fieldName, structName, opts)
 		}
-		return "nil"
+		return interfaceValue(m, t, opts)
 	case "slice":
 		if t.Elem == nil {
 			return "nil"
 		}
-		return "[]" + typeName(*t.Elem, opts) + "{" + genValue(m, *t.Elem, fieldName, structName, opts) + "}"
+		if byteSlice(t) && (opts.ValueStrategy != "random" || opts.ByteLength > 0) {
+			return byteSliceValue(fieldName, structName, opts)
+		}
+		return sliceValue(m, t, fieldName, structName, max(opts.SliceLen, 1), opts, cache)
+	case "array":
+		// The first element is set, the others are zero
+		if t.Elem == nil || t.Len == 0 {
+			return typeName(t, opts) + "{}"
+		}
+		return typeName(t, opts) + "{" + genValue(m, *t.Elem, fieldName, structName, opts, cache) + "}"
 	case "pointer":
-		if t.Elem == nil || t.Elem.Kind == "unknown" {
+		if t.Elem == nil || t.Elem.Kind == "unknown" || t.Elem.Kind == "interface" {
 			return "nil"
 		}
-		if t.Elem.Kind == "external" {
-			if ext, ok := ExternalTypes[t.Elem.Name]; ok {
-				return ext.Value
+		if ext, ok := lookupExternal(*t.Elem, opts); ok {
+			if ext.Pointer {
+				return externalValue(ext, m, fieldName, structName, opts)
+			}
+			return ptrFunc(*t.Elem, opts) + "(" + externalValue(ext, m, fieldName, structName, opts) + ")"
+		}
+		if foreignType(*t.Elem, opts) {
+			if v, ok := depValue(m, *t.Elem, opts, make(map[string]bool)); ok && t.Elem.Kind == "struct" {
+				return "&" + v
 			}
+			return foreignValue(*t.Elem, true, opts)
 		}
-		if opts.ModStyle && (t.Elem.Kind == "struct" || t.Elem.Kind == "enum" || t.Elem.Kind == "typedef") {
-			return genValue(m, *t.Elem, fieldName, structName, opts)
+		if _, ok := fixtureCall(*t.Elem, opts); !ok && optionalScalar(m, t) {
+			return optionalValue(m, *t.Elem, fieldName, structName, opts, cache)
+		}
+		if returnsPointer(opts) && (t.Elem.Kind == "struct" || t.Elem.Kind == "enum" || t.Elem.Kind == "typedef") {
+			return genValue(m, *t.Elem, fieldName, structName, opts, cache)
 		}
 
-		return "ptr(" + genValue(m, *t.Elem, fieldName, structName, opts) + ")"
+		return ptrFunc(*t.Elem, opts) + "(" + genValue(m, *t.Elem, fieldName, structName, opts, cache) + ")"
 	case "external":
-		if ext, ok := ExternalTypes[t.Name]; ok {
-			return ext.Value
+		if ext, ok := lookupExternal(t, opts); ok {
+			return externalValue(ext, m, fieldName, structName, opts)
 		}
 		return "nil"
 	}
 	return "nil"
 }
 
+// genFieldValue generates the value of a struct field: that of the field it references, the default
+// recorded in the model, or for fields referring back to their struct literals nested MaxDepth deep
+func genFieldValue(m *Model, f Field, structName string, opts GenerateOptions, cache *valueCache) string {
+	if v, ok := opts.FieldOverrides[structName+"."+f.Name]; ok {
+		return v
+	}
+	if target, targetStruct, ok := referencedField(m, f); ok {
+		return genFieldValue(m, target, targetStruct, opts, cache)
+	}
+	if f.Default != "" {
+		// Defaults of nullable columns are literals of the element type
+		if f.Type.Kind == "pointer" && f.Type.Elem != nil && jsonLiteral(f.Default) != nil {
+			return ptrFunc(*f.Type.Elem, opts) + "(" + f.Default + ")"
+		}
+		return withoutGenerics(f.Default, opts)
+	}
+	if recursiveField(m, structName, f, cache) {
+		return recursiveValue(m, f.Type, opts)
+	}
+	if n, ok := fieldLen(f, structName, opts); ok && f.Type.Kind == "slice" && f.Type.Elem != nil && !byteSlice(f.Type) {
+		return sliceValue(m, f.Type, f.Name, structName, n, opts, cache)
+	}
+	return genValue(m, f.Type, f.Name, structName, opts, cache)
+}
+
+// genOneOfValue renders the default implementation of a oneof interface as a populated literal
+func genOneOfValue(m *Model, ifaceName string, opts GenerateOptions, cache *valueCache) string {
+	if cache != nil {
+		if v, ok := cache.oneOfs[ifaceName]; ok {
+			return v
+		}
+	}
+
+	v := "nil"
+	if impl, ok := m.OneOfs[ifaceName]; ok && impl != "" {
+		v = oneOfLiteral(m, impl, opts, cache)
+	}
+
+	if cache != nil {
+		cache.oneOfs[ifaceName] = v
+	}
+	return v
+}
+
+// oneOfLiteral renders the oneof implementation impl as a populated literal
+func oneOfLiteral(m *Model, impl string, opts GenerateOptions, cache *valueCache) string {
+	prefixed := impl
+	if opts.TypePrefix != "" {
+		prefixed = opts.TypePrefix + "." + impl
+	}
+	// Fallback to empty struct if no fields found
+	v := "&" + prefixed + "{}"
+	if implStruct, exists := m.Structs[impl]; exists {
+		var structFields []string
+		for _, field := range implStruct.Fields {
+			fieldValue := genFieldValue(m, field, impl, opts, cache)
+			structFields = append(structFields, fmt.Sprintf("%s: %s", field.Name, fieldValue))
+		}
+		if len(structFields) > 0 {
+			v = fmt.Sprintf("&%s{\n\t\t\t%s,\n\t\t}", prefixed, strings.Join(structFields, ",\n\t\t\t"))
+		}
+	}
+	return v
+}
+
 func genPrimitiveValue(typeName, fieldName, structName string) string {
 	switch typeName {
 	case "string":
@@ -513,17 +1505,40 @@ func typeName(t TypeRef, opts GenerateOptions) string {
 		return name
 	}
 
+	// External types of a known package are qualified by the name they are imported as
+	if ext, ok := lookupExternal(t, opts); ok && t.Pkg != "" && ext.PkgPath != "" {
+		return importName(ext.Import) + "." + t.Name
+	}
+	if fixturePkg := opts.FixturePackages[t.Pkg]; fixturePkg != "" && t.Name != "" || foreignType(t, opts) {
+		return packageAlias(t.Pkg) + "." + t.Name + typeArgs(t.TypeArgs, opts)
+	}
+
 	switch t.Kind {
 	case "pointer":
 		if t.Elem != nil {
 			return "*" + typeName(*t.Elem, opts)
 		}
 	case "slice":
+		if byteSlice(t) {
+			return "[]byte"
+		}
 		if t.Elem != nil {
 			return "[]" + typeName(*t.Elem, opts)
 		}
+	case "array":
+		if t.Elem != nil {
+			return "[" + strconv.FormatInt(t.Len, 10) + "]" + typeName(*t.Elem, opts)
+		}
 	case "struct", "enum", "typedef":
 		if t.Name != "" {
+			return prefixType(t.Name) + typeArgs(t.TypeArgs, opts)
+		}
+	case "interface":
+		// error and the empty interface are predeclared
+		if t.Pkg != "" && t.Pkg != opts.TypeImport && t.Name != "" {
+			return packageAlias(t.Pkg) + "." + t.Name
+		}
+		if ast.IsExported(t.Name) {
 			return prefixType(t.Name)
 		}
 	}
@@ -536,39 +1551,61 @@ func typeName(t TypeRef, opts GenerateOptions) string {
 	return "interface{}"
 }
 
-func collectImports(m *Model, typePrefix string) []string {
-	usedExternals := make(map[string]bool)
+func collectImports(m *Model, opts GenerateOptions) []string {
+	importSet := make(map[string]bool)
 
 	for _, s := range m.Structs {
-		for _, f := range s.Fields {
-			collectExternalTypes(f.Type, usedExternals)
+		for _, f := range slices.Concat(s.Fields, promotedAssignments(s, opts)) {
+			collectExternalImports(f.Type, opts, importSet)
+			if f.Default == "" {
+				collectFixturePackages(f.Type, opts, false, importSet)
+			}
 		}
 	}
-
-	// If no external types and no type prefix, no imports needed
-	if len(usedExternals) == 0 && typePrefix == "" {
-		return nil
+	if len(opts.Routes) > 0 {
+		for _, imp := range []string{`"encoding/json"`, `"net/http"`, `"net/http/httptest"`, `"testing"`} {
+			importSet[imp] = true
+		}
 	}
-
-	importSet := make(map[string]bool)
-
-	// Add type prefix import if specified
-	if typePrefix != "" {
-		// The typePrefix is expected to be a package alias or short name
-		// The user should provide the full import path via a separate flag if needed
-		// For now, we assume the typePrefix is already importable or in the same module
+	if opts.Snapshots != "" {
+		for _, imp := range snapshotImports(opts) {
+			importSet[imp] = true
+		}
 	}
-
-	if len(usedExternals) > 0 {
-		for _, imp := range RequiredImports {
+	if opts.YAMLLoaders && len(m.Structs) > 0 {
+		for _, imp := range yamlImports {
 			importSet[imp] = true
 		}
-		for extName := range usedExternals {
-			if ext, ok := ExternalTypes[extName]; ok {
-				importSet[ext.Import] = true
-			}
+	}
+	if hasRapid(m, opts) {
+		importSet[RapidImport] = true
+	}
+	if randomValues(m, opts) {
+		for _, imp := range randomImports {
+			importSet[imp] = true
 		}
 	}
+	if imp := providerImports[opts.Providers]; imp != "" && len(m.Structs) > 0 {
+		importSet[imp] = true
+	}
+	if opts.Dump && len(m.Structs) > 0 {
+		for _, imp := range dumpImports {
+			importSet[imp] = true
+		}
+	}
+	if opts.Asserts != "" && len(fixtureStructs(m)) > 0 {
+		for _, imp := range assertImports(opts) {
+			importSet[imp] = true
+		}
+	}
+	if hasSeedFuncs(m, opts) {
+		importSet[`"context"`] = true
+		importSet[`"database/sql"`] = true
+	}
+	collectStubImports(m, opts, importSet)
+	if imp := typeImport(opts); imp != "" && len(m.Structs)+len(m.Enums)+len(m.TypeDefs) > 0 {
+		importSet[imp] = true
+	}
 
 	if len(importSet) == 0 {
 		return nil
@@ -576,11 +1613,81 @@ func collectImports(m *Model, typePrefix string) []string {
 
 	imports := make([]string, 0, len(importSet))
 	for imp := range importSet {
+		// A package imported both plainly and aliased by its own name would be declared twice
+		if name, path, ok := strings.Cut(imp, " "); ok && importSet[path] && name == importName(path) {
+			continue
+		}
 		imports = append(imports, imp)
 	}
+	sort.Strings(imports)
 	return imports
 }
 
+// typeImport returns the import of the package of the types, named TypePrefix, if the prefix and its
+// import path are known
+func typeImport(opts GenerateOptions) string {
+	if opts.TypePrefix == "" || opts.TypeImport == "" {
+		return ""
+	}
+	return opts.TypePrefix + " " + strconv.Quote(opts.TypeImport)
+}
+
+// sortedKeys returns the keys of a model map in sorted order so output is deterministic
+func sortedKeys[V any](items map[string]V) []string {
+	keys := make([]string, 0, len(items))
+	for k := range items {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// collectFixturePackages adds the imports needed to call into FixturePackages for a value of type t.
+// The type's own package is only needed where its name is spelled out, inside slice literals and the
+// zero values of foreign types.
+func collectFixturePackages(t TypeRef, opts GenerateOptions, named bool, imports map[string]bool) {
+	if _, ok := lookupExternal(t, opts); ok {
+		return
+	}
+	if foreignType(t, opts) {
+		imports[packageAlias(t.Pkg)+" "+strconv.Quote(t.Pkg)] = true
+		return
+	}
+	if t.Kind == "interface" && t.Pkg != "" && t.Pkg != opts.TypeImport {
+		if named {
+			imports[packageAlias(t.Pkg)+" "+strconv.Quote(t.Pkg)] = true
+		}
+		return
+	}
+	if fixturePkg := opts.FixturePackages[t.Pkg]; fixturePkg != "" {
+		imports[fixtureAlias(t.Pkg)+" "+strconv.Quote(fixturePkg)] = true
+		if named {
+			imports[packageAlias(t.Pkg)+" "+strconv.Quote(t.Pkg)] = true
+		}
+		return
+	}
+	if t.Elem != nil {
+		collectFixturePackages(*t.Elem, opts, named || t.Kind == "slice" || t.Kind == "array", imports)
+	}
+}
+
+// collectExternalImports adds the imports of the external types t refers to
+func collectExternalImports(t TypeRef, opts GenerateOptions, imports map[string]bool) {
+	ext, ok := lookupExternal(t, opts)
+	if !ok {
+		ext, ok = interfaceDefault(t, opts)
+	}
+	if ok {
+		imports[ext.Import] = true
+		for _, imp := range ext.Requires {
+			imports[imp] = true
+		}
+	}
+	if t.Elem != nil {
+		collectExternalImports(*t.Elem, opts, imports)
+	}
+}
+
 func collectExternalTypes(t TypeRef, used map[string]bool) {
 	if t.Kind == "external" {
 		used[t.Name] = true
@@ -0,0 +1,178 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"go/types"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TypeParam is a type parameter of a generic struct, such as T of Page[T any]
+type TypeParam struct {
+	Name string
+	// Constraint is the constraint as written in the source, e.g. "any" or "~int | ~string"
+	Constraint string
+}
+
+// ParseTypeParams returns the type parameters declared by a type spec's parameter list, nil for
+// types that aren't generic
+func ParseTypeParams(list *ast.FieldList) []TypeParam {
+	if list == nil {
+		return nil
+	}
+	var params []TypeParam
+	for _, field := range list.List {
+		for _, name := range field.Names {
+			params = append(params, TypeParam{Name: name.Name, Constraint: types.ExprString(field.Type)})
+		}
+	}
+	return params
+}
+
+// fixtureStructs returns the sorted names of the structs of m whose fixture takes no arguments, the
+// ones helpers such as DumpX and the generated tests call. Fixtures of generic structs take a value
+// per type parameter.
+func fixtureStructs(m *Model) []string {
+	var names []string
+	for _, name := range sortedKeys(m.Structs) {
+		if len(m.Structs[name].TypeParams) == 0 {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// genericRef turns the references to the type parameters params of a struct parsed from source,
+// which look like struct names, into type parameter references
+func genericRef(t TypeRef, params []TypeParam) TypeRef {
+	for _, p := range params {
+		if t.Kind == "struct" && t.Name == p.Name && t.Pkg == "" && len(t.TypeArgs) == 0 {
+			return TypeRef{Kind: "typeparam", Name: p.Name}
+		}
+	}
+	if t.Elem != nil {
+		elem := genericRef(*t.Elem, params)
+		t.Elem = &elem
+	}
+	if len(t.TypeArgs) > 0 {
+		args := make([]TypeRef, len(t.TypeArgs))
+		for i, arg := range t.TypeArgs {
+			args[i] = genericRef(arg, params)
+		}
+		t.TypeArgs = args
+	}
+	return t
+}
+
+// typeParamVar returns the name of the fixture parameter holding the value of the type parameter name
+func typeParamVar(name string) string {
+	r, size := utf8.DecodeRuneInString(name)
+	v := string(unicode.ToLower(r)) + name[size:]
+	if token.IsKeyword(v) || v == "value" || v == "mods" || v == name {
+		v += "Value"
+	}
+	return v
+}
+
+// typeArgs renders the type arguments of an instantiated generic type, "[User, int]"
+func typeArgs(args []TypeRef, opts GenerateOptions) string {
+	if len(args) == 0 {
+		return ""
+	}
+	names := make([]string, len(args))
+	for i, arg := range args {
+		names[i] = typeName(arg, opts)
+	}
+	return "[" + strings.Join(names, ", ") + "]"
+}
+
+// fixtureArgs renders the arguments of the fixture call building a value of t: "()", or for an
+// instantiated generic struct its type arguments and a value of each, "[User](FixtureUser())"
+func fixtureArgs(m *Model, t TypeRef, fieldName, structName string, opts GenerateOptions, cache *valueCache) string {
+	if len(t.TypeArgs) == 0 {
+		return "()"
+	}
+	values := make([]string, len(t.TypeArgs))
+	for i, arg := range t.TypeArgs {
+		values[i] = genValue(m, arg, fieldName, structName, opts, cache)
+	}
+	return typeArgs(t.TypeArgs, opts) + "(" + strings.Join(values, ", ") + ")"
+}
+
+// qualifyConstraint qualifies the exported type names of the package in the constraint expr with
+// opts.TypePrefix, so "Number" becomes "models.Number" like the types of fields do
+func qualifyConstraint(expr string, opts GenerateOptions) string {
+	if opts.TypePrefix == "" {
+		return expr
+	}
+	node, err := parser.ParseExpr(expr)
+	if err != nil {
+		return expr
+	}
+	// Identifiers right of a package selector are qualified already
+	selected := make(map[*ast.Ident]bool)
+	ast.Inspect(node, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			selected[sel.Sel] = true
+			if x, ok := sel.X.(*ast.Ident); ok {
+				selected[x] = true
+			}
+		}
+		if ident, ok := n.(*ast.Ident); ok && !selected[ident] && ast.IsExported(ident.Name) {
+			ident.Name = opts.TypePrefix + "." + ident.Name
+		}
+		return true
+	})
+	var b bytes.Buffer
+	if err := printer.Fprint(&b, token.NewFileSet(), node); err != nil {
+		return expr
+	}
+	return b.String()
+}
+
+// genericFixture renders the fixture of the generic struct s. It is generic over the type parameters
+// of s and takes a value of each, which fields of a type parameter are set to:
+// FixturePage[T any](t T, mods ...func(*Page[T])) *Page[T]. The helpers generated along with other
+// fixtures, such as seeds and Rapid generators, aren't generated for generic structs.
+func genericFixture(m *Model, s *Struct, opts GenerateOptions, cache *valueCache) string {
+	var decls, params []string
+	var args []TypeRef
+	for _, p := range s.TypeParams {
+		decls = append(decls, p.Name+" "+qualifyConstraint(p.Constraint, opts))
+		params = append(params, typeParamVar(p.Name)+" "+p.Name)
+		args = append(args, TypeRef{Kind: "typeparam", Name: p.Name})
+	}
+	typ := typeName(TypeRef{Kind: "struct", Name: s.Name, TypeArgs: args}, opts)
+	name := "Fixture" + opts.FuncPrefix + s.Name
+
+	var b strings.Builder
+	b.WriteString(docComment(name, typeName(TypeRef{Kind: "struct", Name: s.Name}, opts), s.Doc, s.Source))
+	addr, pointer, deref := "", "", ""
+	if opts.ModStyle {
+		params = append(params, "mods ..."+modFunc(typ, opts))
+		fmt.Fprintf(&b, "func %s[%s](%s) %s {\n", name, strings.Join(decls, ", "), strings.Join(params, ", "), fixtureResult(typ, opts))
+		addr, pointer, deref = modOperands("value", opts)
+	} else {
+		fmt.Fprintf(&b, "func %s[%s](%s) %s {\n", name, strings.Join(decls, ", "), strings.Join(params, ", "), typ)
+	}
+	fmt.Fprintf(&b, "\tvalue := %s%s{\n", addr, typ)
+	for _, f := range s.Fields {
+		if foreignEmbedded(s, f, opts) {
+			continue
+		}
+		fmt.Fprintf(&b, "\t\t%s: %s,\n", f.Name, genFieldValue(m, f, s.Name, opts, cache))
+	}
+	b.WriteString("\t}\n")
+	b.WriteString(promotedStatements(m, s, opts, cache))
+	if opts.ModStyle {
+		b.WriteString(applyMods(pointer, deref, opts))
+	}
+	b.WriteString("\treturn value\n}\n\n")
+	return b.String()
+}
@@ -0,0 +1,269 @@
+package generator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Graph is the type reference graph of a model: which types a fixture needs to build its value
+type Graph struct {
+	Nodes  []GraphNode `json:"nodes"`
+	Edges  []GraphEdge `json:"edges"`
+	Cycles [][]string  `json:"cycles,omitempty"`
+}
+
+// GraphNode is a type of the model
+type GraphNode struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"` // "struct", "enum", "oneof", "interface", "typedef" or "external"
+	// FanOut is the number of other types reachable from this one, i.e. built along with its fixture
+	FanOut int `json:"fanout"`
+}
+
+// GraphEdge is a reference from one type to another, through a struct field or a oneof implementation
+type GraphEdge struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Field string `json:"field,omitempty"`
+	// Cycle is set if the edge is part of a reference cycle
+	Cycle bool `json:"cycle,omitempty"`
+}
+
+// BuildGraph returns the type reference graph of m with nodes and edges in a deterministic order
+func BuildGraph(m *Model) *Graph {
+	g := &Graph{}
+	kinds := make(map[string]string)
+	for _, name := range sortedKeys(m.Structs) {
+		kinds[name] = "struct"
+	}
+	for _, name := range sortedKeys(m.Enums) {
+		kinds[name] = "enum"
+	}
+	for _, name := range sortedKeys(m.TypeDefs) {
+		kinds[name] = "typedef"
+	}
+	for _, name := range sortedKeys(m.OneOfs) {
+		kinds[name] = "oneof"
+	}
+	for _, name := range sortedKeys(m.Interfaces) {
+		kinds[name] = "interface"
+	}
+
+	for _, name := range sortedKeys(m.Structs) {
+		for _, f := range m.Structs[name].Fields {
+			to, kind := graphTarget(f.Type)
+			if to == "" {
+				continue
+			}
+			if kinds[to] == "" {
+				kinds[to] = kind
+			}
+			g.Edges = append(g.Edges, GraphEdge{From: name, To: to, Field: f.Name})
+		}
+	}
+	for _, name := range sortedKeys(m.OneOfs) {
+		for _, impl := range oneOfVariants(m, name) {
+			if kinds[impl] == "" {
+				kinds[impl] = "struct"
+			}
+			g.Edges = append(g.Edges, GraphEdge{From: name, To: impl})
+		}
+	}
+
+	adj := make(map[string][]string)
+	for _, e := range g.Edges {
+		adj[e.From] = append(adj[e.From], e.To)
+	}
+	inCycle := make(map[string]int)
+	for i, scc := range stronglyConnected(sortedKeys(kinds), adj) {
+		self := len(scc) == 1 && contains(adj[scc[0]], scc[0])
+		if len(scc) < 2 && !self {
+			continue
+		}
+		sort.Strings(scc)
+		g.Cycles = append(g.Cycles, scc)
+		for _, name := range scc {
+			inCycle[name] = i + 1
+		}
+	}
+	for i, e := range g.Edges {
+		g.Edges[i].Cycle = inCycle[e.From] != 0 && inCycle[e.From] == inCycle[e.To]
+	}
+
+	for _, name := range sortedKeys(kinds) {
+		g.Nodes = append(g.Nodes, GraphNode{Name: name, Kind: kinds[name], FanOut: reachable(name, adj)})
+	}
+	sort.Slice(g.Cycles, func(i, j int) bool { return g.Cycles[i][0] < g.Cycles[j][0] })
+	return g
+}
+
+// graphTarget returns the named type a field of type t refers to, looking through pointers, slices and arrays
+func graphTarget(t TypeRef) (name, kind string) {
+	for (t.Kind == "pointer" || t.Kind == "slice" || t.Kind == "array") && t.Elem != nil {
+		t = *t.Elem
+	}
+	switch t.Kind {
+	case "struct", "enum", "oneof", "interface", "typedef", "external":
+		if t.Name == "" || t.Name == "error" {
+			return "", ""
+		}
+		if t.Kind == "struct" && strings.HasPrefix(t.Name, "is") {
+			return t.Name, "oneof"
+		}
+		return t.Name, t.Kind
+	}
+	return "", ""
+}
+
+// stronglyConnected returns the strongly connected components of the graph (Tarjan's algorithm)
+func stronglyConnected(nodes []string, adj map[string][]string) [][]string {
+	index := make(map[string]int)
+	low := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var sccs [][]string
+	next := 1
+
+	var visit func(v string)
+	visit = func(v string) {
+		index[v], low[v] = next, next
+		next++
+		stack = append(stack, v)
+		onStack[v] = true
+		for _, w := range adj[v] {
+			if index[w] == 0 {
+				visit(w)
+				low[v] = min(low[v], low[w])
+			} else if onStack[w] {
+				low[v] = min(low[v], index[w])
+			}
+		}
+		if low[v] == index[v] {
+			var scc []string
+			for {
+				w := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[w] = false
+				scc = append(scc, w)
+				if w == v {
+					break
+				}
+			}
+			sccs = append(sccs, scc)
+		}
+	}
+	for _, v := range nodes {
+		if index[v] == 0 {
+			visit(v)
+		}
+	}
+	return sccs
+}
+
+// reachable counts the nodes reachable from start, not counting start itself
+func reachable(start string, adj map[string][]string) int {
+	seen := map[string]bool{start: true}
+	queue := []string{start}
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		for _, w := range adj[v] {
+			if !seen[w] {
+				seen[w] = true
+				queue = append(queue, w)
+			}
+		}
+	}
+	return len(seen) - 1
+}
+
+func contains(items []string, s string) bool {
+	for _, item := range items {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// DOT renders the graph in Graphviz format. Node shapes tell kinds apart and edges in cycles are red.
+func (g *Graph) DOT() string {
+	shapes := map[string]string{
+		"struct":    "box",
+		"enum":      "ellipse",
+		"oneof":     "diamond",
+		"interface": "hexagon",
+		"typedef":   "note",
+		"external":  "component",
+	}
+	var b strings.Builder
+	b.WriteString("digraph fixtures {\n")
+	b.WriteString("\trankdir=LR;\n")
+	for _, n := range g.Nodes {
+		fmt.Fprintf(&b, "\t%q [shape=%s, tooltip=%q];\n", n.Name, shapes[n.Kind], fmt.Sprintf("%s, fan-out %d", n.Kind, n.FanOut))
+	}
+	for _, e := range g.Edges {
+		attrs := []string{}
+		if e.Field != "" {
+			attrs = append(attrs, fmt.Sprintf("label=%q", e.Field))
+		}
+		if e.Cycle {
+			attrs = append(attrs, "color=red")
+		}
+		fmt.Fprintf(&b, "\t%q -> %q", e.From, e.To)
+		if len(attrs) > 0 {
+			b.WriteString(" [" + strings.Join(attrs, ", ") + "]")
+		}
+		b.WriteString(";\n")
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// Subset returns a model with the named types and every type their fixtures need.
+// Unknown names are ignored.
+func (m *Model) Subset(names []string) *Model {
+	sub := NewModel()
+	sub.Deps = m.Deps
+	queue := append([]string(nil), names...)
+	seen := make(map[string]bool)
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		if s, ok := m.Structs[name]; ok {
+			sub.Structs[name] = s
+			for _, f := range s.Fields {
+				if to, _ := graphTarget(f.Type); to != "" {
+					queue = append(queue, to)
+				}
+				// Keep the struct a field refers to, whose fixture shares the key
+				if structName, _, ok := strings.Cut(f.References, "."); ok {
+					queue = append(queue, structName)
+				}
+			}
+		}
+		if e, ok := m.Enums[name]; ok {
+			sub.Enums[name] = e
+		}
+		if td, ok := m.TypeDefs[name]; ok {
+			sub.TypeDefs[name] = td
+		}
+		if i, ok := m.Interfaces[name]; ok {
+			sub.Interfaces[name] = i
+		}
+		if impl, ok := m.OneOfs[name]; ok {
+			sub.OneOfs[name] = impl
+			if variants, ok := m.Variants[name]; ok {
+				sub.Variants[name] = variants
+			}
+			queue = append(queue, oneOfVariants(m, name)...)
+		}
+	}
+	return sub
+}
@@ -0,0 +1,19 @@
+package generator
+
+import "strings"
+
+// headerComment renders GenerateOptions.Header as the line comments opening a generated file. Lines
+// already written as comments keep their single "//".
+func headerComment(opts GenerateOptions) string {
+	if opts.Header == "" {
+		return ""
+	}
+	var b strings.Builder
+	for _, line := range strings.Split(strings.TrimRight(opts.Header, "\n"), "\n") {
+		if text, ok := strings.CutPrefix(line, "//"); ok {
+			line = strings.TrimPrefix(text, " ")
+		}
+		b.WriteString(strings.TrimRight("// "+line, " ") + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,72 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// hookField reports whether the fixtures of s take f from the XDefaults hooks of GenerateOptions.Hooks:
+// fields of basic types, typedefs and enums, or pointers and slices of them
+func hookField(f Field, opts GenerateOptions) bool {
+	if !opts.Hooks || f.Embedded {
+		return false
+	}
+	var hookable func(t TypeRef) bool
+	hookable = func(t TypeRef) bool {
+		if _, foreign := fixtureCall(t, opts); foreign {
+			return false
+		}
+		switch t.Kind {
+		case "primitive", "typedef", "enum":
+			return true
+		case "pointer", "slice", "array":
+			return t.Elem != nil && hookable(*t.Elem)
+		}
+		return false
+	}
+	return hookable(f.Type)
+}
+
+// hooksName returns the name of the XDefaults variable of s
+func hooksName(s *Struct, opts GenerateOptions) string {
+	return opts.FuncPrefix + s.Name + "Defaults"
+}
+
+// structFieldValue returns the value of f in the fixture of s: a call of its hook with Hooks
+func structFieldValue(m *Model, s *Struct, f Field, opts GenerateOptions, cache *valueCache) string {
+	if hookField(f, opts) {
+		return hooksName(s, opts) + "." + f.Name + "()"
+	}
+	return genFieldValue(m, f, s.Name, opts, cache)
+}
+
+// hookFuncs renders the XDefaultsFuncs type of s with a func per hooked field and the XDefaults variable
+// holding the generated defaults, which test suites re-point to change a field of every fixture
+func hookFuncs(m *Model, s *Struct, opts GenerateOptions, cache *valueCache) string {
+	var fields []Field
+	for _, f := range s.Fields {
+		if hookField(f, opts) && !(sparseField(f, opts) && s.Builder == "") {
+			fields = append(fields, f)
+		}
+	}
+	if len(fields) == 0 {
+		return ""
+	}
+	name := hooksName(s, opts)
+	fixture := "Fixture" + opts.FuncPrefix + s.Name
+	var b strings.Builder
+	fmt.Fprintf(&b, "// %sFuncs holds the funcs %s takes its field values from, see %s.\n", name, fixture, name)
+	fmt.Fprintf(&b, "type %sFuncs struct {\n", name)
+	for _, f := range fields {
+		fmt.Fprintf(&b, "\t%s func() %s\n", f.Name, typeName(f.Type, opts))
+	}
+	b.WriteString("}\n\n")
+	fmt.Fprintf(&b, "// %s are the field values of %s. Re-point a field to change it in every fixture\n", name, fixture)
+	fmt.Fprintf(&b, "// built afterwards, e.g. to the tenant ID or environment URL of a test suite.\n")
+	fmt.Fprintf(&b, "var %s = %sFuncs{\n", name, name)
+	for _, f := range fields {
+		fmt.Fprintf(&b, "\t%s: func() %s { return %s },\n", f.Name, typeName(f.Type, opts), genFieldValue(m, f, s.Name, opts, cache))
+	}
+	b.WriteString("}\n\n")
+	return b.String()
+}
@@ -0,0 +1,110 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// httpSample is a request to an endpoint with its request fixture as body
+type httpSample struct {
+	Endpoint Endpoint
+	Path     string // with {param} segments filled in
+	Body     string // indented JSON, empty without request type
+}
+
+func httpSamples(m *Model, eps []Endpoint, opts GenerateOptions) ([]httpSample, error) {
+	var samples []httpSample
+	for _, e := range eps {
+		s := httpSample{Endpoint: e}
+		var body any
+		if e.Request != "" {
+			var err error
+			if body, err = JSONValue(m, e.Request, opts); err != nil {
+				return nil, fmt.Errorf("%s %s: %w", e.Method, e.Path, err)
+			}
+			data, err := marshalJSON(body)
+			if err != nil {
+				return nil, err
+			}
+			var indented bytes.Buffer
+			json.Indent(&indented, data, "", "  ")
+			s.Body = indented.String()
+		}
+		response, _ := JSONValue(m, e.Response, opts)
+		s.Path = pathParam.ReplaceAllStringFunc(e.Path, func(param string) string {
+			return url.PathEscape(paramValue(param[1:len(param)-1], body, response))
+		})
+		samples = append(samples, s)
+	}
+	return samples, nil
+}
+
+// paramValue fills a path parameter with the matching field of the request or response fixture,
+// so a request for /users/{id} asks for the user the fixtures describe
+func paramValue(param string, values ...any) string {
+	for _, v := range values {
+		obj, ok := v.(JSONObject)
+		if !ok {
+			continue
+		}
+		for _, key := range obj.Keys {
+			if normalizeKey(key) != normalizeKey(param) {
+				continue
+			}
+			switch fv := obj.Values[key].(type) {
+			case string:
+				return fv
+			case json.Number:
+				return fv.String()
+			}
+		}
+	}
+	return "1"
+}
+
+// HTTPRequests renders an .http file (JetBrains HTTP Client / VS Code REST Client) with a request
+// per endpoint, using the request fixture generated with opts as body
+func HTTPRequests(m *Model, eps []Endpoint, baseURL string, opts GenerateOptions) (string, error) {
+	samples, err := httpSamples(m, eps, opts)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "@baseUrl = %s\n", baseURL)
+	for _, s := range samples {
+		fmt.Fprintf(&b, "\n### %s %s\n", s.Endpoint.Method, s.Endpoint.Path)
+		fmt.Fprintf(&b, "%s {{baseUrl}}%s\n", s.Endpoint.Method, s.Path)
+		if s.Body != "" {
+			b.WriteString("Content-Type: application/json\n\n")
+			b.WriteString(s.Body + "\n")
+		}
+	}
+	return b.String(), nil
+}
+
+// CurlScript renders a shell script with a curl command per endpoint, using the request fixture as
+// body. The base URL can be overridden with the BASE_URL environment variable.
+func CurlScript(m *Model, eps []Endpoint, baseURL string, opts GenerateOptions) (string, error) {
+	samples, err := httpSamples(m, eps, opts)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	b.WriteString("#!/bin/sh\n")
+	b.WriteString("set -e\n")
+	fmt.Fprintf(&b, "BASE_URL=\"${BASE_URL:-%s}\"\n", baseURL)
+	for _, s := range samples {
+		fmt.Fprintf(&b, "\n# %s %s\n", s.Endpoint.Method, s.Endpoint.Path)
+		fmt.Fprintf(&b, "curl -sS -X %s \"$BASE_URL%s\"", s.Endpoint.Method, s.Path)
+		if s.Body != "" {
+			b.WriteString(" \\\n  -H 'Content-Type: application/json' \\\n  --data-binary @- <<'EOF'\n")
+			b.WriteString(s.Body + "\nEOF\n")
+		} else {
+			b.WriteString("\n")
+		}
+	}
+	return b.String(), nil
+}
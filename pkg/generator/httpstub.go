@@ -0,0 +1,59 @@
+package generator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// httpStubs renders a FixtureHandler serving the fixtures of opts.Routes as JSON, and a
+// NewFixtureServer starting it with httptest. Routes whose type has no fixture are left out.
+func httpStubs(m *Model, opts GenerateOptions) string {
+	var b strings.Builder
+	b.WriteString("// FixtureHandler returns an http.Handler serving fixtures as JSON on their routes.\n")
+	b.WriteString("// overrides replaces the response of a route, keyed by its pattern.\n")
+	b.WriteString("func FixtureHandler(overrides map[string]func(*http.Request) any) http.Handler {\n")
+	b.WriteString("\tmux := http.NewServeMux()\n")
+	for _, pattern := range sortedKeys(opts.Routes) {
+		name := opts.Routes[pattern]
+		if !HasFixture(m, name) {
+			continue
+		}
+		fmt.Fprintf(&b, "\tmux.HandleFunc(%s, fixtureRoute(%s, overrides, func(*http.Request) any { return Fixture%s%s() }))\n",
+			strconv.Quote(pattern), strconv.Quote(pattern), opts.FuncPrefix, name)
+	}
+	b.WriteString("\treturn mux\n")
+	b.WriteString("}\n\n")
+
+	b.WriteString("func fixtureRoute(pattern string, overrides map[string]func(*http.Request) any, fixture func(*http.Request) any) http.HandlerFunc {\n")
+	b.WriteString("\tif override, ok := overrides[pattern]; ok {\n")
+	b.WriteString("\t\tfixture = override\n")
+	b.WriteString("\t}\n")
+	b.WriteString("\treturn func(w http.ResponseWriter, r *http.Request) {\n")
+	b.WriteString("\t\tw.Header().Set(\"Content-Type\", \"application/json\")\n")
+	b.WriteString("\t\tif err := json.NewEncoder(w).Encode(fixture(r)); err != nil {\n")
+	b.WriteString("\t\t\thttp.Error(w, err.Error(), http.StatusInternalServerError)\n")
+	b.WriteString("\t\t}\n")
+	b.WriteString("\t}\n")
+	b.WriteString("}\n\n")
+
+	b.WriteString("// NewFixtureServer starts a server for FixtureHandler that is closed when the test ends\n")
+	b.WriteString("func NewFixtureServer(t testing.TB, overrides map[string]func(*http.Request) any) *httptest.Server {\n")
+	b.WriteString("\tserver := httptest.NewServer(FixtureHandler(overrides))\n")
+	b.WriteString("\tt.Cleanup(server.Close)\n")
+	b.WriteString("\treturn server\n")
+	b.WriteString("}\n\n")
+	return b.String()
+}
+
+// HasFixture reports whether a fixture function is generated for the type name
+func HasFixture(m *Model, name string) bool {
+	if _, ok := m.Structs[name]; ok {
+		return true
+	}
+	if _, ok := m.Enums[name]; ok {
+		return true
+	}
+	_, ok := m.TypeDefs[name]
+	return ok
+}
@@ -0,0 +1,234 @@
+package generator
+
+import (
+	"fmt"
+	"go/ast"
+	"strings"
+)
+
+// Interface is a named interface type other than a oneof, such as a Logger or Clock a struct is
+// configured with. With the InterfaceStrategy "stub" fields of the type are set to a generated stub.
+type Interface struct {
+	Name    string
+	Methods []Method
+	// Partial is set if not all methods are known, like those of embedded interfaces without type
+	// information. Partial interfaces get no stub.
+	Partial bool `json:",omitempty"`
+	// Source is where the interface is declared, if it comes from Go code
+	Source *Source `json:",omitempty"`
+	// Doc is the doc comment of the interface
+	Doc string `json:",omitempty"`
+}
+
+// Method is a method of an Interface
+type Method struct {
+	Name    string
+	Params  []TypeRef `json:",omitempty"`
+	Results []TypeRef `json:",omitempty"`
+	// Variadic is set if the last parameter is variadic; its type is a slice of the element type
+	Variadic bool `json:",omitempty"`
+}
+
+// InterfaceDefaults are the values of fields of well-known standard library interfaces with the
+// InterfaceStrategy "defaults" or "stub", keyed by import path and name like ExternalTypes. Add
+// entries from an init func to default other interfaces.
+var InterfaceDefaults = map[string]ExternalType{
+	"context.Context":         {Import: `"context"`, Value: "context.Background()"},
+	"io.Reader":               {Import: `"bytes"`, Value: "bytes.NewReader(nil)"},
+	"io.ReadSeeker":           {Import: `"bytes"`, Value: "bytes.NewReader(nil)"},
+	"io.ReaderAt":             {Import: `"bytes"`, Value: "bytes.NewReader(nil)"},
+	"io.ReadWriter":           {Import: `"bytes"`, Value: "new(bytes.Buffer)"},
+	"io.ReadCloser":           {Import: `"io"`, Requires: []string{`"bytes"`}, Value: "io.NopCloser(bytes.NewReader(nil))"},
+	"io.Writer":               {Import: `"io"`, Value: "io.Discard"},
+	"hash.Hash":               {Import: `"crypto/sha256"`, Value: "sha256.New()"},
+	"sync.Locker":             {Import: `"sync"`, Value: "new(sync.Mutex)"},
+	"log/slog.Handler":        {Import: `"log/slog"`, Requires: []string{`"io"`}, Value: "slog.NewTextHandler(io.Discard, nil)"},
+	"net/http.Handler":        {Import: `"net/http"`, Value: "http.NotFoundHandler()"},
+	"net/http.RoundTripper":   {Import: `"net/http"`, Value: "http.DefaultTransport"},
+	"net/http.ResponseWriter": {Import: `"net/http/httptest"`, Value: "httptest.NewRecorder()"},
+}
+
+// parseInterface returns the interface declared as name by t, resolving the types of its methods with
+// the imports of the file. Without type information embedded interfaces make it Partial.
+func parseInterface(name string, t *ast.InterfaceType, imports map[string]string) *Interface {
+	i := &Interface{Name: name}
+	for _, field := range t.Methods.List {
+		fn, ok := field.Type.(*ast.FuncType)
+		if !ok || len(field.Names) == 0 {
+			i.Partial = true
+			continue
+		}
+		method := Method{Name: field.Names[0].Name}
+		method.Params, method.Variadic = parseParams(fn.Params, imports)
+		if fn.Results != nil {
+			method.Results, _ = parseParams(fn.Results, imports)
+		}
+		i.Methods = append(i.Methods, method)
+	}
+	return i
+}
+
+// parseParams returns the types of a parameter list, one per parameter, and whether the last is variadic
+func parseParams(fields *ast.FieldList, imports map[string]string) ([]TypeRef, bool) {
+	var params []TypeRef
+	variadic := false
+	for _, field := range fields.List {
+		expr := field.Type
+		if ellipsis, ok := expr.(*ast.Ellipsis); ok {
+			expr = &ast.ArrayType{Elt: ellipsis.Elt}
+			variadic = true
+		}
+		n := max(len(field.Names), 1)
+		for range n {
+			params = append(params, exprToTypeRef(expr, imports))
+		}
+	}
+	return params, variadic
+}
+
+// interfaceDefault returns the value of a field of the interface t from InterfaceDefaults, if the
+// InterfaceStrategy uses them
+func interfaceDefault(t TypeRef, opts GenerateOptions) (ExternalType, bool) {
+	if t.Kind != "interface" || opts.InterfaceStrategy != "defaults" && opts.InterfaceStrategy != "stub" {
+		return ExternalType{}, false
+	}
+	ext, ok := InterfaceDefaults[t.Pkg+"."+t.Name]
+	return ext, ok
+}
+
+// interfaceValue renders the value of a field of the interface t: a standard implementation from
+// InterfaceDefaults, a stub of an interface of the model, or nil
+func interfaceValue(m *Model, t TypeRef, opts GenerateOptions) string {
+	if ext, ok := interfaceDefault(t, opts); ok {
+		return ext.Value
+	}
+	if i := localInterface(m, t, opts); i != nil && opts.InterfaceStrategy == "stub" && stubbable(m, i, opts) {
+		return stubName(i.Name, opts) + "{}"
+	}
+	return "nil"
+}
+
+// localInterface returns the interface of the model t refers to, or nil for interfaces of other packages
+func localInterface(m *Model, t TypeRef, opts GenerateOptions) *Interface {
+	if t.Kind != "interface" || t.Pkg != "" && t.Pkg != opts.TypeImport {
+		return nil
+	}
+	return m.Interfaces[t.Name]
+}
+
+// stubName returns the name of the stub generated for the interface name
+func stubName(name string, opts GenerateOptions) string {
+	return "Stub" + opts.FuncPrefix + name
+}
+
+// stubbable reports whether a stub implementing i can be generated: all of its methods are known and
+// exported, their signatures can be spelled out, and no type of the model has the name of the stub
+func stubbable(m *Model, i *Interface, opts GenerateOptions) bool {
+	if i.Partial || len(i.Methods) == 0 {
+		return false
+	}
+	name := stubName(i.Name, opts)
+	if m.Structs[name] != nil || m.Enums[name] != nil || m.TypeDefs[name] != nil || m.Interfaces[name] != nil {
+		return false
+	}
+	for _, method := range i.Methods {
+		if !ast.IsExported(method.Name) {
+			return false
+		}
+		for _, t := range append(method.Params[:len(method.Params):len(method.Params)], method.Results...) {
+			if !stubType(t, opts) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// stubType reports whether a stub method can spell out the type t
+func stubType(t TypeRef, opts GenerateOptions) bool {
+	switch t.Kind {
+	case "primitive", "enum", "typedef":
+		return true
+	case "interface":
+		return t.Name == "" || t.Name == "error" || ast.IsExported(t.Name)
+	case "struct":
+		for _, arg := range t.TypeArgs {
+			if !stubType(arg, opts) {
+				return false
+			}
+		}
+		return !strings.HasPrefix(t.Name, "is")
+	case "external":
+		// External types known only by their simple name can't be qualified
+		ext, ok := lookupExternal(t, opts)
+		return ok && t.Pkg != "" && ext.PkgPath != ""
+	case "pointer", "slice", "array":
+		return t.Elem != nil && stubType(*t.Elem, opts)
+	}
+	return false
+}
+
+// interfaceStubs renders a stub per interface of the model fields are set to with the InterfaceStrategy
+// "stub": an empty struct whose methods do nothing and return zero values
+func interfaceStubs(m *Model, opts GenerateOptions) string {
+	var b strings.Builder
+	for _, name := range sortedKeys(m.Interfaces) {
+		i := m.Interfaces[name]
+		if !stubbable(m, i, opts) {
+			continue
+		}
+		stub := stubName(name, opts)
+		fmt.Fprintf(&b, "// %s implements [%s] for fixtures. Its methods do nothing and return zero values.\n", stub, typeName(TypeRef{Kind: "interface", Name: name}, opts))
+		fmt.Fprintf(&b, "type %s struct{}\n\n", stub)
+		for _, method := range i.Methods {
+			params := make([]string, len(method.Params))
+			for j, p := range method.Params {
+				params[j] = typeName(p, opts)
+				if method.Variadic && j == len(method.Params)-1 && p.Elem != nil {
+					params[j] = "..." + typeName(*p.Elem, opts)
+				}
+			}
+			results := make([]string, len(method.Results))
+			for j, r := range method.Results {
+				results[j] = "_ " + typeName(r, opts)
+			}
+			if len(results) == 0 {
+				fmt.Fprintf(&b, "func (%s) %s(%s) {}\n\n", stub, method.Name, strings.Join(params, ", "))
+			} else {
+				fmt.Fprintf(&b, "func (%s) %s(%s) (%s) { return }\n\n", stub, method.Name, strings.Join(params, ", "), strings.Join(results, ", "))
+			}
+		}
+	}
+	return b.String()
+}
+
+// collectStubImports adds the imports of the types the methods of the stubs spell out
+func collectStubImports(m *Model, opts GenerateOptions, imports map[string]bool) {
+	if opts.InterfaceStrategy != "stub" {
+		return
+	}
+	for _, name := range sortedKeys(m.Interfaces) {
+		i := m.Interfaces[name]
+		if !stubbable(m, i, opts) {
+			continue
+		}
+		for _, method := range i.Methods {
+			for _, t := range append(method.Params[:len(method.Params):len(method.Params)], method.Results...) {
+				collectSignatureImports(t, opts, imports)
+			}
+		}
+	}
+}
+
+// collectSignatureImports adds the imports needed to spell out the type t
+func collectSignatureImports(t TypeRef, opts GenerateOptions, imports map[string]bool) {
+	if ext, ok := lookupExternal(t, opts); ok {
+		imports[ext.Import] = true
+		return
+	}
+	if t.Elem != nil {
+		collectSignatureImports(*t.Elem, opts, imports)
+		return
+	}
+	collectFixturePackages(t, opts, true, imports)
+}
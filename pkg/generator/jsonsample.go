@@ -0,0 +1,285 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// jsonNode is a decoded JSON value that keeps object keys in document order
+type jsonNode struct {
+	Kind   string // "object", "array", "string", "number", "bool" or "null"
+	Keys   []string
+	Fields map[string]*jsonNode
+	Items  []*jsonNode
+	Value  string // literal text of strings, numbers and bools
+}
+
+func decodeJSON(data []byte) (*jsonNode, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return decodeJSONNode(dec)
+}
+
+func decodeJSONNode(dec *json.Decoder) (*jsonNode, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch v := tok.(type) {
+	case json.Delim:
+		if v == '{' {
+			n := &jsonNode{Kind: "object", Fields: make(map[string]*jsonNode)}
+			for dec.More() {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				child, err := decodeJSONNode(dec)
+				if err != nil {
+					return nil, err
+				}
+				k := key.(string)
+				if _, dup := n.Fields[k]; !dup {
+					n.Keys = append(n.Keys, k)
+				}
+				n.Fields[k] = child
+			}
+			_, err := dec.Token()
+			return n, err
+		}
+		n := &jsonNode{Kind: "array"}
+		for dec.More() {
+			child, err := decodeJSONNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			n.Items = append(n.Items, child)
+		}
+		_, err := dec.Token()
+		return n, err
+	case string:
+		return &jsonNode{Kind: "string", Value: v}, nil
+	case json.Number:
+		return &jsonNode{Kind: "number", Value: v.String()}, nil
+	case bool:
+		return &jsonNode{Kind: "bool", Value: strconv.FormatBool(v)}, nil
+	}
+	return &jsonNode{Kind: "null"}, nil
+}
+
+// InferJSON infers a Model from a sample JSON payload. The root object (or the objects of a root array)
+// becomes a struct named rootName, nested objects become structs named after their parent and field,
+// and the sample values become the fixture defaults.
+func InferJSON(rootName string, data []byte) (*Model, error) {
+	root, err := decodeJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("json parse error: %w", err)
+	}
+	if root.Kind == "array" {
+		root = mergeObjects(root.Items)
+	}
+	if root == nil || root.Kind != "object" {
+		return nil, fmt.Errorf("json sample must be an object or an array of objects")
+	}
+
+	m := NewModel()
+	inferStruct(m, rootName, root)
+	return m, nil
+}
+
+func inferStruct(m *Model, name string, n *jsonNode) {
+	s := &Struct{Name: name}
+	for _, key := range n.Keys {
+		fieldName := goName(key)
+		ref, def := inferType(m, name+fieldName, n.Fields[key])
+		s.Fields = append(s.Fields, Field{Name: fieldName, Type: ref, Default: def, JSONName: key})
+	}
+	m.Structs[name] = s
+}
+
+// inferType returns the type of a sample value and the Go expression reproducing it.
+// typeName is used if the value is an object that needs its own struct.
+func inferType(m *Model, typeName string, n *jsonNode) (TypeRef, string) {
+	switch n.Kind {
+	case "string":
+		if t, err := time.Parse(time.RFC3339Nano, n.Value); err == nil {
+			return TypeRef{Kind: "external", Name: "Time", Pkg: "time"}, timeLiteral(t)
+		}
+		return TypeRef{Kind: "primitive", Name: "string"}, strconv.Quote(n.Value)
+	case "number":
+		if _, err := strconv.ParseInt(n.Value, 10, 64); err == nil {
+			return TypeRef{Kind: "primitive", Name: "int64"}, n.Value
+		}
+		return TypeRef{Kind: "primitive", Name: "float64"}, n.Value
+	case "bool":
+		return TypeRef{Kind: "primitive", Name: "bool"}, n.Value
+	case "object":
+		inferStruct(m, typeName, n)
+		// Nested structs carry their own sample defaults, so their fixture reproduces the sample
+		return TypeRef{Kind: "struct", Name: typeName}, ""
+	case "array":
+		if len(n.Items) == 0 {
+			return TypeRef{Kind: "slice", Elem: &TypeRef{Kind: "unknown"}}, "nil"
+		}
+		if merged := mergeObjects(n.Items); merged != nil {
+			elem, _ := inferType(m, typeName, merged)
+			return TypeRef{Kind: "slice", Elem: &elem, Name: elem.Name}, ""
+		}
+		elem, _ := inferType(m, typeName, n.Items[0])
+		var values []string
+		for _, item := range n.Items {
+			itemRef, v := inferType(m, typeName, item)
+			if itemRef.Kind != elem.Kind || itemRef.Name != elem.Name || v == "" {
+				// Mixed arrays fall back to the generated default
+				return TypeRef{Kind: "slice", Elem: &elem, Name: elem.Name}, ""
+			}
+			values = append(values, v)
+		}
+		return TypeRef{Kind: "slice", Elem: &elem, Name: elem.Name}, "[]" + TypeName(elem) + "{" + strings.Join(values, ", ") + "}"
+	}
+	return TypeRef{Kind: "unknown"}, ""
+}
+
+// mergeObjects combines the objects of an array into one node holding every key seen, taking
+// each key's value from the first object that has it. It returns nil if items are not all objects.
+func mergeObjects(items []*jsonNode) *jsonNode {
+	if len(items) == 0 {
+		return nil
+	}
+	merged := &jsonNode{Kind: "object", Fields: make(map[string]*jsonNode)}
+	for _, item := range items {
+		if item.Kind != "object" {
+			return nil
+		}
+		for _, key := range item.Keys {
+			if _, ok := merged.Fields[key]; !ok {
+				merged.Keys = append(merged.Keys, key)
+				merged.Fields[key] = item.Fields[key]
+			}
+		}
+	}
+	return merged
+}
+
+// ApplySample uses a sample JSON payload as the fixture defaults of the existing struct typeName,
+// matching JSON keys to field names case-insensitively and ignoring underscores and dashes
+func ApplySample(m *Model, typeName string, data []byte) error {
+	root, err := decodeJSON(data)
+	if err != nil {
+		return fmt.Errorf("json parse error: %w", err)
+	}
+	if root.Kind == "array" && len(root.Items) > 0 {
+		root = root.Items[0]
+	}
+	if root.Kind != "object" {
+		return fmt.Errorf("json sample must be an object or an array of objects")
+	}
+	if _, ok := m.Structs[typeName]; !ok {
+		return fmt.Errorf("struct %s not found", typeName)
+	}
+	applySample(m, typeName, root, make(map[string]bool))
+	return nil
+}
+
+func applySample(m *Model, typeName string, n *jsonNode, seen map[string]bool) {
+	s, ok := m.Structs[typeName]
+	if !ok || seen[typeName] {
+		return
+	}
+	seen[typeName] = true
+
+	for i := range s.Fields {
+		f := &s.Fields[i]
+		v := lookupKey(n, f.Name)
+		if v == nil {
+			continue
+		}
+		if v.Kind == "object" {
+			if name := structName(f.Type); name != "" {
+				applySample(m, name, v, seen)
+			}
+			continue
+		}
+		if def := sampleLiteral(f.Type, v); def != "" {
+			f.Default = def
+		}
+	}
+}
+
+// lookupKey finds the value for a Go field name in a JSON object
+func lookupKey(n *jsonNode, fieldName string) *jsonNode {
+	want := normalizeKey(fieldName)
+	for _, key := range n.Keys {
+		if normalizeKey(key) == want {
+			return n.Fields[key]
+		}
+	}
+	return nil
+}
+
+func normalizeKey(s string) string {
+	s = strings.ToLower(s)
+	return strings.NewReplacer("_", "", "-", "").Replace(s)
+}
+
+// structName returns the struct a field type refers to, looking through a pointer
+func structName(t TypeRef) string {
+	if t.Kind == "pointer" && t.Elem != nil {
+		t = *t.Elem
+	}
+	if t.Kind == "struct" {
+		return t.Name
+	}
+	return ""
+}
+
+// sampleLiteral renders a sample value as a Go expression of type t, or "" if it doesn't fit
+func sampleLiteral(t TypeRef, v *jsonNode) string {
+	switch t.Kind {
+	case "pointer":
+		if t.Elem != nil && t.Elem.Kind == "external" && t.Elem.Name == "Timestamp" && v.Kind == "string" {
+			if ts, err := time.Parse(time.RFC3339Nano, v.Value); err == nil {
+				return "timestamppb.New(" + timeLiteral(ts) + ")"
+			}
+		}
+		if t.Elem == nil || t.Elem.Kind != "primitive" {
+			return ""
+		}
+		lit := sampleLiteral(*t.Elem, v)
+		if lit == "" {
+			return ""
+		}
+		// Explicit instantiation keeps untyped constants from defaulting to int or float64
+		return "ptr[" + t.Elem.Name + "](" + lit + ")"
+	case "external":
+		if t.Name == "Time" && v.Kind == "string" {
+			if ts, err := time.Parse(time.RFC3339Nano, v.Value); err == nil {
+				return timeLiteral(ts)
+			}
+		}
+	case "primitive":
+		switch {
+		case t.Name == "string" && v.Kind == "string":
+			return strconv.Quote(v.Value)
+		case t.Name == "bool" && v.Kind == "bool":
+			return v.Value
+		case v.Kind == "number" && strings.HasPrefix(t.Name, "float"):
+			return v.Value
+		case v.Kind == "number" && t.Name != "string" && t.Name != "bool":
+			if _, err := strconv.ParseInt(v.Value, 10, 64); err == nil {
+				return v.Value
+			}
+		}
+	}
+	return ""
+}
+
+func timeLiteral(t time.Time) string {
+	t = t.UTC()
+	return fmt.Sprintf("time.Date(%d, %d, %d, %d, %d, %d, %d, time.UTC)",
+		t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond())
+}
@@ -0,0 +1,252 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// JSONNameFromTag returns the key from a `json:"..."` struct tag: "" if the tag doesn't name
+// the field and "-" if the field is left out of JSON
+func JSONNameFromTag(tag string) string {
+	name, _, _ := strings.Cut(reflect.StructTag(tag).Get("json"), ",")
+	return name
+}
+
+// jsonKey returns the key of a field in the JSON encoding of its struct
+func (f Field) jsonKey() string {
+	if f.JSONName != "" {
+		return f.JSONName
+	}
+	return f.Name
+}
+
+// JSONObject is a JSON object that keeps its keys in field order
+type JSONObject struct {
+	Keys   []string
+	Values map[string]any
+}
+
+// MarshalJSON writes the object with its keys in order
+func (o JSONObject) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, key := range o.Keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		k, _ := marshalJSON(key)
+		v, err := marshalJSON(o.Values[key])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(k)
+		b.WriteByte(':')
+		b.Write(v)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// marshalJSON is json.Marshal without escaping <, > and &, so text reads as written
+func marshalJSON(v any) ([]byte, error) {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(b.Bytes(), []byte("\n")), nil
+}
+
+// JSONValue returns the default fixture of the named type as a JSON-encodable value, for files
+// consumed outside Go (stub mappings, API examples, request samples). Field keys follow json tags,
+// enums are written as their label (or constant name without the type prefix) and recursive
+// references end in null. Field overrides, faker strings, slice lengths and byte lengths of opts
+// are applied like in the fixtures; random values are written as their static default.
+func JSONValue(m *Model, typeName string, opts GenerateOptions) (any, error) {
+	var t TypeRef
+	switch {
+	case m.Structs[typeName] != nil:
+		t = TypeRef{Kind: "struct", Name: typeName}
+	case m.Enums[typeName] != nil:
+		t = TypeRef{Kind: "enum", Name: typeName}
+	case m.TypeDefs[typeName] != nil:
+		t = TypeRef{Kind: "typedef", Name: typeName}
+	default:
+		return nil, fmt.Errorf("no fixture for type %s", typeName)
+	}
+	return jsonValue(m, t, typeName, typeName, opts, make(map[string]bool)), nil
+}
+
+// zeroJSONValue returns the JSON value of the zero value of the primitive type t
+func zeroJSONValue(t TypeRef) (any, bool) {
+	if t.Kind != "primitive" {
+		return nil, false
+	}
+	switch t.Name {
+	case "string":
+		return "", true
+	case "bool":
+		return false, true
+	}
+	return 0, true
+}
+
+func jsonValue(m *Model, t TypeRef, fieldName, structName string, opts GenerateOptions, path map[string]bool) any {
+	if td, ok := m.TypeDefs[t.Name]; ok && t.Kind != "primitive" {
+		t = td.Underlying
+	}
+	switch t.Kind {
+	case "primitive":
+		if opts.ValueStrategy == "faker" && t.Name == "string" {
+			if v := fakeValue(fieldName, structName, opts.FakerSeed); v != "" {
+				return jsonLiteral(v)
+			}
+		}
+		return jsonLiteral(genPrimitiveValue(t.Name, fieldName, structName))
+	case "pointer":
+		if t.Elem == nil {
+			return nil
+		}
+		return jsonValue(m, *t.Elem, fieldName, structName, opts, path)
+	case "slice":
+		if t.Elem == nil || t.Elem.Kind == "unknown" {
+			return nil
+		}
+		// encoding/json encodes []byte as base64, of the bytes the fixture holds
+		if byteSlice(t) {
+			return base64.StdEncoding.EncodeToString(byteSliceData(fieldName, structName, opts))
+		}
+		return jsonSlice(m, t, fieldName, structName, max(opts.SliceLen, 1), opts, path)
+	case "array":
+		// encoding/json encodes arrays, byte arrays too, as JSON arrays. The elements after the first
+		// are zero; those of other than primitive types are left out, which decodes the same.
+		if t.Elem == nil || t.Elem.Kind == "unknown" {
+			return nil
+		}
+		if t.Len == 0 {
+			return []any{}
+		}
+		values := []any{jsonValue(m, *t.Elem, fieldName, structName, opts, path)}
+		if zero, ok := zeroJSONValue(*t.Elem); ok {
+			for int64(len(values)) < t.Len {
+				values = append(values, zero)
+			}
+		}
+		return values
+	case "enum":
+		e, ok := m.Enums[t.Name]
+		if !ok || len(e.Values) == 0 {
+			return nil
+		}
+		if len(e.Labels) > 0 {
+			return e.Labels[0]
+		}
+		// Protobuf enum constants are named <Type>_<VALUE>, their JSON name is VALUE
+		return strings.TrimPrefix(e.Values[0], t.Name+"_")
+	case "external":
+		if t.Name == "Time" || t.Name == "Timestamp" {
+			return "2000-01-01T00:00:00Z"
+		}
+		return nil
+	case "oneof":
+		if impl := m.OneOfs[t.Name]; impl != "" {
+			return jsonValue(m, TypeRef{Kind: "struct", Name: impl}, fieldName, structName, opts, path)
+		}
+		return nil
+	case "struct":
+		if strings.HasPrefix(t.Name, "is") {
+			return jsonValue(m, TypeRef{Kind: "oneof", Name: t.Name}, fieldName, structName, opts, path)
+		}
+		s, ok := m.Structs[t.Name]
+		if !ok || path[t.Name] {
+			return nil
+		}
+		path[t.Name] = true
+		defer delete(path, t.Name)
+
+		obj := JSONObject{Values: make(map[string]any)}
+		for _, f := range s.Fields {
+			key := f.jsonKey()
+			if key == "-" {
+				continue
+			}
+			var v any
+			if override, ok := opts.FieldOverrides[s.Name+"."+f.Name]; ok {
+				v = jsonLiteral(override)
+			} else if f.Default != "" {
+				v = jsonLiteral(f.Default)
+			}
+			if n, ok := fieldLen(f, s.Name, opts); v == nil && ok && f.Type.Kind == "slice" && f.Type.Elem != nil && !byteSlice(f.Type) {
+				v = jsonSlice(m, f.Type, f.Name, s.Name, n, opts, path)
+			} else if v == nil {
+				v = jsonValue(m, f.Type, f.Name, s.Name, opts, path)
+			}
+			// encoding/json promotes the fields of untagged embedded structs, unless the outer struct
+			// declares them itself
+			if embedded, isObj := v.(JSONObject); f.Embedded && f.JSONName == "" && isObj {
+				for _, k := range embedded.Keys {
+					if _, taken := obj.Values[k]; !taken {
+						obj.Keys = append(obj.Keys, k)
+						obj.Values[k] = embedded.Values[k]
+					}
+				}
+				continue
+			}
+			if _, taken := obj.Values[key]; !taken {
+				obj.Keys = append(obj.Keys, key)
+			}
+			obj.Values[key] = v
+		}
+		return obj
+	}
+	return nil
+}
+
+// jsonSlice returns the JSON array of the slice type t holding n elements, which differ from each
+// other like those of sliceValue
+func jsonSlice(m *Model, t TypeRef, fieldName, structName string, n int, opts GenerateOptions, path map[string]bool) []any {
+	values := make([]any, n)
+	for i := range values {
+		if lit, ok := sliceElem(*t.Elem, fieldName, structName, i, n, opts); ok {
+			values[i] = jsonLiteral(lit)
+			continue
+		}
+		values[i] = jsonValue(m, *t.Elem, fieldName, structName, opts, path)
+		indexedJSON(m, values[i], *t.Elem, i, n, opts)
+	}
+	return values
+}
+
+// indexedJSON suffixes the key field of the JSON object v of element i of a slice of elem
+// holding n elements, like indexedFixture does in the fixtures
+func indexedJSON(m *Model, v any, elem TypeRef, i, n int, opts GenerateOptions) {
+	obj, ok := v.(JSONObject)
+	if !ok || n == 1 || opts.ValueStrategy == "random" || !opts.ModStyle {
+		return
+	}
+	if _, key := indexedStruct(m, elem, opts); key != nil {
+		if s, ok := obj.Values[key.jsonKey()].(string); ok {
+			obj.Values[key.jsonKey()] = s + strconv.Itoa(i+1)
+		}
+	}
+}
+
+// jsonLiteral converts a Go literal (string, number or bool) into its JSON value, or nil
+func jsonLiteral(lit string) any {
+	if s, err := strconv.Unquote(lit); err == nil {
+		return s
+	}
+	if lit == "true" || lit == "false" {
+		return lit == "true"
+	}
+	if _, err := strconv.ParseFloat(lit, 64); err == nil {
+		return json.Number(lit)
+	}
+	return nil
+}
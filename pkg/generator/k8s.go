@@ -0,0 +1,92 @@
+package generator
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	k8sMetaImport     = `metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"`
+	k8sResourceImport = `"k8s.io/apimachinery/pkg/api/resource"`
+	k8sCoreImport     = `corev1 "k8s.io/api/core/v1"`
+)
+
+// Kubernetes API machinery types get valid defaults: TypeMeta carries the Kind and APIVersion of the
+// object it is embedded in, ObjectMeta a name, namespace and UID, and quantities parse.
+func init() {
+	ExternalTypes["TypeMeta"] = ExternalType{
+		Import:    k8sMetaImport,
+		PkgPath:   "k8s.io/apimachinery/pkg/apis/meta/v1",
+		ValueFunc: k8sTypeMeta,
+	}
+	ExternalTypes["ObjectMeta"] = ExternalType{
+		Import:    k8sMetaImport,
+		PkgPath:   "k8s.io/apimachinery/pkg/apis/meta/v1",
+		ValueFunc: k8sObjectMeta,
+	}
+	ExternalTypes["ListMeta"] = ExternalType{
+		Import:  k8sMetaImport,
+		PkgPath: "k8s.io/apimachinery/pkg/apis/meta/v1",
+		Value:   `metav1.ListMeta{ResourceVersion: "1"}`,
+	}
+	ExternalTypes["Quantity"] = ExternalType{
+		Import:  k8sResourceImport,
+		PkgPath: "k8s.io/apimachinery/pkg/api/resource",
+		ValueFunc: func(m *Model, fieldName, structName string, opts GenerateOptions) string {
+			return `resource.MustParse("` + k8sQuantity(fieldName) + `")`
+		},
+	}
+	ExternalTypes["ResourceList"] = ExternalType{
+		Import:   k8sCoreImport,
+		Requires: []string{k8sResourceImport},
+		PkgPath:  "k8s.io/api/core/v1",
+		Value:    `corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m"), corev1.ResourceMemory: resource.MustParse("128Mi")}`,
+	}
+}
+
+func k8sTypeMeta(m *Model, fieldName, structName string, opts GenerateOptions) string {
+	apiVersion := "v1"
+	if s, ok := m.Structs[structName]; ok && s.APIVersion != "" {
+		apiVersion = s.APIVersion
+	}
+	return `metav1.TypeMeta{Kind: "` + structName + `", APIVersion: "` + apiVersion + `"}`
+}
+
+func k8sObjectMeta(m *Model, fieldName, structName string, opts GenerateOptions) string {
+	return `metav1.ObjectMeta{Name: "` + k8sName(structName) + `", Namespace: "default", UID: "00000000-0000-0000-0000-000000000001"}`
+}
+
+// k8sQuantity picks a quantity that makes sense for the resource a field is named after
+func k8sQuantity(fieldName string) string {
+	name := strings.ToLower(fieldName)
+	switch {
+	case strings.Contains(name, "cpu"):
+		return "100m"
+	case strings.Contains(name, "memory"), strings.Contains(name, "storage"):
+		return "128Mi"
+	}
+	return "1"
+}
+
+// k8sName turns a Go type name like "HTTPRoute" into a valid object name like "http-route"
+func k8sName(typeName string) string {
+	runes := []rune(typeName)
+	var b strings.Builder
+	for i, r := range runes {
+		switch {
+		case r == '_':
+			b.WriteByte('-')
+			continue
+		case i > 0 && unicode.IsUpper(r) &&
+			(!unicode.IsUpper(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])):
+			if runes[i-1] != '_' {
+				b.WriteByte('-')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	if b.Len() == 0 {
+		return "fixture"
+	}
+	return b.String()
+}
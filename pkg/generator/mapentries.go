@@ -0,0 +1,24 @@
+package generator
+
+import (
+	"go/ast"
+	"strings"
+)
+
+// mapEntry reports whether the struct name, declared as st, is the entry message of a protobuf map
+// field, like Order_AttributesEntry with a Key and a Value, which some generators declare next to
+// the message. The map field is a Go map, so the entry is no type of its own to build fixtures of.
+func mapEntry(name string, st *ast.StructType) bool {
+	if !strings.Contains(name, "_") || !strings.HasSuffix(name, "Entry") {
+		return false
+	}
+	var fields []string
+	for _, field := range st.Fields.List {
+		for _, ident := range field.Names {
+			if !ProtoInternalField(ident.Name) {
+				fields = append(fields, ident.Name)
+			}
+		}
+	}
+	return len(fields) == 2 && fields[0] == "Key" && fields[1] == "Value"
+}
@@ -0,0 +1,148 @@
+package generator
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// noGenerics reports whether the generated code has to build with a Go version before 1.18, so it
+// can't use the generic ptr helper and gets one helper per pointed-to type instead
+func noGenerics(opts GenerateOptions) bool {
+	return GoMinor(opts.GoVersion) > 0 && GoMinor(opts.GoVersion) < 18
+}
+
+// GoMinor returns the minor version of a Go version like "1.17" or "go1.21.3", or 0 if it can't be parsed
+func GoMinor(version string) int {
+	major, minor, ok := strings.Cut(strings.TrimPrefix(version, "go"), ".")
+	if !ok || major != "1" {
+		return 0
+	}
+	minor, _, _ = strings.Cut(minor, ".")
+	n, err := strconv.Atoi(minor)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// ptrName returns the name of the ptr helper, the PtrFunc of opts or ptr
+func ptrName(opts GenerateOptions) string {
+	if opts.PtrFunc != "" {
+		return opts.PtrFunc
+	}
+	return "ptr"
+}
+
+// ptrFunc returns the helper taking the address of a value of type t: ptr, or ptrString, ptrTimeTime
+// and so on without generics
+func ptrFunc(t TypeRef, opts GenerateOptions) string {
+	if !noGenerics(opts) {
+		return ptrName(opts)
+	}
+	return ptrName(opts) + typeIdent(ptrElemType(t, opts))
+}
+
+// ptrElemType is the Go type of a value passed to a ptr helper
+func ptrElemType(t TypeRef, opts GenerateOptions) string {
+	if ext, ok := lookupExternal(t, opts); ok {
+		return importName(ext.Import) + "." + t.Name
+	}
+	return typeName(t, opts)
+}
+
+// importName returns the name an import spec like `metav1 "k8s.io/..."` or `"time"` is used by
+func importName(spec string) string {
+	if alias, _, ok := strings.Cut(spec, " "); ok && !strings.HasPrefix(alias, `"`) {
+		return alias
+	}
+	path := strings.Trim(spec, `"`)
+	return path[strings.LastIndex(path, "/")+1:]
+}
+
+// typeIdent turns a Go type into an identifier suffix: "string" -> "String", "time.Time" -> "TimeTime",
+// "[]byte" -> "ByteSlice"
+func typeIdent(typ string) string {
+	suffix := ""
+	for strings.HasPrefix(typ, "[]") {
+		typ = typ[2:]
+		suffix += "Slice"
+	}
+	for strings.HasPrefix(typ, "*") {
+		typ = typ[1:]
+		suffix += "Ptr"
+	}
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(typ, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' }) {
+		runes := []rune(part)
+		runes[0] = unicode.ToUpper(runes[0])
+		b.WriteString(string(runes))
+	}
+	return b.String() + suffix
+}
+
+// ptrHelpers renders the typed ptr helpers for every type whose values genValue takes the address of
+func ptrHelpers(m *Model, opts GenerateOptions) string {
+	types := make(map[string]string) // helper -> type
+	var visit func(t TypeRef)
+	visit = func(t TypeRef) {
+		switch t.Kind {
+		case "slice", "array":
+			if t.Elem != nil {
+				visit(*t.Elem)
+			}
+		case "pointer":
+			if t.Elem == nil || t.Elem.Kind == "unknown" {
+				return
+			}
+			if ext, ok := lookupExternal(*t.Elem, opts); ok {
+				if ext.Pointer {
+					return
+				}
+			} else if t.Elem.Kind == "external" || foreignType(*t.Elem, opts) {
+				return
+			} else if returnsPointer(opts) && (t.Elem.Kind == "struct" || t.Elem.Kind == "enum" || t.Elem.Kind == "typedef") {
+				return
+			}
+			types[ptrFunc(*t.Elem, opts)] = ptrElemType(*t.Elem, opts)
+		}
+	}
+	for _, s := range m.Structs {
+		for _, f := range slices.Concat(s.Fields, promotedAssignments(s, opts)) {
+			visit(f.Type)
+		}
+	}
+	// Any payloads of fixtures returning values take the address of the message fixture
+	if !returnsPointer(opts) && usesExternal(m, "Any") {
+		for _, payload := range opts.AnyPayloads {
+			t := TypeRef{Kind: "struct", Name: payload}
+			types[ptrFunc(t, opts)] = ptrElemType(t, opts)
+		}
+	}
+
+	var b strings.Builder
+	for _, helper := range sortedKeys(types) {
+		b.WriteString("func " + helper + "(v " + types[helper] + ") *" + types[helper] + " { return &v }\n\n")
+	}
+	return b.String()
+}
+
+// explicitPtr matches the explicitly instantiated ptr calls in defaults taken from samples
+var explicitPtr = regexp.MustCompile(`\bptr\[(\w+)\]\(`)
+
+// withoutGenerics rewrites ptr[T](...) in a default value to the typed helper of T, and to the
+// PtrFunc of opts
+func withoutGenerics(value string, opts GenerateOptions) string {
+	if !noGenerics(opts) && opts.PtrFunc == "" {
+		return value
+	}
+	return explicitPtr.ReplaceAllStringFunc(value, func(call string) string {
+		typ := explicitPtr.FindStringSubmatch(call)[1]
+		if !noGenerics(opts) {
+			return ptrName(opts) + "[" + typ + "]("
+		}
+		return ptrName(opts) + typeIdent(typ) + "("
+	})
+}
@@ -0,0 +1,142 @@
+package generator
+
+import (
+	"fmt"
+	"go/ast"
+	"slices"
+	"strings"
+)
+
+// AddOneOfVariant records impl as an implementation of the oneof interface iface. The first one
+// recorded is the default fixtures set.
+func (m *Model) AddOneOfVariant(iface, impl string) {
+	if m.OneOfs[iface] == "" {
+		m.OneOfs[iface] = impl
+	}
+	if m.Variants == nil {
+		m.Variants = make(map[string][]string)
+	}
+	if !slices.Contains(m.Variants[iface], impl) {
+		m.Variants[iface] = append(m.Variants[iface], impl)
+	}
+}
+
+// addOneOfWrapper records the struct name, declared as st, as an implementation of the oneofs it is
+// named the wrapper of. protoc-gen-go names wrappers after the message and the field, Order_Card for
+// the Card field of a oneof of Order, and gives them just that field. Nested messages like
+// Order_LineItem have the fields of a message, and the wrappers of their own oneofs, such as
+// Order_LineItem_Sku, continue a longer message name, so neither implements a oneof of Order.
+func (m *Model) addOneOfWrapper(name string, st *ast.StructType) {
+	if st.Fields.NumFields() != 1 {
+		return
+	}
+	for iface := range m.OneOfs {
+		parent := iface[2:] // remove "is" prefix
+		i := strings.LastIndex(parent, "_")
+		if i <= 0 {
+			continue
+		}
+		// Fields named like a method of the message get a trailing underscore
+		field, ok := strings.CutPrefix(name, parent[:i+1])
+		if ok && field != "" && !strings.Contains(strings.TrimRight(field, "_"), "_") {
+			m.AddOneOfVariant(iface, name)
+		}
+	}
+}
+
+// ResolveOneOfs narrows the implementations of the oneofs of m to the types declaring their marker
+// method in files, as protoc-gen-go generates them (func (*User_Email) isUser_Contact() {}). Matching
+// implementations by name can't tell apart the oneofs of a message with several.
+func (m *Model) ResolveOneOfs(files []*ast.File) {
+	markers := make(map[string][]string)
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			fd, ok := decl.(*ast.FuncDecl)
+			if !ok || fd.Recv == nil || len(fd.Recv.List) != 1 {
+				continue
+			}
+			if _, ok := m.OneOfs[fd.Name.Name]; !ok {
+				continue
+			}
+			recv := fd.Recv.List[0].Type
+			if star, ok := recv.(*ast.StarExpr); ok {
+				recv = star.X
+			}
+			if ident, ok := recv.(*ast.Ident); ok {
+				markers[fd.Name.Name] = append(markers[fd.Name.Name], ident.Name)
+			}
+		}
+	}
+	for iface, impls := range markers {
+		m.OneOfs[iface] = ""
+		delete(m.Variants, iface)
+		for _, impl := range impls {
+			m.AddOneOfVariant(iface, impl)
+		}
+	}
+}
+
+// oneOfVariants returns the implementations of the oneof iface, the default first. Models decoded from
+// JSON without variants only know the default.
+func oneOfVariants(m *Model, iface string) []string {
+	if variants := m.Variants[iface]; len(variants) > 0 {
+		return variants
+	}
+	if impl := m.OneOfs[iface]; impl != "" {
+		return []string{impl}
+	}
+	return nil
+}
+
+// variantFuncs renders a FixtureXWithY per implementation Y of each oneof field of s with several,
+// returning the fixture of s with the field set to that implementation, so tests can cover every
+// branch of a oneof. When several fields share the interface the name holds the field as well,
+// FixtureXWithFieldY. Fields of opaque API messages are hidden and set through the builder instead.
+func variantFuncs(m *Model, s *Struct, opts GenerateOptions, cache *valueCache) string {
+	if s.Builder != "" {
+		return ""
+	}
+	typ := typeName(TypeRef{Kind: "struct", Name: s.Name}, opts)
+	oneOf := func(f Field) bool {
+		return f.Type.Kind == "oneof" || f.Type.Kind == "struct" && strings.HasPrefix(f.Type.Name, "is")
+	}
+	shared := make(map[string]int)
+	for _, f := range s.Fields {
+		if oneOf(f) {
+			shared[f.Type.Name]++
+		}
+	}
+	var b strings.Builder
+	for _, f := range s.Fields {
+		if !oneOf(f) {
+			continue
+		}
+		variants := oneOfVariants(m, f.Type.Name)
+		if len(variants) < 2 {
+			continue
+		}
+		with := "With"
+		if shared[f.Type.Name] > 1 {
+			with += f.Name
+		}
+		for _, impl := range variants {
+			name := "Fixture" + opts.FuncPrefix + s.Name + with + strings.TrimPrefix(impl, s.Name+"_")
+			value := oneOfLiteral(m, impl, opts, cache)
+			fmt.Fprintf(&b, "// %s returns the fixture of [%s] with %s set to a %s.\n", name, typ, f.Name, typeName(TypeRef{Kind: "struct", Name: impl}, opts))
+			if !opts.ModStyle {
+				fmt.Fprintf(&b, "func %s() %s {\n", name, typ)
+				fmt.Fprintf(&b, "\tvalue := Fixture%s%s()\n", opts.FuncPrefix, s.Name)
+				fmt.Fprintf(&b, "\tvalue.%s = %s\n", f.Name, value)
+				fmt.Fprintf(&b, "\treturn value\n}\n\n")
+				continue
+			}
+			_, pointer, deref := modOperands("value", opts)
+			fmt.Fprintf(&b, "func %s(mods ...%s) %s {\n", name, modFunc(typ, opts), fixtureResult(typ, opts))
+			fmt.Fprintf(&b, "\tvalue := Fixture%s%s()\n", opts.FuncPrefix, s.Name)
+			fmt.Fprintf(&b, "\tvalue.%s = %s\n", f.Name, value)
+			b.WriteString(applyMods(pointer, deref, opts))
+			fmt.Fprintf(&b, "\treturn value\n}\n\n")
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,87 @@
+package generator
+
+import (
+	"go/ast"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// opaqueBuilder reports whether the named type is a builder of the protobuf opaque API: a
+// Message_builder struct whose Build method returns *Message. Builders aren't messages themselves.
+func opaqueBuilder(pkg *packages.Package, name string) bool {
+	message, ok := strings.CutSuffix(name, "_builder")
+	if !ok || pkg.Types == nil {
+		return false
+	}
+	obj := pkg.Types.Scope().Lookup(name)
+	if obj == nil {
+		return false
+	}
+	sel := types.NewMethodSet(obj.Type()).Lookup(pkg.Types, "Build")
+	if sel == nil {
+		return false
+	}
+	sig := sel.Type().(*types.Signature)
+	if sig.Params().Len() != 0 || sig.Results().Len() != 1 {
+		return false
+	}
+	ptr, ok := sig.Results().At(0).Type().(*types.Pointer)
+	if !ok {
+		return false
+	}
+	result, ok := ptr.Elem().(*types.Named)
+	return ok && result.Obj().Name() == message
+}
+
+// oneofCase reports whether named is the type of the case constants of a oneof in the opaque API
+// (case_Message_Field), which tell the set field of the oneof and are no enum
+func oneofCase(named *types.Named) bool {
+	return strings.HasPrefix(named.Obj().Name(), "case_")
+}
+
+// builderFields returns the fields of the opaque API builder of the named message, if it has one
+func builderFields(pkg *packages.Package, name string) ([]Field, bool) {
+	if !opaqueBuilder(pkg, name+"_builder") {
+		return nil, false
+	}
+	st, ok := pkg.Types.Scope().Lookup(name + "_builder").Type().Underlying().(*types.Struct)
+	if !ok {
+		return nil, false
+	}
+	var fields []Field
+	for i := 0; i < st.NumFields(); i++ {
+		if f := st.Field(i); f.Exported() {
+			fields = append(fields, Field{Name: f.Name(), Type: ResolveType(f.Type())})
+		}
+	}
+	return fields, true
+}
+
+// opaqueMessage reports whether a message struct hides its fields, as the opaque API generates them
+func opaqueMessage(st *ast.StructType) bool {
+	for _, field := range st.Fields.List {
+		for _, name := range field.Names {
+			if strings.HasPrefix(name.Name, "xxx_hidden_") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// UseBuilders switches the messages of pkgs with a builder of the protobuf opaque API over to it,
+// so their fixtures set the fields hidden from struct literals
+func UseBuilders(pkgs []*packages.Package, m *Model) {
+	for _, pkg := range pkgs {
+		for name, s := range m.Structs {
+			if s.Builder != "" || s.Source == nil || s.Source.Pkg != pkg.PkgPath {
+				continue
+			}
+			if fields, ok := builderFields(pkg, name); ok {
+				s.Fields, s.Builder = fields, name+"_builder"
+			}
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// openAPIDocument is the subset of an OpenAPI 3 document needed to build a Model
+type openAPIDocument struct {
+	Components struct {
+		Schemas orderedSchemas `json:"schemas"`
+	} `json:"components"`
+}
+
+// openAPISchema is the subset of an OpenAPI 3 schema object needed to build a Model
+type openAPISchema struct {
+	Ref        string          `json:"$ref"`
+	Type       string          `json:"type"`
+	Format     string          `json:"format"`
+	Enum       []interface{}   `json:"enum"`
+	Items      *openAPISchema  `json:"items"`
+	Properties orderedSchemas  `json:"properties"`
+	Required   []string        `json:"required"`
+	OneOf      []openAPISchema `json:"oneOf"`
+	AllOf      []openAPISchema `json:"allOf"`
+}
+
+// namedSchema is a schema together with the key it was declared under
+type namedSchema struct {
+	Name   string
+	Schema openAPISchema
+}
+
+// orderedSchemas keeps schemas in document order so struct fields come out as declared
+type orderedSchemas []namedSchema
+
+func (o *orderedSchemas) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		var s openAPISchema
+		if err := dec.Decode(&s); err != nil {
+			return fmt.Errorf("%v: %w", key, err)
+		}
+		*o = append(*o, namedSchema{Name: key.(string), Schema: s})
+	}
+	_, err := dec.Token()
+	return err
+}
+
+// ParseOpenAPI reads the component schemas of an OpenAPI 3 document (JSON) into a Model:
+// objects become structs, string enums become enums and oneOf schemas become oneofs
+func ParseOpenAPI(data []byte) (*Model, error) {
+	var doc openAPIDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("openapi parse error: %w", err)
+	}
+
+	p := &openAPIParser{
+		m:       NewModel(),
+		kinds:   make(map[string]string),
+		schemas: make(map[string]openAPISchema),
+		merging: make(map[string]bool),
+	}
+
+	// First pass: record what every named schema turns into so $refs can be resolved
+	for _, ns := range doc.Components.Schemas {
+		p.kinds[goName(ns.Name)] = schemaKind(ns.Schema)
+		p.schemas[goName(ns.Name)] = ns.Schema
+	}
+
+	for _, ns := range doc.Components.Schemas {
+		p.addSchema(goName(ns.Name), ns.Schema)
+	}
+	return p.m, nil
+}
+
+type openAPIParser struct {
+	m       *Model
+	kinds   map[string]string        // Go type name -> "struct", "enum", "oneof" or "typedef"
+	schemas map[string]openAPISchema // Go type name -> component schema, for allOf composition
+	merging map[string]bool          // allOf refs currently being merged, to stop on cycles
+}
+
+func schemaKind(s openAPISchema) string {
+	switch {
+	case len(s.OneOf) > 0:
+		return "oneof"
+	case len(s.Enum) > 0:
+		return "enum"
+	case s.Type == "object" || len(s.Properties) > 0 || len(s.AllOf) > 0:
+		return "struct"
+	case s.Type == "array":
+		return "unknown"
+	}
+	return "typedef"
+}
+
+func (p *openAPIParser) addSchema(name string, s openAPISchema) {
+	switch schemaKind(s) {
+	case "oneof":
+		p.m.OneOfs[name] = ""
+		for _, variant := range s.OneOf {
+			if variant.Ref != "" {
+				p.m.AddOneOfVariant(name, refName(variant.Ref))
+			}
+		}
+
+	case "enum":
+		e := &Enum{Name: name}
+		for _, v := range s.Enum {
+			e.Values = append(e.Values, name+goName(fmt.Sprint(v)))
+			e.Labels = append(e.Labels, fmt.Sprint(v))
+		}
+		p.m.Enums[name] = e
+
+	case "struct":
+		st := &Struct{Name: name}
+		p.addProperties(st, name, s)
+		p.m.Structs[name] = st
+
+	case "typedef":
+		underlying := p.typeRef(name, "", s)
+		if underlying.Kind == "primitive" {
+			p.m.TypeDefs[name] = &TypeDef{Name: name, Underlying: underlying}
+		}
+	}
+}
+
+// addProperties adds the properties of s, including those merged in via allOf, as fields of st.
+// Inline types are named after owner, the schema that declares them.
+func (p *openAPIParser) addProperties(st *Struct, owner string, s openAPISchema) {
+	for _, part := range s.AllOf {
+		if part.Ref != "" {
+			// Composition by reference: copy the referenced schema's fields
+			if base, ok := p.schemas[refName(part.Ref)]; ok && !p.merging[part.Ref] {
+				p.merging[part.Ref] = true
+				p.addProperties(st, refName(part.Ref), base)
+				delete(p.merging, part.Ref)
+			}
+			continue
+		}
+		p.addProperties(st, owner, part)
+	}
+
+	required := make(map[string]bool)
+	for _, r := range s.Required {
+		required[r] = true
+	}
+	for _, prop := range s.Properties {
+		fieldName := goName(prop.Name)
+		ref := p.typeRef(owner, fieldName, prop.Schema)
+		// Optional properties become pointers. Collections and oneofs are already nilable,
+		// and times are rendered as values.
+		if !required[prop.Name] && ref.Kind != "slice" && ref.Kind != "oneof" && ref.Kind != "external" && ref.Kind != "unknown" {
+			elem := ref
+			ref = TypeRef{Kind: "pointer", Elem: &elem}
+		}
+		st.Fields = append(st.Fields, Field{Name: fieldName, Type: ref, JSONName: prop.Name})
+	}
+}
+
+func (p *openAPIParser) typeRef(parent, fieldName string, s openAPISchema) TypeRef {
+	if s.Ref != "" {
+		name := refName(s.Ref)
+		kind := p.kinds[name]
+		if kind == "" || kind == "unknown" {
+			kind = "struct"
+		}
+		return TypeRef{Kind: kind, Name: name}
+	}
+
+	switch s.Type {
+	case "string":
+		switch s.Format {
+		case "date-time", "date":
+			return TypeRef{Kind: "external", Name: "Time"}
+		case "byte", "binary":
+			elem := TypeRef{Kind: "primitive", Name: "byte"}
+			return TypeRef{Kind: "slice", Elem: &elem, Name: "byte"}
+		}
+		if len(s.Enum) > 0 && parent != "" {
+			// Inline enum: declare it as its own type named after the field
+			name := parent + fieldName
+			p.kinds[name] = "enum"
+			p.addSchema(name, s)
+			return TypeRef{Kind: "enum", Name: name}
+		}
+		return TypeRef{Kind: "primitive", Name: "string"}
+	case "integer":
+		if s.Format == "int32" {
+			return TypeRef{Kind: "primitive", Name: "int32"}
+		}
+		return TypeRef{Kind: "primitive", Name: "int64"}
+	case "number":
+		if s.Format == "float" {
+			return TypeRef{Kind: "primitive", Name: "float32"}
+		}
+		return TypeRef{Kind: "primitive", Name: "float64"}
+	case "boolean":
+		return TypeRef{Kind: "primitive", Name: "bool"}
+	case "array":
+		if s.Items == nil {
+			return TypeRef{Kind: "unknown"}
+		}
+		elem := p.typeRef(parent, fieldName, *s.Items)
+		return TypeRef{Kind: "slice", Elem: &elem, Name: elem.Name}
+	}
+
+	if len(s.Properties) > 0 && parent != "" {
+		// Inline object: declare it as its own struct named after the field
+		name := parent + fieldName
+		p.kinds[name] = "struct"
+		p.addSchema(name, s)
+		return TypeRef{Kind: "struct", Name: name}
+	}
+	return TypeRef{Kind: "unknown"}
+}
+
+// refName turns "#/components/schemas/user_profile" into "UserProfile"
+func refName(ref string) string {
+	return goName(ref[strings.LastIndex(ref, "/")+1:])
+}
+
+// goName converts an identifier like "user_id", "created-at" or "firstName" into an exported Go name
+func goName(s string) string {
+	words := strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var b strings.Builder
+	for _, w := range words {
+		if strings.EqualFold(w, "id") {
+			b.WriteString("ID")
+			continue
+		}
+		runes := []rune(w)
+		runes[0] = unicode.ToUpper(runes[0])
+		b.WriteString(string(runes))
+	}
+	if b.Len() == 0 {
+		return "Value"
+	}
+	name := b.String()
+	if unicode.IsDigit(rune(name[0])) {
+		name = "V" + name
+	}
+	return name
+}
@@ -0,0 +1,96 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// AddOpenAPIExamples sets the example of every component schema of an OpenAPI 3 document (JSON)
+// that has a fixture in m to that fixture's value generated with opts, so API docs show the data
+// used in tests.
+// OpenAPI 3.1 documents get an `examples` list, older ones an `example`. Everything else in the
+// document is kept in order. It returns the updated document and the names of the schemas set.
+func AddOpenAPIExamples(doc []byte, m *Model, opts GenerateOptions) ([]byte, []string, error) {
+	root, err := decodeJSON(doc)
+	if err != nil {
+		return nil, nil, fmt.Errorf("openapi parse error: %w", err)
+	}
+	if root.Kind != "object" {
+		return nil, nil, fmt.Errorf("openapi document must be an object")
+	}
+	key := "example"
+	if v := root.Fields["openapi"]; v != nil && strings.HasPrefix(v.Value, "3.1") {
+		key = "examples"
+	}
+
+	var updated []string
+	components := root.Fields["components"]
+	if components != nil && components.Fields["schemas"] != nil {
+		schemas := components.Fields["schemas"]
+		for _, name := range schemas.Keys {
+			schema := schemas.Fields[name]
+			if schema.Kind != "object" {
+				continue
+			}
+			value, err := JSONValue(m, goName(name), opts)
+			if err != nil {
+				continue
+			}
+			if key == "examples" {
+				value = []any{value}
+			}
+			data, err := json.Marshal(value)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", name, err)
+			}
+			example, err := decodeJSON(data)
+			if err != nil {
+				return nil, nil, err
+			}
+			schema.set(key, example)
+			updated = append(updated, name)
+		}
+	}
+
+	// Keep characters like < and & as they are written in descriptions
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(root); err != nil {
+		return nil, nil, err
+	}
+	return out.Bytes(), updated, nil
+}
+
+// set sets the value of key, appending it if the object doesn't have it yet
+func (n *jsonNode) set(key string, v *jsonNode) {
+	if _, ok := n.Fields[key]; !ok {
+		n.Keys = append(n.Keys, key)
+	}
+	n.Fields[key] = v
+}
+
+// MarshalJSON writes the node back as compact JSON, keeping object keys in document order
+func (n *jsonNode) MarshalJSON() ([]byte, error) {
+	switch n.Kind {
+	case "object":
+		obj := JSONObject{Keys: n.Keys, Values: make(map[string]any, len(n.Fields))}
+		for k, v := range n.Fields {
+			obj.Values[k] = v
+		}
+		return obj.MarshalJSON()
+	case "array":
+		if n.Items == nil {
+			return []byte("[]"), nil
+		}
+		return marshalJSON(n.Items)
+	case "string":
+		return marshalJSON(n.Value)
+	case "number", "bool":
+		return []byte(n.Value), nil
+	}
+	return []byte("null"), nil
+}
@@ -0,0 +1,48 @@
+package generator
+
+import (
+	"strconv"
+)
+
+// optionalScalar reports whether t is a pointer to a scalar: a basic type, enum or typedef, like the
+// fields of proto3 optional fields and other values whose presence is tracked by a pointer. Without
+// type information enums and typedefs of the model are referenced as structs.
+func optionalScalar(m *Model, t TypeRef) bool {
+	if t.Kind != "pointer" || t.Elem == nil {
+		return false
+	}
+	switch t.Elem.Kind {
+	case "primitive", "enum", "typedef":
+		return true
+	case "struct":
+		return t.Elem.Pkg == "" && (m.Enums[t.Elem.Name] != nil || m.TypeDefs[t.Elem.Name] != nil)
+	}
+	return false
+}
+
+// nilOptional reports whether the fixtures leave the optional scalar field f nil with
+// GenerateOptions.NilOptionals. Fields with an override, a default or a referenced field are set.
+func nilOptional(m *Model, f Field, structName string, opts GenerateOptions) bool {
+	if !opts.NilOptionals || !optionalScalar(m, f.Type) || f.Default != "" || f.References != "" {
+		return false
+	}
+	_, overridden := opts.FieldOverrides[structName+"."+f.Name]
+	return !overridden
+}
+
+// optionalValue renders the value of a pointer to the scalar t, named like the field it is set to.
+// Untyped constants are converted to t, since ptr(1) would point to an int. Enum and typedef fixtures
+// returning pointers are used as they are.
+func optionalValue(m *Model, t TypeRef, fieldName, structName string, opts GenerateOptions, cache *valueCache) string {
+	if t.Kind != "primitive" {
+		if returnsPointer(opts) {
+			return "Fixture" + opts.FuncPrefix + t.Name + "()"
+		}
+		return ptrFunc(t, opts) + "(" + genValue(m, t, fieldName, structName, opts, cache) + ")"
+	}
+	v := primitiveValue(t.Name, fieldName, structName, opts)
+	if _, err := strconv.ParseFloat(v, 64); err == nil && t.Name != "int" && !noGenerics(opts) {
+		v = t.Name + "(" + v + ")"
+	}
+	return ptrFunc(t, opts) + "(" + v + ")"
+}
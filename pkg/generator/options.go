@@ -0,0 +1,38 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// optionHelpers renders a With<Type><Field>(v) mod per field of s setting it to v, so mod style fixtures
+// compose without lambdas: FixtureUser(WithUserEmail("a@b.c")). Pointer fields of sparse fixtures keep
+// the WithX mods populating them instead, oneof fields have a fixture per implementation.
+func optionHelpers(s *Struct, opts GenerateOptions) string {
+	// Fields of opaque API messages are hidden, their builder sets them instead
+	if !opts.Options || !opts.ModStyle || s.Builder != "" {
+		return ""
+	}
+	typ := typeName(TypeRef{Kind: "struct", Name: s.Name}, opts)
+
+	var b strings.Builder
+	for _, f := range s.Fields {
+		if sparseField(f, opts) || f.Type.Kind == "oneof" || f.Type.Kind == "struct" && strings.HasPrefix(f.Type.Name, "is") {
+			continue
+		}
+		name := "With" + opts.FuncPrefix + s.Name + f.Name
+		fmt.Fprintf(&b, "// %s sets %s to v.\n", name, f.Name)
+		if opts.ModSignature == "value" {
+			fmt.Fprintf(&b, "func %s(v %s) func(%s) %s {\n", name, typeName(f.Type, opts), typ, typ)
+			fmt.Fprintf(&b, "\treturn func(value %s) %s {\n", typ, typ)
+			fmt.Fprintf(&b, "\t\tvalue.%s = v\n", f.Name)
+			fmt.Fprintf(&b, "\t\treturn value\n")
+		} else {
+			fmt.Fprintf(&b, "func %s(v %s) func(*%s) {\n", name, typeName(f.Type, opts), typ)
+			fmt.Fprintf(&b, "\treturn func(value *%s) {\n", typ)
+			fmt.Fprintf(&b, "\t\tvalue.%s = v\n", f.Name)
+		}
+		fmt.Fprintf(&b, "\t}\n}\n\n")
+	}
+	return b.String()
+}
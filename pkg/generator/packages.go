@@ -0,0 +1,456 @@
+package generator
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// ExtractFromPackages builds the model of the types declared in pkgs, loaded with their syntax and
+// type information (packages.NeedSyntax, NeedTypes and NeedTypesInfo). Unlike ParseSource, types of
+// other packages are resolved, promoted fields and validators are found and generic instantiations
+// keep their type arguments.
+func ExtractFromPackages(pkgs []*packages.Package) *Model {
+	m := NewModel()
+	for _, pkg := range pkgs {
+		m.Merge(ExtractPackage(pkg))
+	}
+	return m
+}
+
+// ExtractPackage builds the model of the types declared in pkg, see ExtractFromPackages. Packages
+// are extracted independently of each other, so several can be extracted concurrently and merged.
+func ExtractPackage(pkg *packages.Package) *Model {
+	m := NewModel()
+	extractEnums(pkg, m)
+	extractOneOfs(pkg, m)
+	extractTypeDefs(pkg, m)
+	extractInterfaces(pkg, m)
+	extractStructs(pkg, m)
+	extractTableNames(pkg, m)
+	return m
+}
+
+// extractEnums adds the named types of pkg with constants as enums, their values in declaration
+// order, so the first declared one is the default
+func extractEnums(pkg *packages.Package, m *Model) {
+	docs := TypeDocs(pkg.Syntax)
+	consts := make(map[string][]*types.Const)
+	for ident, obj := range pkg.TypesInfo.Defs {
+		c, ok := obj.(*types.Const)
+		if !ok {
+			continue
+		}
+		if InternalConstant(ident.Name) {
+			continue
+		}
+		named, ok := c.Type().(*types.Named)
+		if !ok || oneofCase(named) {
+			continue
+		}
+		name := named.Obj().Name()
+		if _, ok := m.Enums[name]; !ok {
+			m.Enums[name] = &Enum{Name: name, Source: packageSource(pkg, named.Obj().Pos()), Doc: docs[name]}
+		}
+		consts[name] = append(consts[name], c)
+	}
+	// Defs is a map, its order changes from run to run
+	for name, cs := range consts {
+		slices.SortFunc(cs, func(a, b *types.Const) int { return int(a.Pos() - b.Pos()) })
+		for _, c := range cs {
+			m.Enums[name].Values = append(m.Enums[name].Values, c.Name())
+		}
+	}
+}
+
+func extractOneOfs(pkg *packages.Package, m *Model) {
+	for _, file := range pkg.Syntax {
+		for _, decl := range file.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok || gd.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gd.Specs {
+				ts := spec.(*ast.TypeSpec)
+				name := ts.Name.Name
+
+				if st, ok := ts.Type.(*ast.StructType); ok {
+					m.addOneOfWrapper(name, st)
+				}
+
+				if _, ok := ts.Type.(*ast.InterfaceType); ok {
+					if len(name) > 2 && name[:2] == "is" {
+						m.OneOfs[name] = ""
+					}
+				}
+			}
+		}
+	}
+	m.ResolveOneOfs(pkg.Syntax)
+}
+
+func extractInterfaces(pkg *packages.Package, m *Model) {
+	docs := TypeDocs(pkg.Syntax)
+	for _, file := range pkg.Syntax {
+		for _, decl := range file.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok || gd.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gd.Specs {
+				ts := spec.(*ast.TypeSpec)
+				name := ts.Name.Name
+				if _, ok := ts.Type.(*ast.InterfaceType); !ok || !ast.IsExported(name) || ts.TypeParams != nil {
+					continue
+				}
+				// Constraints with type sets aren't field types
+				iface, ok := pkg.TypesInfo.Defs[ts.Name].Type().Underlying().(*types.Interface)
+				if !ok || !iface.IsMethodSet() {
+					continue
+				}
+				i := &Interface{Name: name, Source: packageSource(pkg, ts.Name.Pos()), Doc: docs[name]}
+				for j := 0; j < iface.NumMethods(); j++ {
+					fn := iface.Method(j)
+					sig := fn.Type().(*types.Signature)
+					method := Method{Name: fn.Name(), Variadic: sig.Variadic()}
+					for k := 0; k < sig.Params().Len(); k++ {
+						method.Params = append(method.Params, ResolveType(sig.Params().At(k).Type()))
+					}
+					for k := 0; k < sig.Results().Len(); k++ {
+						method.Results = append(method.Results, ResolveType(sig.Results().At(k).Type()))
+					}
+					i.Methods = append(i.Methods, method)
+				}
+				m.Interfaces[name] = i
+			}
+		}
+	}
+}
+
+func extractStructs(pkg *packages.Package, m *Model) {
+	docs := TypeDocs(pkg.Syntax)
+	for _, file := range pkg.Syntax {
+		for _, decl := range file.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok || gd.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gd.Specs {
+				ts := spec.(*ast.TypeSpec)
+				st, ok := ts.Type.(*ast.StructType)
+				if !ok || opaqueBuilder(pkg, ts.Name.Name) || mapEntry(ts.Name.Name, st) {
+					continue
+				}
+				s := &Struct{Name: ts.Name.Name, TypeParams: ParseTypeParams(ts.TypeParams), Source: packageSource(pkg, ts.Name.Pos()), Doc: docs[ts.Name.Name]}
+				for _, field := range st.Fields.List {
+					tr := ResolveType(pkg.TypesInfo.TypeOf(field.Type))
+					if len(field.Names) == 0 && tr.Kind == "external" {
+						// Embedded external types such as metav1.TypeMeta are set through their type name
+						s.Fields = append(s.Fields, Field{Name: tr.Name, Type: tr})
+						if tr.Name == "TypeMeta" {
+							s.APIVersion = APIVersion(pkg)
+						}
+						continue
+					}
+					if len(field.Names) == 0 {
+						if f, ok := embeddedField(field, tr); ok {
+							s.Fields = append(s.Fields, f)
+						}
+						continue
+					}
+					for _, name := range field.Names {
+						if ProtoInternalField(name.Name) {
+							continue
+						}
+						s.Fields = append(s.Fields, Field{
+							Name:     name.Name,
+							Type:     tr,
+							Column:   fieldColumn(field, name.Name),
+							JSONName: fieldJSONName(field),
+							SliceLen: fieldSliceLen(field),
+						})
+					}
+				}
+				// Literals can't set the hidden fields of opaque API messages, their builder can
+				if fields, ok := builderFields(pkg, s.Name); ok && opaqueMessage(st) {
+					s.Fields, s.Builder = fields, s.Name+"_builder"
+				}
+				s.Validator = validator(pkg, s.Name)
+				s.Promoted = promotedFields(pkg, s.Name)
+				m.Structs[s.Name] = s
+			}
+		}
+	}
+}
+
+// embeddedField returns the embedded field declared by field, named by its type like Go does. Types
+// of pkg are set through their fixture, those of other packages through a fixtures package for them
+// or else through their promoted fields.
+func embeddedField(field *ast.Field, tr TypeRef) (Field, bool) {
+	named := tr
+	if named.Kind == "pointer" && named.Elem != nil {
+		named = *named.Elem
+	}
+	if named.Pkg == "" || named.Kind != "struct" && named.Kind != "enum" {
+		return Field{}, false
+	}
+	return Field{
+		Name:     named.Name,
+		Type:     tr,
+		Column:   fieldColumn(field, named.Name),
+		JSONName: fieldJSONName(field),
+		Embedded: true,
+	}, true
+}
+
+// promotedFields returns the fields promoted from the embedded structs of the named struct type.
+// Each candidate is resolved with go/types field lookup, so a field shadowed by a shallower one of
+// the same name, or ambiguous between two embedded structs, is left out.
+func promotedFields(pkg *packages.Package, name string) []Field {
+	if pkg.Types == nil {
+		return nil
+	}
+	obj := pkg.Types.Scope().Lookup(name)
+	if obj == nil {
+		return nil
+	}
+	st, ok := obj.Type().Underlying().(*types.Struct)
+	if !ok {
+		return nil
+	}
+
+	var result []Field
+	var walk func(st *types.Struct, index []int, selector string, seen map[*types.Struct]bool)
+	walk = func(st *types.Struct, index []int, selector string, seen map[*types.Struct]bool) {
+		for i := 0; i < st.NumFields(); i++ {
+			f := st.Field(i)
+			path := append(append([]int(nil), index...), i)
+			if len(index) > 0 {
+				found, at, _ := types.LookupFieldOrMethod(obj.Type(), true, pkg.Types, f.Name())
+				if found == f && slices.Equal(at, path) {
+					tag := st.Tag(i)
+					result = append(result, Field{
+						Name:     selector + f.Name(),
+						Type:     ResolveType(f.Type()),
+						Column:   ColumnFromTag(tag, f.Name()),
+						JSONName: JSONNameFromTag(tag),
+						Embedded: f.Embedded(),
+					})
+				}
+			}
+			if !f.Embedded() {
+				continue
+			}
+			typ := f.Type()
+			if ptr, ok := typ.(*types.Pointer); ok {
+				typ = ptr.Elem()
+			}
+			// Fields of other packages are found by the lookup only if they are exported
+			inner, ok := typ.Underlying().(*types.Struct)
+			if !ok || seen[inner] {
+				continue
+			}
+			seen[inner] = true
+			walk(inner, path, selector+f.Name()+".", seen)
+			delete(seen, inner)
+		}
+	}
+	walk(st, nil, "", map[*types.Struct]bool{st: true})
+	return result
+}
+
+// validator returns the protoc-gen-validate style method of the named type, preferring ValidateAll
+// (all violations) over Validate (the first one)
+func validator(pkg *packages.Package, name string) string {
+	if pkg.Types == nil {
+		return ""
+	}
+	obj := pkg.Types.Scope().Lookup(name)
+	if obj == nil {
+		return ""
+	}
+	methods := types.NewMethodSet(types.NewPointer(obj.Type()))
+	for _, method := range []string{"ValidateAll", "Validate"} {
+		sel := methods.Lookup(pkg.Types, method)
+		if sel == nil {
+			continue
+		}
+		sig := sel.Type().(*types.Signature)
+		if sig.Params().Len() == 0 && sig.Results().Len() == 1 && sig.Results().At(0).Type().String() == "error" {
+			return method
+		}
+	}
+	return ""
+}
+
+// packageSource returns the declaration at pos in pkg, named by its file so it stays stable across checkouts
+func packageSource(pkg *packages.Package, pos token.Pos) *Source {
+	p := pkg.Fset.Position(pos)
+	if !p.IsValid() {
+		return nil
+	}
+	return &Source{Pkg: pkg.PkgPath, File: filepath.Base(p.Filename), Line: p.Line}
+}
+
+// extractTableNames records the tables of structs that declare a GORM-style
+// `func (T) TableName() string { return "..." }` method
+func extractTableNames(pkg *packages.Package, m *Model) {
+	for _, file := range pkg.Syntax {
+		for _, decl := range file.Decls {
+			fd, ok := decl.(*ast.FuncDecl)
+			if !ok || fd.Name.Name != "TableName" || fd.Recv == nil || len(fd.Recv.List) != 1 || fd.Body == nil || len(fd.Body.List) != 1 {
+				continue
+			}
+			ret, ok := fd.Body.List[0].(*ast.ReturnStmt)
+			if !ok || len(ret.Results) != 1 {
+				continue
+			}
+			lit, ok := ret.Results[0].(*ast.BasicLit)
+			if !ok || lit.Kind != token.STRING {
+				continue
+			}
+			recv := fd.Recv.List[0].Type
+			if star, ok := recv.(*ast.StarExpr); ok {
+				recv = star.X
+			}
+			ident, ok := recv.(*ast.Ident)
+			if !ok {
+				continue
+			}
+			if s, ok := m.Structs[ident.Name]; ok {
+				s.Table, _ = strconv.Unquote(lit.Value)
+			}
+		}
+	}
+}
+
+func extractTypeDefs(pkg *packages.Package, m *Model) {
+	docs := TypeDocs(pkg.Syntax)
+	for _, file := range pkg.Syntax {
+		for _, decl := range file.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok || gd.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gd.Specs {
+				ts := spec.(*ast.TypeSpec)
+				name := ts.Name.Name
+
+				// Skip unexported types
+				if name[0] >= 'a' && name[0] <= 'z' {
+					continue
+				}
+
+				// Check if it's a type alias like `type TenantID string`
+				if ident, ok := ts.Type.(*ast.Ident); ok {
+					underlying := ResolveType(pkg.TypesInfo.TypeOf(ident))
+					if underlying.Kind == "primitive" {
+						m.TypeDefs[name] = &TypeDef{
+							Name:       name,
+							Underlying: underlying,
+							Source:     packageSource(pkg, ts.Name.Pos()),
+							Doc:        docs[name],
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// IsExternal reports whether obj is one of ExternalTypes, registered by import path and name
+// or by its simple name
+func IsExternal(obj *types.TypeName) bool {
+	if obj.Pkg() != nil {
+		if _, ok := ExternalTypes[obj.Pkg().Path()+"."+obj.Name()]; ok {
+			return true
+		}
+	}
+	ext, ok := ExternalTypes[obj.Name()]
+	if !ok {
+		return false
+	}
+	return ext.PkgPath == "" || obj.Pkg() != nil && obj.Pkg().Path() == ext.PkgPath
+}
+
+// APIVersion returns the Kubernetes group/version of pkg, taking the group from a
+// "+groupName=" marker comment and the version from the package path (e.g. ".../v1beta1")
+func APIVersion(pkg *packages.Package) string {
+	version := pkg.PkgPath[strings.LastIndex(pkg.PkgPath, "/")+1:]
+	if !k8sVersion.MatchString(version) {
+		return ""
+	}
+	for _, file := range pkg.Syntax {
+		for _, cg := range file.Comments {
+			for _, c := range cg.List {
+				text := strings.TrimSpace(strings.TrimPrefix(c.Text, "//"))
+				if group, ok := strings.CutPrefix(text, "+groupName="); ok && group != "" {
+					return group + "/" + version
+				}
+			}
+		}
+	}
+	return version
+}
+
+var k8sVersion = regexp.MustCompile(`^v[0-9]+((alpha|beta)[0-9]+)?$`)
+
+// ResolveType returns the reference to the type t of a type-checked package. Named types keep the
+// import path of their package; types of ExternalTypes are external.
+func ResolveType(t types.Type) TypeRef {
+	switch tt := t.(type) {
+	case *types.Basic:
+		return TypeRef{Kind: "primitive", Name: tt.Name()}
+	case *types.Named:
+		name := tt.Obj().Name()
+		var pkg string
+		if tt.Obj().Pkg() != nil {
+			pkg = tt.Obj().Pkg().Path()
+		}
+		if IsExternal(tt.Obj()) {
+			return TypeRef{Kind: "external", Name: name, Pkg: pkg}
+		}
+		if _, ok := tt.Underlying().(*types.Struct); ok {
+			ref := TypeRef{Kind: "struct", Name: name, Pkg: pkg}
+			for i := 0; i < tt.TypeArgs().Len(); i++ {
+				ref.TypeArgs = append(ref.TypeArgs, ResolveType(tt.TypeArgs().At(i)))
+			}
+			return ref
+		}
+		if _, ok := tt.Underlying().(*types.Interface); ok {
+			if strings.HasPrefix(name, "is") {
+				return TypeRef{Kind: "oneof", Name: name, Pkg: pkg}
+			}
+			return TypeRef{Kind: "interface", Name: name, Pkg: pkg}
+		}
+		return TypeRef{Kind: "enum", Name: name, Pkg: pkg}
+	case *types.Alias:
+		// any
+		if tt.Obj().Pkg() == nil {
+			return ResolveType(types.Unalias(tt))
+		}
+	case *types.Interface:
+		if tt.Empty() {
+			return TypeRef{Kind: "interface"}
+		}
+	case *types.TypeParam:
+		return TypeRef{Kind: "typeparam", Name: tt.Obj().Name()}
+	case *types.Pointer:
+		elem := ResolveType(tt.Elem())
+		return TypeRef{Kind: "pointer", Elem: &elem}
+	case *types.Slice:
+		elem := ResolveType(tt.Elem())
+		return TypeRef{Kind: "slice", Elem: &elem}
+	case *types.Array:
+		elem := ResolveType(tt.Elem())
+		return TypeRef{Kind: "array", Elem: &elem, Len: tt.Len()}
+	}
+	return TypeRef{Kind: "unknown"}
+}
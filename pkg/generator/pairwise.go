@@ -0,0 +1,191 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// pairwiseValue is a value of a field in a pairwise set: expr is assigned to the field, unless keep
+// leaves the fixture's own value in place
+type pairwiseValue struct {
+	expr string
+	keep bool
+}
+
+// pairwiseValues returns the interesting values of f: every value of an enum, both bools, and nil
+// besides the fixture's value for pointers and slices. Other fields have none.
+func pairwiseValues(m *Model, s *Struct, f Field, opts GenerateOptions, cache *valueCache) []pairwiseValue {
+	switch f.Type.Kind {
+	case "enum":
+		e, ok := m.Enums[f.Type.Name]
+		if _, foreign := fixtureCall(f.Type, opts); !ok || foreign {
+			return nil
+		}
+		var values []pairwiseValue
+		for _, v := range e.Values {
+			if !InternalConstant(v) {
+				values = append(values, pairwiseValue{expr: typeName(TypeRef{Kind: "enum", Name: v}, opts)})
+			}
+		}
+		return values
+	case "primitive":
+		if f.Type.Name == "bool" {
+			return []pairwiseValue{{expr: "true"}, {expr: "false"}}
+		}
+	case "pointer", "slice":
+		if f.Type.Kind == "pointer" && sparseField(f, opts) && s.Builder == "" {
+			// Sparse fixtures leave the field nil, the other value populates it
+			value := genFieldValue(m, f, s.Name, opts, cache)
+			if call, ok := nestedFixture(m, f, opts); ok {
+				value = strings.Replace(call, "(mods...)", "()", 1)
+			}
+			return []pairwiseValue{{keep: true}, {expr: value}}
+		}
+		return []pairwiseValue{{keep: true}, {expr: "nil"}}
+	}
+	return nil
+}
+
+// pairwiseRows returns rows of value indexes, one per field with sizes[i] values, such that every pair
+// of values of two fields appears in some row. Rows are picked greedily, each starting from the first
+// pair not yet covered and taking for the other fields the value covering the most new pairs, which
+// stays deterministic and close to the minimum for the few fields and values fixtures combine.
+func pairwiseRows(sizes []int) [][]int {
+	if len(sizes) == 1 {
+		rows := make([][]int, sizes[0])
+		for v := range rows {
+			rows[v] = []int{v}
+		}
+		return rows
+	}
+
+	type pair struct{ i, a, j, b int }
+	covered := make(map[pair]bool)
+	var pairs []pair
+	for i := range sizes {
+		for j := i + 1; j < len(sizes); j++ {
+			for a := 0; a < sizes[i]; a++ {
+				for b := 0; b < sizes[j]; b++ {
+					pairs = append(pairs, pair{i, a, j, b})
+				}
+			}
+		}
+	}
+
+	var rows [][]int
+	for uncovered := len(pairs); uncovered > 0; {
+		var first pair
+		for _, p := range pairs {
+			if !covered[p] {
+				first = p
+				break
+			}
+		}
+		row := make([]int, len(sizes))
+		for k := range row {
+			row[k] = -1
+		}
+		row[first.i], row[first.j] = first.a, first.b
+		for k := range sizes {
+			if row[k] >= 0 {
+				continue
+			}
+			best, bestGain := 0, -1
+			for v := 0; v < sizes[k]; v++ {
+				gain := 0
+				for other, value := range row {
+					if value < 0 || other == k {
+						continue
+					}
+					p := pair{other, value, k, v}
+					if k < other {
+						p = pair{k, v, other, value}
+					}
+					if !covered[p] {
+						gain++
+					}
+				}
+				if gain > bestGain {
+					best, bestGain = v, gain
+				}
+			}
+			row[k] = best
+		}
+		for i := range row {
+			for j := i + 1; j < len(row); j++ {
+				if p := (pair{i, row[i], j, row[j]}); !covered[p] {
+					covered[p] = true
+					uncovered--
+				}
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// pairwiseFunc renders PairwiseX, returning fixtures of s that cover every pair of interesting values
+// of the given fields. Fields without interesting values are left out of the combinations.
+func pairwiseFunc(m *Model, s *Struct, fields []string, opts GenerateOptions, cache *valueCache) string {
+	var selected []Field
+	var values [][]pairwiseValue
+	for _, name := range fields {
+		for _, f := range s.Fields {
+			if f.Name != name {
+				continue
+			}
+			if v := pairwiseValues(m, s, f, opts, cache); len(v) > 1 {
+				selected = append(selected, f)
+				values = append(values, v)
+			}
+		}
+	}
+	names := make([]string, len(selected))
+	for i, f := range selected {
+		names[i] = f.Name
+	}
+	if len(selected) == 0 {
+		return ""
+	}
+	sizes := make([]int, len(values))
+	for i, v := range values {
+		sizes[i] = len(v)
+	}
+	rows := pairwiseRows(sizes)
+
+	typ := typeName(TypeRef{Kind: "struct", Name: s.Name}, opts)
+	result := typ
+	if returnsPointer(opts) {
+		result = "*" + typ
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "// Pairwise%s%s returns fixtures of [%s] covering every pair of values of %s.\n", opts.FuncPrefix, s.Name, typ, joinNames(names))
+	fmt.Fprintf(&b, "func Pairwise%s%s() []%s {\n", opts.FuncPrefix, s.Name, result)
+	fmt.Fprintf(&b, "\tfixtures := make([]%s, %d)\n", result, len(rows))
+	fmt.Fprintf(&b, "\tfor i := range fixtures {\n\t\tfixtures[i] = Fixture%s%s()\n\t}\n", opts.FuncPrefix, s.Name)
+	for r, row := range rows {
+		for i, v := range row {
+			value := values[i][v]
+			switch {
+			case value.keep:
+			case s.Builder == "":
+				fmt.Fprintf(&b, "\tfixtures[%d].%s = %s\n", r, names[i], value.expr)
+			case value.expr == "nil" && selected[i].Type.Kind == "pointer":
+				// The hidden fields of opaque API messages are changed through their accessors
+				fmt.Fprintf(&b, "\tfixtures[%d].Clear%s()\n", r, names[i])
+			default:
+				fmt.Fprintf(&b, "\tfixtures[%d].Set%s(%s)\n", r, names[i], value.expr)
+			}
+		}
+	}
+	fmt.Fprintf(&b, "\treturn fixtures\n}\n\n")
+	return b.String()
+}
+
+// joinNames lists names as "A, B and C"
+func joinNames(names []string) string {
+	if len(names) == 1 {
+		return names[0]
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
+}
@@ -0,0 +1,32 @@
+package generator
+
+// PluginProtocolVersion is the version of the plugin protocol, sent in every request
+const PluginProtocolVersion = 1
+
+// PluginRequest is written as JSON to the stdin of a plugin. A plugin is any executable reading one
+// request and writing one PluginResponse as JSON to stdout, like a protoc plugin; anything it writes
+// to stderr is passed through.
+type PluginRequest struct {
+	Version int    `json:"version"`
+	Model   *Model `json:"model"`
+	// Options are the options the built-in fixtures are generated with
+	Options GenerateOptions `json:"options"`
+	// Package is the package name of the generated Go file
+	Package string `json:"package"`
+	// Parameter is the free-form plugin parameter given on the command line
+	Parameter string `json:"parameter,omitempty"`
+}
+
+// PluginResponse is what a plugin writes to stdout: the files to write, or an error
+type PluginResponse struct {
+	Files []PluginFile `json:"files,omitempty"`
+	// Error fails the run with this message; no files are written
+	Error string `json:"error,omitempty"`
+}
+
+// PluginFile is a file produced by a plugin
+type PluginFile struct {
+	// Name is a slash-separated path relative to the plugin output directory
+	Name    string `json:"name"`
+	Content string `json:"content"`
+}
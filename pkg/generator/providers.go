@@ -0,0 +1,42 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// providerImports are the imports of the provider set of each GenerateOptions.Providers framework
+var providerImports = map[string]string{
+	"wire": `"github.com/google/wire"`,
+	"fx":   `"go.uber.org/fx"`,
+}
+
+// providerFuncs renders a ProvideFixtureX constructor per struct and the set of them for google/wire
+// (wire.NewSet) or uber/fx (fx.Provide). The fixtures take variadic mods, which both frameworks would
+// resolve as a dependency, so the constructors take no parameters.
+func providerFuncs(m *Model, opts GenerateOptions) string {
+	var b strings.Builder
+	var providers []string
+	for _, name := range fixtureStructs(m) {
+		typ := fixtureResult(typeName(TypeRef{Kind: "struct", Name: name}, opts), opts)
+		fixture := "Fixture" + opts.FuncPrefix + name
+		fmt.Fprintf(&b, "// Provide%s provides the fixture of [%s] as a constructor for dependency injection.\n", fixture, typeName(TypeRef{Kind: "struct", Name: name}, opts))
+		fmt.Fprintf(&b, "func Provide%s() %s {\n\treturn %s()\n}\n\n", fixture, typ, fixture)
+		providers = append(providers, "Provide"+fixture)
+	}
+	if len(providers) == 0 {
+		return ""
+	}
+
+	set := "Fixture" + opts.FuncPrefix + "Providers"
+	list := "\n\t" + strings.Join(providers, ",\n\t") + ",\n"
+	switch opts.Providers {
+	case "wire":
+		fmt.Fprintf(&b, "// %s provides every fixture to wire injectors, in place of the providers of the real values.\n", set)
+		fmt.Fprintf(&b, "var %s = wire.NewSet(%s)\n\n", set, list)
+	case "fx":
+		fmt.Fprintf(&b, "// %s provides every fixture to an fx application, in place of the constructors of the real values.\n", set)
+		fmt.Fprintf(&b, "var %s = fx.Provide(%s)\n\n", set, list)
+	}
+	return b.String()
+}
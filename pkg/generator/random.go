@@ -0,0 +1,91 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// randomImports are used by randomHelper
+var randomImports = []string{`"math/rand"`, `"strconv"`, `"sync"`}
+
+// randomValues reports whether fixtures draw their values at runtime, with ValueStrategy "random"
+func randomValues(m *Model, opts GenerateOptions) bool {
+	return opts.ValueStrategy == "random" && len(m.Structs)+len(m.TypeDefs)+len(m.Enums) > 0
+}
+
+// randomSeedFunc returns the name of the func reseeding the values fixtures draw with ValueStrategy "random"
+func randomSeedFunc(opts GenerateOptions) string {
+	return "Seed" + opts.FuncPrefix + "Fixtures"
+}
+
+// primitiveValue renders the value of a basic type: a literal, or with ValueStrategy "random" a draw
+// of the fixtures' random source. With "faker", strings named like an email, phone or city get a
+// realistic literal.
+func primitiveValue(typeName, fieldName, structName string, opts GenerateOptions) string {
+	if opts.ValueStrategy == "faker" && typeName == "string" {
+		if v := fakeValue(fieldName, structName, opts.FakerSeed); v != "" {
+			return v
+		}
+	}
+	if opts.ValueStrategy != "random" {
+		return genPrimitiveValue(typeName, fieldName, structName)
+	}
+	switch typeName {
+	case "string":
+		prefix := fieldName
+		if fieldName == "ID" || fieldName == "Id" {
+			prefix = structName + "ID"
+		}
+		return fmt.Sprintf("fixtureRandomString(%q)", prefix)
+	case "bool":
+		return "fixtureRandom(2) == 1"
+	case "int":
+		return "1 + fixtureRandom(100)"
+	case "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64", "byte", "rune":
+		return typeName + "(1 + fixtureRandom(100))"
+	case "float32", "float64":
+		return typeName + "(1+fixtureRandom(10000)) / 100"
+	}
+	return "nil"
+}
+
+// enumValue renders the value of the enum fixture: the first value, or with ValueStrategy "random"
+// one of values drawn at runtime
+func enumValue(typ string, values []string, opts GenerateOptions) string {
+	if opts.ValueStrategy != "random" || len(values) == 1 {
+		return values[0]
+	}
+	return fmt.Sprintf("[]%s{%s}[fixtureRandom(%d)]", typ, strings.Join(values, ", "), len(values))
+}
+
+// randomHelper renders the random source the fixtures draw their values from with ValueStrategy
+// "random". It starts from the same seed every run, so fixtures only vary where tests reseed it.
+func randomHelper(opts GenerateOptions) string {
+	name := randomSeedFunc(opts)
+	return fmt.Sprintf(`var (
+	fixtureRandMu sync.Mutex
+	fixtureRand   = rand.New(rand.NewSource(1))
+)
+
+// %s reseeds the random values fixtures are built with, which start from seed 1. Tests vary
+// the fixtures by seed and reproduce a failure by setting the seed it was found with.
+func %s(seed int64) {
+	fixtureRandMu.Lock()
+	defer fixtureRandMu.Unlock()
+	fixtureRand.Seed(seed)
+}
+
+// fixtureRandom returns a random number in [0, n)
+func fixtureRandom(n int) int {
+	fixtureRandMu.Lock()
+	defer fixtureRandMu.Unlock()
+	return fixtureRand.Intn(n)
+}
+
+// fixtureRandomString returns prefix followed by a random number, so values still tell what they are
+func fixtureRandomString(prefix string) string {
+	return prefix + "-" + strconv.Itoa(fixtureRandom(1000000))
+}
+
+`, name, name)
+}
@@ -0,0 +1,230 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RapidImport is the property testing library the Rapid generators are written for
+const RapidImport = `"pgregory.net/rapid"`
+
+// rapidPrimitives are the rapid generators of the basic types
+var rapidPrimitives = map[string]string{
+	"string": "rapid.String()", "bool": "rapid.Bool()", "byte": "rapid.Byte()", "rune": "rapid.Rune()",
+	"int": "rapid.Int()", "int8": "rapid.Int8()", "int16": "rapid.Int16()", "int32": "rapid.Int32()", "int64": "rapid.Int64()",
+	"uint": "rapid.Uint()", "uint8": "rapid.Uint8()", "uint16": "rapid.Uint16()", "uint32": "rapid.Uint32()", "uint64": "rapid.Uint64()",
+	"float32": "rapid.Float32()", "float64": "rapid.Float64()",
+}
+
+// rapidGen returns a rapid generator of values of t built from the generators of its parts, so a
+// failing value shrinks part by part. Types without one (external, oneof or of other packages) keep
+// their fixture value.
+func rapidGen(m *Model, t TypeRef, opts GenerateOptions) (string, bool) {
+	if _, foreign := fixtureCall(t, opts); foreign {
+		return "", false
+	}
+	switch t.Kind {
+	case "primitive":
+		gen, ok := rapidPrimitives[t.Name]
+		return gen, ok
+	case "typedef":
+		td, ok := m.TypeDefs[t.Name]
+		if !ok {
+			return "", false
+		}
+		gen, ok := rapidPrimitives[td.Underlying.Name]
+		typ := typeName(t, opts)
+		return fmt.Sprintf("rapid.Map(%s, func(v %s) %s { return %s(v) })", gen, td.Underlying.Name, typ, typ), ok
+	case "enum":
+		if td, ok := m.TypeDefs[t.Name]; ok {
+			return rapidGen(m, TypeRef{Kind: "typedef", Name: td.Name}, opts)
+		}
+		e, ok := m.Enums[t.Name]
+		if !ok || !enumHasValue(e) {
+			return "", false
+		}
+		if w := opts.Weights[t.Name]; len(w) > 0 {
+			var values, weights []string
+			for _, v := range e.Values {
+				if w[v] > 0 {
+					values = append(values, typeName(TypeRef{Kind: "enum", Name: v}, opts))
+					weights = append(weights, fmt.Sprint(w[v]))
+				}
+			}
+			typ := typeName(t, opts)
+			return fmt.Sprintf("rapid.Map(rapidWeighted(%s), func(i int) %s { return []%s{%s}[i] })",
+				strings.Join(weights, ", "), typ, typ, strings.Join(values, ", ")), true
+		}
+		var values []string
+		for _, v := range e.Values {
+			if !InternalConstant(v) {
+				values = append(values, typeName(TypeRef{Kind: "enum", Name: v}, opts))
+			}
+		}
+		return fmt.Sprintf("rapid.SampledFrom([]%s{%s})", typeName(t, opts), strings.Join(values, ", ")), true
+	case "struct":
+		s, ok := m.Structs[t.Name]
+		if !ok || s.Builder != "" || len(s.TypeParams) > 0 || foreignType(t, opts) {
+			return "", false
+		}
+		gen := "Rapid" + opts.FuncPrefix + t.Name + "()"
+		if returnsPointer(opts) {
+			typ := typeName(t, opts)
+			gen = fmt.Sprintf("rapid.Map(%s, func(v *%s) %s { return *v })", gen, typ, typ)
+		}
+		return gen, true
+	case "pointer":
+		if t.Elem == nil {
+			return "", false
+		}
+		if t.Elem.Kind == "struct" && returnsPointer(opts) {
+			if s, ok := m.Structs[t.Elem.Name]; !ok || s.Builder != "" || len(s.TypeParams) > 0 {
+				return "", false
+			}
+			// nil ends recursive types, and the generator returns pointers already
+			typ := typeName(t, opts)
+			return fmt.Sprintf("rapid.OneOf(rapid.Just[%s](nil), Rapid%s%s())", typ, opts.FuncPrefix, t.Elem.Name), true
+		}
+		elem, ok := rapidGen(m, *t.Elem, opts)
+		return fmt.Sprintf("rapid.Ptr(%s, true)", elem), ok
+	case "slice":
+		if t.Elem == nil {
+			return "", false
+		}
+		elem, ok := rapidGen(m, *t.Elem, opts)
+		return fmt.Sprintf("rapid.SliceOfN(%s, 0, 3)", elem), ok
+	case "array":
+		if t.Elem == nil {
+			return "", false
+		}
+		// Arrays are drawn as slices of their length
+		elem, ok := rapidGen(m, *t.Elem, opts)
+		typ := typeName(t, opts)
+		return fmt.Sprintf("rapid.Map(rapid.SliceOfN(%s, %d, %d), func(v []%s) (a %s) { copy(a[:], v); return a })",
+			elem, t.Len, t.Len, typeName(*t.Elem, opts), typ), ok
+	}
+	return "", false
+}
+
+// rapidFunc renders RapidX, a rapid generator of s drawing every field on its own. Fields named in
+// pin keep the value of the fixture. Oneof fields with Weights are drawn from their implementations.
+func rapidFunc(m *Model, s *Struct, opts GenerateOptions) string {
+	// The hidden fields of opaque API messages can't be assigned
+	if s.Builder != "" {
+		return ""
+	}
+	typ := typeName(TypeRef{Kind: "struct", Name: s.Name}, opts)
+	result := typ
+	if returnsPointer(opts) {
+		result = "*" + typ
+	}
+	name := "Rapid" + opts.FuncPrefix + s.Name
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "// %s returns a rapid generator of [%s] drawing each field on its own, so failing values\n", name, typ)
+	fmt.Fprintf(&b, "// shrink field by field. The fields named in pin keep their fixture value.\n")
+	fmt.Fprintf(&b, "func %s(pin ...string) *rapid.Generator[%s] {\n", name, result)
+	fmt.Fprintf(&b, "\treturn rapid.Custom(func(t *rapid.T) %s {\n", result)
+	fmt.Fprintf(&b, "\t\tvalue := Fixture%s%s()\n", opts.FuncPrefix, s.Name)
+	for _, f := range s.Fields {
+		if f.Embedded {
+			continue
+		}
+		if impls := weightedOneOf(f.Type, opts); len(impls) > 0 {
+			b.WriteString(rapidOneOf(m, f, impls, opts))
+			continue
+		}
+		gen, ok := rapidGen(m, f.Type, opts)
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(&b, "\t\tif !rapidPinned(pin, %q) {\n", f.Name)
+		fmt.Fprintf(&b, "\t\t\tvalue.%s = %s.Draw(t, %q)\n", f.Name, gen, f.Name)
+		fmt.Fprintf(&b, "\t\t}\n")
+	}
+	fmt.Fprintf(&b, "\t\treturn value\n")
+	fmt.Fprintf(&b, "\t})\n}\n\n")
+	return b.String()
+}
+
+// hasRapid reports whether any struct of m gets a RapidX generator
+func hasRapid(m *Model, opts GenerateOptions) bool {
+	if !opts.Rapid {
+		return false
+	}
+	for _, s := range m.Structs {
+		if s.Builder == "" && len(s.TypeParams) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// rapidHelper is shared by the RapidX generators
+const rapidHelper = `// rapidPinned reports whether field is among the pinned fields of a Rapid generator
+func rapidPinned(pin []string, field string) bool {
+	for _, p := range pin {
+		if p == field {
+			return true
+		}
+	}
+	return false
+}
+
+`
+
+// weightedOneOf returns the implementations of the oneof t with a weight, in order
+func weightedOneOf(t TypeRef, opts GenerateOptions) []string {
+	if t.Kind != "oneof" && (t.Kind != "struct" || !strings.HasPrefix(t.Name, "is")) {
+		return nil
+	}
+	w := opts.Weights[t.Name]
+	var impls []string
+	for _, impl := range sortedKeys(w) {
+		if w[impl] > 0 {
+			impls = append(impls, impl)
+		}
+	}
+	return impls
+}
+
+// rapidOneOf renders drawing the oneof field f from its weighted implementations. The interface of a
+// oneof is unexported, so the implementation is picked by index instead of by a generator of it.
+func rapidOneOf(m *Model, f Field, impls []string, opts GenerateOptions) string {
+	w := opts.Weights[f.Type.Name]
+	var weights []string
+	for _, impl := range impls {
+		weights = append(weights, fmt.Sprint(w[impl]))
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "\t\tif !rapidPinned(pin, %q) {\n", f.Name)
+	fmt.Fprintf(&b, "\t\t\tswitch rapidWeighted(%s).Draw(t, %q) {\n", strings.Join(weights, ", "), f.Name)
+	for i, impl := range impls {
+		fmt.Fprintf(&b, "\t\t\tcase %d:\n\t\t\t\tvalue.%s = %s\n", i, f.Name, oneOfLiteral(m, impl, opts, nil))
+	}
+	fmt.Fprintf(&b, "\t\t\t}\n\t\t}\n")
+	return b.String()
+}
+
+// rapidWeightedHelper draws the weighted enum values and oneof implementations of GenerateOptions.Weights.
+// Indexes shrink towards 0, the first value.
+const rapidWeightedHelper = `// rapidWeighted returns a rapid generator of indexes into weights, drawing i with probability
+// weights[i] / sum(weights)
+func rapidWeighted(weights ...int) *rapid.Generator[int] {
+	total := 0
+	for _, w := range weights {
+		total += w
+	}
+	return rapid.Custom(func(t *rapid.T) int {
+		n := rapid.IntRange(0, total-1).Draw(t, "weight")
+		for i, w := range weights {
+			if n < w {
+				return i
+			}
+			n -= w
+		}
+		return len(weights) - 1
+	})
+}
+
+`
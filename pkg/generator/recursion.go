@@ -0,0 +1,127 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// recursiveField reports whether the field f of structName refers back to structName, directly
+// (Category.Parent) or through other structs and oneofs, so calling its fixture would never return.
+// Struct values can't be nil, so cycles through them are broken at a pointer, slice or interface
+// further along, which every cycle of valid Go types has.
+func recursiveField(m *Model, structName string, f Field, cache *valueCache) bool {
+	if f.Type.Kind == "struct" && !strings.HasPrefix(f.Type.Name, "is") {
+		return false
+	}
+	key := structName + "." + f.Name
+	if cache != nil {
+		if recursive, ok := cache.recursive[key]; ok {
+			return recursive
+		}
+	}
+	to, _ := graphTarget(f.Type)
+	seen := make(map[string]bool)
+	queue := []string{to}
+	recursive := false
+	for len(queue) > 0 && !recursive {
+		name := queue[0]
+		queue = queue[1:]
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		recursive = name == structName
+		queue = append(queue, oneOfVariants(m, name)...)
+		if s, ok := m.Structs[name]; ok {
+			for _, sf := range s.Fields {
+				next, _ := graphTarget(sf.Type)
+				queue = append(queue, next)
+			}
+		}
+	}
+	if cache != nil {
+		cache.recursive[key] = recursive
+	}
+	return recursive
+}
+
+// recursiveValue renders the value of a recursive field as literals nested opts.MaxDepth levels deep,
+// ending in nil, or the empty literal of arrays, instead of calling fixtures that would call each
+// other forever
+func recursiveValue(m *Model, t TypeRef, opts GenerateOptions) string {
+	if opts.MaxDepth <= 0 {
+		if t.Kind == "array" {
+			return typeName(t, opts) + "{}"
+		}
+		return "nil"
+	}
+	opts.MaxDepth--
+	return nestedValue(m, t, opts)
+}
+
+// nestedValue renders a value of t for recursiveValue. Oneofs and structs are rendered as literals,
+// whose recursive fields take one level less.
+func nestedValue(m *Model, t TypeRef, opts GenerateOptions) string {
+	switch t.Kind {
+	case "pointer":
+		if t.Elem == nil {
+			return "nil"
+		}
+		if s, ok := m.Structs[t.Elem.Name]; ok && t.Elem.Kind == "struct" {
+			if s.Builder != "" {
+				// Build returns a pointer already
+				return structLiteral(m, s, opts)
+			}
+			return "&" + structLiteral(m, s, opts)
+		}
+		return "nil"
+	case "slice":
+		if t.Elem == nil {
+			return "nil"
+		}
+		elem := nestedValue(m, *t.Elem, opts)
+		if elem == "nil" {
+			return "nil"
+		}
+		return "[]" + typeName(*t.Elem, opts) + "{" + elem + "}"
+	case "array":
+		if t.Elem == nil {
+			return "nil"
+		}
+		if elem := nestedValue(m, *t.Elem, opts); elem != "nil" && t.Len > 0 {
+			return typeName(t, opts) + "{" + elem + "}"
+		}
+		return typeName(t, opts) + "{}"
+	case "struct", "oneof":
+		if t.Kind == "oneof" || strings.HasPrefix(t.Name, "is") {
+			if impl := m.OneOfs[t.Name]; impl != "" {
+				return oneOfLiteral(m, impl, opts, nil)
+			}
+			return "nil"
+		}
+		if s, ok := m.Structs[t.Name]; ok && s.Builder == "" {
+			return structLiteral(m, s, opts)
+		}
+	}
+	return "nil"
+}
+
+// structLiteral renders a literal of s with the fields its fixture sets, through its builder for opaque
+// API messages
+func structLiteral(m *Model, s *Struct, opts GenerateOptions) string {
+	typ := typeName(TypeRef{Kind: "struct", Name: s.Name}, opts)
+	if s.Builder != "" {
+		typ = typeName(TypeRef{Kind: "struct", Name: s.Builder}, opts)
+	}
+	var fields []string
+	for _, f := range s.Fields {
+		if foreignEmbedded(s, f, opts) {
+			continue
+		}
+		fields = append(fields, fmt.Sprintf("%s: %s", f.Name, genFieldValue(m, f, s.Name, opts, nil)))
+	}
+	if len(fields) == 0 {
+		return typ + "{}" + build(s)
+	}
+	return fmt.Sprintf("%s{\n\t\t\t%s,\n\t\t}%s", typ, strings.Join(fields, ",\n\t\t\t"), build(s))
+}
@@ -0,0 +1,46 @@
+package generator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// registryFunc returns the name of the func listing every fixture with GenerateOptions.Registry
+func registryFunc(opts GenerateOptions) string {
+	return opts.FuncPrefix + "Fixtures"
+}
+
+// registryFuncs renders a Fixtures() func returning a constructor per fixture keyed by type name, so
+// test harnesses can run the same checks over every fixture, e.g. a serialization round trip for all
+// message types. Constructors return pointers, so protobuf messages satisfy proto.Message.
+func registryFuncs(m *Model, opts GenerateOptions) string {
+	var names []string
+	names = append(names, sortedKeys(m.TypeDefs)...)
+	for _, name := range sortedKeys(m.Enums) {
+		if enumHasValue(m.Enums[name]) {
+			names = append(names, name)
+		}
+	}
+	names = append(names, fixtureStructs(m)...)
+
+	anyType := "any"
+	if noGenerics(opts) {
+		anyType = "interface{}"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "// %s returns a constructor of every fixture by type name, for tests iterating over all of them.\n", registryFunc(opts))
+	b.WriteString("// Each call builds a new value, returned as a pointer.\n")
+	fmt.Fprintf(&b, "func %s() map[string]func() %s {\n", registryFunc(opts), anyType)
+	fmt.Fprintf(&b, "\treturn map[string]func() %s{\n", anyType)
+	for _, name := range names {
+		call := "Fixture" + opts.FuncPrefix + name + "()"
+		value := "return " + call
+		if !returnsPointer(opts) {
+			value = "v := " + call + "; return &v"
+		}
+		fmt.Fprintf(&b, "\t\t%s: func() %s { %s },\n", strconv.Quote(name), anyType, value)
+	}
+	b.WriteString("\t}\n}\n\n")
+	return b.String()
+}
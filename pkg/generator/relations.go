@@ -0,0 +1,72 @@
+package generator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Relate records relations between the fields of m, mapping "Struct.Field" to the "Struct.Field" it
+// refers to (e.g. "Order.CustomerID" to "Customer.ID"). Fixtures and seeds then give a referencing
+// field the value of the field it refers to, so related fixtures match without stitching them
+// together. Both fields need the same type, and relations can't form a cycle.
+func (m *Model) Relate(relations map[string]string) error {
+	from := make([]string, 0, len(relations))
+	for f := range relations {
+		from = append(from, f)
+	}
+	sort.Strings(from)
+	for _, ref := range from {
+		to := relations[ref]
+		f, err := m.relationField(ref)
+		if err != nil {
+			return err
+		}
+		target, err := m.relationField(to)
+		if err != nil {
+			return err
+		}
+		if TypeName(f.Type) != TypeName(target.Type) {
+			return fmt.Errorf("relation %s: %s is a %s, %s a %s", ref, ref, TypeName(f.Type), to, TypeName(target.Type))
+		}
+		for seen, next := map[string]bool{ref: true}, to; next != ""; next = relations[next] {
+			if seen[next] {
+				return fmt.Errorf("relation %s: %s refers back to it", ref, to)
+			}
+			seen[next] = true
+		}
+		f.References = to
+	}
+	return nil
+}
+
+// relationField returns the field named "Struct.Field" of m
+func (m *Model) relationField(name string) (*Field, error) {
+	structName, fieldName, ok := strings.Cut(name, ".")
+	if !ok {
+		return nil, fmt.Errorf("relation %q is not of the form Struct.Field", name)
+	}
+	s, ok := m.Structs[structName]
+	if !ok {
+		return nil, fmt.Errorf("relation %s: no struct %s", name, structName)
+	}
+	for i := range s.Fields {
+		if s.Fields[i].Name == fieldName {
+			return &s.Fields[i], nil
+		}
+	}
+	return nil, fmt.Errorf("relation %s: %s has no field %s", name, structName, fieldName)
+}
+
+// referencedField returns the field f refers to and the struct holding it, if it is in m
+func referencedField(m *Model, f Field) (Field, string, bool) {
+	if f.References == "" {
+		return Field{}, "", false
+	}
+	target, err := m.relationField(f.References)
+	if err != nil {
+		return Field{}, "", false
+	}
+	structName, _, _ := strings.Cut(f.References, ".")
+	return *target, structName, true
+}
@@ -0,0 +1,63 @@
+package generator
+
+import (
+	"fmt"
+	"go/format"
+	"strings"
+)
+
+// GenerateRoundTripTests renders a test file for package pkgName marshaling the fixture of every
+// struct to JSON and back, failing when the result differs from the fixture. With encoding
+// "protojson", protobuf messages are round-tripped with protojson and compared with proto.Equal.
+func GenerateRoundTripTests(m *Model, pkgName string, opts GenerateOptions, encoding string) ([]byte, error) {
+	if opts.TypeImport == "" {
+		opts.TypeImport = modelPackage(m)
+	}
+	var b strings.Builder
+	if header := headerComment(opts); header != "" {
+		b.WriteString(header + "\n")
+	}
+	fmt.Fprintf(&b, "package %s\n\n", pkgName)
+	b.WriteString("import (\n\t\"encoding/json\"\n\t\"reflect\"\n\t\"testing\"\n")
+	if encoding == "protojson" {
+		b.WriteString("\n\t\"google.golang.org/protobuf/encoding/protojson\"\n\t\"google.golang.org/protobuf/proto\"\n")
+	}
+	// The types are named in new(T)
+	if imp := typeImport(opts); imp != "" && len(fixtureStructs(m)) > 0 {
+		b.WriteString("\n\t" + imp + "\n")
+	}
+	b.WriteString(")\n\n")
+
+	b.WriteString("func TestFixturesRoundTrip(t *testing.T) {\n")
+	for _, name := range fixtureStructs(m) {
+		typ := typeName(TypeRef{Kind: "struct", Name: name}, opts)
+		fixture := "Fixture" + opts.FuncPrefix + name + "()"
+		if returnsPointer(opts) {
+			fmt.Fprintf(&b, "\tt.Run(%q, func(t *testing.T) { roundTripFixture(t, %s, new(%s)) })\n", name, fixture, typ)
+		} else {
+			fmt.Fprintf(&b, "\tt.Run(%q, func(t *testing.T) {\n\t\twant := %s\n\t\troundTripFixture(t, &want, new(%s))\n\t})\n", name, fixture, typ)
+		}
+	}
+	b.WriteString("}\n\n")
+
+	b.WriteString("// roundTripFixture marshals want and unmarshals it into got, which must equal want afterwards\n")
+	b.WriteString("func roundTripFixture(t *testing.T, want, got interface{}) {\n")
+	b.WriteString("\tt.Helper()\n")
+	if encoding == "protojson" {
+		b.WriteString("\tif msg, ok := want.(proto.Message); ok {\n")
+		b.WriteString("\t\tdata, err := protojson.Marshal(msg)\n")
+		b.WriteString("\t\tif err != nil {\n\t\t\tt.Fatalf(\"marshal: %v\", err)\n\t\t}\n")
+		b.WriteString("\t\tif err := protojson.Unmarshal(data, got.(proto.Message)); err != nil {\n\t\t\tt.Fatalf(\"unmarshal %s: %v\", data, err)\n\t\t}\n")
+		b.WriteString("\t\tif !proto.Equal(msg, got.(proto.Message)) {\n\t\t\tt.Errorf(\"round trip through %s changed the fixture\", data)\n\t\t}\n")
+		b.WriteString("\t\treturn\n\t}\n")
+	}
+	b.WriteString("\tdata, err := json.Marshal(want)\n")
+	b.WriteString("\tif err != nil {\n\t\tt.Fatalf(\"marshal: %v\", err)\n\t}\n")
+	b.WriteString("\tif err := json.Unmarshal(data, got); err != nil {\n\t\tt.Fatalf(\"unmarshal %s: %v\", data, err)\n\t}\n")
+	b.WriteString("\tif !reflect.DeepEqual(want, got) {\n")
+	b.WriteString("\t\tagain, _ := json.Marshal(got)\n")
+	b.WriteString("\t\tt.Errorf(\"round trip changed the fixture:\\nmarshaled: %s\\nunmarshaled: %s\", data, again)\n")
+	b.WriteString("\t}\n}\n")
+
+	return format.Source([]byte(b.String()))
+}
@@ -0,0 +1,173 @@
+package generator
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// seedFunc renders a SeedX helper that inserts the fixture of s into a database with a
+// parameterized INSERT and returns it. It returns "" for structs without column mappings.
+func seedFunc(s *Struct, opts GenerateOptions) string {
+	var columns, args []string
+	for _, f := range columnFields(s, opts) {
+		columns = append(columns, f.Column)
+		args = append(args, "value."+f.Name)
+	}
+	if len(columns) == 0 {
+		return ""
+	}
+
+	placeholders := make([]string, len(columns))
+	for i := range placeholders {
+		if opts.SeedPlaceholder == "?" {
+			placeholders[i] = "?"
+		} else {
+			placeholders[i] = "$" + strconv.Itoa(i+1)
+		}
+	}
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", tableName(s), strings.Join(columns, ", "), strings.Join(placeholders, ", "))
+
+	typ := typeName(TypeRef{Kind: "struct", Name: s.Name}, opts)
+	// The parameter must not shadow the package the type is qualified with
+	db := "db"
+	if opts.TypePrefix == db {
+		db = "conn"
+	}
+	var b strings.Builder
+	if opts.ModStyle {
+		fmt.Fprintf(&b, "func Seed%s%s(ctx context.Context, %s *sql.DB, mods ...%s) (%s, error) {\n", opts.FuncPrefix, s.Name, db, modFunc(typ, opts), fixtureResult(typ, opts))
+		fmt.Fprintf(&b, "\tvalue := Fixture%s%s(mods...)\n", opts.FuncPrefix, s.Name)
+	} else {
+		fmt.Fprintf(&b, "func Seed%s%s(ctx context.Context, %s *sql.DB) (%s, error) {\n", opts.FuncPrefix, s.Name, db, typ)
+		fmt.Fprintf(&b, "\tvalue := Fixture%s%s()\n", opts.FuncPrefix, s.Name)
+	}
+	fmt.Fprintf(&b, "\tif _, err := %s.ExecContext(ctx, %s, %s); err != nil {\n", db, strconv.Quote(query), strings.Join(args, ", "))
+	if returnsPointer(opts) {
+		fmt.Fprintf(&b, "\t\treturn nil, err\n")
+	} else {
+		fmt.Fprintf(&b, "\t\treturn value, err\n")
+	}
+	fmt.Fprintf(&b, "\t}\n")
+	fmt.Fprintf(&b, "\treturn value, nil\n")
+	fmt.Fprintf(&b, "}\n\n")
+	return b.String()
+}
+
+// hasSeedFuncs reports whether any struct of m gets a SeedX helper
+func hasSeedFuncs(m *Model, opts GenerateOptions) bool {
+	if !opts.Seed {
+		return false
+	}
+	for _, s := range m.Structs {
+		if len(s.TypeParams) == 0 && len(columnFields(s, opts)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// columnFields returns the fields of s stored in a column, followed by the columns promoted from
+// embedded structs the way sqlx and GORM flatten them. A promoted column is only taken if no
+// field before it maps to the same column, and not through an embedded pointer the fixture leaves nil.
+func columnFields(s *Struct, opts GenerateOptions) []Field {
+	var result []Field
+	columns := make(map[string]bool)
+	for _, f := range s.Fields {
+		if f.Column != "" && seedable(f.Type) {
+			result = append(result, f)
+			columns[f.Column] = true
+		}
+	}
+	// Embedded pointers left nil by sparse fixtures or for lack of a fixture
+	unset := make(map[string]bool)
+	for _, f := range s.Fields {
+		unset[f.Name] = f.Embedded && (sparseField(f, opts) || f.Type.Kind == "pointer" && foreignEmbedded(s, f, opts))
+	}
+	for _, f := range s.Promoted {
+		embedded, _, _ := strings.Cut(f.Name, ".")
+		if f.Column == "" || !seedable(f.Type) || columns[f.Column] || unset[embedded] {
+			continue
+		}
+		result = append(result, f)
+		columns[f.Column] = true
+	}
+	return result
+}
+
+// seedable reports whether a field of type t can be passed as a single SQL parameter.
+// Nested structs and collections are relations, not columns.
+func seedable(t TypeRef) bool {
+	if t.Kind == "pointer" && t.Elem != nil {
+		t = *t.Elem
+	}
+	switch t.Kind {
+	case "primitive", "enum", "typedef", "external":
+		return true
+	case "slice":
+		return t.Elem != nil && t.Elem.Kind == "primitive" && (t.Elem.Name == "byte" || t.Elem.Name == "uint8")
+	}
+	return false
+}
+
+// tableName returns the table of s, defaulting to the snake_case plural of its name like GORM does
+func tableName(s *Struct) string {
+	if s.Table != "" {
+		return s.Table
+	}
+	return plural(snakeCase(s.Name))
+}
+
+// snakeCase turns a Go name like "UserID" or "HTTPRequest" into "user_id" or "http_request"
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) &&
+			(!unicode.IsUpper(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// plural is the inverse of singular for the common English suffixes
+func plural(name string) string {
+	switch {
+	case strings.HasSuffix(name, "y") && len(name) > 1 && !strings.ContainsAny(name[len(name)-2:len(name)-1], "aeiou"):
+		return name[:len(name)-1] + "ies"
+	case strings.HasSuffix(name, "s"), strings.HasSuffix(name, "x"), strings.HasSuffix(name, "ch"), strings.HasSuffix(name, "sh"):
+		return name + "es"
+	}
+	return name + "s"
+}
+
+// ColumnFromTag returns the database column of a struct field from its `db:"..."` (sqlx) or
+// `gorm:"column:..."` tag. GORM fields without an explicit column use the snake_case field name.
+// It returns "" for untagged and ignored ("-") fields.
+func ColumnFromTag(tag, fieldName string) string {
+	st := reflect.StructTag(tag)
+	if db, ok := st.Lookup("db"); ok {
+		name, _, _ := strings.Cut(db, ",")
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	gorm, ok := st.Lookup("gorm")
+	if !ok || gorm == "-" || strings.HasPrefix(gorm, "-:") {
+		return ""
+	}
+	for _, setting := range strings.Split(gorm, ";") {
+		key, value, _ := strings.Cut(setting, ":")
+		if strings.EqualFold(strings.TrimSpace(key), "column") && value != "" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return snakeCase(fieldName)
+}
@@ -0,0 +1,130 @@
+package generator
+
+import (
+	"fmt"
+	"go/ast"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// fieldSliceLen returns the number of elements a struct field gets from its fixture:"len=N" tag, 0
+// without one
+func fieldSliceLen(field *ast.Field) int {
+	if field.Tag == nil {
+		return 0
+	}
+	tag, err := strconv.Unquote(field.Tag.Value)
+	if err != nil {
+		return 0
+	}
+	for _, opt := range strings.Split(reflect.StructTag(tag).Get("fixture"), ",") {
+		if v, ok := strings.CutPrefix(strings.TrimSpace(opt), "len="); ok {
+			if n, err := strconv.Atoi(v); err == nil && n > 0 {
+				return n
+			}
+		}
+	}
+	return 0
+}
+
+// fieldLen returns the number of elements of the slice field f of structName set for the field
+// alone, by SliceLens or else the tag of the field, and whether one is
+func fieldLen(f Field, structName string, opts GenerateOptions) (int, bool) {
+	if n, ok := opts.SliceLens[structName+"."+f.Name]; ok {
+		return n, true
+	}
+	return f.SliceLen, f.SliceLen > 0
+}
+
+// sliceValue renders a literal of the slice type t holding n elements. The elements differ from each
+// other where the values are static: strings and numbers count up, "Tags1", "Tags2" and 1, 2, bools
+// alternate, and the fixtures of structs get a mod suffixing their key field the same way.
+func sliceValue(m *Model, t TypeRef, fieldName, structName string, n int, opts GenerateOptions, cache *valueCache) string {
+	values := make([]string, n)
+	indexed := false
+	for i := range values {
+		if lit, ok := sliceElem(*t.Elem, fieldName, structName, i, n, opts); ok {
+			values[i] = lit
+			continue
+		}
+		value := genValue(m, *t.Elem, fieldName, structName, opts, cache)
+		values[i] = value
+		if n > 1 && opts.ValueStrategy != "random" {
+			values[i] = indexedFixture(m, value, *t.Elem, i, opts)
+			indexed = indexed || values[i] != value
+		}
+	}
+	// Fixtures with mods go on a line each
+	if indexed {
+		return "[]" + typeName(*t.Elem, opts) + "{\n" + strings.Join(values, ",\n") + ",\n}"
+	}
+	return "[]" + typeName(*t.Elem, opts) + "{" + strings.Join(values, ", ") + "}"
+}
+
+// sliceElem returns the literal of element i of a slice of elem holding n elements where it differs
+// from the other elements: for strings, numbers and bools with static values
+func sliceElem(elem TypeRef, fieldName, structName string, i, n int, opts GenerateOptions) (string, bool) {
+	if n == 1 || elem.Kind != "primitive" || opts.ValueStrategy == "random" {
+		return "", false
+	}
+	switch elem.Name {
+	case "string":
+		if opts.ValueStrategy == "faker" {
+			return "", false
+		}
+		return genPrimitiveValue("string", fieldName+strconv.Itoa(i+1), structName), true
+	case "bool":
+		return strconv.FormatBool(i%2 == 0), true
+	}
+	return strconv.Itoa(i + 1), true
+}
+
+// indexedStruct returns the struct of the slice element type elem and its key field, which elements
+// of a slice get suffixed with their index: the ID field, or else the first field, if it is an
+// exported string. Fields referencing another struct, overridden fields and faker values are left
+// as they are, so the elements keep agreeing with -relation, -override and the faker.
+func indexedStruct(m *Model, elem TypeRef, opts GenerateOptions) (*Struct, *Field) {
+	if elem.Kind == "pointer" && elem.Elem != nil {
+		elem = *elem.Elem
+	}
+	s, ok := m.Structs[elem.Name]
+	if elem.Kind != "struct" || !ok || len(s.Fields) == 0 || opts.ValueStrategy == "faker" {
+		return s, nil
+	}
+	key := &s.Fields[0]
+	for i, f := range s.Fields {
+		if f.Name == "ID" || f.Name == "Id" {
+			key = &s.Fields[i]
+			break
+		}
+	}
+	if key.Type.Kind != "primitive" || key.Type.Name != "string" || !ast.IsExported(key.Name) || key.References != "" {
+		return s, nil
+	}
+	if _, ok := opts.FieldOverrides[s.Name+"."+key.Name]; ok {
+		return s, nil
+	}
+	return s, key
+}
+
+// indexedFixture adds a mod to the fixture call value of element i of a slice of elem, suffixing
+// the key field of the struct with i+1. Values other than calls of ModStyle fixtures taking
+// mods are returned as they are.
+func indexedFixture(m *Model, value string, elem TypeRef, i int, opts GenerateOptions) string {
+	s, key := indexedStruct(m, elem, opts)
+	if key == nil || !opts.ModStyle || s.Builder != "" || foreignType(TypeRef{Kind: "struct", Name: s.Name}, opts) {
+		return value
+	}
+	call := "Fixture" + opts.FuncPrefix + s.Name + "()"
+	if !strings.HasSuffix(value, call) {
+		return value
+	}
+	typ := typeName(TypeRef{Kind: "struct", Name: s.Name}, opts)
+	stmt := fmt.Sprintf("v.%s += %q", key.Name, strconv.Itoa(i+1))
+	mod := "func(v *" + typ + ") { " + stmt + " }"
+	if opts.ModSignature == "value" {
+		mod = "func(v " + typ + ") " + typ + " { " + stmt + "; return v }"
+	}
+	return strings.TrimSuffix(value, ")") + mod + ")"
+}
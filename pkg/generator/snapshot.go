@@ -0,0 +1,118 @@
+package generator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// TypeHash returns a short hash of the definition of the named type in m. It changes whenever the
+// fields of a struct, the values of an enum or the underlying type of a typedef change.
+func TypeHash(m *Model, name string) string {
+	var def any
+	// Doc comments don't change the schema
+	switch {
+	case m.Structs[name] != nil:
+		s := *m.Structs[name]
+		s.Doc = ""
+		def = s
+	case m.Enums[name] != nil:
+		e := *m.Enums[name]
+		e.Doc = ""
+		def = e
+	case m.TypeDefs[name] != nil:
+		td := *m.TypeDefs[name]
+		td.Doc = ""
+		def = td
+	default:
+		return ""
+	}
+	data, _ := json.Marshal(def)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// snapshotFuncs renders a WriteSnapshots function serializing every fixture into a directory
+// together with a manifest, so test suites in other languages can assert against the same data.
+// With opts.Snapshots == "protojson", protobuf messages are written with protojson.
+func snapshotFuncs(m *Model, opts GenerateOptions) string {
+	var names []string
+	names = append(names, sortedKeys(m.TypeDefs)...)
+	names = append(names, sortedKeys(m.Enums)...)
+	names = append(names, fixtureStructs(m)...)
+
+	var b strings.Builder
+	b.WriteString("// fixtureSnapshots lists every fixture with the schema hash of its type\n")
+	b.WriteString("var fixtureSnapshots = []struct {\n")
+	b.WriteString("\tType       string\n")
+	b.WriteString("\tSchemaHash string\n")
+	b.WriteString("\tValue      func() any\n")
+	b.WriteString("}{\n")
+	for _, name := range names {
+		call := "Fixture" + opts.FuncPrefix + name + "()"
+		value := "return " + call
+		if !returnsPointer(opts) {
+			// Pointers, so protobuf messages satisfy proto.Message
+			value = "v := " + call + "; return &v"
+		}
+		fmt.Fprintf(&b, "\t{%s, %s, func() any { %s }},\n", strconv.Quote(name), strconv.Quote(TypeHash(m, name)), value)
+	}
+	b.WriteString("}\n\n")
+
+	b.WriteString("// WriteSnapshots serializes every fixture to <dir>/<Type>.json and describes them in\n")
+	b.WriteString("// <dir>/manifest.json (type, schema hash, file, format)\n")
+	b.WriteString("func WriteSnapshots(dir string) error {\n")
+	b.WriteString("\tif err := os.MkdirAll(dir, 0755); err != nil {\n")
+	b.WriteString("\t\treturn err\n")
+	b.WriteString("\t}\n")
+	b.WriteString("\ttype entry struct {\n")
+	b.WriteString("\t\tType       string `json:\"type\"`\n")
+	b.WriteString("\t\tSchemaHash string `json:\"schemaHash\"`\n")
+	b.WriteString("\t\tFile       string `json:\"file\"`\n")
+	b.WriteString("\t\tFormat     string `json:\"format\"`\n")
+	b.WriteString("\t}\n")
+	b.WriteString("\tvar manifest []entry\n")
+	b.WriteString("\tfor _, s := range fixtureSnapshots {\n")
+	b.WriteString("\t\tvalue := s.Value()\n")
+	b.WriteString("\t\tformat := \"json\"\n")
+	if opts.Snapshots == "protojson" {
+		b.WriteString("\t\tvar data []byte\n")
+		b.WriteString("\t\tvar err error\n")
+		b.WriteString("\t\tif msg, ok := value.(proto.Message); ok {\n")
+		b.WriteString("\t\t\tformat = \"protojson\"\n")
+		b.WriteString("\t\t\tdata, err = protojson.MarshalOptions{Multiline: true, Indent: \"  \"}.Marshal(msg)\n")
+		b.WriteString("\t\t} else {\n")
+		b.WriteString("\t\t\tdata, err = json.MarshalIndent(value, \"\", \"  \")\n")
+		b.WriteString("\t\t}\n")
+	} else {
+		b.WriteString("\t\tdata, err := json.MarshalIndent(value, \"\", \"  \")\n")
+	}
+	b.WriteString("\t\tif err != nil {\n")
+	b.WriteString("\t\t\treturn fmt.Errorf(\"%s: %w\", s.Type, err)\n")
+	b.WriteString("\t\t}\n")
+	b.WriteString("\t\tfile := s.Type + \".json\"\n")
+	b.WriteString("\t\tif err := os.WriteFile(filepath.Join(dir, file), append(data, '\\n'), 0644); err != nil {\n")
+	b.WriteString("\t\t\treturn err\n")
+	b.WriteString("\t\t}\n")
+	b.WriteString("\t\tmanifest = append(manifest, entry{Type: s.Type, SchemaHash: s.SchemaHash, File: file, Format: format})\n")
+	b.WriteString("\t}\n")
+	b.WriteString("\tdata, err := json.MarshalIndent(manifest, \"\", \"  \")\n")
+	b.WriteString("\tif err != nil {\n")
+	b.WriteString("\t\treturn err\n")
+	b.WriteString("\t}\n")
+	b.WriteString("\treturn os.WriteFile(filepath.Join(dir, \"manifest.json\"), append(data, '\\n'), 0644)\n")
+	b.WriteString("}\n\n")
+	return b.String()
+}
+
+// snapshotImports returns the imports used by snapshotFuncs
+func snapshotImports(opts GenerateOptions) []string {
+	imports := []string{`"encoding/json"`, `"fmt"`, `"os"`, `"path/filepath"`}
+	if opts.Snapshots == "protojson" {
+		imports = append(imports, `"google.golang.org/protobuf/encoding/protojson"`, `"google.golang.org/protobuf/proto"`)
+	}
+	return imports
+}
@@ -0,0 +1,73 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// sparseField reports whether f is left nil by the fixtures of sparse mode
+func sparseField(f Field, opts GenerateOptions) bool {
+	return opts.Sparse && opts.ModStyle && f.Type.Kind == "pointer"
+}
+
+// withHelpers renders a WithX mod per pointer field of s, populating the field sparse fixtures leave nil.
+// Fields pointing to a type with a fixture take mods for that fixture.
+func withHelpers(m *Model, s *Struct, opts GenerateOptions, cache *valueCache) string {
+	// Fields of opaque API messages are hidden, their builder sets the pointer fields instead
+	if s.Builder != "" {
+		return ""
+	}
+	typ := s.Name
+	if opts.TypePrefix != "" {
+		typ = opts.TypePrefix + "." + s.Name
+	}
+
+	var b strings.Builder
+	for _, f := range s.Fields {
+		if !sparseField(f, opts) {
+			continue
+		}
+		name := "With" + opts.FuncPrefix + s.Name + f.Name
+		params, value := "", genFieldValue(m, f, s.Name, opts, cache)
+		if call, ok := nestedFixture(m, f, opts); ok {
+			params = "mods ..." + modFunc(typeName(*f.Type.Elem, opts), opts)
+			value = call
+			fmt.Fprintf(&b, "// %s populates %s with its fixture, modified by mods.\n", name, f.Name)
+		} else {
+			fmt.Fprintf(&b, "// %s populates %s with its fixture value.\n", name, f.Name)
+		}
+
+		if opts.ModSignature == "value" {
+			fmt.Fprintf(&b, "func %s(%s) func(%s) %s {\n", name, params, typ, typ)
+			fmt.Fprintf(&b, "\treturn func(value %s) %s {\n", typ, typ)
+			fmt.Fprintf(&b, "\t\tvalue.%s = %s\n", f.Name, value)
+			fmt.Fprintf(&b, "\t\treturn value\n")
+		} else {
+			fmt.Fprintf(&b, "func %s(%s) func(*%s) {\n", name, params, typ)
+			fmt.Fprintf(&b, "\treturn func(value *%s) {\n", typ)
+			fmt.Fprintf(&b, "\t\tvalue.%s = %s\n", f.Name, value)
+		}
+		fmt.Fprintf(&b, "\t}\n}\n\n")
+	}
+	return b.String()
+}
+
+// nestedFixture returns the call of the fixture of the type a pointer field points to, passing on mods
+func nestedFixture(m *Model, f Field, opts GenerateOptions) (string, bool) {
+	elem := f.Type.Elem
+	if f.Default != "" || elem == nil || len(elem.TypeArgs) > 0 || elem.Kind != "struct" && elem.Kind != "enum" && elem.Kind != "typedef" {
+		return "", false
+	}
+	fn := "Fixture" + opts.FuncPrefix + elem.Name + "(mods...)"
+	if _, ok := fixtureCall(*elem, opts); ok {
+		fn = fixtureAlias(elem.Pkg) + "." + fn
+	} else if e, ok := m.Enums[elem.Name]; ok && !enumHasValue(e) {
+		return "", false
+	} else if strings.HasPrefix(elem.Name, "is") || !HasFixture(m, elem.Name) {
+		return "", false
+	}
+	if !returnsPointer(opts) {
+		fn = ptrFunc(*elem, opts) + "(" + fn + ")"
+	}
+	return fn, true
+}
@@ -0,0 +1,391 @@
+package generator
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// sqlToken is a token of a DDL script. Quoted identifiers are unquoted and marked as idents,
+// string literals keep their content without quotes.
+type sqlToken struct {
+	Text   string
+	String bool // single-quoted string literal
+}
+
+// tokenizeSQL splits a DDL script into tokens, dropping comments
+func tokenizeSQL(src string) []sqlToken {
+	var tokens []sqlToken
+	r := []rune(src)
+	for i := 0; i < len(r); {
+		c := r[i]
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '-' && i+1 < len(r) && r[i+1] == '-':
+			for i < len(r) && r[i] != '\n' {
+				i++
+			}
+		case c == '#':
+			for i < len(r) && r[i] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < len(r) && r[i+1] == '*':
+			i += 2
+			for i+1 < len(r) && !(r[i] == '*' && r[i+1] == '/') {
+				i++
+			}
+			i += 2
+		case c == '\'' || c == '"' || c == '`':
+			var b strings.Builder
+			i++
+			for i < len(r) {
+				if r[i] == c {
+					// A doubled quote is an escaped quote
+					if i+1 < len(r) && r[i+1] == c {
+						b.WriteRune(c)
+						i += 2
+						continue
+					}
+					break
+				}
+				b.WriteRune(r[i])
+				i++
+			}
+			i++
+			tokens = append(tokens, sqlToken{Text: b.String(), String: c == '\''})
+		case unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_' || c == '.':
+			start := i
+			for i < len(r) && (unicode.IsLetter(r[i]) || unicode.IsDigit(r[i]) || r[i] == '_' || r[i] == '.' || r[i] == '$') {
+				i++
+			}
+			tokens = append(tokens, sqlToken{Text: string(r[start:i])})
+		default:
+			tokens = append(tokens, sqlToken{Text: string(c)})
+			i++
+		}
+	}
+	return tokens
+}
+
+// splitSQL groups tokens by top-level separator sep, e.g. statements by ";" or definitions by ","
+func splitSQL(tokens []sqlToken, sep string) [][]sqlToken {
+	var parts [][]sqlToken
+	var cur []sqlToken
+	depth := 0
+	for _, t := range tokens {
+		if !t.String {
+			switch t.Text {
+			case "(":
+				depth++
+			case ")":
+				depth--
+			}
+			if depth == 0 && t.Text == sep {
+				if len(cur) > 0 {
+					parts = append(parts, cur)
+				}
+				cur = nil
+				continue
+			}
+		}
+		cur = append(cur, t)
+	}
+	if len(cur) > 0 {
+		parts = append(parts, cur)
+	}
+	return parts
+}
+
+// isKeyword reports whether t is the unquoted keyword kw
+func isKeyword(t sqlToken, kw string) bool {
+	return !t.String && strings.EqualFold(t.Text, kw)
+}
+
+// hasKeywords reports whether tokens start with the given keywords
+func hasKeywords(tokens []sqlToken, kws ...string) bool {
+	if len(tokens) < len(kws) {
+		return false
+	}
+	for i, kw := range kws {
+		if !isKeyword(tokens[i], kw) {
+			return false
+		}
+	}
+	return true
+}
+
+// parenGroup returns the tokens inside the parenthesis opening at tokens[start], and the index after it
+func parenGroup(tokens []sqlToken, start int) ([]sqlToken, int) {
+	depth := 0
+	for i := start; i < len(tokens); i++ {
+		if tokens[i].String {
+			continue
+		}
+		switch tokens[i].Text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+			if depth == 0 {
+				return tokens[start+1 : i], i + 1
+			}
+		}
+	}
+	return tokens[start+1:], len(tokens)
+}
+
+// unqualified strips a schema qualifier like "public.users"
+func unqualified(name string) string {
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+// ParseDDL reads CREATE TABLE and CREATE TYPE ... AS ENUM statements (Postgres or MySQL dialect)
+// into a Model: tables become structs, columns become fields (nullable columns are pointers)
+// and enum types become enums
+func ParseDDL(src string) (*Model, error) {
+	m := NewModel()
+	statements := splitSQL(tokenizeSQL(src), ";")
+
+	// Enum types first, so columns can refer to types declared later in the script
+	for _, stmt := range statements {
+		if hasKeywords(stmt, "CREATE", "TYPE") && len(stmt) > 5 && isKeyword(stmt[3], "AS") && isKeyword(stmt[4], "ENUM") {
+			name := goName(unqualified(stmt[2].Text))
+			labels, _ := parenGroup(stmt, 5)
+			m.Enums[name] = sqlEnum(name, labels)
+		}
+	}
+
+	for _, stmt := range statements {
+		if !hasKeywords(stmt, "CREATE", "TABLE") && !hasKeywords(stmt, "CREATE", "TEMPORARY", "TABLE") {
+			continue
+		}
+		i := 2
+		if isKeyword(stmt[1], "TEMPORARY") {
+			i = 3
+		}
+		if hasKeywords(stmt[i:], "IF", "NOT", "EXISTS") {
+			i += 3
+		}
+		if i+1 >= len(stmt) || stmt[i+1].Text != "(" {
+			return nil, fmt.Errorf("sql parse error: unsupported CREATE TABLE statement near %q", stmt[min(i, len(stmt)-1)].Text)
+		}
+		// The struct is named after the table, the seeds keep its schema
+		table := stmt[i].Text
+		defs, _ := parenGroup(stmt, i+1)
+		s, err := sqlTable(m, table, defs)
+		if err != nil {
+			return nil, err
+		}
+		m.Structs[s.Name] = s
+	}
+	return m, nil
+}
+
+func sqlEnum(name string, labels []sqlToken) *Enum {
+	e := &Enum{Name: name}
+	for _, l := range labels {
+		if l.String {
+			e.Values = append(e.Values, name+goName(l.Text))
+			e.Labels = append(e.Labels, l.Text)
+		}
+	}
+	return e
+}
+
+// sqlConstraintKeywords end the type of a column definition
+var sqlConstraintKeywords = map[string]bool{
+	"NOT": true, "NULL": true, "PRIMARY": true, "DEFAULT": true, "UNIQUE": true, "REFERENCES": true,
+	"CHECK": true, "AUTO_INCREMENT": true, "COLLATE": true, "GENERATED": true, "CONSTRAINT": true,
+	"COMMENT": true, "ON": true, "CHARACTER": true, "AUTOINCREMENT": true,
+}
+
+func sqlTable(m *Model, table string, defs []sqlToken) (*Struct, error) {
+	s := &Struct{Name: singular(goName(unqualified(table))), Table: table}
+	notNull := make(map[string]bool)
+
+	for _, def := range splitSQL(defs, ",") {
+		first := strings.ToUpper(def[0].Text)
+		if !def[0].String && (first == "CONSTRAINT" || first == "PRIMARY" || first == "UNIQUE" || first == "FOREIGN" ||
+			first == "KEY" || first == "INDEX" || first == "CHECK" || first == "EXCLUDE" || first == "FULLTEXT") {
+			// Table constraint: only a primary key changes how columns are generated
+			for j, t := range def {
+				if isKeyword(t, "PRIMARY") && j+2 < len(def) && isKeyword(def[j+1], "KEY") {
+					cols, _ := parenGroup(def, j+2)
+					for _, c := range cols {
+						if c.Text != "," {
+							notNull[c.Text] = true
+						}
+					}
+				}
+			}
+			continue
+		}
+		if len(def) < 2 {
+			return nil, fmt.Errorf("sql parse error: column %q in table %s has no type", def[0].Text, table)
+		}
+
+		column := def[0].Text
+		// The type runs until the first constraint keyword, and may have arguments like varchar(255)
+		var typeWords []string
+		j := 1
+		var enumLabels []sqlToken
+		for j < len(def) && !(sqlConstraintKeywords[strings.ToUpper(def[j].Text)] && !def[j].String && j > 1) {
+			text := def[j].Text
+			next := j + 1
+			if text == "(" {
+				var args []sqlToken
+				args, next = parenGroup(def, j)
+				if len(typeWords) > 0 && strings.EqualFold(typeWords[len(typeWords)-1], "ENUM") {
+					enumLabels = args
+				}
+				var argText []string
+				for _, a := range args {
+					argText = append(argText, a.Text)
+				}
+				text = "(" + strings.Join(argText, "") + ")"
+			}
+			// Arguments and array brackets belong to the preceding word, as in varchar(255) or text[]
+			if len(typeWords) > 0 && (text[0] == '(' || text == "[" || text == "]") {
+				typeWords[len(typeWords)-1] += text
+			} else {
+				typeWords = append(typeWords, text)
+			}
+			j = next
+		}
+
+		nullable := true
+		for k := j; k < len(def); k++ {
+			if isKeyword(def[k], "PRIMARY") || (isKeyword(def[k], "NOT") && k+1 < len(def) && isKeyword(def[k+1], "NULL")) {
+				nullable = false
+			}
+		}
+
+		fieldName := goName(column)
+		sqlType := strings.Join(typeWords, " ")
+		ref := sqlTypeRef(m, sqlType, s.Name+fieldName, enumLabels)
+		s.Fields = append(s.Fields, Field{Name: fieldName, Type: ref, Column: column, Default: sqlStringDefault(sqlType, s.Name, fieldName)})
+		if !nullable {
+			notNull[column] = true
+		}
+	}
+
+	// Nullable columns become pointers, except slices which are already nilable
+	for i := range s.Fields {
+		f := &s.Fields[i]
+		if !notNull[f.Column] && f.Type.Kind != "slice" && f.Type.Kind != "unknown" {
+			elem := f.Type
+			f.Type = TypeRef{Kind: "pointer", Elem: &elem}
+		}
+	}
+	return s, nil
+}
+
+// sqlStringDefault returns the Go literal of a value valid for the column types mapped to strings
+// whose values have a format, like uuid or inet, or "" for other types. UUIDs are hashed from the
+// struct and field name, so they stay the same for every run and differ between columns.
+func sqlStringDefault(sqlType, structName, fieldName string) string {
+	base := strings.ToLower(sqlType)
+	if i := strings.IndexAny(base, "( "); i >= 0 {
+		base = base[:i]
+	}
+	switch base {
+	case "uuid":
+		sum := sha256.Sum256([]byte(structName + "." + fieldName))
+		// A version 4, variant 1 UUID
+		sum[6] = sum[6]&0x0f | 0x40
+		sum[8] = sum[8]&0x3f | 0x80
+		return fmt.Sprintf(`"%x-%x-%x-%x-%x"`, sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+	case "json", "jsonb":
+		return `"{}"`
+	case "inet":
+		return `"192.0.2.1"`
+	case "cidr":
+		return `"192.0.2.0/24"`
+	case "macaddr":
+		return `"00:00:5e:00:53:01"`
+	case "xml":
+		return `"<` + fieldName + `/>"`
+	}
+	return ""
+}
+
+// sqlTypeRef maps a column type to a TypeRef. enumName is used for inline MySQL enums.
+func sqlTypeRef(m *Model, sqlType, enumName string, enumLabels []sqlToken) TypeRef {
+	t := strings.ToLower(sqlType)
+	if strings.HasSuffix(t, "[]") {
+		elem := sqlTypeRef(m, strings.TrimSuffix(t, "[]"), enumName, nil)
+		return TypeRef{Kind: "slice", Elem: &elem, Name: elem.Name}
+	}
+	words := strings.Fields(t)
+	if len(words) == 0 {
+		return TypeRef{Kind: "unknown"}
+	}
+	base := words[0]
+	if i := strings.Index(base, "("); i >= 0 {
+		base = base[:i]
+	}
+	unsigned := strings.Contains(t, "unsigned")
+
+	switch base {
+	case "enum":
+		m.Enums[enumName] = sqlEnum(enumName, enumLabels)
+		return TypeRef{Kind: "enum", Name: enumName}
+	case "varchar", "char", "character", "text", "tinytext", "mediumtext", "longtext", "uuid", "citext",
+		"json", "jsonb", "nvarchar", "nchar", "inet", "cidr", "macaddr", "xml", "set":
+		return TypeRef{Kind: "primitive", Name: "string"}
+	case "boolean", "bool", "bit":
+		return TypeRef{Kind: "primitive", Name: "bool"}
+	case "tinyint":
+		// MySQL's conventional boolean
+		if strings.HasPrefix(t, "tinyint(1)") {
+			return TypeRef{Kind: "primitive", Name: "bool"}
+		}
+		return intTypeRef("int8", unsigned)
+	case "smallint", "int2", "smallserial":
+		return intTypeRef("int16", unsigned)
+	case "int", "integer", "int4", "serial", "mediumint":
+		return intTypeRef("int32", unsigned)
+	case "bigint", "int8", "bigserial":
+		return intTypeRef("int64", unsigned)
+	case "real", "float4":
+		return TypeRef{Kind: "primitive", Name: "float32"}
+	case "double", "float8", "float", "numeric", "decimal", "money":
+		return TypeRef{Kind: "primitive", Name: "float64"}
+	case "timestamp", "timestamptz", "datetime", "date", "time", "timetz":
+		return TypeRef{Kind: "external", Name: "Time"}
+	case "bytea", "blob", "tinyblob", "mediumblob", "longblob", "binary", "varbinary":
+		elem := TypeRef{Kind: "primitive", Name: "byte"}
+		return TypeRef{Kind: "slice", Elem: &elem, Name: "byte"}
+	}
+
+	// A type declared with CREATE TYPE ... AS ENUM (the type name was lowercased above)
+	want := goName(unqualified(base))
+	for name := range m.Enums {
+		if strings.EqualFold(name, want) {
+			return TypeRef{Kind: "enum", Name: name}
+		}
+	}
+	return TypeRef{Kind: "unknown"}
+}
+
+func intTypeRef(name string, unsigned bool) TypeRef {
+	if unsigned {
+		name = "u" + name
+	}
+	return TypeRef{Kind: "primitive", Name: name}
+}
+
+// singular turns a plural table name like "Users" or "Categories" into a struct name
+func singular(name string) string {
+	switch {
+	case strings.HasSuffix(name, "ies") && len(name) > 3:
+		return name[:len(name)-3] + "y"
+	case strings.HasSuffix(name, "sses"), strings.HasSuffix(name, "xes"), strings.HasSuffix(name, "ches"), strings.HasSuffix(name, "shes"):
+		return name[:len(name)-2]
+	case strings.HasSuffix(name, "s") && !strings.HasSuffix(name, "ss") && len(name) > 1:
+		return name[:len(name)-1]
+	}
+	return name
+}
@@ -0,0 +1,104 @@
+package generator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SQLSeed holds the seed statements of one table
+type SQLSeed struct {
+	Table string
+	// Insert adds the fixture row
+	Insert string
+	// Delete removes it again, matching on the first column
+	Delete string
+}
+
+// SQLSeeds returns the seed statements of every struct mapped to database columns, ordered by
+// struct name, with the same default values the generated fixtures use
+func SQLSeeds(m *Model) []SQLSeed {
+	var seeds []SQLSeed
+	for _, name := range fixtureStructs(m) {
+		s := m.Structs[name]
+		var columns, values []string
+		for _, f := range s.Fields {
+			if f.Column == "" {
+				continue
+			}
+			columns = append(columns, f.Column)
+			values = append(values, sqlValue(m, f, s.Name))
+		}
+		if len(columns) == 0 {
+			continue
+		}
+		table := tableName(s)
+		seeds = append(seeds, SQLSeed{
+			Table:  table,
+			Insert: fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s);", table, strings.Join(columns, ", "), strings.Join(values, ", ")),
+			Delete: fmt.Sprintf("DELETE FROM %s WHERE %s = %s;", table, columns[0], values[0]),
+		})
+	}
+	return seeds
+}
+
+// GenerateSQLSeeds renders an INSERT statement per table-backed struct with the same default values
+// the generated fixtures use, so repository tests can load matching rows
+func GenerateSQLSeeds(m *Model) string {
+	var b strings.Builder
+	for _, seed := range SQLSeeds(m) {
+		b.WriteString(seed.Insert + "\n")
+	}
+	return b.String()
+}
+
+// sqlValue renders the default value of a field as a SQL literal
+func sqlValue(m *Model, f Field, structName string) string {
+	if target, targetStruct, ok := referencedField(m, f); ok {
+		return sqlValue(m, target, targetStruct)
+	}
+	if f.Default != "" {
+		if s, err := strconv.Unquote(f.Default); err == nil {
+			return sqlString(s)
+		}
+		if _, err := strconv.ParseFloat(f.Default, 64); err == nil {
+			return f.Default
+		}
+	}
+
+	t := f.Type
+	if t.Kind == "pointer" && t.Elem != nil {
+		t = *t.Elem
+	}
+	if td, ok := m.TypeDefs[t.Name]; ok && t.Kind != "primitive" {
+		t = td.Underlying
+	}
+	switch t.Kind {
+	case "primitive":
+		switch t.Name {
+		case "string":
+			s, _ := strconv.Unquote(genPrimitiveValue(t.Name, f.Name, structName))
+			return sqlString(s)
+		case "bool":
+			return "TRUE"
+		}
+		return genPrimitiveValue(t.Name, f.Name, structName)
+	case "external":
+		if t.Name == "Time" || t.Name == "Timestamp" {
+			return "'2000-01-01 00:00:00'"
+		}
+	case "enum":
+		if e, ok := m.Enums[t.Name]; ok && len(e.Labels) > 0 {
+			return sqlString(e.Labels[0])
+		}
+	case "slice":
+		if t.Elem != nil && t.Elem.Name == "byte" {
+			return sqlString(f.Name)
+		}
+	}
+	return "NULL"
+}
+
+func sqlString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
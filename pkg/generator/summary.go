@@ -0,0 +1,185 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Summary describes what a generation run produced, so large runs can be audited
+type Summary struct {
+	Structs  int `json:"structs"`
+	Enums    int `json:"enums"`
+	OneOfs   int `json:"oneofs"`
+	TypeDefs int `json:"typedefs"`
+	Fields   int `json:"fields"`
+	// Skipped lists the types and fields that got no fixture or value
+	Skipped []Skipped `json:"skipped,omitempty"`
+	// Missing lists the fields whose value calls the fixture of a type that isn't generated, which
+	// has to be declared next to the output for it to compile
+	Missing []Skipped `json:"missing,omitempty"`
+	// Imports are the imports the generated file needs, as "path" or "alias path"
+	Imports []string `json:"imports,omitempty"`
+	// Bytes is the size of the generated file, set by the caller once it is written
+	Bytes int `json:"bytes"`
+}
+
+// Skipped is a type or field left out of the fixtures
+type Skipped struct {
+	Type   string `json:"type"`
+	Field  string `json:"field,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// Summarize counts the types of m that get fixtures and lists the fields whose fixture value is nil
+// or calls a fixture that isn't generated
+func Summarize(m *Model, opts GenerateOptions) *Summary {
+	if opts.TypeImport == "" {
+		opts.TypeImport = modelPackage(m)
+	}
+	s := &Summary{
+		Structs:  len(m.Structs),
+		TypeDefs: len(m.TypeDefs),
+	}
+	for _, imp := range collectImports(m, opts) {
+		s.Imports = append(s.Imports, strings.ReplaceAll(imp, `"`, ""))
+	}
+	for _, name := range sortedKeys(m.Enums) {
+		if enumHasValue(m.Enums[name]) {
+			s.Enums++
+		} else {
+			s.Skipped = append(s.Skipped, Skipped{Type: name, Reason: "enum has no values"})
+		}
+	}
+	for _, name := range sortedKeys(m.OneOfs) {
+		if m.OneOfs[name] != "" {
+			s.OneOfs++
+		}
+	}
+	for _, name := range sortedKeys(m.Structs) {
+		st := m.Structs[name]
+		for _, f := range st.Fields {
+			s.Fields++
+			// Foreign embedded values are set through their promoted fields
+			if _, ok := opts.FieldOverrides[name+"."+f.Name]; ok {
+				continue
+			}
+			if f.Default != "" || foreignEmbedded(st, f, opts) && f.Type.Kind != "pointer" {
+				continue
+			}
+			reason := skipReason(m, f.Type, opts)
+			switch {
+			case strings.HasPrefix(reason, missingFixture):
+				s.Missing = append(s.Missing, Skipped{Type: name, Field: f.Name, Reason: reason})
+			case reason != "":
+				s.Skipped = append(s.Skipped, Skipped{Type: name, Field: f.Name, Reason: reason})
+			}
+		}
+	}
+	return s
+}
+
+// missingFixture starts the reasons of fields whose value calls a fixture that isn't generated
+const missingFixture = "references missing fixture "
+
+// skipReason tells why a field of type t is left nil, or that its value references a missing fixture,
+// or returns "" if it gets a value
+func skipReason(m *Model, t TypeRef, opts GenerateOptions) string {
+	if foreignType(t, opts) {
+		if _, ok := depValue(m, t, opts, make(map[string]bool)); ok {
+			return ""
+		}
+		return "no fixture for " + t.Pkg + "." + t.Name
+	}
+	switch t.Kind {
+	case "unknown":
+		return "unsupported type"
+	case "pointer", "slice", "array":
+		if t.Elem == nil {
+			return "unsupported type"
+		}
+		return skipReason(m, *t.Elem, opts)
+	case "external":
+		if _, ok := lookupExternal(t, opts); !ok {
+			return "no default for external type " + t.Name
+		}
+	case "oneof":
+		if m.OneOfs[t.Name] == "" {
+			return "no implementation of oneof " + t.Name
+		}
+	case "interface":
+		if _, ok := lookupExternal(t, opts); !ok && interfaceValue(m, t, opts) == "nil" {
+			return "no implementation of interface " + typeName(t, opts)
+		}
+	case "struct":
+		if strings.HasPrefix(t.Name, "is") {
+			if m.OneOfs[t.Name] == "" {
+				return "no implementation of oneof " + t.Name
+			}
+			return ""
+		}
+		if _, ok := lookupExternal(t, opts); ok {
+			return ""
+		}
+		if _, ok := fixtureCall(t, opts); ok {
+			return ""
+		}
+		if _, ok := m.Structs[t.Name]; !ok && m.TypeDefs[t.Name] == nil {
+			return missingFixture + "Fixture" + opts.FuncPrefix + t.Name + "()"
+		}
+	case "enum":
+		if _, ok := lookupExternal(t, opts); ok {
+			return ""
+		}
+		if _, ok := fixtureCall(t, opts); ok {
+			return ""
+		}
+		if !HasFixture(m, t.Name) {
+			return missingFixture + "Fixture" + opts.FuncPrefix + t.Name + "()"
+		}
+	}
+	return ""
+}
+
+// enumHasValue reports whether an enum has a value its fixture can return
+func enumHasValue(e *Enum) bool {
+	for _, v := range e.Values {
+		if !InternalConstant(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// String renders the summary as a few lines of text
+func (s *Summary) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d structs, %d enums, %d oneofs, %d typedefs, %d fields, %s\n",
+		s.Structs, s.Enums, s.OneOfs, s.TypeDefs, s.Fields, byteSize(s.Bytes))
+	if len(s.Imports) > 0 {
+		fmt.Fprintf(&b, "imports: %s\n", strings.Join(s.Imports, ", "))
+	}
+	if len(s.Skipped) > 0 {
+		fmt.Fprintf(&b, "skipped %d:\n", len(s.Skipped))
+		for _, sk := range s.Skipped {
+			name := sk.Type
+			if sk.Field != "" {
+				name += "." + sk.Field
+			}
+			fmt.Fprintf(&b, "  %s: %s\n", name, sk.Reason)
+		}
+	}
+	if len(s.Missing) > 0 {
+		fmt.Fprintf(&b, "missing fixtures %d:\n", len(s.Missing))
+		for _, sk := range s.Missing {
+			fmt.Fprintf(&b, "  %s.%s: %s\n", sk.Type, sk.Field, sk.Reason)
+		}
+	}
+	return b.String()
+}
+
+func byteSize(n int) string {
+	if n < 1024 {
+		return fmt.Sprintf("%d B", n)
+	}
+	return fmt.Sprintf("%.1f KB", float64(n)/1024)
+}
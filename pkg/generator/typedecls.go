@@ -0,0 +1,83 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// structDecl renders the declaration of the struct s for DeclareTypes, its fields tagged with their
+// JSON keys and database columns
+func structDecl(s *Struct, opts GenerateOptions) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "type %s struct {\n", s.Name)
+	for _, f := range s.Fields {
+		fmt.Fprintf(&b, "\t%s %s", f.Name, declTypeName(f.Type, opts))
+		var tags []string
+		if f.JSONName != "" {
+			tags = append(tags, fmt.Sprintf("json:%q", f.JSONName))
+		}
+		if f.Column != "" {
+			tags = append(tags, fmt.Sprintf("db:%q", f.Column))
+		}
+		if len(tags) > 0 {
+			b.WriteString(" `" + strings.Join(tags, " ") + "`")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("}\n\n")
+	return b.String()
+}
+
+// declTypeName is typeName qualifying external types by their package, as the models of OpenAPI
+// documents and SQL schemas refer to time.Time by its name alone
+func declTypeName(t TypeRef, opts GenerateOptions) string {
+	switch t.Kind {
+	case "pointer", "slice":
+		if t.Elem != nil && !byteSlice(t) {
+			prefix := "*"
+			if t.Kind == "slice" {
+				prefix = "[]"
+			}
+			return prefix + declTypeName(*t.Elem, opts)
+		}
+	case "external":
+		if ext, ok := lookupExternal(t, opts); ok && ext.PkgPath != "" && !strings.Contains(t.Name, ".") {
+			return importName(ext.Import) + "." + t.Name
+		}
+	}
+	return typeName(t, opts)
+}
+
+// enumDecl renders the declaration of the enum e for DeclareTypes: a string type whose constants
+// hold the labels of the enum, or an int type counting up from 1 for enums without labels
+func enumDecl(e *Enum) string {
+	var b strings.Builder
+	if len(e.Labels) == len(e.Values) && len(e.Labels) > 0 {
+		fmt.Fprintf(&b, "type %s string\n\nconst (\n", e.Name)
+		for i, v := range e.Values {
+			fmt.Fprintf(&b, "\t%s %s = %q\n", v, e.Name, e.Labels[i])
+		}
+	} else {
+		fmt.Fprintf(&b, "type %s int\n\nconst (\n", e.Name)
+		for i, v := range e.Values {
+			fmt.Fprintf(&b, "\t%s %s = %d\n", v, e.Name, i+1)
+		}
+	}
+	b.WriteString(")\n\n")
+	return b.String()
+}
+
+// oneOfDecl renders the declaration of the oneof name for DeclareTypes: an interface with an
+// unexported method that the pointers to its variants implement
+func oneOfDecl(m *Model, name string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "type %s interface {\n\tis%s()\n}\n\n", name, name)
+	variants := m.Variants[name]
+	if len(variants) == 0 && m.OneOfs[name] != "" {
+		variants = []string{m.OneOfs[name]}
+	}
+	for _, v := range variants {
+		fmt.Fprintf(&b, "func (*%s) is%s() {}\n\n", v, name)
+	}
+	return b.String()
+}
@@ -0,0 +1,66 @@
+package generator
+
+import (
+	"go/ast"
+	"go/token"
+	"strings"
+)
+
+// TypeDocs returns the doc comments of the types declared in files by name, as text without comment
+// markers. Directives and marker lines such as +kubebuilder:validation:Required are left out.
+func TypeDocs(files []*ast.File) map[string]string {
+	docs := make(map[string]string)
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok || gd.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gd.Specs {
+				ts := spec.(*ast.TypeSpec)
+				doc := ts.Doc
+				// The comment of a single type declaration belongs to the GenDecl
+				if doc == nil && len(gd.Specs) == 1 {
+					doc = gd.Doc
+				}
+				if text := docText(doc); text != "" {
+					docs[ts.Name.Name] = text
+				}
+			}
+		}
+	}
+	return docs
+}
+
+// docText returns the text of a doc comment without marker lines
+func docText(doc *ast.CommentGroup) string {
+	var lines []string
+	for _, line := range strings.Split(doc.Text(), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "+") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// typeDocLines renders the doc of the type name as a paragraph of the doc comment of its fixture,
+// led by the type name unless the doc starts with it already
+func typeDocLines(name, doc string) string {
+	if doc == "" {
+		return ""
+	}
+	if !strings.HasPrefix(doc, name+" ") {
+		doc = name + ": " + doc
+	}
+	var b strings.Builder
+	b.WriteString("//\n")
+	for _, line := range strings.Split(doc, "\n") {
+		if line == "" {
+			b.WriteString("//\n")
+			continue
+		}
+		b.WriteString("// " + line + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,68 @@
+package generator
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+)
+
+// maxTypeErrors is how many type errors TypeCheck lists before summarizing the rest
+const maxTypeErrors = 10
+
+// Validate type-checks the fixtures generated for m as package pkgName, importing the packages they
+// use (the TypePrefix package among them) from source, so an uncompilable file is caught before it is
+// written. Fixtures generated into the package of their types need its files, see TypeCheck.
+func Validate(m *Model, pkgName string, opts GenerateOptions) error {
+	var b bytes.Buffer
+	if err := GenerateTo(&b, m, pkgName, opts); err != nil {
+		return err
+	}
+	fset := token.NewFileSet()
+	return TypeCheck(fset, b.Bytes(), nil, importer.ForCompiler(fset, "source", nil))
+}
+
+// TypeCheck type-checks the generated code together with files, the other files of its package parsed
+// with fset, resolving imports with imp. The error lists the type errors with their position and, in
+// the generated code, the line they are on: undefined fixtures, values of the wrong type, missing imports.
+func TypeCheck(fset *token.FileSet, code []byte, files []*ast.File, imp types.Importer) error {
+	f, err := parser.ParseFile(fset, "fixtures.go", code, 0)
+	if err != nil {
+		return fmt.Errorf("generated fixtures don't parse: %w", err)
+	}
+	var typeErrors []types.Error
+	conf := types.Config{
+		Importer: imp,
+		Error: func(err error) {
+			var te types.Error
+			if errors.As(err, &te) {
+				typeErrors = append(typeErrors, te)
+			}
+		},
+	}
+	conf.Check(f.Name.Name, fset, append(files[:len(files):len(files)], f), nil)
+	if len(typeErrors) == 0 {
+		return nil
+	}
+
+	lines := strings.Split(string(code), "\n")
+	var b strings.Builder
+	fmt.Fprintf(&b, "generated fixtures don't compile, %d type errors:", len(typeErrors))
+	for i, te := range typeErrors {
+		if i == maxTypeErrors {
+			fmt.Fprintf(&b, "\n... and %d more", len(typeErrors)-maxTypeErrors)
+			break
+		}
+		pos := fset.Position(te.Pos)
+		fmt.Fprintf(&b, "\n%s: %s", pos, te.Msg)
+		if pos.Filename == "fixtures.go" && pos.Line > 0 && pos.Line <= len(lines) {
+			fmt.Fprintf(&b, "\n\t%s", strings.TrimSpace(lines[pos.Line-1]))
+		}
+	}
+	return errors.New(b.String())
+}
@@ -0,0 +1,57 @@
+package generator
+
+import (
+	"fmt"
+	"go/format"
+	"strings"
+)
+
+// ProtovalidateImport is the protovalidate package used by GenerateValidationTests
+const ProtovalidateImport = "buf.build/go/protovalidate"
+
+// GenerateValidationTests renders a test file for package pkgName asserting that the default fixture
+// of every struct with a Validator method passes it. With protovalidate, the fixtures of all other
+// structs that are protobuf messages are checked with protovalidate.Validate.
+func GenerateValidationTests(m *Model, pkgName string, opts GenerateOptions, protovalidate bool) ([]byte, error) {
+	var b strings.Builder
+	if header := headerComment(opts); header != "" {
+		b.WriteString(header + "\n")
+	}
+	fmt.Fprintf(&b, "package %s\n\n", pkgName)
+	b.WriteString("import (\n\t\"testing\"\n")
+	if protovalidate {
+		fmt.Fprintf(&b, "\n\t%q\n\t\"google.golang.org/protobuf/proto\"\n", ProtovalidateImport)
+	}
+	b.WriteString(")\n\n")
+
+	b.WriteString("func TestFixturesValid(t *testing.T) {\n")
+	for _, name := range fixtureStructs(m) {
+		s := m.Structs[name]
+		if s.Validator == "" && !protovalidate {
+			continue
+		}
+		fixture := "Fixture" + opts.FuncPrefix + name + "()"
+		fmt.Fprintf(&b, "\tt.Run(%q, func(t *testing.T) {\n", name)
+		fmt.Fprintf(&b, "\t\tfixture := %s\n", fixture)
+		if s.Validator != "" {
+			fmt.Fprintf(&b, "\t\tif err := fixture.%s(); err != nil {\n\t\t\tt.Error(err)\n\t\t}\n", s.Validator)
+		} else if returnsPointer(opts) {
+			b.WriteString("\t\tvalidateFixture(t, fixture)\n")
+		} else {
+			b.WriteString("\t\tvalidateFixture(t, &fixture)\n")
+		}
+		b.WriteString("\t})\n")
+	}
+	b.WriteString("}\n")
+
+	if protovalidate {
+		b.WriteString("\n// validateFixture checks fixture with protovalidate if it is a protobuf message\n")
+		b.WriteString("func validateFixture(t *testing.T, fixture interface{}) {\n")
+		b.WriteString("\tt.Helper()\n")
+		b.WriteString("\tmsg, ok := fixture.(proto.Message)\n")
+		b.WriteString("\tif !ok {\n\t\tt.Skip(\"not a protobuf message\")\n\t}\n")
+		b.WriteString("\tif err := protovalidate.Validate(msg); err != nil {\n\t\tt.Error(err)\n\t}\n")
+		b.WriteString("}\n")
+	}
+	return format.Source([]byte(b.String()))
+}
@@ -0,0 +1,87 @@
+package generator
+
+const (
+	durationpbPath   = "google.golang.org/protobuf/types/known/durationpb"
+	wrapperspbPath   = "google.golang.org/protobuf/types/known/wrapperspb"
+	structpbPath     = "google.golang.org/protobuf/types/known/structpb"
+	fieldmaskpbPath  = "google.golang.org/protobuf/types/known/fieldmaskpb"
+	emptypbPath      = "google.golang.org/protobuf/types/known/emptypb"
+	wrapperspbImport = `wrapperspb "` + wrapperspbPath + `"`
+	structpbImport   = `structpb "` + structpbPath + `"`
+)
+
+// The well-known protobuf messages other than Timestamp and Any get the same values every run:
+// durations of a minute, wrappers of the value a field of the wrapped type would get, and empty
+// field masks. They are registered by import path as their names, like Duration or Value, are
+// common in other packages.
+func init() {
+	RegisterExternalType(durationpbPath, "Duration", ExternalType{
+		Import:   `durationpb "` + durationpbPath + `"`,
+		Requires: []string{`"time"`},
+		Value:    "durationpb.New(time.Minute)",
+		Pointer:  true,
+	})
+	for name, wrapped := range map[string]string{
+		"StringValue": "string",
+		"BoolValue":   "bool",
+		"Int32Value":  "int32",
+		"Int64Value":  "int64",
+		"UInt32Value": "uint32",
+		"UInt64Value": "uint64",
+		"FloatValue":  "float32",
+		"DoubleValue": "float64",
+	} {
+		RegisterExternalType(wrapperspbPath, name, ExternalType{
+			Import:    wrapperspbImport,
+			ValueFunc: wrapperValue(name, wrapped),
+			Pointer:   true,
+		})
+	}
+	RegisterExternalType(wrapperspbPath, "BytesValue", ExternalType{
+		Import: wrapperspbImport,
+		ValueFunc: func(m *Model, fieldName, structName string, opts GenerateOptions) string {
+			return "wrapperspb.Bytes(" + byteSliceValue(fieldName, structName, opts) + ")"
+		},
+		Pointer: true,
+	})
+	RegisterExternalType(structpbPath, "Struct", ExternalType{
+		Import: structpbImport,
+		ValueFunc: func(m *Model, fieldName, structName string, opts GenerateOptions) string {
+			return `&structpb.Struct{Fields: map[string]*structpb.Value{"` + fieldName + `": structpb.NewStringValue("` + fieldName + `")}}`
+		},
+		Pointer: true,
+	})
+	RegisterExternalType(structpbPath, "Value", ExternalType{
+		Import: structpbImport,
+		ValueFunc: func(m *Model, fieldName, structName string, opts GenerateOptions) string {
+			return `structpb.NewStringValue("` + fieldName + `")`
+		},
+		Pointer: true,
+	})
+	RegisterExternalType(structpbPath, "ListValue", ExternalType{
+		Import: structpbImport,
+		ValueFunc: func(m *Model, fieldName, structName string, opts GenerateOptions) string {
+			return `&structpb.ListValue{Values: []*structpb.Value{structpb.NewStringValue("` + fieldName + `")}}`
+		},
+		Pointer: true,
+	})
+	RegisterExternalType(fieldmaskpbPath, "FieldMask", ExternalType{
+		Import:  `fieldmaskpb "` + fieldmaskpbPath + `"`,
+		Value:   "&fieldmaskpb.FieldMask{}",
+		Pointer: true,
+	})
+	RegisterExternalType(emptypbPath, "Empty", ExternalType{
+		Import:  `emptypb "` + emptypbPath + `"`,
+		Value:   "&emptypb.Empty{}",
+		Pointer: true,
+	})
+}
+
+// wrapperValue returns the ValueFunc of the wrapperspb message name, wrapping the static value of
+// the Go type wrapped with the constructor named after the message, like wrapperspb.Int32
+func wrapperValue(name, wrapped string) func(m *Model, fieldName, structName string, opts GenerateOptions) string {
+	constructor := "wrapperspb." + name[:len(name)-len("Value")]
+	return func(m *Model, fieldName, structName string, opts GenerateOptions) string {
+		return constructor + "(" + genPrimitiveValue(wrapped, fieldName, structName) + ")"
+	}
+}
@@ -0,0 +1,102 @@
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Endpoint pairs an HTTP route with the types of its request and response bodies
+type Endpoint struct {
+	Method   string
+	Path     string // may contain {param} segments
+	Request  string // request body type, empty for requests without body
+	Response string
+}
+
+// ParseEndpoint parses "METHOD /path" and "Request:Response" (or just "Response") into an Endpoint
+func ParseEndpoint(route, types string) (Endpoint, error) {
+	method, path, ok := strings.Cut(strings.TrimSpace(route), " ")
+	path = strings.TrimSpace(path)
+	if !ok || method == "" || !strings.HasPrefix(path, "/") {
+		return Endpoint{}, fmt.Errorf("endpoint %q: want \"METHOD /path\"", route)
+	}
+	e := Endpoint{Method: strings.ToUpper(method), Path: path, Response: types}
+	if req, resp, ok := strings.Cut(types, ":"); ok {
+		e.Request, e.Response = req, resp
+	}
+	if e.Response == "" {
+		return Endpoint{}, fmt.Errorf("endpoint %q: no response type", route)
+	}
+	return e, nil
+}
+
+// Name returns a file name friendly identifier like "post-v1-users-id"
+func (e Endpoint) Name() string {
+	name := strings.ToLower(e.Method + "-" + e.Path)
+	name = nonWord.ReplaceAllString(name, "-")
+	return strings.Trim(name, "-")
+}
+
+var (
+	nonWord   = regexp.MustCompile(`[^a-z0-9]+`)
+	pathParam = regexp.MustCompile(`\{[^/}]+\}`)
+)
+
+// WireMockMapping renders a WireMock stub mapping for e: requests matching the route (and, if e has
+// a request type, a body equal to its fixture) are answered with the response fixture, both
+// generated with opts
+func WireMockMapping(m *Model, e Endpoint, opts GenerateOptions) ([]byte, error) {
+	response, err := JSONValue(m, e.Response, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	request := JSONObject{Values: make(map[string]any)}
+	add := func(obj *JSONObject, key string, v any) {
+		obj.Keys = append(obj.Keys, key)
+		obj.Values[key] = v
+	}
+	add(&request, "method", e.Method)
+	if pathParam.MatchString(e.Path) {
+		literals := pathParam.Split(e.Path, -1)
+		for i, lit := range literals {
+			literals[i] = regexp.QuoteMeta(lit)
+		}
+		add(&request, "urlPathPattern", strings.Join(literals, "[^/]+"))
+	} else {
+		add(&request, "urlPath", e.Path)
+	}
+	if e.Request != "" {
+		body, err := JSONValue(m, e.Request, opts)
+		if err != nil {
+			return nil, err
+		}
+		add(&request, "bodyPatterns", []any{JSONObject{
+			Keys:   []string{"equalToJson", "ignoreExtraElements"},
+			Values: map[string]any{"equalToJson": body, "ignoreExtraElements": true},
+		}})
+	}
+
+	mapping := JSONObject{
+		Keys: []string{"name", "request", "response"},
+		Values: map[string]any{
+			"name":    e.Method + " " + e.Path,
+			"request": request,
+			"response": JSONObject{
+				Keys: []string{"status", "headers", "jsonBody"},
+				Values: map[string]any{
+					"status":   200,
+					"headers":  map[string]string{"Content-Type": "application/json"},
+					"jsonBody": response,
+				},
+			},
+		},
+	}
+	data, err := json.MarshalIndent(mapping, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
@@ -0,0 +1,253 @@
+package generator
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// YAMLImport is the YAML package the LoadXFixture loaders decode with. It decodes through
+// encoding/json, so YAML keys match the json tags of the fields like the keys of YAMLFiles do.
+const YAMLImport = "sigs.k8s.io/yaml"
+
+// yamlImports are used by yamlLoaders
+var yamlImports = []string{`"os"`, `"path/filepath"`, `"testing"`, `"` + YAMLImport + `"`}
+
+// YAMLFile returns the name of the testdata file YAMLFiles writes the fixture of the struct to
+func YAMLFile(structName string) string {
+	return snakeCase(structName) + ".yaml"
+}
+
+// YAMLFiles renders the default fixture of every struct as a YAML file, keyed by its YAMLFile name.
+// Fields are keyed like encoding/json decodes them. Values YAML can't round-trip into the Go type, such
+// as enums, oneofs and external types other than time.Time, are left out: LoadXFixture keeps the
+// fixture's value for them. Field overrides, faker strings and slice lengths of opts are applied like
+// in the fixtures, so the files agree with them. Opaque API messages can't be decoded by
+// encoding/json and get no file.
+func YAMLFiles(m *Model, opts GenerateOptions) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	for _, name := range fixtureStructs(m) {
+		if m.Structs[name].Builder != "" {
+			continue
+		}
+		v, _ := yamlValue(m, TypeRef{Kind: "struct", Name: name}, name, name, opts, make(map[string]bool))
+		var b strings.Builder
+		if err := writeYAML(&b, v, 0); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		files[YAMLFile(name)] = []byte(b.String())
+	}
+	return files, nil
+}
+
+// yamlValue returns the JSON-encodable value of the fixture of t, or false if YAML can't hold it
+func yamlValue(m *Model, t TypeRef, fieldName, structName string, opts GenerateOptions, path map[string]bool) (any, bool) {
+	if td, ok := m.TypeDefs[t.Name]; ok && t.Kind != "primitive" {
+		t = td.Underlying
+	}
+	switch t.Kind {
+	case "primitive":
+		v := jsonValue(m, t, fieldName, structName, opts, path)
+		return v, v != nil
+	case "pointer":
+		if t.Elem == nil {
+			return nil, false
+		}
+		return yamlValue(m, *t.Elem, fieldName, structName, opts, path)
+	case "slice":
+		if t.Elem == nil {
+			return nil, false
+		}
+		if byteSlice(t) {
+			return jsonValue(m, t, fieldName, structName, opts, path), true
+		}
+		return yamlSlice(m, t, fieldName, structName, max(opts.SliceLen, 1), opts, path)
+	case "array":
+		if t.Elem == nil || t.Len == 0 {
+			return nil, false
+		}
+		if t.Elem.Kind == "primitive" {
+			return jsonValue(m, t, fieldName, structName, opts, path), true
+		}
+		// The elements after the first decode to zero values
+		elem, ok := yamlValue(m, *t.Elem, fieldName, structName, opts, path)
+		return []any{elem}, ok
+	case "external":
+		if t.Name == "Time" && (t.Pkg == "" || t.Pkg == "time") {
+			return "2000-01-01T00:00:00Z", true
+		}
+	case "struct":
+		s, ok := m.Structs[t.Name]
+		if !ok || strings.HasPrefix(t.Name, "is") || path[t.Name] || s.Builder != "" {
+			return nil, false
+		}
+		path[t.Name] = true
+		defer delete(path, t.Name)
+
+		obj := JSONObject{Values: make(map[string]any)}
+		for _, f := range s.Fields {
+			key := f.jsonKey()
+			if key == "-" {
+				continue
+			}
+			var v any
+			var ok bool
+			// Values that aren't literals are left out, keeping the fixture's
+			if override, overridden := opts.FieldOverrides[s.Name+"."+f.Name]; overridden {
+				v = jsonLiteral(override)
+				ok = v != nil
+			} else if f.Default != "" && jsonLiteral(f.Default) != nil {
+				v, ok = jsonLiteral(f.Default), true
+			} else if n, sized := fieldLen(f, s.Name, opts); sized && f.Type.Kind == "slice" && f.Type.Elem != nil && !byteSlice(f.Type) {
+				v, ok = yamlSlice(m, f.Type, f.Name, s.Name, n, opts, path)
+			} else {
+				v, ok = yamlValue(m, f.Type, f.Name, s.Name, opts, path)
+			}
+			if !ok {
+				continue
+			}
+			// encoding/json promotes the fields of untagged embedded structs, unless the outer struct
+			// declares them itself
+			if embedded, isObj := v.(JSONObject); f.Embedded && f.JSONName == "" && isObj {
+				for _, k := range embedded.Keys {
+					if _, taken := obj.Values[k]; !taken {
+						obj.Keys = append(obj.Keys, k)
+						obj.Values[k] = embedded.Values[k]
+					}
+				}
+				continue
+			}
+			if _, taken := obj.Values[key]; !taken {
+				obj.Keys = append(obj.Keys, key)
+			}
+			obj.Values[key] = v
+		}
+		return obj, true
+	}
+	return nil, false
+}
+
+// yamlSlice returns the values of the slice type t holding n elements like jsonSlice, or false if
+// YAML can't hold them
+func yamlSlice(m *Model, t TypeRef, fieldName, structName string, n int, opts GenerateOptions, path map[string]bool) (any, bool) {
+	values := make([]any, n)
+	for i := range values {
+		if lit, ok := sliceElem(*t.Elem, fieldName, structName, i, n, opts); ok {
+			values[i] = jsonLiteral(lit)
+			continue
+		}
+		v, ok := yamlValue(m, *t.Elem, fieldName, structName, opts, path)
+		if !ok {
+			return nil, false
+		}
+		indexedJSON(m, v, *t.Elem, i, n, opts)
+		values[i] = v
+	}
+	return values, true
+}
+
+// plainYAMLKey matches keys written without quotes
+var plainYAMLKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// writeYAML writes v as block YAML indented by indent levels. Scalars are written as JSON, which
+// YAML reads the same, so strings like "yes" or "1" stay strings.
+func writeYAML(b *strings.Builder, v any, indent int) error {
+	pad := strings.Repeat("  ", indent)
+	switch v := v.(type) {
+	case JSONObject:
+		if len(v.Keys) == 0 {
+			b.WriteString(pad + "{}\n")
+		}
+		for _, key := range v.Keys {
+			k := key
+			if !plainYAMLKey.MatchString(k) {
+				data, _ := marshalJSON(k)
+				k = string(data)
+			}
+			if err := writeYAMLEntry(b, pad+k+":", v.Values[key], indent); err != nil {
+				return err
+			}
+		}
+	case []any:
+		if len(v) == 0 {
+			b.WriteString(pad + "[]\n")
+		}
+		for _, elem := range v {
+			if err := writeYAMLEntry(b, pad+"-", elem, indent); err != nil {
+				return err
+			}
+		}
+	default:
+		data, err := marshalJSON(v)
+		if err != nil {
+			return err
+		}
+		b.WriteString(pad + string(data) + "\n")
+	}
+	return nil
+}
+
+// writeYAMLEntry writes a mapping key or sequence dash followed by v, nested below it unless v is a
+// scalar or empty
+func writeYAMLEntry(b *strings.Builder, prefix string, v any, indent int) error {
+	switch v := v.(type) {
+	case JSONObject:
+		if len(v.Keys) > 0 {
+			b.WriteString(prefix + "\n")
+			return writeYAML(b, v, indent+1)
+		}
+		b.WriteString(prefix + " {}\n")
+		return nil
+	case []any:
+		if len(v) > 0 {
+			b.WriteString(prefix + "\n")
+			return writeYAML(b, v, indent+1)
+		}
+		b.WriteString(prefix + " []\n")
+		return nil
+	}
+	data, err := marshalJSON(v)
+	if err != nil {
+		return err
+	}
+	b.WriteString(prefix + " " + string(data) + "\n")
+	return nil
+}
+
+// yamlLoaders renders a LoadXFixture(t, name) per struct decoding testdata/<name> over its default
+// fixture, so a file only has to hold the fields a test changes
+func yamlLoaders(m *Model, opts GenerateOptions) string {
+	var b strings.Builder
+	b.WriteString(`// loadYAMLFixture decodes the YAML file testdata/<name> into value, failing the test if it can't
+func loadYAMLFixture(t testing.TB, name string, value interface{}) {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := yaml.Unmarshal(data, value); err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+}
+
+`)
+	for _, name := range fixtureStructs(m) {
+		if m.Structs[name].Builder != "" {
+			continue
+		}
+		typ := typeName(TypeRef{Kind: "struct", Name: name}, opts)
+		fn := "Load" + opts.FuncPrefix + name + "Fixture"
+		result, addr := typ, "&"
+		if returnsPointer(opts) {
+			result, addr = "*"+typ, ""
+		}
+		fmt.Fprintf(&b, "// %s returns the fixture of [%s] with the fields set in the YAML file testdata/<name>,\n", fn, typ)
+		fmt.Fprintf(&b, "// e.g. a copy of the generated %s.\n", YAMLFile(name))
+		fmt.Fprintf(&b, "func %s(t testing.TB, name string) %s {\n", fn, result)
+		fmt.Fprintf(&b, "\tt.Helper()\n")
+		fmt.Fprintf(&b, "\tvalue := Fixture%s%s()\n", opts.FuncPrefix, name)
+		fmt.Fprintf(&b, "\tloadYAMLFixture(t, name, %svalue)\n", addr)
+		fmt.Fprintf(&b, "\treturn value\n}\n\n")
+	}
+	return b.String()
+}
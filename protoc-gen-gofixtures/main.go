@@ -0,0 +1,80 @@
+// Command protoc-gen-gofixtures is a protoc and buf plugin generating fixtures for the messages of the
+// files it is run on, in the same codegen pipeline as protoc-gen-go:
+//
+//	protoc --go_out=. --gofixtures_out=. --gofixtures_opt=prefix=PB,style=mod,outpkg=fixtures shop/v1/order.proto
+//
+// It writes one file per Go package, <file>_fixtures.pb.go next to the .pb.go of its first file, or in
+// the outpkg subdirectory when outpkg names another package than the messages'.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"path"
+
+	"fixture-generator/pkg/generator"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func main() {
+	var flags flag.FlagSet
+	prefix := flags.String("prefix", "", "inserted into fixture function names (e.g. 'PB' -> FixturePBOrder)")
+	style := flags.String("style", "mod", "fixture style: 'mod' (functional options) or 'classic'")
+	outPkg := flags.String("outpkg", "", "package of the fixtures (default: the package of the messages)")
+	protogen.Options{ParamFunc: flags.Set}.Run(func(gen *protogen.Plugin) error {
+		gen.SupportedFeatures = uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL | pluginpb.CodeGeneratorResponse_FEATURE_SUPPORTS_EDITIONS)
+		gen.SupportedEditionsMinimum = descriptorpb.Edition_EDITION_PROTO2
+		gen.SupportedEditionsMaximum = descriptorpb.Edition_EDITION_2023
+		if *style != "mod" && *style != "classic" {
+			return fmt.Errorf("style must be 'mod' or 'classic', not %q", *style)
+		}
+		files := resolvedFiles(gen)
+		done := make(map[protogen.GoImportPath]bool)
+		for _, f := range gen.Files {
+			if !f.Generate || done[f.GoImportPath] {
+				continue
+			}
+			// All files of a package share one output, so helpers are declared once
+			done[f.GoImportPath] = true
+			opts := generator.GenerateOptions{
+				TypeImport: string(f.GoImportPath),
+				FuncPrefix: *prefix,
+				ModStyle:   *style == "mod",
+				Header:     "Code generated by protoc-gen-gofixtures. DO NOT EDIT.\nsource: " + f.Desc.Path(),
+			}
+			pkg, dir := string(f.GoPackageName), path.Dir(f.GeneratedFilenamePrefix)
+			if *outPkg != "" && *outPkg != pkg {
+				opts.TypePrefix = pkg
+				pkg, dir = *outPkg, path.Join(dir, *outPkg)
+			}
+			code, err := generator.GenerateFormattedWithOptions(generator.ParseDescriptors(files, string(f.GoImportPath)), pkg, opts)
+			if err != nil {
+				return fmt.Errorf("%s: %w", f.Desc.Path(), err)
+			}
+			g := gen.NewGeneratedFile(path.Join(dir, path.Base(f.GeneratedFilenamePrefix)+"_fixtures.pb.go"), "")
+			if _, err := g.Write([]byte(code)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// resolvedFiles returns the descriptors of the request with the Go packages protogen resolved for them,
+// so M options and import_path apply to the fixtures as they do to the .pb.go files
+func resolvedFiles(gen *protogen.Plugin) []*descriptorpb.FileDescriptorProto {
+	var files []*descriptorpb.FileDescriptorProto
+	for _, f := range gen.Files {
+		fd := proto.Clone(f.Proto).(*descriptorpb.FileDescriptorProto)
+		if fd.Options == nil {
+			fd.Options = &descriptorpb.FileOptions{}
+		}
+		fd.Options.GoPackage = proto.String(string(f.GoImportPath) + ";" + string(f.GoPackageName))
+		files = append(files, fd)
+	}
+	return files
+}